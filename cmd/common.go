@@ -9,6 +9,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/dongdio/OpenList/v4/initialize"
+	"github.com/dongdio/OpenList/v4/internal/cache_backend"
 	"github.com/dongdio/OpenList/v4/internal/db"
 	"github.com/dongdio/OpenList/v4/utility/utils"
 )
@@ -40,6 +41,7 @@ func Init() {
 
 // Release performs cleanup operations before application shutdown
 func Release() {
+	cache_backend.Close()
 	db.Close()
 }
 
@@ -45,6 +45,11 @@ const (
 	ForwardDirectLinkParams = "forward_direct_link_params"
 	IgnoreDirectLinkParams  = "ignore_direct_link_params"
 	WebauthnLoginEnabled    = "webauthn_login_enabled"
+	AuditRetentionDays      = "audit_retention_days"
+	AuthMaxRetries          = "auth_max_retries"
+	AuthLockDuration        = "auth_lock_duration"
+	TwoFactorRequiredAdmins = "two_factor_required_admins"
+	TwoFactorGraceDays      = "two_factor_grace_days"
 
 	// index
 	SearchIndex     = "search_index"
@@ -97,6 +102,11 @@ const (
 	SSODefaultDir        = "sso_default_dir"
 	SSODefaultPermission = "sso_default_permission"
 	SSOCompatibilityMode = "sso_compatibility_mode"
+	SSOGroupClaim        = "sso_group_claim"
+
+	// scim
+	SCIMEnabled = "scim_enabled"
+	SCIMToken   = "scim_token"
 
 	// ldap
 	LdapLoginEnabled      = "ldap_login_enabled"
@@ -108,6 +118,7 @@ const (
 	LdapDefaultPermission = "ldap_default_permission"
 	LdapDefaultDir        = "ldap_default_dir"
 	LdapLoginTips         = "ldap_login_tips"
+	LdapGroupAttribute    = "ldap_group_attribute"
 
 	// s3
 	S3Buckets         = "s3_buckets"
@@ -135,6 +146,7 @@ const (
 	TaskMoveThreadsNum                    = "move_task_threads_num"
 	TaskDecompressDownloadThreadsNum      = "decompress_download_task_threads_num"
 	TaskDecompressUploadThreadsNum        = "decompress_upload_task_threads_num"
+	TaskSyncThreadsNum                    = "sync_task_threads_num"
 	StreamMaxClientDownloadSpeed          = "max_client_download_speed"
 	StreamMaxClientUploadSpeed            = "max_client_upload_speed"
 	StreamMaxServerDownloadSpeed          = "max_server_download_speed"
@@ -166,8 +178,24 @@ const (
 	RequestHeaderKey
 	UserAgentKey
 	PathKey
+	CheckpointKey
+	VerifyKey
+	QuotaAccountedKey
+	SessionIDKey
+	ProtocolKey
+	ShareKey
+	FileRequestKey
+	WebDAVLockTokensKey
+	S3KeyKey
 )
 
+// TrashDir is the folder at the root of a storage keeping the removed objs when the trash is enabled
+const TrashDir = ".trash"
+
+// S3MultipartDir is the folder in the temp dir staging the parts of the S3 multipart uploads,
+// it's kept when the temp dir is cleaned so the uploads can go on after a restart
+const S3MultipartDir = "s3_multipart"
+
 const (
 	ChromeUserAgent = "Mozilla/5.0 (Macintosh; Apple macOS 15_5) AppleWebKit/537.36 (KHTML, like Gecko) Safari/537.36 Chrome/138.0.0.0"
 )
\ No newline at end of file
@@ -444,4 +444,30 @@ func (d *Alias) ArchiveDecompress(ctx context.Context, srcObj, dstDir model.Obj,
 	}
 }
 
+func (d *Alias) ResolveCapabilities(ctx context.Context, path string) (driver.Capabilities, error) {
+	root, sub := d.getRootAndPath(path)
+	dsts, ok := d.pathMap[root]
+	if !ok {
+		// the virtual root listing the alias names
+		return driver.GetCapabilities(d).ReadOnly(), nil
+	}
+	var caps driver.Capabilities
+	for i, dst := range dsts {
+		c := fs.GetCapabilities(ctx, stdpath.Join(dst, sub))
+		switch {
+		case i == 0:
+			caps = c
+		case d.ParallelWrite:
+			// every path is written, all of them need the capability
+			caps = caps.And(c)
+		default:
+			caps = caps.Or(c)
+		}
+	}
+	if !d.Writable {
+		caps = caps.ReadOnly()
+	}
+	return caps, nil
+}
+
 var _ driver.Driver = (*Alias)(nil)
\ No newline at end of file
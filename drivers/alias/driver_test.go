@@ -0,0 +1,76 @@
+package alias_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	_ "github.com/dongdio/OpenList/v4/drivers/alias"
+	_ "github.com/dongdio/OpenList/v4/drivers/local"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/fs"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestResolveCapabilities(t *testing.T) {
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/cap_local",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, t.TempDir()),
+	})
+	if err != nil {
+		t.Fatalf("failed create local storage: %+v", err)
+	}
+	aliases := []struct {
+		mountPath string
+		addition  string
+		put       bool
+	}{
+		// the missing path has no capabilities, either path may be written
+		{"/alias_or", `{"paths":"d:/cap_local\nd:/nowhere","writable":true}`, true},
+		// every path is written, the missing one can't be
+		{"/alias_and", `{"paths":"d:/cap_local\nd:/nowhere","writable":true,"parallel_write":true}`, false},
+		{"/alias_ro", `{"paths":"d:/cap_local"}`, false},
+	}
+	for _, a := range aliases {
+		_, err = op.CreateStorage(ctx, model.Storage{Driver: "Alias", MountPath: a.mountPath, Addition: a.addition})
+		if err != nil {
+			t.Fatalf("failed create alias %s: %+v", a.mountPath, err)
+		}
+		caps := fs.GetCapabilities(ctx, a.mountPath)
+		if caps.Put != a.put || caps.Mkdir != a.put || caps.Remove != a.put {
+			t.Errorf("%s: expected write capabilities %v, got %+v", a.mountPath, a.put, caps)
+		}
+	}
+
+	// the virtual root listing several alias names is read only
+	_, err = op.CreateStorage(ctx, model.Storage{
+		Driver:    "Alias",
+		MountPath: "/alias_multi",
+		Addition:  `{"paths":"a:/cap_local\nb:/cap_local","writable":true}`,
+	})
+	if err != nil {
+		t.Fatalf("failed create alias: %+v", err)
+	}
+	if caps := fs.GetCapabilities(ctx, "/alias_multi"); caps.Put || caps.Mkdir {
+		t.Errorf("expected the virtual root to be read only, got %+v", caps)
+	}
+	if caps := fs.GetCapabilities(ctx, "/alias_multi/a"); !caps.Put || !caps.Mkdir {
+		t.Errorf("expected a path of the alias to be writable, got %+v", caps)
+	}
+}
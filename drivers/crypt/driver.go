@@ -413,4 +413,15 @@ func (d *Crypt) Put(ctx context.Context, dstDir model.Obj, streamer model.FileSt
 //	return nil, errs.NotSupport
 // }
 
+func (d *Crypt) ResolveCapabilities(ctx context.Context, path string) (driver.Capabilities, error) {
+	if d.remoteStorage == nil || d.cipher == nil {
+		return driver.Capabilities{}, errs.New("crypt storage not init")
+	}
+	remoteActualPath, err := d.getActualPathForRemote(path, true)
+	if err != nil {
+		return driver.Capabilities{}, errs.Wrap(err, "failed to convert path to remote path")
+	}
+	return op.GetStorageCapabilities(ctx, d.remoteStorage, remoteActualPath), nil
+}
+
 var _ driver.Driver = (*Crypt)(nil)
\ No newline at end of file
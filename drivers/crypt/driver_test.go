@@ -0,0 +1,50 @@
+package crypt_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	_ "github.com/dongdio/OpenList/v4/drivers/alias"
+	_ "github.com/dongdio/OpenList/v4/drivers/crypt"
+	_ "github.com/dongdio/OpenList/v4/drivers/local"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/fs"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestResolveCapabilities(t *testing.T) {
+	ctx := context.Background()
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/remote", Addition: fmt.Sprintf(`{"root_folder_path":%q}`, t.TempDir())},
+		{Driver: "Alias", MountPath: "/remote_ro", Addition: `{"paths":"d:/remote"}`},
+		{Driver: "Crypt", MountPath: "/crypt", Addition: `{"remote_path":"/remote","password":"pw","encrypted_suffix":".bin","filename_encryption":"off","directory_name_encryption":"false"}`},
+		{Driver: "Crypt", MountPath: "/crypt_ro", Addition: `{"remote_path":"/remote_ro","password":"pw","encrypted_suffix":".bin","filename_encryption":"off","directory_name_encryption":"false"}`},
+	}
+	for _, s := range storages {
+		if _, err := op.CreateStorage(ctx, s); err != nil {
+			t.Fatalf("failed create storage %s: %+v", s.MountPath, err)
+		}
+	}
+	if caps := fs.GetCapabilities(ctx, "/crypt"); !caps.Put || !caps.Mkdir || !caps.Remove {
+		t.Errorf("expected the capabilities of the writable remote, got %+v", caps)
+	}
+	// the remote is a read only alias, the crypt storage can't write through it
+	if caps := fs.GetCapabilities(ctx, "/crypt_ro"); caps.Put || caps.Mkdir || caps.Remove {
+		t.Errorf("expected the capabilities of the read only remote, got %+v", caps)
+	}
+}
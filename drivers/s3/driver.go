@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/url"
 	stdpath "path"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -21,7 +23,9 @@ import (
 	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/http_range"
 	"github.com/dongdio/OpenList/v4/utility/stream"
+	"github.com/dongdio/OpenList/v4/utility/utils"
 )
 
 type S3 struct {
@@ -194,9 +198,7 @@ func (d *S3) Remove(ctx context.Context, obj model.Obj) error {
 
 func (d *S3) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
 	uploader := s3manager.NewUploader(d.Session)
-	if s.GetSize() > s3manager.MaxUploadParts*s3manager.DefaultUploadPartSize {
-		uploader.PartSize = s.GetSize() / (s3manager.MaxUploadParts - 1)
-	}
+	uploader.PartSize = getPartSize(s.GetSize())
 	key := getKey(stdpath.Join(dstDir.GetPath(), s.GetName()), false)
 	contentType := s.GetMimetype()
 	log.Debugln("key:", key)
@@ -213,4 +215,102 @@ func (d *S3) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up
 	return err
 }
 
-var _ driver.Driver = (*S3)(nil)
\ No newline at end of file
+func (d *S3) PutResumable(ctx context.Context, dstDir model.Obj, s model.FileStreamer, cp *model.TransferCheckpoint, up driver.UpdateProgress) (model.Obj, error) {
+	size := s.GetSize()
+	partSize := getPartSize(size)
+	// a single part upload can't be resumed
+	if size <= partSize {
+		return nil, d.Put(ctx, dstDir, s, up)
+	}
+	key := getKey(stdpath.Join(dstDir.GetPath(), s.GetName()), false)
+	parts := d.resumedParts(ctx, key, partSize, cp)
+	if len(parts) == 0 {
+		contentType := s.GetMimetype()
+		output, err := d.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      &d.Bucket,
+			Key:         &key,
+			ContentType: &contentType,
+		})
+		if err != nil {
+			return nil, errs.Wrap(err, "failed create multipart upload")
+		}
+		cp.Start(*output.UploadId)
+		cp.SetExtra(checkpointKey, key)
+	} else {
+		log.Debugf("resume multipart upload of [%s] from part %d", key, len(parts)+1)
+	}
+	uploadID := cp.GetSessionID()
+
+	completed := make([]*s3.CompletedPart, 0, (size+partSize-1)/partSize)
+	var offset int64
+	for _, part := range parts {
+		completed = append(completed, &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(int64(part.Number)),
+		})
+		offset = part.Offset + part.Size
+	}
+	up(float64(offset) * 100 / float64(size))
+	buf := make([]byte, partSize)
+	for number := len(parts) + 1; offset < size; number++ {
+		if utils.IsCanceled(ctx) {
+			return nil, ctx.Err()
+		}
+		length := min(partSize, size-offset)
+		reader, err := s.RangeRead(http_range.Range{Start: offset, Length: length})
+		if err != nil {
+			return nil, errs.Wrapf(err, "failed read part %d", number)
+		}
+		if _, err = io.ReadFull(reader, buf[:length]); err != nil {
+			return nil, errs.Wrapf(err, "failed read part %d", number)
+		}
+		if err = driver.ServerUploadLimitWaitN(ctx, int(length)); err != nil {
+			return nil, err
+		}
+		output, err := d.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+			Bucket:     &d.Bucket,
+			Key:        &key,
+			UploadId:   &uploadID,
+			PartNumber: aws.Int64(int64(number)),
+			Body:       bytes.NewReader(buf[:length]),
+		})
+		if err != nil {
+			return nil, errs.Wrapf(err, "failed upload part %d", number)
+		}
+		cp.Commit(model.CheckpointPart{
+			Number: number,
+			Offset: offset,
+			Size:   length,
+			ETag:   aws.StringValue(output.ETag),
+		})
+		completed = append(completed, &s3.CompletedPart{
+			ETag:       output.ETag,
+			PartNumber: aws.Int64(int64(number)),
+		})
+		offset += length
+		up(float64(offset) * 100 / float64(size))
+	}
+	_, err := d.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &d.Bucket,
+		Key:             &key,
+		UploadId:        &uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, "failed complete multipart upload")
+	}
+	return nil, nil
+}
+
+// AbortResumable aborts the multipart upload of cp, the parts uploaded are dropped by the bucket
+func (d *S3) AbortResumable(ctx context.Context, cp *model.TransferCheckpoint) error {
+	uploadID, key := cp.GetSessionID(), cp.GetExtra(checkpointKey)
+	if uploadID == "" || key == "" {
+		return nil
+	}
+	cp.Reset()
+	return d.abortMultipartUpload(ctx, key, uploadID)
+}
+
+var _ driver.Driver = (*S3)(nil)
+var _ driver.ResumablePut = (*S3)(nil)
\ No newline at end of file
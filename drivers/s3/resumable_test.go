@@ -0,0 +1,220 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/itsHenry35/gofakes3"
+	"github.com/itsHenry35/gofakes3/s3mem"
+	"golang.org/x/time/rate"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/stream"
+)
+
+const testPartSize = s3manager.DefaultUploadPartSize
+
+// newTestS3 returns an S3 driver on an in-memory server and counts the uploaded parts
+func newTestS3(t *testing.T) (*S3, *atomic.Int32) {
+	stream.ServerUploadLimit = rate.NewLimiter(rate.Inf, 0)
+	faker := gofakes3.New(s3mem.New())
+	var partUploads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") != "" {
+			partUploads.Add(1)
+		}
+		faker.Server().ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	d := &S3{Addition: Addition{
+		Bucket:          "test",
+		Endpoint:        srv.URL,
+		AccessKeyID:     "test-access-key",
+		SecretAccessKey: "test-secret-key",
+		ForcePathStyle:  true,
+	}}
+	if err := d.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(d.Bucket)}); err != nil {
+		t.Fatal(err)
+	}
+	return d, &partUploads
+}
+
+func testFile(data []byte) model.FileStreamer {
+	return &stream.FileStream{
+		Obj:    &model.Object{Name: "a.bin", Size: int64(len(data))},
+		Reader: bytes.NewReader(data),
+	}
+}
+
+func putResumable(ctx context.Context, d *S3, data []byte, cp *model.TransferCheckpoint) error {
+	_, err := d.PutResumable(ctx, &model.Object{Path: "/", IsFolder: true}, testFile(data), cp, func(float64) {})
+	return err
+}
+
+func checkObject(t *testing.T, d *S3, data []byte) {
+	t.Helper()
+	output, err := d.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(d.Bucket), Key: aws.String("a.bin")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Body.Close()
+	got, err := io.ReadAll(output.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("expected the uploaded object to be the file, got %d bytes", len(got))
+	}
+}
+
+func testData() []byte {
+	data := make([]byte, 2*testPartSize+1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func TestPutResumable(t *testing.T) {
+	d, partUploads := newTestS3(t)
+	data := testData()
+
+	// the upload is interrupted once the first part is committed
+	ctx, cancel := context.WithCancel(context.Background())
+	cp := model.NewTransferCheckpoint("fp")
+	var commits int
+	cp.SetCommitFunc(func() {
+		commits++
+		if len(cp.GetParts()) == 1 {
+			cancel()
+		}
+	})
+	if err := putResumable(ctx, d, data, cp); err == nil {
+		t.Fatal("expected the canceled upload to fail")
+	}
+	sessionID := cp.GetSessionID()
+	if sessionID == "" || len(cp.GetParts()) != 1 || cp.Uploaded != testPartSize {
+		t.Fatalf("expected one committed part, got %+v", cp)
+	}
+
+	// the retry continues the session after the committed part
+	partUploads.Store(0)
+	if err := putResumable(context.Background(), d, data, cp); err != nil {
+		t.Fatalf("failed resume: %+v", err)
+	}
+	if cp.GetSessionID() != sessionID {
+		t.Errorf("expected the session %s to be resumed, got %s", sessionID, cp.GetSessionID())
+	}
+	if n := partUploads.Load(); n != 2 {
+		t.Errorf("expected the 2 remaining parts to be uploaded, got %d", n)
+	}
+	if commits != 4 {
+		t.Errorf("expected the checkpoint to be saved on start and each part, got %d", commits)
+	}
+	checkObject(t, d, data)
+}
+
+func TestPutResumableSessionLost(t *testing.T) {
+	d, partUploads := newTestS3(t)
+	data := testData()
+
+	// the session was completed or aborted on the remote
+	cp := model.NewTransferCheckpoint("fp")
+	cp.Start("gone")
+	cp.Commit(model.CheckpointPart{Number: 1, Offset: 0, Size: testPartSize, ETag: `"etag"`})
+	if err := putResumable(context.Background(), d, data, cp); err != nil {
+		t.Fatalf("failed start over: %+v", err)
+	}
+	if id := cp.GetSessionID(); id == "" || id == "gone" {
+		t.Errorf("expected a new session, got %q", id)
+	}
+	if n := partUploads.Load(); n != 3 {
+		t.Errorf("expected all 3 parts to be uploaded, got %d", n)
+	}
+	checkObject(t, d, data)
+}
+
+func TestPutResumableFingerprintMismatch(t *testing.T) {
+	d, partUploads := newTestS3(t)
+	data := testData()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cp := model.NewTransferCheckpoint("old")
+	cp.SetCommitFunc(func() {
+		if len(cp.GetParts()) == 1 {
+			cancel()
+		}
+	})
+	_ = putResumable(ctx, d, make([]byte, len(data)), cp)
+	sessionID := cp.GetSessionID()
+	if sessionID == "" {
+		t.Fatal("expected the upload to be started")
+	}
+
+	// the src file changed, the upload of the old one is aborted and its checkpoint is dropped
+	if err := d.AbortResumable(context.Background(), cp); err != nil {
+		t.Fatalf("failed abort: %+v", err)
+	}
+	if _, err := d.client.ListParts(&s3.ListPartsInput{Bucket: aws.String(d.Bucket), Key: aws.String("a.bin"), UploadId: aws.String(sessionID)}); err == nil {
+		t.Error("expected the old upload to be aborted")
+	}
+	cp = model.ResumeCheckpoint(cp, "new")
+	partUploads.Store(0)
+	if err := putResumable(context.Background(), d, data, cp); err != nil {
+		t.Fatalf("failed upload: %+v", err)
+	}
+	if cp.GetSessionID() == sessionID {
+		t.Error("expected a new session")
+	}
+	if n := partUploads.Load(); n != 3 {
+		t.Errorf("expected all 3 parts to be uploaded, got %d", n)
+	}
+	checkObject(t, d, data)
+}
+
+func TestPutResumableStaleParts(t *testing.T) {
+	d, partUploads := newTestS3(t)
+	data := testData()
+
+	// the committed part doesn't match the one on the remote
+	old := make([]byte, len(data))
+	ctx, cancel := context.WithCancel(context.Background())
+	cp := model.NewTransferCheckpoint("old")
+	cp.SetCommitFunc(func() {
+		if len(cp.GetParts()) == 1 {
+			cancel()
+		}
+	})
+	_ = putResumable(ctx, d, old, cp)
+	sessionID := cp.GetSessionID()
+	parts := cp.GetParts()
+	if len(parts) != 1 {
+		t.Fatalf("expected one committed part, got %+v", parts)
+	}
+
+	cp.SetCommitFunc(nil)
+	cp.Truncate(0)
+	parts[0].ETag = `"other"`
+	cp.Commit(parts[0])
+	partUploads.Store(0)
+	if err := putResumable(context.Background(), d, data, cp); err != nil {
+		t.Fatalf("failed start over: %+v", err)
+	}
+	if cp.GetSessionID() == sessionID {
+		t.Error("expected a new session")
+	}
+	if n := partUploads.Load(); n != 3 {
+		t.Errorf("expected all 3 parts to be uploaded, got %d", n)
+	}
+	checkObject(t, d, data)
+}
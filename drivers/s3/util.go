@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/dongdio/OpenList/v4/internal/model"
@@ -256,4 +257,71 @@ func (d *S3) removeFile(src string) error {
 	}
 	_, err := d.client.DeleteObject(input)
 	return err
-}
\ No newline at end of file
+}
+
+// getPartSize keeps the number of parts under s3manager.MaxUploadParts
+func getPartSize(size int64) int64 {
+	if size > s3manager.MaxUploadParts*s3manager.DefaultUploadPartSize {
+		return size / (s3manager.MaxUploadParts - 1)
+	}
+	return s3manager.DefaultUploadPartSize
+}
+
+// checkpointKey keeps the key of the multipart upload in the checkpoint, so it can be aborted
+const checkpointKey = "key"
+
+// resumedParts returns the parts of the checkpoint which are still on the remote, they are contiguous from the first part.
+// The checkpoint is reset if the upload can't be resumed.
+func (d *S3) resumedParts(ctx context.Context, key string, partSize int64, cp *model.TransferCheckpoint) []model.CheckpointPart {
+	uploadID := cp.GetSessionID()
+	if uploadID == "" {
+		return nil
+	}
+	uploaded := make(map[int64]string)
+	err := d.client.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   &d.Bucket,
+		Key:      &key,
+		UploadId: &uploadID,
+	}, func(output *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range output.Parts {
+			uploaded[aws.Int64Value(part.PartNumber)] = aws.StringValue(part.ETag)
+		}
+		return true
+	})
+	if err != nil {
+		log.Warnf("failed list parts of upload [%s], start over: %v", uploadID, err)
+		d.discardUpload(ctx, key, uploadID, cp)
+		return nil
+	}
+	var parts []model.CheckpointPart
+	for i, part := range cp.GetParts() {
+		if part.Number != i+1 || part.Offset != int64(i)*partSize || part.Size != partSize ||
+			uploaded[int64(part.Number)] != part.ETag {
+			break
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		d.discardUpload(ctx, key, uploadID, cp)
+	} else {
+		cp.Truncate(len(parts))
+	}
+	return parts
+}
+
+// discardUpload resets cp to start over, the upload of cp is aborted so its parts don't stay in the bucket
+func (d *S3) discardUpload(ctx context.Context, key, uploadID string, cp *model.TransferCheckpoint) {
+	cp.Reset()
+	if err := d.abortMultipartUpload(ctx, key, uploadID); err != nil {
+		log.Warnf("%v", err)
+	}
+}
+
+func (d *S3) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, err := d.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &d.Bucket,
+		Key:      &key,
+		UploadId: &uploadID,
+	})
+	return errs.Wrapf(err, "failed abort multipart upload [%s]", uploadID)
+}
@@ -21,7 +21,14 @@ func InitApp(server ...bool) {
 	initUpgradePatch()
 
 	if len(server) > 0 && server[0] {
+		initCacheBackend()
 		initCron()
+		initWebhook()
+		initWebDAV()
+		initS3()
+		initAudit()
+		initSchedule()
+		initTrash()
 		// 只有server启动时加载
 		initOfflineDownloadTools()
 		initLoadStorages()
@@ -0,0 +1,14 @@
+package initialize
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+)
+
+func initAudit() {
+	if _, err := global.CronConfig.AddFunc("@daily", audit.Clean); err != nil {
+		log.Errorf("failed to add audit log cleanup job: %+v", err)
+	}
+}
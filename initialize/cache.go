@@ -0,0 +1,17 @@
+package initialize
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/cache_backend"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+func initCacheBackend() {
+	if err := cache_backend.Init(conf.Conf.Cache); err != nil {
+		log.Errorf("failed init cache backend %s, fall back to memory: %+v", conf.Conf.Cache.Backend, err)
+		return
+	}
+	op.SubscribeCacheInvalidation()
+}
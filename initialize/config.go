@@ -10,6 +10,7 @@ import (
 	"github.com/shirou/gopsutil/v4/mem"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/dongdio/OpenList/v4/consts"
 	"github.com/dongdio/OpenList/v4/drivers/base"
 	"github.com/dongdio/OpenList/v4/global"
 	"github.com/dongdio/OpenList/v4/internal/conf"
@@ -173,6 +174,9 @@ func ensureTempDirExists(pwd string) {
 	convertAbsPath(&conf.Conf.BleveDir)
 	convertAbsPath(&conf.Conf.Log.Name)
 	convertAbsPath(&conf.Conf.Database.DBFile)
+	if conf.Conf.Cache.BoltFile != "" {
+		convertAbsPath(&conf.Conf.Cache.BoltFile)
+	}
 	if conf.Conf.DistDir != "" {
 		convertAbsPath(&conf.Conf.DistDir)
 	}
@@ -202,7 +206,7 @@ func initURL() {
 	conf.URL = u
 }
 
-// CleanTempDir removes all files from the temporary directory
+// CleanTempDir removes all files from the temporary directory, except the staged parts of the S3 multipart uploads
 func CleanTempDir() {
 	files, err := os.ReadDir(conf.Conf.TempDir)
 	if err != nil {
@@ -211,6 +215,9 @@ func CleanTempDir() {
 	}
 
 	for _, file := range files {
+		if file.Name() == consts.S3MultipartDir {
+			continue
+		}
 		filePath := filepath.Join(conf.Conf.TempDir, file.Name())
 		if err = os.RemoveAll(filePath); err != nil {
 			log.Errorf("failed to delete temp file '%s': %v", filePath, err)
@@ -0,0 +1,17 @@
+package initialize
+
+import (
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+func initS3() {
+	op.FollowS3Objects()
+	if err := op.CheckS3KeyEncryption(); err != nil {
+		utils.Log.Fatalf("failed check the encryption of the s3 keys: %+v", err)
+	}
+	if conf.Conf.EncryptionKey == "" {
+		utils.Log.Warnf("encryption_key is not configured, the s3 keys of the users can't be created")
+	}
+}
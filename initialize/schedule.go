@@ -0,0 +1,15 @@
+package initialize
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/schedule"
+)
+
+func initSchedule() {
+	schedule.Init()
+	if _, err := global.CronConfig.AddFunc("@daily", schedule.CleanRuns); err != nil {
+		log.Errorf("failed to add scheduled job runs cleanup job: %+v", err)
+	}
+}
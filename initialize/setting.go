@@ -169,6 +169,11 @@ func InitialSettings() []model.SettingItem {
 		{Key: consts.ForwardDirectLinkParams, Value: "false", Type: consts.TypeBool, Group: model.GLOBAL},
 		{Key: consts.IgnoreDirectLinkParams, Value: "sign,openlist_ts", Type: consts.TypeString, Group: model.GLOBAL},
 		{Key: consts.WebauthnLoginEnabled, Value: "false", Type: consts.TypeBool, Group: model.GLOBAL, Flag: model.PUBLIC},
+		{Key: consts.AuditRetentionDays, Value: "90", Type: consts.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `days to keep the audit log, 0 keeps it forever`},
+		{Key: consts.AuthMaxRetries, Value: "5", Type: consts.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `failed logins of an account or an ip before it is locked, 0 disables the lockout`},
+		{Key: consts.AuthLockDuration, Value: "5", Type: consts.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `minutes of the first lock, doubled for each further failure up to 24 hours`},
+		{Key: consts.TwoFactorRequiredAdmins, Value: "false", Type: consts.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `require the admins to enable 2FA or a passkey, the groups can require it for their members too`},
+		{Key: consts.TwoFactorGraceDays, Value: "7", Type: consts.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `days the required users can still use the api before enabling 2FA, WebDAV, FTP and SFTP need an api token at once`},
 
 		// single settings
 		{Key: consts.Token, Value: token, Type: consts.TypeString, Group: model.SINGLE, Flag: model.PRIVATE},
@@ -193,6 +198,9 @@ func InitialSettings() []model.SettingItem {
 		{Key: consts.SSODefaultDir, Value: "/", Type: consts.TypeString, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: consts.SSODefaultPermission, Value: "0", Type: consts.TypeNumber, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: consts.SSOCompatibilityMode, Value: "false", Type: consts.TypeBool, Group: model.SSO, Flag: model.PUBLIC},
+		{Key: consts.SSOGroupClaim, Value: "groups", Type: consts.TypeString, Group: model.SSO, Flag: model.PRIVATE},
+		{Key: consts.SCIMEnabled, Value: "false", Type: consts.TypeBool, Group: model.SSO, Flag: model.PRIVATE, Help: `provision the users and the groups with SCIM 2.0 at /scim/v2, the new users take the sso default dir and permission`},
+		{Key: consts.SCIMToken, Value: "", Type: consts.TypeString, Group: model.SSO, Flag: model.PRIVATE, Help: `the bearer token of the SCIM client, SCIM is refused if it's empty`},
 
 		// ldap settings
 		{Key: consts.LdapLoginEnabled, Value: "false", Type: consts.TypeBool, Group: model.LDAP, Flag: model.PUBLIC},
@@ -204,6 +212,7 @@ func InitialSettings() []model.SettingItem {
 		{Key: consts.LdapDefaultDir, Value: "/", Type: consts.TypeString, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: consts.LdapDefaultPermission, Value: "0", Type: consts.TypeNumber, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: consts.LdapLoginTips, Value: "login with ldap", Type: consts.TypeString, Group: model.LDAP, Flag: model.PUBLIC},
+		{Key: consts.LdapGroupAttribute, Value: "memberOf", Type: consts.TypeString, Group: model.LDAP, Flag: model.PRIVATE},
 
 		// s3 settings
 		{Key: consts.S3AccessKeyId, Value: "", Type: consts.TypeString, Group: model.S3, Flag: model.PRIVATE},
@@ -227,6 +236,7 @@ func InitialSettings() []model.SettingItem {
 		{Key: consts.TaskCopyThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Copy.Workers), Type: consts.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: consts.TaskDecompressDownloadThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Decompress.Workers), Type: consts.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: consts.TaskDecompressUploadThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.DecompressUpload.Workers), Type: consts.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: consts.TaskSyncThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Sync.Workers), Type: consts.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: consts.StreamMaxClientDownloadSpeed, Value: "-1", Type: consts.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: consts.StreamMaxClientUploadSpeed, Value: "-1", Type: consts.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: consts.StreamMaxServerDownloadSpeed, Value: "-1", Type: consts.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
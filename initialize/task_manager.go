@@ -57,4 +57,9 @@ func initTaskManager() {
 	op.RegisterSettingChangingCallback(func() {
 		fs.ArchiveContentUploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(consts.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)))
 	})
+
+	fs.SyncTaskManager = tache.NewManager[*fs.SyncTask](tache.WithWorks(setting.GetInt(consts.TaskSyncThreadsNum, conf.Conf.Tasks.Sync.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("sync", conf.Conf.Tasks.Sync.TaskPersistant), db.UpdateTaskDataFunc("sync", conf.Conf.Tasks.Sync.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Sync.MaxRetry))
+	op.RegisterSettingChangingCallback(func() {
+		fs.SyncTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(consts.TaskSyncThreadsNum, conf.Conf.Tasks.Sync.Workers)))
+	})
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package initialize
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+func initTrash() {
+	_, err := global.CronConfig.AddFunc("@hourly", func() {
+		op.PurgeExpiredTrash(context.Background())
+	})
+	if err != nil {
+		log.Errorf("failed to add trash purge job: %+v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package initialize
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+func initWebDAV() {
+	op.FollowDeadProps()
+	if _, err := global.CronConfig.AddFunc("@every 1m", op.SweepWebDAVLocks); err != nil {
+		log.Errorf("failed to add webdav lock sweep job: %+v", err)
+	}
+}
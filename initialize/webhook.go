@@ -0,0 +1,15 @@
+package initialize
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/webhook"
+)
+
+func initWebhook() {
+	webhook.Init()
+	if _, err := global.CronConfig.AddFunc("@daily", webhook.CleanDeliveries); err != nil {
+		log.Errorf("failed to add webhook cleanup job: %+v", err)
+	}
+}
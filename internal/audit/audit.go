@@ -0,0 +1,114 @@
+// Package audit records the file operations, downloads, logins and admin changes
+// with the user, the protocol and the client ip they come from.
+// The records are written to the database in the background, so recording never slows the operation down.
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/setting"
+)
+
+// The protocols an operation comes from, set to the context with consts.ProtocolKey
+const (
+	ProtocolWeb         = "web"
+	ProtocolWebDAV      = "webdav"
+	ProtocolFTP         = "ftp"
+	ProtocolSFTP        = "sftp"
+	ProtocolS3          = "s3"
+	ProtocolShare       = "share"
+	ProtocolFileRequest = "file_request"
+	ProtocolSCIM        = "scim"
+	ProtocolInternal    = "internal"
+)
+
+const (
+	queueSize     = 4096
+	batchSize     = 256
+	flushInterval = time.Second
+)
+
+var (
+	queue     = make(chan *model.AuditLog, queueSize)
+	startOnce sync.Once
+)
+
+// Record fills the user, the protocol and the client ip of entry from ctx and queues it,
+// the user already set in entry is kept. err is the result of the operation.
+func Record(ctx context.Context, entry model.AuditLog, err error) {
+	if entry.Username == "" {
+		if user, ok := ctx.Value(consts.UserKey).(*model.User); ok {
+			entry.UserID, entry.Username = user.ID, user.Username
+		}
+	}
+	if entry.Protocol == "" {
+		entry.Protocol, _ = ctx.Value(consts.ProtocolKey).(string)
+		if entry.Protocol == "" {
+			entry.Protocol = ProtocolInternal
+		}
+	}
+	if entry.IP == "" {
+		entry.IP, _ = ctx.Value(consts.ClientIPKey).(string)
+	}
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.CreatedAt = time.Now()
+
+	startOnce.Do(func() { go write() })
+	select {
+	case queue <- &entry:
+	default:
+		log.Warnf("audit queue is full, dropped: %+v", entry)
+	}
+}
+
+// write saves the queued records in batches
+func write() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]*model.AuditLog, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := db.CreateAuditLogs(batch); err != nil {
+			log.Errorf("failed save audit logs: %+v", err)
+		}
+		batch = make([]*model.AuditLog, 0, batchSize)
+	}
+	for {
+		select {
+		case entry := <-queue:
+			batch = append(batch, entry)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Clean removes the records older than the retention set by consts.AuditRetentionDays, 0 keeps them forever
+func Clean() {
+	days := setting.GetInt(consts.AuditRetentionDays, 90)
+	if days <= 0 {
+		return
+	}
+	if err := db.DeleteAuditLogsBefore(time.Now().AddDate(0, 0, -days)); err != nil {
+		log.Errorf("failed clean audit logs: %+v", err)
+	}
+}
+
+func GetAuditLogs(q *model.AuditQuery, pageIndex, pageSize int) ([]model.AuditLog, int64, error) {
+	return db.GetAuditLogs(q, pageIndex, pageSize)
+}
@@ -0,0 +1,63 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+// TestRecordAndExport checks the records take the user, protocol and ip from the context and can be filtered and exported
+func TestRecordAndExport(t *testing.T) {
+	ctx := context.WithValue(context.Background(), consts.UserKey, &model.User{ID: 7, Username: "alice"})
+	ctx = context.WithValue(ctx, consts.ProtocolKey, audit.ProtocolWebDAV)
+	ctx = context.WithValue(ctx, consts.ClientIPKey, "10.0.0.1")
+	audit.Record(ctx, model.AuditLog{Action: model.AuditRemove, Path: "/local/a.txt"}, nil)
+	audit.Record(ctx, model.AuditLog{Action: model.AuditMove, Path: "/local/b.txt", DstPath: "/other"}, errs.PermissionDenied)
+	audit.Record(context.Background(), model.AuditLog{Action: model.AuditMkdir, Path: "/local/c"}, nil)
+
+	q := &model.AuditQuery{Username: "alice", Path: "/local"}
+	var logs []model.AuditLog
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if logs, _, _ = audit.GetAuditLogs(q, 1, 10); len(logs) == 2 {
+			break
+		}
+	}
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 records of alice, got %+v", logs)
+	}
+	if l := logs[1]; l.Protocol != audit.ProtocolWebDAV || l.IP != "10.0.0.1" || l.UserID != 7 || !l.Success {
+		t.Errorf("expected the context to be recorded, got %+v", l)
+	}
+	if logs[0].Success || logs[0].Error == "" {
+		t.Errorf("expected the failure to be recorded, got %+v", logs[0])
+	}
+
+	var buf bytes.Buffer
+	if err := audit.Export(&buf, q, audit.FormatCSV); err != nil {
+		t.Fatalf("failed export: %+v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[1], "/local/a.txt") {
+		t.Errorf("unexpected csv export: %s", buf.String())
+	}
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// The export formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+const exportBatchSize = 1000
+
+var csvHeader = []string{"id", "time", "user_id", "username", "protocol", "action", "path", "dst_path", "size", "success", "error", "ip", "detail"}
+
+// Export writes the records matching q to w in format, the oldest first
+func Export(w io.Writer, q *model.AuditQuery, format string) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return errs.WithStack(err)
+		}
+		err := db.WalkAuditLogs(q, exportBatchSize, func(logs []model.AuditLog) error {
+			for i := range logs {
+				if err := cw.Write(csvRecord(&logs[i])); err != nil {
+					return err
+				}
+			}
+			cw.Flush()
+			return cw.Error()
+		})
+		if err != nil {
+			return err
+		}
+		cw.Flush()
+		return errs.WithStack(cw.Error())
+	case FormatJSONL:
+		enc := utils.JSONTool.NewEncoder(w)
+		return db.WalkAuditLogs(q, exportBatchSize, func(logs []model.AuditLog) error {
+			for i := range logs {
+				if err := enc.Encode(&logs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return errs.Errorf("unsupported audit export format: %s", format)
+}
+
+func csvRecord(l *model.AuditLog) []string {
+	return []string{
+		strconv.FormatUint(uint64(l.ID), 10),
+		l.CreatedAt.Format(time.RFC3339),
+		strconv.FormatUint(uint64(l.UserID), 10),
+		l.Username,
+		l.Protocol,
+		l.Action,
+		l.Path,
+		l.DstPath,
+		strconv.FormatInt(l.Size, 10),
+		strconv.FormatBool(l.Success),
+		l.Error,
+		l.IP,
+		l.Detail,
+	}
+}
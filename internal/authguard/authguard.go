@@ -0,0 +1,394 @@
+// Package authguard slows down the password guessing against every login entry,
+// the web and ldap login, WebDAV, FTP, SFTP and S3 share the same counters.
+// The failures are counted per account, per account and client ip pair, and per client ip.
+// Once an account and ip pair or an ip counter reaches consts.AuthMaxRetries it's locked for
+// consts.AuthLockDuration minutes, doubled for each further failure.
+// The account counter catches the guessing spread over many ips, it only delays the next login of the account
+// by accountBaseDelay doubled for each further failure up to accountMaxDelay, so nobody can lock the others
+// out of their accounts. The failures of the unknown accounts only count for the ip.
+// The counters are kept in the shared cache backend if there is one, so they are shared by the replicas
+// and survive the restarts.
+package authguard
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/cache_backend"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/internal/setting"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const (
+	KindUser = "user"
+	KindIP   = "ip"
+
+	// maxLock caps the exponential backoff
+	maxLock = 24 * time.Hour
+	// accountBaseDelay and accountMaxDelay bound the delay of the account counters
+	accountBaseDelay = time.Second
+	accountMaxDelay  = time.Minute
+	// forgetAfter is how long an unlocked counter is kept after its last failure
+	forgetAfter   = 24 * time.Hour
+	pruneInterval = time.Minute
+	// backendKeyPrefix is the prefix of the counters in the cache backend
+	backendKeyPrefix = "authguard:"
+)
+
+// Lockout is the state of a counter, it's listed to the admin while it's locked
+type Lockout struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+	// IP is the client ip an account is locked for, empty for the ip counters
+	// and for the account counters which delay the logins from all the ips
+	IP string `json:"ip,omitempty"`
+	// Failures is the count of the failures since the last success
+	Failures     int       `json:"failures"`
+	LockedUntil  time.Time `json:"locked_until"`
+	LastFailure  time.Time `json:"last_failure"`
+	LastProtocol string    `json:"last_protocol"`
+}
+
+// Locked reports whether the counter is locked at now
+func (l *Lockout) Locked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
+
+// account reports whether l is the counter of an account from all the ips
+func (l *Lockout) account() bool {
+	return l.Kind == KindUser && l.IP == ""
+}
+
+// Notifier is called when a counter gets locked
+type Notifier func(l Lockout)
+
+// mu only guards the counters of this node, the backend is never accessed with it held
+// so the logins don't wait on each other for the backend
+var (
+	mu        sync.Mutex
+	counters  = make(map[string]*Lockout)
+	lastPrune time.Time
+	notifiers []Notifier
+)
+
+// RegisterNotifier adds fn to be called in a new goroutine for each lockout
+func RegisterNotifier(fn Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifiers = append(notifiers, fn)
+}
+
+// key is the key of the counter, the ip of an account counter follows the username after a NUL
+// which can't be part of either of them
+func key(kind, value, ip string) string {
+	if ip != "" {
+		return kind + ":" + value + "\x00" + ip
+	}
+	return kind + ":" + value
+}
+
+func (l *Lockout) key() string {
+	return key(l.Kind, l.Value, l.IP)
+}
+
+// normalizeIP strips the port from the remote address of the FTP and SFTP connections
+func normalizeIP(ip string) string {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return ip
+}
+
+// counterKeys returns the counters of username, of username from ip and of ip, the empty ones are skipped
+func counterKeys(username, ip string) []Lockout {
+	var ks []Lockout
+	ip = normalizeIP(ip)
+	if username != "" {
+		ks = append(ks, Lockout{Kind: KindUser, Value: username})
+		if ip != "" {
+			ks = append(ks, Lockout{Kind: KindUser, Value: username, IP: ip})
+		}
+	}
+	if ip != "" {
+		ks = append(ks, Lockout{Kind: KindIP, Value: ip})
+	}
+	return ks
+}
+
+// remoteCounter is a counter read from the backend, ok is false if the backend is not used for it
+type remoteCounter struct {
+	l  *Lockout
+	ok bool
+}
+
+// fetch reads the counters with keys ks from the backend, it must be called without mu held
+func fetch(ks []string) []remoteCounter {
+	res := make([]remoteCounter, len(ks))
+	if !cache_backend.Enabled() {
+		return res
+	}
+	b := cache_backend.Get()
+	for i, k := range ks {
+		data, found, err := b.Get(backendKeyPrefix + k)
+		if err != nil {
+			log.Warnf("failed get auth guard counter from backend: %+v", err)
+			continue
+		}
+		res[i].ok = true
+		if !found {
+			continue
+		}
+		var l Lockout
+		if err = utils.JSONTool.Unmarshal(data, &l); err != nil {
+			log.Warnf("failed decode auth guard counter: %+v", err)
+			continue
+		}
+		res[i].l = &l
+	}
+	return res
+}
+
+// resolve returns the counter with key k, the one in the backend wins over the local one, mu must be held
+func resolve(k string, r remoteCounter) (*Lockout, bool) {
+	if !r.ok {
+		l, ok := counters[k]
+		return l, ok
+	}
+	if r.l == nil {
+		delete(counters, k)
+		return nil, false
+	}
+	counters[k] = r.l
+	return r.l, true
+}
+
+// save writes the counters ls to the backend, it must be called without mu held
+func save(ls []Lockout, now time.Time) {
+	if !cache_backend.Enabled() {
+		return
+	}
+	b := cache_backend.Get()
+	for i := range ls {
+		data, err := utils.JSONTool.Marshal(&ls[i])
+		if err == nil {
+			err = b.Set(backendKeyPrefix+ls[i].key(), data, max(ls[i].LockedUntil.Sub(now), 0)+forgetAfter)
+		}
+		if err != nil {
+			log.Warnf("failed save auth guard counter to backend: %+v", err)
+		}
+	}
+}
+
+// drop deletes the counters with keys ks from the backend, it must be called without mu held
+func drop(ks []string) {
+	if len(ks) == 0 || !cache_backend.Enabled() {
+		return
+	}
+	backendKeys := make([]string, 0, len(ks))
+	for _, k := range ks {
+		backendKeys = append(backendKeys, backendKeyPrefix+k)
+	}
+	if err := cache_backend.Get().Del(backendKeys...); err != nil {
+		log.Warnf("failed delete auth guard counters from backend: %+v", err)
+	}
+}
+
+func keysOf(cs []Lockout) []string {
+	ks := make([]string, len(cs))
+	for i := range cs {
+		ks[i] = cs[i].key()
+	}
+	return ks
+}
+
+// Locked returns how long the logins of the account username from the client ip are still refused,
+// 0 if they are not. username may be empty when it's unknown, e.g. for a bearer token.
+func Locked(username, ip string) time.Duration {
+	ks := keysOf(counterKeys(username, ip))
+	remotes := fetch(ks)
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	var remaining time.Duration
+	for i, k := range ks {
+		if l, ok := resolve(k, remotes[i]); ok && l.Locked(now) {
+			remaining = max(remaining, l.LockedUntil.Sub(now))
+		}
+	}
+	return remaining
+}
+
+// Fail counts a failed login of username from ip through protocol,
+// the failure only counts for the ip if there is no such user
+func Fail(username, ip, protocol string) {
+	maxRetries := setting.GetInt(consts.AuthMaxRetries, 5)
+	if maxRetries <= 0 {
+		return
+	}
+	base := time.Duration(setting.GetInt(consts.AuthLockDuration, 5)) * time.Minute
+	if username != "" {
+		if _, err := op.GetUserByName(username); err != nil {
+			username = ""
+		}
+	}
+	cs := counterKeys(username, ip)
+	ks := keysOf(cs)
+	remotes := fetch(ks)
+
+	mu.Lock()
+	now := time.Now()
+	prune(now)
+	updated := make([]Lockout, 0, len(cs))
+	var locked []Lockout
+	for i, k := range ks {
+		l, ok := resolve(k, remotes[i])
+		if !ok {
+			l = &cs[i]
+		}
+		l.Failures++
+		l.LastFailure = now
+		l.LastProtocol = protocol
+		if l.Failures >= maxRetries {
+			if l.account() {
+				l.LockedUntil = now.Add(backoffCapped(accountBaseDelay, l.Failures-maxRetries, accountMaxDelay))
+				// the account counter is only notified once, the delay of every further failure is not worth it
+				if l.Failures == maxRetries {
+					locked = append(locked, *l)
+				}
+			} else {
+				l.LockedUntil = now.Add(backoff(base, l.Failures-maxRetries))
+				locked = append(locked, *l)
+			}
+		}
+		counters[k] = l
+		updated = append(updated, *l)
+	}
+	fns := notifiers
+	mu.Unlock()
+	save(updated, now)
+
+	for _, l := range locked {
+		switch {
+		case l.account():
+			log.Warnf("[auth guard] %s %s is delayed after %d failed logins, last through %s",
+				l.Kind, l.Value, l.Failures, l.LastProtocol)
+		case l.IP != "":
+			log.Warnf("[auth guard] %s %s is locked for %s until %s after %d failed logins, last through %s",
+				l.Kind, l.Value, l.IP, l.LockedUntil.Format(time.RFC3339), l.Failures, l.LastProtocol)
+		default:
+			log.Warnf("[auth guard] %s %s is locked until %s after %d failed logins, last through %s",
+				l.Kind, l.Value, l.LockedUntil.Format(time.RFC3339), l.Failures, l.LastProtocol)
+		}
+		for _, fn := range fns {
+			go fn(l)
+		}
+	}
+}
+
+// backoff doubles base n times, capped by maxLock
+func backoff(base time.Duration, n int) time.Duration {
+	return backoffCapped(base, n, maxLock)
+}
+
+// backoffCapped doubles base n times, capped by limit
+func backoffCapped(base time.Duration, n int, limit time.Duration) time.Duration {
+	d := base
+	for i := 0; i < n && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// Success resets the counters of username, of username from ip and of ip after a successful login
+func Success(username, ip string) {
+	ks := keysOf(counterKeys(username, ip))
+	mu.Lock()
+	for _, k := range ks {
+		delete(counters, k)
+	}
+	mu.Unlock()
+	drop(ks)
+}
+
+// prune forgets the unlocked counters without failures for forgetAfter, mu must be held.
+// The counters in the backend expire by themselves.
+func prune(now time.Time) {
+	if now.Sub(lastPrune) < pruneInterval {
+		return
+	}
+	lastPrune = now
+	for k, l := range counters {
+		if !l.Locked(now) && now.Sub(l.LastFailure) > forgetAfter {
+			delete(counters, k)
+		}
+	}
+}
+
+// Lockouts returns the locked counters seen by this node, the latest failure first
+func Lockouts() []Lockout {
+	mu.Lock()
+	ks := make([]string, 0, len(counters))
+	for k := range counters {
+		ks = append(ks, k)
+	}
+	mu.Unlock()
+	remotes := fetch(ks)
+
+	mu.Lock()
+	now := time.Now()
+	res := make([]Lockout, 0)
+	for i, k := range ks {
+		if l, ok := resolve(k, remotes[i]); ok && l.Locked(now) {
+			res = append(res, *l)
+		}
+	}
+	mu.Unlock()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastFailure.After(res[j].LastFailure)
+	})
+	return res
+}
+
+// Unlock resets the counter of kind and value, an account is unlocked for all the ips.
+// It reports whether there was one.
+func Unlock(kind, value string) bool {
+	if kind == KindIP {
+		value = normalizeIP(value)
+	}
+	k := key(kind, value, "")
+	found := false
+	if remote := fetch([]string{k}); remote[0].l != nil {
+		found = true
+	}
+	mu.Lock()
+	if _, ok := counters[k]; ok {
+		found = true
+	}
+	ks := []string{k}
+	delete(counters, k)
+	if kind == KindUser {
+		prefix := k + "\x00"
+		for ck := range counters {
+			if strings.HasPrefix(ck, prefix) {
+				found = true
+				ks = append(ks, ck)
+				delete(counters, ck)
+			}
+		}
+	}
+	mu.Unlock()
+	drop(ks)
+	if kind == KindUser && cache_backend.Enabled() {
+		if err := cache_backend.Get().DelPrefix(backendKeyPrefix + k + "\x00"); err != nil {
+			log.Warnf("failed delete auth guard counters from backend: %+v", err)
+		}
+	}
+	return found
+}
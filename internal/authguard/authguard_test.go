@@ -0,0 +1,95 @@
+package authguard_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/authguard"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+// TestLockout checks the account is locked for the guessing ip only, the ip is locked
+// and the account is only delayed for the other ips with the default 5 retries
+func TestLockout(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "alice", BasePath: "/"}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	notified := make(chan authguard.Lockout, 8)
+	authguard.RegisterNotifier(func(l authguard.Lockout) { notified <- l })
+
+	// the same account guessed from one ip, the ip is locked too
+	for i := 0; i < 5; i++ {
+		if authguard.Locked("alice", "10.0.0.1") > 0 {
+			t.Fatalf("expected alice not to be locked after %d failures", i)
+		}
+		authguard.Fail("alice", "10.0.0.1", "webdav")
+	}
+	if authguard.Locked("alice", "10.0.0.1") <= time.Minute {
+		t.Errorf("expected alice to be locked for the guessing ip")
+	}
+	if d := authguard.Locked("alice", "10.0.1.1"); d > time.Second {
+		t.Errorf("expected alice to be delayed at most a second for the other ips, got %s", d)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case l := <-notified:
+			if l.LastProtocol != "webdav" || (l.Kind == authguard.KindUser && l.Value != "alice") ||
+				(l.Kind == authguard.KindIP && l.Value != "10.0.0.1") {
+				t.Errorf("unexpected lockout notified: %+v", l)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("expected the lockouts to be notified")
+		}
+	}
+	if !authguard.Unlock(authguard.KindUser, "alice") || !authguard.Unlock(authguard.KindIP, "10.0.0.1") ||
+		authguard.Locked("alice", "10.0.0.1") > 0 {
+		t.Errorf("expected alice and the ip to be unlocked")
+	}
+
+	// the same account guessed from many ips, the account is delayed but none of the ips is locked
+	for i := 0; i < 7; i++ {
+		authguard.Fail("alice", "10.0.3."+strconv.Itoa(i), "web")
+	}
+	if d := authguard.Locked("alice", "10.0.4.1"); d <= 0 || d > time.Minute {
+		t.Errorf("expected alice to be delayed up to a minute, got %s", d)
+	}
+	if authguard.Locked("", "10.0.3.1") > 0 {
+		t.Errorf("expected the ips not to be locked")
+	}
+	authguard.Unlock(authguard.KindUser, "alice")
+	for len(notified) > 0 {
+		<-notified
+	}
+
+	// many accounts guessed from the same ip, the port of the ftp and sftp address is ignored,
+	// the unknown accounts are not tracked
+	for i := 0; i < 5; i++ {
+		authguard.Fail("user"+strconv.Itoa(i), "10.0.2.1:"+strconv.Itoa(2000+i), "ftp")
+	}
+	if authguard.Locked("", "10.0.2.1") <= 0 {
+		t.Errorf("expected the ip to be locked")
+	}
+	if n := len(authguard.Lockouts()); n != 1 {
+		t.Errorf("expected 1 lockout, got %d", n)
+	}
+	authguard.Success("", "10.0.2.1")
+	if authguard.Locked("", "10.0.2.1") > 0 {
+		t.Errorf("expected the ip to be reset by a success")
+	}
+}
@@ -0,0 +1,119 @@
+// Package cache_backend provides the second level cache shared by op.List and op.Link.
+// The in-process MemCache stays the first level, a backend makes listings survive
+// restarts (bolt) or shares them between several replicas (redis).
+package cache_backend
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	Memory = "memory"
+	Bolt   = "bolt"
+	Redis  = "redis"
+)
+
+// Invalidation is broadcast to other nodes when a cached key changes
+type Invalidation struct {
+	Node string   `json:"node"`
+	Keys []string `json:"keys"`
+	// Recursive asks the receiver to drop the sub directories of Keys too
+	Recursive bool `json:"recursive"`
+}
+
+// Backend is a key-value store with expiration
+type Backend interface {
+	Name() string
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Del(keys ...string) error
+	// DelPrefix removes every key starting with prefix
+	DelPrefix(prefix string) error
+	// Publish sends inv to the other nodes sharing this backend
+	Publish(inv Invalidation) error
+	// Subscribe registers the handler for invalidations sent by other nodes
+	Subscribe(handler func(inv Invalidation))
+	Close() error
+}
+
+// NodeID identifies this process in invalidation messages
+var NodeID = random.String(16)
+
+var (
+	current Backend = nopBackend{}
+	mu      sync.RWMutex
+)
+
+// Init creates the backend configured in conf.Conf.Cache
+func Init(c conf.Cache) error {
+	b, err := New(c)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	old := current
+	current = b
+	mu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	log.Infof("cache backend: %s", b.Name())
+	return nil
+}
+
+// New creates a backend without installing it
+func New(c conf.Cache) (Backend, error) {
+	switch c.Backend {
+	case "", Memory:
+		return nopBackend{}, nil
+	case Bolt:
+		return newBoltBackend(c.BoltFile, c.Prefix)
+	case Redis:
+		return newRedisBackend(c.Redis, c.Prefix)
+	default:
+		return nil, errs.Errorf("unknown cache backend: %s", c.Backend)
+	}
+}
+
+// Get returns the installed backend
+func Get() Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Enabled reports whether a shared backend is installed
+func Enabled() bool {
+	_, ok := Get().(nopBackend)
+	return !ok
+}
+
+// Close closes the installed backend and falls back to memory only
+func Close() {
+	mu.Lock()
+	old := current
+	current = nopBackend{}
+	mu.Unlock()
+	if err := old.Close(); err != nil {
+		log.Errorf("failed close cache backend: %+v", err)
+	}
+}
+
+// nopBackend keeps the old behavior: only the in-process cache is used
+type nopBackend struct{}
+
+func (nopBackend) Name() string                            { return Memory }
+func (nopBackend) Get(string) ([]byte, bool, error)        { return nil, false, nil }
+func (nopBackend) Set(string, []byte, time.Duration) error { return nil }
+func (nopBackend) Del(...string) error                     { return nil }
+func (nopBackend) DelPrefix(string) error                  { return nil }
+func (nopBackend) Publish(Invalidation) error              { return nil }
+func (nopBackend) Subscribe(func(inv Invalidation))        {}
+func (nopBackend) Close() error                            { return nil }
@@ -0,0 +1,165 @@
+package cache_backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+var boltBucket = []byte("cache")
+
+const boltSweepInterval = time.Minute * 10
+
+// boltBackend stores the cache in a single file under the data dir.
+// The file is locked by one process, so there are no other nodes to notify.
+type boltBackend struct {
+	db     *bolt.DB
+	prefix string
+	closed chan struct{}
+}
+
+func newBoltBackend(file, prefix string) (*boltBackend, error) {
+	if file == "" {
+		return nil, errs.New("bolt cache file is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, errs.Wrap(err, "failed create cache dir")
+	}
+	db, err := bolt.Open(file, 0o600, &bolt.Options{Timeout: time.Second * 3})
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed open bolt cache [%s]", file)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errs.Wrap(err, "failed create bolt cache bucket")
+	}
+	b := &boltBackend{db: db, prefix: prefix, closed: make(chan struct{})}
+	go b.sweep()
+	return b, nil
+}
+
+func (b *boltBackend) Name() string {
+	return Bolt
+}
+
+// value layout: 8 bytes expire unix nano (0 means never) + payload
+func encodeBoltValue(value []byte, ttl time.Duration) []byte {
+	buf := make([]byte, 8+len(value))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(buf, uint64(time.Now().Add(ttl).UnixNano()))
+	}
+	copy(buf[8:], value)
+	return buf
+}
+
+func boltValueExpired(raw []byte, now time.Time) bool {
+	if len(raw) < 8 {
+		return true
+	}
+	exp := int64(binary.BigEndian.Uint64(raw))
+	return exp != 0 && exp <= now.UnixNano()
+}
+
+func (b *boltBackend) Get(key string) ([]byte, bool, error) {
+	var value []byte
+	expired := false
+	err := b.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltBucket).Get([]byte(b.prefix + key))
+		if raw == nil {
+			return nil
+		}
+		if boltValueExpired(raw, time.Now()) {
+			expired = true
+			return nil
+		}
+		value = bytes.Clone(raw[8:])
+		return nil
+	})
+	if err != nil {
+		return nil, false, errs.WithStack(err)
+	}
+	if expired {
+		_ = b.Del(key)
+	}
+	return value, value != nil, nil
+}
+
+func (b *boltBackend) Set(key string, value []byte, ttl time.Duration) error {
+	return errs.WithStack(b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(b.prefix+key), encodeBoltValue(value, ttl))
+	}))
+}
+
+func (b *boltBackend) Del(keys ...string) error {
+	return errs.WithStack(b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, key := range keys {
+			if err := bucket.Delete([]byte(b.prefix + key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func (b *boltBackend) DelPrefix(prefix string) error {
+	p := []byte(b.prefix + prefix)
+	return errs.WithStack(b.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, _ := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = c.Next() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func (b *boltBackend) Publish(Invalidation) error {
+	return nil
+}
+
+func (b *boltBackend) Subscribe(func(inv Invalidation)) {}
+
+func (b *boltBackend) sweep() {
+	ticker := time.NewTicker(boltSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			err := b.db.Update(func(tx *bolt.Tx) error {
+				c := tx.Bucket(boltBucket).Cursor()
+				for k, v := c.First(); k != nil; k, v = c.Next() {
+					if boltValueExpired(v, now) {
+						if err := c.Delete(); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				log.Warnf("failed sweep bolt cache: %+v", err)
+			}
+		case <-b.closed:
+			return
+		}
+	}
+}
+
+func (b *boltBackend) Close() error {
+	close(b.closed)
+	return b.db.Close()
+}
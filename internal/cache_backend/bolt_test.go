@@ -0,0 +1,62 @@
+package cache_backend
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+)
+
+func TestBoltBackend(t *testing.T) {
+	b, err := newBoltBackend(filepath.Join(t.TempDir(), "cache.db"), "test:")
+	if err != nil {
+		t.Fatalf("failed open bolt backend: %+v", err)
+	}
+	defer b.Close()
+
+	objs := []model.Obj{
+		&model.Object{Name: "a.txt", Size: 1},
+		&model.ObjWrapName{Name: "b", Obj: &model.ObjThumb{Object: model.Object{Name: "b.png", Size: 2}}},
+	}
+	data, ok := EncodeObjs(objs)
+	if !ok {
+		t.Fatalf("expected objs to be encodable")
+	}
+	for _, key := range []string{"list:/a", "list:/a/b", "list:/ab"} {
+		if err = b.Set(key, data, time.Minute); err != nil {
+			t.Fatalf("failed set %s: %+v", key, err)
+		}
+	}
+	if err = b.Set("list:/expired", data, time.Nanosecond); err != nil {
+		t.Fatalf("failed set: %+v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok, _ = b.Get("list:/expired"); ok {
+		t.Errorf("expected expired key to be missing")
+	}
+
+	value, ok, err := b.Get("list:/a")
+	if err != nil || !ok {
+		t.Fatalf("expected list:/a to exist, err: %+v", err)
+	}
+	got, err := DecodeObjs(value)
+	if err != nil {
+		t.Fatalf("failed decode objs: %+v", err)
+	}
+	if len(got) != 2 || got[1].GetName() != "b" || got[1].GetSize() != 2 {
+		t.Errorf("unexpected decoded objs: %+v", got)
+	}
+	if _, ok = model.UnwrapObj(got[1]).(*model.ObjThumb); !ok {
+		t.Errorf("expected wrapped obj to be restored as ObjThumb")
+	}
+
+	if err = b.DelPrefix("list:/a/"); err != nil {
+		t.Fatalf("failed del prefix: %+v", err)
+	}
+	for key, want := range map[string]bool{"list:/a": true, "list:/a/b": false, "list:/ab": true} {
+		if _, ok, _ = b.Get(key); ok != want {
+			t.Errorf("expected %s exist = %v, got %v", key, want, ok)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package cache_backend
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const (
+	kindObject   = "object"
+	kindThumb    = "thumb"
+	kindURL      = "url"
+	kindThumbURL = "thumb_url"
+)
+
+// cachedObj is the portable form of the objects defined in internal/model.
+// Drivers that return their own object types keep them in the in-process cache only,
+// because they may type assert the object when it is passed back to them.
+type cachedObj struct {
+	Kind     string    `json:"kind"`
+	WrapName string    `json:"wrap_name,omitempty"`
+	ID       string    `json:"id,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Ctime    time.Time `json:"ctime"`
+	IsFolder bool      `json:"is_folder"`
+	Hash     string    `json:"hash,omitempty"`
+	Thumb    string    `json:"thumb,omitempty"`
+	URL      string    `json:"url,omitempty"`
+}
+
+func fromObject(o *model.Object, kind string) cachedObj {
+	c := cachedObj{
+		Kind:     kind,
+		ID:       o.ID,
+		Path:     o.Path,
+		Name:     o.Name,
+		Size:     o.Size,
+		Modified: o.Modified,
+		Ctime:    o.Ctime,
+		IsFolder: o.IsFolder,
+	}
+	if len(o.HashInfo.Export()) > 0 {
+		c.Hash = o.HashInfo.String()
+	}
+	return c
+}
+
+func (c cachedObj) object() model.Object {
+	o := model.Object{
+		ID:       c.ID,
+		Path:     c.Path,
+		Name:     c.Name,
+		Size:     c.Size,
+		Modified: c.Modified,
+		Ctime:    c.Ctime,
+		IsFolder: c.IsFolder,
+	}
+	if c.Hash != "" {
+		o.HashInfo = utils.FromString(c.Hash)
+	}
+	return o
+}
+
+// EncodeObjs returns false if any of objs can't be restored losslessly
+func EncodeObjs(objs []model.Obj) ([]byte, bool) {
+	items := make([]cachedObj, 0, len(objs))
+	for _, obj := range objs {
+		var wrapName string
+		if w, ok := obj.(*model.ObjWrapName); ok {
+			wrapName = w.Name
+			obj = w.Obj
+		}
+		var item cachedObj
+		switch o := obj.(type) {
+		case *model.Object:
+			item = fromObject(o, kindObject)
+		case *model.ObjThumb:
+			item = fromObject(&o.Object, kindThumb)
+			item.Thumb = o.Thumbnail.Thumbnail
+		case *model.ObjectURL:
+			item = fromObject(&o.Object, kindURL)
+			item.URL = o.Url.Url
+		case *model.ObjThumbURL:
+			item = fromObject(&o.Object, kindThumbURL)
+			item.Thumb = o.Thumbnail.Thumbnail
+			item.URL = o.Url.Url
+		default:
+			return nil, false
+		}
+		item.WrapName = wrapName
+		items = append(items, item)
+	}
+	data, err := utils.JSONTool.Marshal(items)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func DecodeObjs(data []byte) ([]model.Obj, error) {
+	var items []cachedObj
+	if err := utils.JSONTool.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	objs := make([]model.Obj, 0, len(items))
+	for _, item := range items {
+		var obj model.Obj
+		switch item.Kind {
+		case kindThumb:
+			obj = &model.ObjThumb{Object: item.object(), Thumbnail: model.Thumbnail{Thumbnail: item.Thumb}}
+		case kindURL:
+			obj = &model.ObjectURL{Object: item.object(), Url: model.Url{Url: item.URL}}
+		case kindThumbURL:
+			obj = &model.ObjThumbURL{Object: item.object(), Thumbnail: model.Thumbnail{Thumbnail: item.Thumb}, Url: model.Url{Url: item.URL}}
+		default:
+			o := item.object()
+			obj = &o
+		}
+		if item.WrapName != "" {
+			obj = &model.ObjWrapName{Name: item.WrapName, Obj: obj}
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+type cachedLink struct {
+	URL         string        `json:"url"`
+	Header      http.Header   `json:"header,omitempty"`
+	Expiration  time.Duration `json:"expiration"`
+	Concurrency int           `json:"concurrency,omitempty"`
+	PartSize    int           `json:"part_size,omitempty"`
+}
+
+// EncodeLink only accepts plain url links, links backed by a local file or a reader are process bound
+func EncodeLink(link *model.Link) ([]byte, bool) {
+	if link == nil || link.URL == "" || link.MFile != nil || link.RangeReader != nil || link.Expiration == nil {
+		return nil, false
+	}
+	data, err := utils.JSONTool.Marshal(cachedLink{
+		URL:         link.URL,
+		Header:      link.Header,
+		Expiration:  *link.Expiration,
+		Concurrency: link.Concurrency,
+		PartSize:    link.PartSize,
+	})
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func DecodeLink(data []byte) (*model.Link, error) {
+	var c cachedLink
+	if err := utils.JSONTool.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &model.Link{
+		URL:         c.URL,
+		Header:      c.Header,
+		Expiration:  &c.Expiration,
+		Concurrency: c.Concurrency,
+		PartSize:    c.PartSize,
+	}, nil
+}
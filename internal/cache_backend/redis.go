@@ -0,0 +1,172 @@
+package cache_backend
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const (
+	redisInvalidateChannel = "cache:invalidate"
+	redisTimeout           = time.Second * 5
+	redisScanCount         = 500
+)
+
+// redisBackend works with any server speaking the Redis protocol (redis, valkey, dragonfly, ...).
+// Invalidations are delivered through pub/sub so every replica drops its in-process cache.
+type redisBackend struct {
+	client *redis.Client
+	prefix string
+
+	mu       sync.Mutex
+	handlers []func(inv Invalidation)
+	pubsub   *redis.PubSub
+}
+
+func newRedisBackend(c conf.Redis, prefix string) (*redisBackend, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     c.Address,
+		Username: c.Username,
+		Password: c.Password,
+		DB:       c.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, errs.Wrapf(err, "failed connect redis [%s]", c.Address)
+	}
+	return &redisBackend{client: client, prefix: prefix}, nil
+}
+
+func (r *redisBackend) Name() string {
+	return Redis
+}
+
+func (r *redisBackend) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), redisTimeout)
+}
+
+func (r *redisBackend) Get(key string) ([]byte, bool, error) {
+	ctx, cancel := r.ctx()
+	defer cancel()
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errs.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errs.WithStack(err)
+	}
+	return value, true, nil
+}
+
+func (r *redisBackend) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := r.ctx()
+	defer cancel()
+	return errs.WithStack(r.client.Set(ctx, r.prefix+key, value, ttl).Err())
+}
+
+func (r *redisBackend) Del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	ctx, cancel := r.ctx()
+	defer cancel()
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = r.prefix + key
+	}
+	return errs.WithStack(r.client.Del(ctx, fullKeys...).Err())
+}
+
+// escapeGlob escapes the special characters of the redis MATCH pattern
+func escapeGlob(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\', '^', '-':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func (r *redisBackend) DelPrefix(prefix string) error {
+	ctx := context.Background()
+	iter := r.client.Scan(ctx, 0, escapeGlob(r.prefix+prefix)+"*", redisScanCount).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) >= redisScanCount {
+			if err := r.client.Unlink(ctx, keys...).Err(); err != nil {
+				return errs.WithStack(err)
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return errs.WithStack(err)
+	}
+	if len(keys) > 0 {
+		return errs.WithStack(r.client.Unlink(ctx, keys...).Err())
+	}
+	return nil
+}
+
+func (r *redisBackend) Publish(inv Invalidation) error {
+	inv.Node = NodeID
+	msg, err := utils.JSONTool.MarshalToString(inv)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	ctx, cancel := r.ctx()
+	defer cancel()
+	return errs.WithStack(r.client.Publish(ctx, r.prefix+redisInvalidateChannel, msg).Err())
+}
+
+func (r *redisBackend) Subscribe(handler func(inv Invalidation)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, handler)
+	if r.pubsub != nil {
+		return
+	}
+	r.pubsub = r.client.Subscribe(context.Background(), r.prefix+redisInvalidateChannel)
+	go func(ch <-chan *redis.Message) {
+		for msg := range ch {
+			var inv Invalidation
+			if err := utils.JSONTool.UnmarshalFromString(msg.Payload, &inv); err != nil {
+				log.Warnf("invalid cache invalidation message: %s", msg.Payload)
+				continue
+			}
+			if inv.Node == NodeID {
+				continue
+			}
+			r.mu.Lock()
+			handlers := r.handlers
+			r.mu.Unlock()
+			for _, h := range handlers {
+				h(inv)
+			}
+		}
+	}(r.pubsub.Channel())
+}
+
+func (r *redisBackend) Close() error {
+	r.mu.Lock()
+	if r.pubsub != nil {
+		_ = r.pubsub.Close()
+		r.pubsub = nil
+	}
+	r.mu.Unlock()
+	return r.client.Close()
+}
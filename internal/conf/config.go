@@ -72,6 +72,7 @@ type TasksConfig struct {
 	Move               TaskConfig `json:"move" envPrefix:"MOVE_"`
 	Decompress         TaskConfig `json:"decompress" envPrefix:"DECOMPRESS_"`
 	DecompressUpload   TaskConfig `json:"decompress_upload" envPrefix:"DECOMPRESS_UPLOAD_"`
+	Sync               TaskConfig `json:"sync" envPrefix:"SYNC_"`
 	AllowRetryCanceled bool       `json:"allow_retry_canceled" env:"ALLOW_RETRY_CANCELED"`
 }
 
@@ -105,12 +106,29 @@ type SFTP struct {
 	Listen string `json:"listen" env:"LISTEN"`
 }
 
+type Redis struct {
+	Address  string `json:"address" env:"ADDRESS"`
+	Username string `json:"username" env:"USERNAME"`
+	Password string `json:"password" env:"PASSWORD"`
+	DB       int    `json:"db" env:"DB"`
+}
+
+type Cache struct {
+	// Backend is one of memory, bolt, redis
+	Backend  string `json:"backend" env:"BACKEND"`
+	BoltFile string `json:"bolt_file" env:"BOLT_FILE"`
+	Prefix   string `json:"prefix" env:"PREFIX"`
+	Redis    Redis  `json:"redis" envPrefix:"REDIS_"`
+}
+
 type Config struct {
 	Force                 bool        `json:"force" env:"FORCE"`
 	SiteURL               string      `json:"site_url" env:"SITE_URL"`
 	Cdn                   string      `json:"cdn" env:"CDN"`
 	JwtSecret             string      `json:"jwt_secret" env:"JWT_SECRET"`
+	EncryptionKey         string      `json:"encryption_key" env:"ENCRYPTION_KEY"` // encrypts the s3 key secrets, it must be set to the same value on every node and can't be changed
 	TokenExpiresIn        int         `json:"token_expires_in" env:"TOKEN_EXPIRES_IN"`
+	RefreshTokenExpiresIn int         `json:"refresh_token_expires_in" env:"REFRESH_TOKEN_EXPIRES_IN"`
 	Database              Database    `json:"database" envPrefix:"DB_"`
 	Meilisearch           Meilisearch `json:"meilisearch" envPrefix:"MEILISEARCH_"`
 	Scheme                Scheme      `json:"scheme"`
@@ -128,6 +146,7 @@ type Config struct {
 	S3                    S3          `json:"s3" envPrefix:"S3_"`
 	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
 	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
+	Cache                 Cache       `json:"cache" envPrefix:"CACHE_"`
 	LastLaunchedVersion   string      `json:"last_launched_version"`
 }
 
@@ -136,6 +155,7 @@ func DefaultConfig(dataDir string) *Config {
 	indexDir := filepath.Join(dataDir, "bleve")
 	logPath := filepath.Join(dataDir, "log/log.log")
 	dbPath := filepath.Join(dataDir, "data.db")
+	cachePath := filepath.Join(dataDir, "cache.db")
 	return &Config{
 		Scheme: Scheme{
 			Address:    "0.0.0.0",
@@ -146,9 +166,10 @@ func DefaultConfig(dataDir string) *Config {
 			CertFile:   "",
 			KeyFile:    "",
 		},
-		JwtSecret:      random.String(16),
-		TokenExpiresIn: 48,
-		TempDir:        tempDir,
+		JwtSecret:             random.String(16),
+		TokenExpiresIn:        48,
+		RefreshTokenExpiresIn: 720,
+		TempDir:               tempDir,
 		Database: Database{
 			Type:        "sqlite3",
 			Port:        0,
@@ -212,6 +233,11 @@ func DefaultConfig(dataDir string) *Config {
 				Workers:  5,
 				MaxRetry: 2,
 			},
+			Sync: TaskConfig{
+				Workers:  2,
+				MaxRetry: 1,
+				// TaskPersistant: true,
+			},
 			AllowRetryCanceled: false,
 		},
 		Cors: Cors{
@@ -240,6 +266,14 @@ func DefaultConfig(dataDir string) *Config {
 			Enable: false,
 			Listen: ":5222",
 		},
+		Cache: Cache{
+			Backend:  "memory",
+			BoltFile: cachePath,
+			Prefix:   "openlist:",
+			Redis: Redis{
+				Address: "localhost:6379",
+			},
+		},
 		LastLaunchedVersion: "",
 	}
 }
\ No newline at end of file
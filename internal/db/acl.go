@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func aclRuleOrder() string {
+	return fmt.Sprintf("%s, %s", columnName("priority"), columnName("id"))
+}
+
+func GetACLRules(pageIndex, pageSize int) (rules []model.ACLRule, count int64, err error) {
+	ruleDB := db.Model(&model.ACLRule{})
+	if err = ruleDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get acl rules count")
+	}
+	if err = ruleDB.Order(aclRuleOrder()).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&rules).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find acl rules")
+	}
+	return rules, count, nil
+}
+
+// GetEnabledACLRules returns the enabled rules in the evaluation order
+func GetEnabledACLRules() ([]model.ACLRule, error) {
+	var rules []model.ACLRule
+	err := db.Where(fmt.Sprintf("%s = ?", columnName("disabled")), false).Order(aclRuleOrder()).Find(&rules).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed find enabled acl rules")
+	}
+	return rules, nil
+}
+
+func GetACLRuleByID(id uint) (*model.ACLRule, error) {
+	var r model.ACLRule
+	if err := db.First(&r, id).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get acl rule")
+	}
+	return &r, nil
+}
+
+func CreateACLRule(r *model.ACLRule) error {
+	return errs.WithStack(db.Create(r).Error)
+}
+
+func UpdateACLRule(r *model.ACLRule) error {
+	return errs.WithStack(db.Save(r).Error)
+}
+
+func DeleteACLRuleByID(id uint) error {
+	return errs.WithStack(db.Delete(&model.ACLRule{}, id).Error)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func GetAPITokensByUserID(userID uint, pageIndex, pageSize int) (tokens []model.APIToken, count int64, err error) {
+	tokenDB := db.Model(&model.APIToken{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID)
+	if err = tokenDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get user's api tokens count")
+	}
+	if err = tokenDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&tokens).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find user's api tokens")
+	}
+	return tokens, count, nil
+}
+
+// GetValidAPITokens returns the tokens which are not expired
+func GetValidAPITokens() ([]model.APIToken, error) {
+	var tokens []model.APIToken
+	err := db.Where(fmt.Sprintf("%s IS NULL OR %s > ?", columnName("expires_at"), columnName("expires_at")), time.Now()).
+		Find(&tokens).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed find valid api tokens")
+	}
+	return tokens, nil
+}
+
+func GetAPITokenByID(id uint) (*model.APIToken, error) {
+	var t model.APIToken
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get api token")
+	}
+	return &t, nil
+}
+
+func GetAPITokenByHash(hash string) (*model.APIToken, error) {
+	var t model.APIToken
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("token_hash")), hash).First(&t).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get api token")
+	}
+	return &t, nil
+}
+
+func GetAPITokenByKeyID(keyID string) (*model.APIToken, error) {
+	var t model.APIToken
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("key_id")), keyID).First(&t).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get api token")
+	}
+	return &t, nil
+}
+
+func CreateAPIToken(t *model.APIToken) error {
+	return errs.WithStack(db.Create(t).Error)
+}
+
+func UpdateAPIToken(t *model.APIToken) error {
+	return errs.WithStack(db.Save(t).Error)
+}
+
+func UpdateAPITokenLastUsed(id uint, t time.Time) error {
+	return errs.WithStack(db.Model(&model.APIToken{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Update("last_used_at", t).Error)
+}
+
+func DeleteAPITokenByID(id uint) error {
+	return errs.WithStack(db.Delete(&model.APIToken{}, id).Error)
+}
+
+func DeleteAPITokensByUserID(userID uint) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Delete(&model.APIToken{}).Error)
+}
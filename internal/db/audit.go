@@ -0,0 +1,71 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func CreateAuditLogs(logs []*model.AuditLog) error {
+	return errs.WithStack(db.Create(logs).Error)
+}
+
+func auditQuery(q *model.AuditQuery) *gorm.DB {
+	tx := db.Model(&model.AuditLog{})
+	if q.Username != "" {
+		tx = tx.Where(fmt.Sprintf("%s = ?", columnName("username")), q.Username)
+	}
+	if q.Protocol != "" {
+		tx = tx.Where(fmt.Sprintf("%s = ?", columnName("protocol")), q.Protocol)
+	}
+	if q.Action != "" {
+		tx = tx.Where(fmt.Sprintf("%s = ?", columnName("action")), q.Action)
+	}
+	if q.Path != "" {
+		// the path matches both the source and the destination by prefix
+		tx = tx.Where(fmt.Sprintf("(%s LIKE ? OR %s LIKE ?)", columnName("path"), columnName("dst_path")), q.Path+"%", q.Path+"%")
+	}
+	if q.Success != nil {
+		tx = tx.Where(fmt.Sprintf("%s = ?", columnName("success")), *q.Success)
+	}
+	if q.IP != "" {
+		tx = tx.Where(fmt.Sprintf("%s = ?", columnName("ip")), q.IP)
+	}
+	if !q.Since.IsZero() {
+		tx = tx.Where(fmt.Sprintf("%s >= ?", columnName("created_at")), q.Since)
+	}
+	if !q.Until.IsZero() {
+		tx = tx.Where(fmt.Sprintf("%s < ?", columnName("created_at")), q.Until)
+	}
+	return tx
+}
+
+// GetAuditLogs returns the audit logs matching q, the latest first
+func GetAuditLogs(q *model.AuditQuery, pageIndex, pageSize int) (logs []model.AuditLog, count int64, err error) {
+	tx := auditQuery(q)
+	if err = tx.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get audit logs count")
+	}
+	err = tx.Order(fmt.Sprintf("%s DESC", columnName("id"))).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&logs).Error
+	if err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find audit logs")
+	}
+	return logs, count, nil
+}
+
+// WalkAuditLogs calls fn with the audit logs matching q in batches, the oldest first
+func WalkAuditLogs(q *model.AuditQuery, batchSize int, fn func(logs []model.AuditLog) error) error {
+	var logs []model.AuditLog
+	err := auditQuery(q).Order(columnName("id")).FindInBatches(&logs, batchSize, func(_ *gorm.DB, _ int) error {
+		return fn(logs)
+	}).Error
+	return errs.WithStack(err)
+}
+
+func DeleteAuditLogsBefore(t time.Time) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s < ?", columnName("created_at")), t).Delete(&model.AuditLog{}).Error)
+}
@@ -51,6 +51,28 @@ func Init(d *gorm.DB) {
 		&model.SearchNode{},
 		&model.TaskItem{},
 		&model.SSHPublicKey{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.ScheduledJob{},
+		&model.ScheduledJobRun{},
+		&model.TrashItem{},
+		&model.QuotaUsage{},
+		&model.APIToken{},
+		&model.Group{},
+		&model.UserGroup{},
+		&model.ACLRule{},
+		&model.Session{},
+		&model.AuditLog{},
+		&model.Share{},
+		&model.FileRequest{},
+		&model.RecoveryCode{},
+		&model.TwoFactorGrace{},
+		&model.DeadProp{},
+		&model.WebDAVLock{},
+		&model.S3Key{},
+		&model.S3Object{},
+		&model.S3MultipartUpload{},
+		&model.S3MultipartPart{},
 	}
 
 	err := AutoMigrate(modelsToMigrate...)
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"fmt"
+	stdpath "path"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+func GetDeadProps(path string) ([]model.DeadProp, error) {
+	var props []model.DeadProp
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("path")), path).Find(&props).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find dead props")
+	}
+	return props, nil
+}
+
+// PatchDeadProps sets props and removes the props named by remove of path in one transaction
+func PatchDeadProps(path string, props []model.DeadProp, remove []model.DeadProp) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range append(props, remove...) {
+			err := tx.Where(fmt.Sprintf("%s = ? AND %s = ? AND %s = ?", columnName("path"), columnName("space"), columnName("local")), path, p.Space, p.Local).
+				Delete(&model.DeadProp{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(props) == 0 {
+			return nil
+		}
+		for i := range props {
+			props[i].ID = 0
+			props[i].Path = path
+		}
+		return tx.Create(&props).Error
+	}))
+}
+
+// GetDeadPropsUnder returns the props of path and its sub paths
+func GetDeadPropsUnder(path string) ([]model.DeadProp, error) {
+	props, err := deadPropsUnder(db, path)
+	return props, errs.Wrapf(err, "failed find dead props")
+}
+
+// GetDeadPropsWithChildren returns the props of path and its direct children
+func GetDeadPropsWithChildren(path string) ([]model.DeadProp, error) {
+	var props []model.DeadProp
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	err := db.Where(fmt.Sprintf("%s = ? OR (%s LIKE ? AND %s NOT LIKE ?)", columnName("path"), columnName("path"), columnName("path")),
+		path, prefix+"%", prefix+"%/%").Find(&props).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed find dead props")
+	}
+	// the wildcards in path may make LIKE match more
+	n := 0
+	for _, p := range props {
+		if p.Path == path || (p.Path != "/" && stdpath.Dir(p.Path) == path) {
+			props[n] = p
+			n++
+		}
+	}
+	return props[:n], nil
+}
+
+// deadPropsUnder returns the props of path and its sub paths.
+// The wildcards in path may make LIKE match more, the result is filtered again.
+func deadPropsUnder(tx *gorm.DB, path string) ([]model.DeadProp, error) {
+	var props []model.DeadProp
+	err := tx.Where(fmt.Sprintf("%s = ? OR %s LIKE ?", columnName("path"), columnName("path")), path, strings.TrimSuffix(path, "/")+"/%").
+		Find(&props).Error
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, p := range props {
+		if utils.IsSubPath(path, p.Path) {
+			props[n] = p
+			n++
+		}
+	}
+	return props[:n], nil
+}
+
+func deleteDeadPropsUnder(tx *gorm.DB, path string) error {
+	props, err := deadPropsUnder(tx, path)
+	if err != nil || len(props) == 0 {
+		return err
+	}
+	ids := make([]uint, len(props))
+	for i := range props {
+		ids[i] = props[i].ID
+	}
+	return tx.Delete(&model.DeadProp{}, ids).Error
+}
+
+// DeleteDeadProps deletes the props of path and its sub paths
+func DeleteDeadProps(path string) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		return deleteDeadPropsUnder(tx, path)
+	}))
+}
+
+// MoveDeadProps moves the props of src and its sub paths to dst, the props of dst are replaced
+func MoveDeadProps(src, dst string) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		// the overwritten dst must not keep its props even if src has none
+		if err := deleteDeadPropsUnder(tx, dst); err != nil {
+			return err
+		}
+		props, err := deadPropsUnder(tx, src)
+		if err != nil || len(props) == 0 {
+			return err
+		}
+		for _, p := range props {
+			err = tx.Model(&model.DeadProp{}).Where(fmt.Sprintf("%s = ?", columnName("id")), p.ID).
+				Update("path", dst+strings.TrimPrefix(p.Path, src)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// CopyDeadProps copies the props of src and its sub paths to dst, the props of dst are replaced
+func CopyDeadProps(src, dst string) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		// the overwritten dst must not keep its props even if src has none
+		if err := deleteDeadPropsUnder(tx, dst); err != nil {
+			return err
+		}
+		props, err := deadPropsUnder(tx, src)
+		if err != nil || len(props) == 0 {
+			return err
+		}
+		for i := range props {
+			props[i].ID = 0
+			props[i].Path = dst + strings.TrimPrefix(props[i].Path, src)
+		}
+		return tx.Create(&props).Error
+	}))
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// GetFileRequests returns the file requests of the user, or of all the users if userID is 0, the latest first
+func GetFileRequests(userID uint, pageIndex, pageSize int) (requests []model.FileRequest, count int64, err error) {
+	requestDB := db.Model(&model.FileRequest{})
+	if userID != 0 {
+		requestDB = requestDB.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID)
+	}
+	if err = requestDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get file requests count")
+	}
+	err = requestDB.Order(fmt.Sprintf("%s DESC", columnName("created_at"))).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&requests).Error
+	if err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find file requests")
+	}
+	return requests, count, nil
+}
+
+func GetFileRequestByID(id string) (*model.FileRequest, error) {
+	var r model.FileRequest
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).First(&r).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get file request")
+	}
+	return &r, nil
+}
+
+func CreateFileRequest(r *model.FileRequest) error {
+	return errs.WithStack(db.Create(r).Error)
+}
+
+// UpdateFileRequest saves the settings of the file request, the statistics are left as they are
+func UpdateFileRequest(r *model.FileRequest) error {
+	return errs.WithStack(db.Model(r).Select("pwd_hash", "salt", "expires_at", "max_file_size", "allowed_types",
+		"quota", "rename_policy", "disabled", "remark").Updates(r).Error)
+}
+
+func DeleteFileRequestByID(id string) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).Delete(&model.FileRequest{}).Error)
+}
+
+// DeleteFileRequestsByUserID deletes the file requests of the user and returns their ids
+func DeleteFileRequestsByUserID(userID uint) ([]string, error) {
+	var ids []string
+	requestDB := db.Model(&model.FileRequest{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID)
+	if err := requestDB.Pluck("id", &ids).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find user's file requests")
+	}
+	if err := requestDB.Delete(&model.FileRequest{}).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed delete user's file requests")
+	}
+	return ids, nil
+}
+
+// TakeFileRequestQuota adds size bytes to the usage of the file request, it returns false without
+// adding if the quota would be exceeded, so the quota holds under concurrent uploads
+func TakeFileRequestQuota(id string, size int64) (bool, error) {
+	res := db.Model(&model.FileRequest{}).
+		Where(fmt.Sprintf("%s = ? AND (%s = 0 OR %s + ? <= %s)", columnName("id"), columnName("quota"),
+			columnName("used"), columnName("quota")), id, size).
+		Update("used", gorm.Expr(fmt.Sprintf("%s + ?", columnName("used")), size))
+	if res.Error != nil {
+		return false, errs.Wrapf(res.Error, "failed take file request quota")
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// ReleaseFileRequestQuota subtracts size bytes from the usage of the file request
+func ReleaseFileRequestQuota(id string, size int64) error {
+	return errs.WithStack(db.Model(&model.FileRequest{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Update("used", gorm.Expr(fmt.Sprintf("%s - ?", columnName("used")), size)).Error)
+}
+
+// AddFileRequestUpload counts an upload through the file request
+func AddFileRequestUpload(id string) error {
+	return errs.WithStack(db.Model(&model.FileRequest{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Updates(map[string]any{
+			"uploads":        gorm.Expr(fmt.Sprintf("%s + 1", columnName("uploads"))),
+			"last_upload_at": time.Now(),
+		}).Error)
+}
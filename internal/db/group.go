@@ -0,0 +1,178 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func GetGroups(pageIndex, pageSize int) (groups []model.Group, count int64, err error) {
+	groupDB := db.Model(&model.Group{})
+	if err = groupDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get groups count")
+	}
+	if err = groupDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&groups).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find groups")
+	}
+	return groups, count, nil
+}
+
+func GetAllGroups() ([]model.Group, error) {
+	var groups []model.Group
+	if err := db.Order(columnName("id")).Find(&groups).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find groups")
+	}
+	return groups, nil
+}
+
+func GetGroupByID(id uint) (*model.Group, error) {
+	var g model.Group
+	if err := db.First(&g, id).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get group")
+	}
+	return &g, nil
+}
+
+func GetGroupByName(name string) (*model.Group, error) {
+	var g model.Group
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("name")), name).First(&g).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get group")
+	}
+	return &g, nil
+}
+
+func GetGroupsByIDs(ids []uint) ([]model.Group, error) {
+	var groups []model.Group
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	if err := db.Order(columnName("id")).Find(&groups, ids).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find groups")
+	}
+	return groups, nil
+}
+
+func CreateGroup(g *model.Group) error {
+	return errs.WithStack(db.Create(g).Error)
+}
+
+func UpdateGroup(g *model.Group) error {
+	return errs.WithStack(db.Save(g).Error)
+}
+
+// DeleteGroupByID deletes the group and the memberships of it
+func DeleteGroupByID(id uint) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("group_id")), id).Delete(&model.UserGroup{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Group{}, id).Error
+	}))
+}
+
+func GetUserGroupIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&model.UserGroup{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).
+		Order(columnName("group_id")).Pluck("group_id", &ids).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed get user's groups")
+	}
+	return ids, nil
+}
+
+// SetUserGroups replaces the groups of the user
+func SetUserGroups(userID uint, groupIDs []uint) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Delete(&model.UserGroup{}).Error; err != nil {
+			return err
+		}
+		if len(groupIDs) == 0 {
+			return nil
+		}
+		memberships := make([]model.UserGroup, 0, len(groupIDs))
+		for _, id := range groupIDs {
+			memberships = append(memberships, model.UserGroup{UserID: userID, GroupID: id})
+		}
+		return tx.Create(&memberships).Error
+	}))
+}
+
+// GetGroupMembers returns the users in the group, the groups of the users are not loaded
+func GetGroupMembers(groupID uint) ([]model.User, error) {
+	var users []model.User
+	members := db.Model(&model.UserGroup{}).Select("user_id").Where(fmt.Sprintf("%s = ?", columnName("group_id")), groupID)
+	if err := db.Where(fmt.Sprintf("%s IN (?)", columnName("id")), members).Order(columnName("id")).Find(&users).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find group's members")
+	}
+	return users, nil
+}
+
+// AddGroupMembers adds the users to the group, the users already in it are skipped
+func AddGroupMembers(groupID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	memberships := make([]model.UserGroup, 0, len(userIDs))
+	for _, id := range userIDs {
+		memberships = append(memberships, model.UserGroup{UserID: id, GroupID: groupID})
+	}
+	return errs.WithStack(db.Clauses(clause.OnConflict{DoNothing: true}).Create(&memberships).Error)
+}
+
+// RemoveGroupMembers removes the users from the group
+func RemoveGroupMembers(groupID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ? AND %s IN ?", columnName("group_id"), columnName("user_id")), groupID, userIDs).
+		Delete(&model.UserGroup{}).Error)
+}
+
+// SetGroupMembers replaces the members of the group
+func SetGroupMembers(groupID uint, userIDs []uint) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("group_id")), groupID).Delete(&model.UserGroup{}).Error; err != nil {
+			return err
+		}
+		return createGroupMembers(tx, groupID, userIDs)
+	}))
+}
+
+// CreateGroupWithMembers creates the group and its memberships in one transaction
+func CreateGroupWithMembers(g *model.Group, userIDs []uint) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(g).Error; err != nil {
+			return err
+		}
+		return createGroupMembers(tx, g.ID, userIDs)
+	}))
+}
+
+func createGroupMembers(tx *gorm.DB, groupID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	memberships := make([]model.UserGroup, 0, len(userIDs))
+	for _, id := range userIDs {
+		memberships = append(memberships, model.UserGroup{UserID: id, GroupID: groupID})
+	}
+	return tx.Create(&memberships).Error
+}
+
+// loadUserGroups fills the GroupIDs and the Groups of u
+func loadUserGroups(u *model.User) error {
+	ids, err := GetUserGroupIDs(u.ID)
+	if err != nil {
+		return err
+	}
+	groups, err := GetGroupsByIDs(ids)
+	if err != nil {
+		return err
+	}
+	u.GroupIDs, u.Groups = ids, groups
+	return nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func whereQuotaOwner(kind string, ownerID uint) *gorm.DB {
+	return db.Model(&model.QuotaUsage{}).
+		Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("kind"), columnName("owner_id")), kind, ownerID)
+}
+
+// GetQuotaUsed returns the bytes used by the owner, 0 if nothing is recorded yet
+func GetQuotaUsed(kind string, ownerID uint) (int64, error) {
+	var usage model.QuotaUsage
+	err := whereQuotaOwner(kind, ownerID).First(&usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errs.Wrapf(err, "failed get quota usage")
+	}
+	return usage.Used, nil
+}
+
+// AddQuotaUsed adds delta to the bytes used by the owner, the update is done in sql so concurrent writes add up.
+// The usage never goes below 0, so the removes of the files written before the quota can't make room.
+func AddQuotaUsed(kind string, ownerID uint, delta int64) error {
+	used := columnName("used")
+	return updateQuotaUsed(kind, ownerID,
+		gorm.Expr(fmt.Sprintf("CASE WHEN %s + ? < 0 THEN 0 ELSE %s + ? END", used, used), delta, delta), max(delta, 0))
+}
+
+// GetQuotaUsers returns the users having a quota with their groups
+func GetQuotaUsers() ([]model.User, error) {
+	var users []model.User
+	if err := db.Where(fmt.Sprintf("%s > ?", columnName("quota")), 0).Find(&users).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find users having a quota")
+	}
+	for i := range users {
+		if err := loadUserGroups(&users[i]); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// SetQuotaUsed overwrites the bytes used by the owner, it's used after a recompute
+func SetQuotaUsed(kind string, ownerID uint, used int64) error {
+	return updateQuotaUsed(kind, ownerID, used, used)
+}
+
+// updateQuotaUsed updates the row of the owner, or creates it with initial if there is none
+func updateQuotaUsed(kind string, ownerID uint, value any, initial int64) error {
+	var count int64
+	if err := whereQuotaOwner(kind, ownerID).Count(&count).Error; err != nil {
+		return errs.Wrapf(err, "failed get quota usage")
+	}
+	if count == 0 {
+		err := db.Create(&model.QuotaUsage{Kind: kind, OwnerID: ownerID, Used: initial}).Error
+		if err == nil {
+			return nil
+		}
+		// the row may have been created by a concurrent write, update it in that case
+		if whereQuotaOwner(kind, ownerID).Count(&count).Error != nil || count == 0 {
+			return errs.Wrapf(err, "failed create quota usage")
+		}
+	}
+	return errs.WithStack(whereQuotaOwner(kind, ownerID).Update("used", value).Error)
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func GetS3KeysByUserID(userID uint, pageIndex, pageSize int) (keys []model.S3Key, count int64, err error) {
+	keyDB := db.Model(&model.S3Key{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID)
+	if err = keyDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get user's s3 keys count")
+	}
+	if err = keyDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&keys).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find user's s3 keys")
+	}
+	return keys, count, nil
+}
+
+// GetEnabledS3Keys returns the keys which are not disabled
+func GetEnabledS3Keys() ([]model.S3Key, error) {
+	var keys []model.S3Key
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("disabled")), false).Find(&keys).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find enabled s3 keys")
+	}
+	return keys, nil
+}
+
+// GetLatestS3Key returns the last created key
+func GetLatestS3Key() (*model.S3Key, error) {
+	var k model.S3Key
+	if err := db.Order(fmt.Sprintf("%s DESC", columnName("id"))).First(&k).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get s3 key")
+	}
+	return &k, nil
+}
+
+func GetS3KeyByID(id uint) (*model.S3Key, error) {
+	var k model.S3Key
+	if err := db.First(&k, id).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get s3 key")
+	}
+	return &k, nil
+}
+
+func GetS3KeyByAccessKeyID(accessKeyID string) (*model.S3Key, error) {
+	var k model.S3Key
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("access_key_id")), accessKeyID).First(&k).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get s3 key")
+	}
+	return &k, nil
+}
+
+func CreateS3Key(k *model.S3Key) error {
+	return errs.WithStack(db.Create(k).Error)
+}
+
+func UpdateS3Key(k *model.S3Key) error {
+	return errs.WithStack(db.Save(k).Error)
+}
+
+func UpdateS3KeyLastUsed(id uint, t time.Time) error {
+	return errs.WithStack(db.Model(&model.S3Key{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Update("last_used_at", t).Error)
+}
+
+func DeleteS3KeyByID(id uint) error {
+	return errs.WithStack(db.Delete(&model.S3Key{}, id).Error)
+}
+
+func GetS3KeysOfUser(userID uint) ([]model.S3Key, error) {
+	var keys []model.S3Key
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Find(&keys).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find user's s3 keys")
+	}
+	return keys, nil
+}
+
+func DeleteS3KeysByUserID(userID uint) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Delete(&model.S3Key{}).Error)
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func CreateS3MultipartUpload(u *model.S3MultipartUpload) error {
+	return errs.WithStack(db.Create(u).Error)
+}
+
+// GetS3MultipartUploads returns the uploads in progress staged on the node
+func GetS3MultipartUploads(node string) ([]model.S3MultipartUpload, error) {
+	var uploads []model.S3MultipartUpload
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("node")), node).Find(&uploads).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find s3 multipart uploads")
+	}
+	return uploads, nil
+}
+
+func GetS3MultipartUploadByID(id string) (*model.S3MultipartUpload, error) {
+	var u model.S3MultipartUpload
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).First(&u).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get s3 multipart upload")
+	}
+	return &u, nil
+}
+
+// GetS3MultipartParts returns the parts staged for the upload
+func GetS3MultipartParts(uploadID string) ([]model.S3MultipartPart, error) {
+	var parts []model.S3MultipartPart
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("upload_id")), uploadID).Find(&parts).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find s3 multipart parts")
+	}
+	return parts, nil
+}
+
+// SaveS3MultipartPart creates or replaces the part with the same number, the upload is active since the part
+func SaveS3MultipartPart(p *model.S3MultipartPart) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(p).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.S3MultipartUpload{}).Where(fmt.Sprintf("%s = ?", columnName("id")), p.UploadID).
+			Update("last_active", p.LastModified).Error
+	}))
+}
+
+// DeleteS3MultipartUpload deletes the upload and its parts
+func DeleteS3MultipartUpload(id string) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(fmt.Sprintf("%s = ?", columnName("upload_id")), id).Delete(&model.S3MultipartPart{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where(fmt.Sprintf("%s = ?", columnName("id")), id).Delete(&model.S3MultipartUpload{}).Error
+	}))
+}
+
+// DeleteIdleS3MultipartUploads deletes the uploads of the other nodes than node, and their parts,
+// which are idle since before
+func DeleteIdleS3MultipartUploads(node string, before time.Time) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		err := tx.Model(&model.S3MultipartUpload{}).
+			Where(fmt.Sprintf("%s <> ? AND %s < ?", columnName("node"), columnName("last_active")), node, before).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		err = tx.Where(fmt.Sprintf("%s IN ?", columnName("upload_id")), ids).Delete(&model.S3MultipartPart{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where(fmt.Sprintf("%s IN ?", columnName("id")), ids).Delete(&model.S3MultipartUpload{}).Error
+	}))
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// s3ObjectsBatch keeps the IN list under the variable limit of sqlite
+const s3ObjectsBatch = 500
+
+// GetS3Object returns the record of path, nil if there is none
+func GetS3Object(path string) (*model.S3Object, error) {
+	var objects []model.S3Object
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("path")), path).Limit(1).Find(&objects).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get s3 object")
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	return &objects[0], nil
+}
+
+// GetS3Objects returns the records of paths, the paths without record are skipped
+func GetS3Objects(paths []string) ([]model.S3Object, error) {
+	var objects []model.S3Object
+	for i := 0; i < len(paths); i += s3ObjectsBatch {
+		var batch []model.S3Object
+		err := db.Where(fmt.Sprintf("%s IN ?", columnName("path")), paths[i:min(i+s3ObjectsBatch, len(paths))]).
+			Find(&batch).Error
+		if err != nil {
+			return nil, errs.Wrapf(err, "failed find s3 objects")
+		}
+		objects = append(objects, batch...)
+	}
+	return objects, nil
+}
+
+// SaveS3Object creates or replaces the record of o.Path
+func SaveS3Object(o *model.S3Object) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Model(&model.S3Object{}).Where(fmt.Sprintf("%s = ?", columnName("path")), o.Path).Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		o.ID = 0
+		if len(ids) > 0 {
+			o.ID = ids[0]
+		}
+		return tx.Save(o).Error
+	}))
+}
+
+// s3ObjectsUnder returns the records of path and its sub paths.
+// The wildcards in path may make LIKE match more, the result is filtered again.
+func s3ObjectsUnder(tx *gorm.DB, path string) ([]model.S3Object, error) {
+	var objects []model.S3Object
+	err := tx.Where(fmt.Sprintf("%s = ? OR %s LIKE ?", columnName("path"), columnName("path")), path, strings.TrimSuffix(path, "/")+"/%").
+		Find(&objects).Error
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, o := range objects {
+		if utils.IsSubPath(path, o.Path) {
+			objects[n] = o
+			n++
+		}
+	}
+	return objects[:n], nil
+}
+
+func deleteS3ObjectsUnder(tx *gorm.DB, path string) error {
+	objects, err := s3ObjectsUnder(tx, path)
+	if err != nil || len(objects) == 0 {
+		return err
+	}
+	ids := make([]uint, len(objects))
+	for i := range objects {
+		ids[i] = objects[i].ID
+	}
+	return tx.Delete(&model.S3Object{}, ids).Error
+}
+
+// DeleteS3Objects deletes the records of path and its sub paths
+func DeleteS3Objects(path string) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		return deleteS3ObjectsUnder(tx, path)
+	}))
+}
+
+// MoveS3Objects moves the records of src and its sub paths to dst, the records of dst are replaced
+func MoveS3Objects(src, dst string) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		objects, err := s3ObjectsUnder(tx, src)
+		if err != nil || len(objects) == 0 {
+			return err
+		}
+		if err = deleteS3ObjectsUnder(tx, dst); err != nil {
+			return err
+		}
+		for _, o := range objects {
+			err = tx.Model(&model.S3Object{}).Where(fmt.Sprintf("%s = ?", columnName("id")), o.ID).
+				Update("path", dst+strings.TrimPrefix(o.Path, src)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// CopyS3Objects copies the records of src and its sub paths to dst, the records of dst are replaced
+func CopyS3Objects(src, dst string) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		objects, err := s3ObjectsUnder(tx, src)
+		if err != nil || len(objects) == 0 {
+			return err
+		}
+		if err = deleteS3ObjectsUnder(tx, dst); err != nil {
+			return err
+		}
+		for i := range objects {
+			objects[i].ID = 0
+			objects[i].Path = dst + strings.TrimPrefix(objects[i].Path, src)
+		}
+		return tx.Create(&objects).Error
+	}))
+}
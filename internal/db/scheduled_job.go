@@ -0,0 +1,83 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func GetScheduledJobs(pageIndex, pageSize int) (jobs []model.ScheduledJob, count int64, err error) {
+	jobDB := db.Model(&model.ScheduledJob{})
+	if err = jobDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get scheduled jobs count")
+	}
+	if err = jobDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find scheduled jobs")
+	}
+	return jobs, count, nil
+}
+
+func GetEnabledScheduledJobs() ([]model.ScheduledJob, error) {
+	var jobs []model.ScheduledJob
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("disabled")), false).Find(&jobs).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find enabled scheduled jobs")
+	}
+	return jobs, nil
+}
+
+func GetScheduledJobByID(id uint) (*model.ScheduledJob, error) {
+	var j model.ScheduledJob
+	if err := db.First(&j, id).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get scheduled job")
+	}
+	return &j, nil
+}
+
+func CreateScheduledJob(j *model.ScheduledJob) error {
+	return errs.WithStack(db.Create(j).Error)
+}
+
+func UpdateScheduledJob(j *model.ScheduledJob) error {
+	return errs.WithStack(db.Save(j).Error)
+}
+
+// UpdateScheduledJobResult only updates the result columns, the job may be edited while it's running
+func UpdateScheduledJobResult(id uint, lastRunAt time.Time, lastError string) error {
+	return errs.WithStack(db.Model(&model.ScheduledJob{ID: id}).Updates(map[string]any{
+		"last_run_at": lastRunAt,
+		"last_error":  lastError,
+	}).Error)
+}
+
+func DeleteScheduledJobByID(id uint) error {
+	err := db.Where(fmt.Sprintf("%s = ?", columnName("job_id")), id).Delete(&model.ScheduledJobRun{}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed delete scheduled job runs")
+	}
+	return errs.WithStack(db.Delete(&model.ScheduledJob{}, id).Error)
+}
+
+func CreateScheduledJobRun(r *model.ScheduledJobRun) error {
+	return errs.WithStack(db.Create(r).Error)
+}
+
+func UpdateScheduledJobRun(r *model.ScheduledJobRun) error {
+	return errs.WithStack(db.Save(r).Error)
+}
+
+func GetScheduledJobRuns(jobID uint, pageIndex, pageSize int) (runs []model.ScheduledJobRun, count int64, err error) {
+	runDB := db.Model(&model.ScheduledJobRun{}).Where(fmt.Sprintf("%s = ?", columnName("job_id")), jobID)
+	if err = runDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get scheduled job runs count")
+	}
+	if err = runDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find scheduled job runs")
+	}
+	return runs, count, nil
+}
+
+func DeleteScheduledJobRunsBefore(t time.Time) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s < ?", columnName("started_at")), t).Delete(&model.ScheduledJobRun{}).Error)
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// GetSessionsByUserID returns the sessions of the user which are not expired, the latest seen first
+func GetSessionsByUserID(userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	err := db.Where(fmt.Sprintf("%s = ? AND %s > ?", columnName("user_id"), columnName("expires_at")), userID, time.Now()).
+		Order(fmt.Sprintf("%s DESC", columnName("last_seen_at"))).Find(&sessions).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed find user's sessions")
+	}
+	return sessions, nil
+}
+
+func GetSessionByID(id string) (*model.Session, error) {
+	var s model.Session
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).First(&s).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get session")
+	}
+	return &s, nil
+}
+
+func GetSessionByRefreshHash(hash string) (*model.Session, error) {
+	var s model.Session
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("refresh_hash")), hash).First(&s).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get session")
+	}
+	return &s, nil
+}
+
+func GetSessionByPrevRefreshHash(hash string) (*model.Session, error) {
+	var s model.Session
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("prev_refresh_hash")), hash).First(&s).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get session")
+	}
+	return &s, nil
+}
+
+func CreateSession(s *model.Session) error {
+	return errs.WithStack(db.Create(s).Error)
+}
+
+// RotateSessionRefresh saves the rotated refresh token of s only if its token is still oldHash,
+// it reports false if the token was rotated by another refresh meanwhile
+func RotateSessionRefresh(s *model.Session, oldHash string) (bool, error) {
+	res := db.Model(&model.Session{}).
+		Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("id"), columnName("refresh_hash")), s.ID, oldHash).
+		Updates(map[string]any{
+			"refresh_hash":      s.RefreshHash,
+			"prev_refresh_hash": oldHash,
+			"device":            s.Device,
+			"ip":                s.IP,
+			"user_agent":        s.UserAgent,
+			"last_seen_at":      s.LastSeenAt,
+			"expires_at":        s.ExpiresAt,
+		})
+	if res.Error != nil {
+		return false, errs.Wrapf(res.Error, "failed rotate session refresh token")
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func UpdateSessionLastSeen(id string, lastSeen time.Time, ip string) error {
+	return errs.WithStack(db.Model(&model.Session{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Updates(map[string]any{"last_seen_at": lastSeen, "ip": ip}).Error)
+}
+
+func DeleteSessionByID(id string) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).Delete(&model.Session{}).Error)
+}
+
+// DeleteSessionsByUserID deletes the sessions of the user and returns their ids
+func DeleteSessionsByUserID(userID uint) ([]string, error) {
+	var ids []string
+	sessionDB := db.Model(&model.Session{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID)
+	if err := sessionDB.Pluck("id", &ids).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find user's sessions")
+	}
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Delete(&model.Session{}).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed delete user's sessions")
+	}
+	return ids, nil
+}
+
+func DeleteExpiredSessions() error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s < ?", columnName("expires_at")), time.Now()).Delete(&model.Session{}).Error)
+}
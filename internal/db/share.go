@@ -0,0 +1,81 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// GetShares returns the shares of the user, or of all the users if userID is 0, the latest first
+func GetShares(userID uint, pageIndex, pageSize int) (shares []model.Share, count int64, err error) {
+	shareDB := db.Model(&model.Share{})
+	if userID != 0 {
+		shareDB = shareDB.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID)
+	}
+	if err = shareDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get shares count")
+	}
+	err = shareDB.Order(fmt.Sprintf("%s DESC", columnName("created_at"))).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&shares).Error
+	if err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find shares")
+	}
+	return shares, count, nil
+}
+
+func GetShareByID(id string) (*model.Share, error) {
+	var s model.Share
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).First(&s).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get share")
+	}
+	return &s, nil
+}
+
+func CreateShare(s *model.Share) error {
+	return errs.WithStack(db.Create(s).Error)
+}
+
+// UpdateShare saves the settings of the share, the statistics are left as they are
+func UpdateShare(s *model.Share) error {
+	return errs.WithStack(db.Model(s).Select("path", "is_dir", "pwd_hash", "salt", "expires_at", "max_downloads",
+		"allow_preview", "allow_download", "allow_upload", "disabled", "remark").Updates(s).Error)
+}
+
+func DeleteShareByID(id string) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("id")), id).Delete(&model.Share{}).Error)
+}
+
+// DeleteSharesByUserID deletes the shares of the user and returns their ids
+func DeleteSharesByUserID(userID uint) ([]string, error) {
+	var ids []string
+	shareDB := db.Model(&model.Share{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID)
+	if err := shareDB.Pluck("id", &ids).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find user's shares")
+	}
+	if err := shareDB.Delete(&model.Share{}).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed delete user's shares")
+	}
+	return ids, nil
+}
+
+// IncreaseShareStat adds 1 to the counter column of the share and records the access time
+func IncreaseShareStat(id, column string) error {
+	return errs.WithStack(db.Model(&model.Share{}).Where(fmt.Sprintf("%s = ?", columnName("id")), id).
+		Updates(map[string]any{column: gorm.Expr(fmt.Sprintf("%s + 1", columnName(column))), "last_access_at": time.Now()}).Error)
+}
+
+// TakeShareDownload counts a download of the share, it returns false without counting
+// if the share reaches its max downloads, so the limit holds under concurrent downloads
+func TakeShareDownload(id string) (bool, error) {
+	res := db.Model(&model.Share{}).
+		Where(fmt.Sprintf("%s = ? AND (%s = 0 OR %s < %s)", columnName("id"), columnName("max_downloads"),
+			columnName("downloads"), columnName("max_downloads")), id).
+		Updates(map[string]any{"downloads": gorm.Expr(fmt.Sprintf("%s + 1", columnName("downloads"))), "last_access_at": time.Now()})
+	if res.Error != nil {
+		return false, errs.Wrapf(res.Error, "failed count share download")
+	}
+	return res.RowsAffected > 0, nil
+}
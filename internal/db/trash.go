@@ -0,0 +1,61 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// GetTrashItems returns the items of a storage removed from under pathPrefix, newest first
+func GetTrashItems(storageID uint, pathPrefix string, pageIndex, pageSize int) (items []model.TrashItem, count int64, err error) {
+	itemDB := db.Model(&model.TrashItem{}).Where(fmt.Sprintf("%s = ?", columnName("storage_id")), storageID)
+	if pathPrefix != "" && pathPrefix != "/" {
+		itemDB = itemDB.Where(fmt.Sprintf("(%s = ? OR %s LIKE ?)", columnName("path"), columnName("path")),
+			pathPrefix, fmt.Sprintf("%s/%%", pathPrefix))
+	}
+	if err = itemDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get trash items count")
+	}
+	if err = itemDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find trash items")
+	}
+	return items, count, nil
+}
+
+func GetTrashItemByID(id uint) (*model.TrashItem, error) {
+	var item model.TrashItem
+	if err := db.First(&item, id).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get trash item")
+	}
+	return &item, nil
+}
+
+// GetTrashItemsBefore returns the items of a storage removed before t
+func GetTrashItemsBefore(storageID uint, t time.Time) ([]model.TrashItem, error) {
+	var items []model.TrashItem
+	err := db.Where(fmt.Sprintf("%s = ? AND %s < ?", columnName("storage_id"), columnName("deleted_at")), storageID, t).
+		Find(&items).Error
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed find expired trash items")
+	}
+	return items, nil
+}
+
+func CreateTrashItem(item *model.TrashItem) error {
+	return errs.WithStack(db.Create(item).Error)
+}
+
+func UpdateTrashItem(item *model.TrashItem) error {
+	return errs.WithStack(db.Save(item).Error)
+}
+
+func DeleteTrashItemByID(id uint) error {
+	return errs.WithStack(db.Delete(&model.TrashItem{}, id).Error)
+}
+
+// DeleteTrashItemsByStorageID drops the records of a storage, the objs are left in its trash folder
+func DeleteTrashItemsByStorageID(storageID uint) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("storage_id")), storageID).Delete(&model.TrashItem{}).Error)
+}
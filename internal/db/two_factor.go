@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&model.RecoveryCode{}).Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Count(&count).Error
+	return count, errs.Wrapf(err, "failed count recovery codes")
+}
+
+// SetRecoveryCodes replaces the recovery codes of the user with codes
+func SetRecoveryCodes(userID uint, codes []model.RecoveryCode) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	}))
+}
+
+// TakeRecoveryCode deletes the recovery code of the user with hash, it reports whether there was one.
+// The code is deleted in one statement so it can't be used twice by concurrent logins.
+func TakeRecoveryCode(userID uint, hash string) (bool, error) {
+	res := db.Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("user_id"), columnName("hash")), userID, hash).
+		Delete(&model.RecoveryCode{})
+	if res.Error != nil {
+		return false, errs.Wrapf(res.Error, "failed take recovery code")
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// GetTwoFactorGrace returns the grace of the user, it's created with g.Since if the user has none
+func GetTwoFactorGrace(g *model.TwoFactorGrace) error {
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(g).Error; err != nil {
+		return errs.Wrapf(err, "failed create 2fa grace")
+	}
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("user_id")), g.UserID).First(g).Error)
+}
+
+func DeleteTwoFactorGrace(userID uint) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userID).Delete(&model.TwoFactorGrace{}).Error)
+}
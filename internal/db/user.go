@@ -16,7 +16,7 @@ func GetUserByRole(role int) (*model.User, error) {
 	if err := db.Where(user).Take(&user).Error; err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return &user, loadUserGroups(&user)
 }
 
 func GetUserByName(username string) (*model.User, error) {
@@ -24,7 +24,7 @@ func GetUserByName(username string) (*model.User, error) {
 	if err := db.Where(user).First(&user).Error; err != nil {
 		return nil, errs.Wrapf(err, "failed find user")
 	}
-	return &user, nil
+	return &user, loadUserGroups(&user)
 }
 
 func GetUserBySSOID(ssoID string) (*model.User, error) {
@@ -32,7 +32,7 @@ func GetUserBySSOID(ssoID string) (*model.User, error) {
 	if err := db.Where(user).First(&user).Error; err != nil {
 		return nil, errs.Wrapf(err, "The single sign on platform is not bound to any users")
 	}
-	return &user, nil
+	return &user, loadUserGroups(&user)
 }
 
 func GetUserByID(id uint) (*model.User, error) {
@@ -40,15 +40,28 @@ func GetUserByID(id uint) (*model.User, error) {
 	if err := db.First(&u, id).Error; err != nil {
 		return nil, errs.Wrapf(err, "failed get old user")
 	}
-	return &u, nil
+	return &u, loadUserGroups(&u)
 }
 
 func CreateUser(u *model.User) error {
-	return errs.WithStack(db.Create(u).Error)
+	if err := db.Create(u).Error; err != nil {
+		return errs.WithStack(err)
+	}
+	if u.GroupIDs == nil {
+		return nil
+	}
+	return SetUserGroups(u.ID, u.GroupIDs)
 }
 
+// UpdateUser saves u, the groups are replaced if u.GroupIDs isn't nil
 func UpdateUser(u *model.User) error {
-	return errs.WithStack(db.Save(u).Error)
+	if err := db.Save(u).Error; err != nil {
+		return errs.WithStack(err)
+	}
+	if u.GroupIDs == nil {
+		return nil
+	}
+	return SetUserGroups(u.ID, u.GroupIDs)
 }
 
 func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err error) {
@@ -63,10 +76,18 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 		Error; err != nil {
 		return nil, 0, errs.Wrapf(err, "failed get find users")
 	}
+	for i := range users {
+		if err = loadUserGroups(&users[i]); err != nil {
+			return nil, 0, err
+		}
+	}
 	return users, count, nil
 }
 
 func DeleteUserByID(id uint) error {
+	if err := SetUserGroups(id, nil); err != nil {
+		return err
+	}
 	return errs.WithStack(db.Delete(&model.User{}, id).Error)
 }
 
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"fmt"
+	stdpath "path"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// whereWebDAVLockAlive filters out the locks expired at now
+func whereWebDAVLockAlive(tx *gorm.DB, now time.Time) *gorm.DB {
+	return tx.Model(&model.WebDAVLock{}).
+		Where(fmt.Sprintf("%s IS NULL OR %s > ?", columnName("expires_at"), columnName("expires_at")), now)
+}
+
+// pathAncestors returns path and all its parents up to /
+func pathAncestors(path string) []string {
+	res := []string{path}
+	for path != "/" {
+		path = stdpath.Dir(path)
+		res = append(res, path)
+	}
+	return res
+}
+
+// GetWebDAVLocksOn returns the alive locks on path, on its parents and on its sub paths
+func GetWebDAVLocksOn(path string, now time.Time) ([]model.WebDAVLock, error) {
+	locks, err := webDAVLocksOn(db, path, now)
+	return locks, errs.Wrapf(err, "failed find webdav locks")
+}
+
+// webDAVLocksOn returns the alive locks on path, on its parents and on its sub paths.
+// The wildcards in path may make LIKE match more, the result is filtered again.
+func webDAVLocksOn(tx *gorm.DB, path string, now time.Time) ([]model.WebDAVLock, error) {
+	var locks []model.WebDAVLock
+	ancestors := pathAncestors(path)
+	err := whereWebDAVLockAlive(tx, now).
+		Where(fmt.Sprintf("%s IN ? OR %s LIKE ?", columnName("root"), columnName("root")), ancestors, strings.TrimSuffix(path, "/")+"/%").
+		Find(&locks).Error
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, l := range locks {
+		if slices.Contains(ancestors, l.Root) || utils.IsSubPath(path, l.Root) {
+			locks[n] = l
+			n++
+		}
+	}
+	return locks[:n], nil
+}
+
+// CreateWebDAVLock saves l if it doesn't conflict with the alive locks, or returns errs.ObjectLocked.
+// A lock conflicts with the lock on the same path and the infinite depth lock on a parent,
+// an infinite depth lock conflicts with the locks on the sub paths too.
+func CreateWebDAVLock(l *model.WebDAVLock, now time.Time) error {
+	return errs.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteExpiredWebDAVLocks(tx, now); err != nil {
+			return err
+		}
+		locks, err := webDAVLocksOn(tx, l.Root, now)
+		if err != nil {
+			return err
+		}
+		for _, other := range locks {
+			if other.Covers(l.Root) || !l.ZeroDepth && utils.IsSubPath(l.Root, other.Root) {
+				return errs.Wrapf(errs.ObjectLocked, "%s is locked by %s", l.Root, other.Root)
+			}
+		}
+		return tx.Create(l).Error
+	}))
+}
+
+// GetWebDAVLock returns the alive lock of token
+func GetWebDAVLock(token string, now time.Time) (*model.WebDAVLock, error) {
+	var l model.WebDAVLock
+	if err := whereWebDAVLockAlive(db, now).Where(fmt.Sprintf("%s = ?", columnName("token")), token).First(&l).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get webdav lock")
+	}
+	return &l, nil
+}
+
+// GetWebDAVLocks returns all the alive locks, the latest first
+func GetWebDAVLocks(now time.Time) ([]model.WebDAVLock, error) {
+	var locks []model.WebDAVLock
+	if err := whereWebDAVLockAlive(db, now).Order(fmt.Sprintf("%s DESC", columnName("created_at"))).Find(&locks).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find webdav locks")
+	}
+	return locks, nil
+}
+
+// RefreshWebDAVLock sets the timeout of the lock of token
+func RefreshWebDAVLock(token string, duration time.Duration, expiresAt *time.Time) error {
+	return errs.WithStack(db.Model(&model.WebDAVLock{}).Where(fmt.Sprintf("%s = ?", columnName("token")), token).
+		Updates(map[string]any{"duration": duration, "expires_at": expiresAt}).Error)
+}
+
+// DeleteWebDAVLock deletes the lock of token and reports whether there was one
+func DeleteWebDAVLock(token string) (bool, error) {
+	res := db.Where(fmt.Sprintf("%s = ?", columnName("token")), token).Delete(&model.WebDAVLock{})
+	if res.Error != nil {
+		return false, errs.Wrapf(res.Error, "failed delete webdav lock")
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func deleteExpiredWebDAVLocks(tx *gorm.DB, now time.Time) error {
+	return tx.Where(fmt.Sprintf("%s <= ?", columnName("expires_at")), now).Delete(&model.WebDAVLock{}).Error
+}
+
+// DeleteExpiredWebDAVLocks deletes the locks expired at now
+func DeleteExpiredWebDAVLocks(now time.Time) error {
+	return errs.WithStack(deleteExpiredWebDAVLocks(db, now))
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func GetWebhooks(pageIndex, pageSize int) (hooks []model.Webhook, count int64, err error) {
+	hookDB := db.Model(&model.Webhook{})
+	if err = hookDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get webhooks count")
+	}
+	if err = hookDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&hooks).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find webhooks")
+	}
+	return hooks, count, nil
+}
+
+func GetEnabledWebhooks() ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	if err := db.Where(fmt.Sprintf("%s = ?", columnName("disabled")), false).Find(&hooks).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed find enabled webhooks")
+	}
+	return hooks, nil
+}
+
+func GetWebhookByID(id uint) (*model.Webhook, error) {
+	var w model.Webhook
+	if err := db.First(&w, id).Error; err != nil {
+		return nil, errs.Wrapf(err, "failed get webhook")
+	}
+	return &w, nil
+}
+
+func CreateWebhook(w *model.Webhook) error {
+	return errs.WithStack(db.Create(w).Error)
+}
+
+func UpdateWebhook(w *model.Webhook) error {
+	return errs.WithStack(db.Save(w).Error)
+}
+
+func DeleteWebhookByID(id uint) error {
+	err := db.Where(fmt.Sprintf("%s = ?", columnName("webhook_id")), id).Delete(&model.WebhookDelivery{}).Error
+	if err != nil {
+		return errs.Wrapf(err, "failed delete webhook deliveries")
+	}
+	return errs.WithStack(db.Delete(&model.Webhook{}, id).Error)
+}
+
+func CreateWebhookDelivery(d *model.WebhookDelivery) error {
+	return errs.WithStack(db.Create(d).Error)
+}
+
+func UpdateWebhookDelivery(d *model.WebhookDelivery) error {
+	return errs.WithStack(db.Save(d).Error)
+}
+
+func GetWebhookDeliveries(webhookID uint, pageIndex, pageSize int) (deliveries []model.WebhookDelivery, count int64, err error) {
+	deliveryDB := db.Model(&model.WebhookDelivery{}).Where(fmt.Sprintf("%s = ?", columnName("webhook_id")), webhookID)
+	if err = deliveryDB.Count(&count).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed get webhook deliveries count")
+	}
+	if err = deliveryDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, errs.Wrapf(err, "failed find webhook deliveries")
+	}
+	return deliveries, count, nil
+}
+
+func DeleteWebhookDeliveriesBefore(t time.Time) error {
+	return errs.WithStack(db.Where(fmt.Sprintf("%s < ?", columnName("created_at")), t).Delete(&model.WebhookDelivery{}).Error)
+}
@@ -0,0 +1,111 @@
+package driver
+
+import (
+	"context"
+)
+
+// Capabilities tells which optional interfaces a driver implements
+type Capabilities struct {
+	Mkdir             bool `json:"mkdir"`
+	Move              bool `json:"move"`
+	Rename            bool `json:"rename"`
+	Copy              bool `json:"copy"`
+	Remove            bool `json:"remove"`
+	Put               bool `json:"put"`
+	PutURL            bool `json:"put_url"`
+	ResumablePut      bool `json:"resumable_put"`
+	ArchiveReader     bool `json:"archive_reader"`
+	ArchiveDecompress bool `json:"archive_decompress"`
+	Other             bool `json:"other"`
+}
+
+// CapabilitiesResolver is implemented by drivers forwarding to other storages, such as Alias and Crypt,
+// their capabilities depend on the storage a path finally lands on
+type CapabilitiesResolver interface {
+	// ResolveCapabilities path is relative to the root of the storage
+	ResolveCapabilities(ctx context.Context, path string) (Capabilities, error)
+}
+
+// GetCapabilities computes the capabilities of d from the interfaces it implements
+func GetCapabilities(d any) Capabilities {
+	var c Capabilities
+	switch d.(type) {
+	case Mkdir, MkdirResult:
+		c.Mkdir = true
+	}
+	switch d.(type) {
+	case Move, MoveResult:
+		c.Move = true
+	}
+	switch d.(type) {
+	case Rename, RenameResult:
+		c.Rename = true
+	}
+	switch d.(type) {
+	case Copy, CopyResult:
+		c.Copy = true
+	}
+	switch d.(type) {
+	case Put, PutResult:
+		c.Put = true
+	}
+	switch d.(type) {
+	case PutURL, PutURLResult:
+		c.PutURL = true
+	}
+	switch d.(type) {
+	case ArchiveDecompress, ArchiveDecompressResult:
+		c.ArchiveDecompress = true
+	}
+	_, c.ResumablePut = d.(ResumablePut)
+	_, c.Remove = d.(Remove)
+	_, c.ArchiveReader = d.(ArchiveReader)
+	_, c.Other = d.(Other)
+	if m, ok := d.(Meta); ok && m.Config().NoUpload {
+		c.Put = false
+		c.ResumablePut = false
+	}
+	return c
+}
+
+// And keeps the capabilities supported by both c and o
+func (c Capabilities) And(o Capabilities) Capabilities {
+	return Capabilities{
+		Mkdir:             c.Mkdir && o.Mkdir,
+		Move:              c.Move && o.Move,
+		Rename:            c.Rename && o.Rename,
+		Copy:              c.Copy && o.Copy,
+		Remove:            c.Remove && o.Remove,
+		Put:               c.Put && o.Put,
+		PutURL:            c.PutURL && o.PutURL,
+		ResumablePut:      c.ResumablePut && o.ResumablePut,
+		ArchiveReader:     c.ArchiveReader && o.ArchiveReader,
+		ArchiveDecompress: c.ArchiveDecompress && o.ArchiveDecompress,
+		Other:             c.Other && o.Other,
+	}
+}
+
+// Or keeps the capabilities supported by either c or o
+func (c Capabilities) Or(o Capabilities) Capabilities {
+	return Capabilities{
+		Mkdir:             c.Mkdir || o.Mkdir,
+		Move:              c.Move || o.Move,
+		Rename:            c.Rename || o.Rename,
+		Copy:              c.Copy || o.Copy,
+		Remove:            c.Remove || o.Remove,
+		Put:               c.Put || o.Put,
+		PutURL:            c.PutURL || o.PutURL,
+		ResumablePut:      c.ResumablePut || o.ResumablePut,
+		ArchiveReader:     c.ArchiveReader || o.ArchiveReader,
+		ArchiveDecompress: c.ArchiveDecompress || o.ArchiveDecompress,
+		Other:             c.Other || o.Other,
+	}
+}
+
+// ReadOnly drops all the capabilities that modify the storage
+func (c Capabilities) ReadOnly() Capabilities {
+	return Capabilities{
+		ArchiveReader: c.ArchiveReader,
+		Other:         c.Other,
+	}
+}
@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+)
+
+// fakeMeta only answers Config, the other methods of Meta are never called by GetCapabilities
+type fakeMeta struct {
+	Meta
+	config Config
+}
+
+func (d *fakeMeta) Config() Config {
+	return d.config
+}
+
+type fakeWriter struct {
+	fakeMeta
+}
+
+func (d *fakeWriter) MakeDir(context.Context, model.Obj, string) error {
+	return nil
+}
+
+func (d *fakeWriter) Remove(context.Context, model.Obj) error {
+	return nil
+}
+
+func (d *fakeWriter) Put(context.Context, model.Obj, model.FileStreamer, UpdateProgress) error {
+	return nil
+}
+
+func (d *fakeWriter) PutResumable(context.Context, model.Obj, model.FileStreamer, *model.TransferCheckpoint, UpdateProgress) (model.Obj, error) {
+	return nil, nil
+}
+
+func (d *fakeWriter) AbortResumable(context.Context, *model.TransferCheckpoint) error {
+	return nil
+}
+
+func TestGetCapabilities(t *testing.T) {
+	c := GetCapabilities(&fakeWriter{})
+	want := Capabilities{Mkdir: true, Remove: true, Put: true, ResumablePut: true}
+	if c != want {
+		t.Errorf("expected %+v, got %+v", want, c)
+	}
+	c = GetCapabilities(&fakeWriter{fakeMeta{config: Config{NoUpload: true}}})
+	want = Capabilities{Mkdir: true, Remove: true}
+	if c != want {
+		t.Errorf("expected no upload with NoUpload, got %+v", c)
+	}
+	if c = GetCapabilities(&fakeMeta{}); c != (Capabilities{}) {
+		t.Errorf("expected no capabilities, got %+v", c)
+	}
+}
+
+func TestCapabilitiesAndOr(t *testing.T) {
+	a := Capabilities{Mkdir: true, Put: true, ResumablePut: true, ArchiveReader: true}
+	b := Capabilities{Put: true, ResumablePut: true, Remove: true}
+	if c, want := a.And(b), (Capabilities{Put: true, ResumablePut: true}); c != want {
+		t.Errorf("And: expected %+v, got %+v", want, c)
+	}
+	b.ResumablePut = false
+	if c := a.And(b); c.ResumablePut {
+		t.Error("And: expected ResumablePut to need both")
+	}
+	if c, want := a.Or(b), (Capabilities{Mkdir: true, Put: true, ResumablePut: true, Remove: true, ArchiveReader: true}); c != want {
+		t.Errorf("Or: expected %+v, got %+v", want, c)
+	}
+	if c, want := a.ReadOnly(), (Capabilities{ArchiveReader: true}); c != want {
+		t.Errorf("ReadOnly: expected %+v, got %+v", want, c)
+	}
+}
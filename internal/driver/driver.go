@@ -167,6 +167,20 @@ type PutResult interface {
 	Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up UpdateProgress) (model.Obj, error)
 }
 
+type ResumablePut interface {
+	// PutResumable put a file like PutResult, but the upload can be continued from cp
+	// 1. If cp.GetSessionID() is not empty, check the session on the remote and continue after the last
+	//    committed part, read the rest of the file with `file.RangeRead`. If the session is gone, start over.
+	// 2. When a new upload session is created, call `cp.Start` with its id.
+	// 3. Call `cp.Commit` once a part is committed on the remote, the checkpoint is persisted with the task.
+	// 4. Keep in cp.Extra whatever AbortResumable needs to find the session.
+	// Only called for cross-storage transfers, other uploads still go through Put.
+	PutResumable(ctx context.Context, dstDir model.Obj, file model.FileStreamer, cp *model.TransferCheckpoint, up UpdateProgress) (model.Obj, error)
+	// AbortResumable drops the session of cp on the remote, it's called when cp is discarded
+	// so the committed parts don't stay on the remote forever
+	AbortResumable(ctx context.Context, cp *model.TransferCheckpoint) error
+}
+
 type PutURLResult interface {
 	// PutURL directly put a URL into the storage
 	// Applicable to index-based drivers like URL-Tree or drivers that support uploading files as URLs
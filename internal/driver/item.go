@@ -14,9 +14,10 @@ type Item struct {
 }
 
 type Info struct {
-	Common     []Item `json:"common"`
-	Additional []Item `json:"additional"`
-	Config     Config `json:"config"`
+	Common       []Item       `json:"common"`
+	Additional   []Item       `json:"additional"`
+	Config       Config       `json:"config"`
+	Capabilities Capabilities `json:"capabilities"`
 }
 
 type IRootPath interface {
@@ -0,0 +1,65 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// CheckACL checks the acl rules allow the user in ctx to do action on path,
+// all the fs functions check it so every protocol gets the same answer.
+// The trash is only reachable by the admins, it's not found for the other users.
+// The calls without a user in ctx are made by the server itself and aren't checked.
+func CheckACL(ctx context.Context, action, path string) error {
+	user, _ := ctx.Value(consts.UserKey).(*model.User)
+	if user != nil && !user.IsAdmin() && InTrash(path) {
+		return errs.WithStack(errs.ObjectNotFound)
+	}
+	return op.CheckACL(user, action, path)
+}
+
+// checkACLTree checks the acl rules allow the user in ctx to do action on path and everything under it,
+// the actions on a dir apply to all the objs in it
+func checkACLTree(ctx context.Context, action, path string) error {
+	if err := CheckACL(ctx, action, path); err != nil {
+		return err
+	}
+	user, _ := ctx.Value(consts.UserKey).(*model.User)
+	return op.CheckACLTree(user, action, path)
+}
+
+// checkTransferACL checks the user can do action on srcPath and all the objs under it, and write the result into dstDirPath
+func checkTransferACL(ctx context.Context, action, srcPath, dstDirPath string) error {
+	if err := checkACLTree(ctx, action, srcPath); err != nil {
+		return err
+	}
+	return CheckACL(ctx, model.ACLWrite, stdpath.Join(dstDirPath, stdpath.Base(srcPath)))
+}
+
+// checkRenameACL checks the user can rename srcPath and all the objs under it, which move with it,
+// and write dstPath
+func checkRenameACL(ctx context.Context, srcPath, dstPath string) error {
+	if err := checkACLTree(ctx, model.ACLRename, srcPath); err != nil {
+		return err
+	}
+	return CheckACL(ctx, model.ACLWrite, dstPath)
+}
+
+// filterACL drops the objs in dirPath the user in ctx isn't allowed to list
+func filterACL(ctx context.Context, dirPath string, objs []model.Obj) []model.Obj {
+	user, _ := ctx.Value(consts.UserKey).(*model.User)
+	if !op.HasACLRules(user) {
+		return objs
+	}
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		if op.CheckACL(user, model.ACLList, stdpath.Join(dirPath, obj.GetName())) == nil {
+			res = append(res, obj)
+		}
+	}
+	return res
+}
@@ -115,6 +115,7 @@ func (t *ArchiveDownloadTask) RunWithoutPushUploadTask() (*ArchiveContentUploadT
 		DstActualPath: t.DstActualPath,
 		dstStorage:    t.DstStorage,
 		DstStorageMp:  t.DstStorageMp,
+		Verify:        t.Verify,
 	}
 	return uploadTask, nil
 }
@@ -130,6 +131,7 @@ type ArchiveContentUploadTask struct {
 	DstActualPath string
 	dstStorage    driver.Driver
 	DstStorageMp  string
+	Verify        bool
 	finalized     bool
 	groupID       string
 }
@@ -221,6 +223,7 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 				DstActualPath: nextDstActualPath,
 				dstStorage:    t.dstStorage,
 				DstStorageMp:  t.DstStorageMp,
+				Verify:        t.Verify,
 				groupID:       t.groupID,
 			})
 			if err != nil {
@@ -252,6 +255,14 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 		if err != nil {
 			return err
 		}
+		if t.Verify {
+			t.status = "verifying"
+			err = verifyTransfer(t.Ctx(), localVerifySource(t.FilePath, info.Size()),
+				t.dstStorage, stdpath.Join(t.DstActualPath, t.ObjName))
+			if err != nil {
+				return err
+			}
+		}
 	}
 	t.deleteSrcFile()
 	return nil
@@ -375,6 +386,7 @@ func archiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 			DstActualPath: dstDirActualPath,
 			SrcStorageMp:  srcStorage.GetStorage().MountPath,
 			DstStorageMp:  dstStorage.GetStorage().MountPath,
+			Verify:        needVerify(ctx),
 		},
 		ArchiveDecompressArgs: args,
 	}
@@ -7,11 +7,14 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/tache"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/internal/task_group"
+	"github.com/dongdio/OpenList/v4/internal/webhook"
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/utility/errs"
 	"github.com/dongdio/OpenList/v4/utility/stream"
@@ -34,10 +37,15 @@ const (
 	moveType
 )
 
+// abortCheckpointTimeout bounds aborting the upload session of a discarded checkpoint
+const abortCheckpointTimeout = 30 * time.Second
+
 type FileTransferTask struct {
 	TaskData
 	TaskType taskType
-	groupID  string
+	// Checkpoint is only used when the dst storage implements driver.ResumablePut
+	Checkpoint *model.TransferCheckpoint `json:"checkpoint,omitempty"`
+	groupID    string
 }
 
 func (t *FileTransferTask) GetName() string {
@@ -65,10 +73,36 @@ func (t *FileTransferTask) Run() error {
 
 func (t *FileTransferTask) OnSucceeded() {
 	task_group.TransferCoordinator.Done(t.groupID, true)
+	webhook.NotifyTask(t.TaskType.String(), stdpath.Join(t.DstStorageMp, t.DstActualPath), t, true)
 }
 
 func (t *FileTransferTask) OnFailed() {
+	if t.Checkpoint != nil {
+		// the task isn't retried any more, the upload won't be resumed
+		t.abortCheckpoint()
+		t.Checkpoint = nil
+		t.Persist()
+	}
+	if t.TaskType == moveType {
+		// the dst may be incomplete or fail the verification, don't remove the src
+		task_group.TransferCoordinator.AppendPayload(t.groupID, task_group.SrcPathToKeep(stdpath.Join(t.SrcStorageMp, t.SrcActualPath)))
+	}
 	task_group.TransferCoordinator.Done(t.groupID, false)
+	webhook.NotifyTask(t.TaskType.String(), stdpath.Join(t.DstStorageMp, t.DstActualPath), t, false)
+}
+
+// abortCheckpoint drops the upload session of the checkpoint on the dst storage
+func (t *FileTransferTask) abortCheckpoint() {
+	r, ok := t.DstStorage.(driver.ResumablePut)
+	if !ok || t.Checkpoint.GetSessionID() == "" {
+		return
+	}
+	// the task ctx may be canceled already
+	ctx, cancel := context.WithTimeout(context.Background(), abortCheckpointTimeout)
+	defer cancel()
+	if err := r.AbortResumable(ctx, t.Checkpoint); err != nil {
+		log.Warnf("failed abort the upload of [%s](%s): %+v", t.DstStorageMp, t.DstActualPath, err)
+	}
 }
 
 func (t *FileTransferTask) SetRetry(retry int, maxRetry int) {
@@ -118,6 +152,7 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 			DstActualPath: dstDirActualPath,
 			SrcStorageMp:  srcStorage.GetStorage().MountPath,
 			DstStorageMp:  dstStorage.GetStorage().MountPath,
+			Verify:        needVerify(ctx),
 		},
 		TaskType: taskType,
 	}
@@ -136,7 +171,10 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 		t.Base.SetCtx(ctx)
 		err = t.RunWithNextTaskCallback(callback)
 		if hasSuccess || err == nil {
-			if taskType == moveType {
+			if taskType == moveType && errs.Is(err, errs.VerifyFailed) {
+				// keep the src, some files don't match it
+				task_group.RefreshAndRemove(dstDirPath)
+			} else if taskType == moveType {
 				task_group.RefreshAndRemove(dstDirPath, task_group.SrcPathToRemove(srcObjPath))
 			} else {
 				op.DeleteCache(t.DstStorage, dstDirActualPath)
@@ -195,6 +233,7 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 					DstActualPath: dstActualPath,
 					SrcStorageMp:  t.SrcStorageMp,
 					DstStorageMp:  t.DstStorageMp,
+					Verify:        t.Verify,
 				},
 			})
 			if err != nil {
@@ -220,7 +259,36 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 	}
 	t.SetTotalBytes(ss.GetSize())
 	t.Status = "uploading"
-	return op.Put(t.Ctx(), t.DstStorage, t.DstActualPath, ss, t.SetProgress, true)
+	ctx := t.Ctx()
+	if _, ok := t.DstStorage.(driver.ResumablePut); ok {
+		// a retry or a restart continues the upload from the checkpoint if the src file is unchanged
+		fingerprint := fmt.Sprintf("%s|%d|%d|%s", stdpath.Join(t.SrcStorageMp, t.SrcActualPath),
+			srcObj.GetSize(), srcObj.ModTime().Unix(), stdpath.Join(t.DstStorageMp, t.DstActualPath))
+		if t.Checkpoint != nil && t.Checkpoint.Fingerprint != fingerprint {
+			// the upload starts over, the session of the changed src or dst is dropped
+			t.abortCheckpoint()
+		}
+		t.Checkpoint = model.ResumeCheckpoint(t.Checkpoint, fingerprint)
+		if t.Checkpoint.GetSessionID() != "" {
+			t.Status = "resuming upload"
+		}
+		t.Checkpoint.SetCommitFunc(t.Persist)
+		ctx = context.WithValue(ctx, consts.CheckpointKey, t.Checkpoint)
+	}
+	err = op.Put(ctx, t.DstStorage, t.DstActualPath, ss, t.SetProgress, true)
+	if err != nil {
+		return err
+	}
+	if t.Checkpoint != nil {
+		t.Checkpoint = nil
+		t.Persist()
+	}
+	if t.Verify {
+		t.Status = "verifying"
+		err = verifyTransfer(t.Ctx(), objVerifySource(t.Ctx(), t.SrcStorage, t.SrcActualPath, srcObj),
+			t.DstStorage, stdpath.Join(t.DstActualPath, srcObj.GetName()))
+	}
+	return err
 }
 
 var (
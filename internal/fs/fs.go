@@ -3,9 +3,11 @@ package fs
 import (
 	"context"
 	"io"
+	stdpath "path"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/dongdio/OpenList/v4/internal/audit"
 	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
@@ -23,14 +25,18 @@ type ListArgs struct {
 }
 
 func List(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error) {
-	res, err := list(ctx, path, args)
+	err := CheckACL(ctx, model.ACLList, path)
+	var res []model.Obj
+	if err == nil {
+		res, err = list(ctx, path, args)
+	}
 	if err != nil {
 		if !args.NoLog {
 			log.Errorf("failed list %s: %+v", path, err)
 		}
 		return nil, err
 	}
-	return res, nil
+	return filterACL(ctx, path, res), nil
 }
 
 type GetArgs struct {
@@ -38,7 +44,11 @@ type GetArgs struct {
 }
 
 func Get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
-	res, err := get(ctx, path)
+	err := CheckACL(ctx, model.ACLList, path)
+	var res model.Obj
+	if err == nil {
+		res, err = get(ctx, path)
+	}
 	if err != nil {
 		if !args.NoLog {
 			log.Warnf("failed get %s: %s", path, err)
@@ -49,7 +59,17 @@ func Get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
 }
 
 func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
-	res, file, err := link(ctx, path, args)
+	err := CheckACL(ctx, model.ACLRead, path)
+	var res *model.Link
+	var file model.Obj
+	if err == nil {
+		res, file, err = link(ctx, path, args)
+	}
+	entry := model.AuditLog{Action: model.AuditDownload, Path: path}
+	if file != nil {
+		entry.Size = file.GetSize()
+	}
+	audit.Record(ctx, entry, err)
 	if err != nil {
 		log.Errorf("failed link %s: %+v", path, err)
 		return nil, nil, err
@@ -58,7 +78,11 @@ func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, m
 }
 
 func MakeDir(ctx context.Context, path string, lazyCache ...bool) error {
-	err := makeDir(ctx, path, lazyCache...)
+	err := CheckACL(ctx, model.ACLWrite, path)
+	if err == nil {
+		err = makeDir(ctx, path, lazyCache...)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditMkdir, Path: path}, err)
 	if err != nil {
 		log.Errorf("failed make dir %s: %+v", path, err)
 	}
@@ -66,7 +90,12 @@ func MakeDir(ctx context.Context, path string, lazyCache ...bool) error {
 }
 
 func Move(ctx context.Context, srcPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
-	req, err := transfer(ctx, moveType, srcPath, dstDirPath, lazyCache...)
+	err := checkTransferACL(ctx, model.ACLMove, srcPath, dstDirPath)
+	var req task.TaskExtensionInfo
+	if err == nil {
+		req, err = transfer(ctx, moveType, srcPath, dstDirPath, lazyCache...)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditMove, Path: srcPath, DstPath: dstDirPath}, err)
 	if err != nil {
 		log.Errorf("failed move %s to %s: %+v", srcPath, dstDirPath, err)
 	}
@@ -74,7 +103,12 @@ func Move(ctx context.Context, srcPath, dstDirPath string, lazyCache ...bool) (t
 }
 
 func Copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
-	res, err := transfer(ctx, copyType, srcObjPath, dstDirPath, lazyCache...)
+	err := checkTransferACL(ctx, model.ACLCopy, srcObjPath, dstDirPath)
+	var res task.TaskExtensionInfo
+	if err == nil {
+		res, err = transfer(ctx, copyType, srcObjPath, dstDirPath, lazyCache...)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditCopy, Path: srcObjPath, DstPath: dstDirPath}, err)
 	if err != nil {
 		log.Errorf("failed copy %s to %s: %+v", srcObjPath, dstDirPath, err)
 	}
@@ -82,7 +116,12 @@ func Copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool)
 }
 
 func Rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) error {
-	err := rename(ctx, srcPath, dstName, lazyCache...)
+	dstPath := stdpath.Join(stdpath.Dir(srcPath), dstName)
+	err := checkRenameACL(ctx, srcPath, dstPath)
+	if err == nil {
+		err = rename(ctx, srcPath, dstName, lazyCache...)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditRename, Path: srcPath, DstPath: dstPath}, err)
 	if err != nil {
 		log.Errorf("failed rename %s to %s: %+v", srcPath, dstName, err)
 	}
@@ -90,15 +129,37 @@ func Rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) err
 }
 
 func Remove(ctx context.Context, path string) error {
-	err := remove(ctx, path)
+	err := checkACLTree(ctx, model.ACLDelete, path)
+	if err == nil {
+		err = remove(ctx, path)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditRemove, Path: path}, err)
 	if err != nil {
 		log.Errorf("failed remove %s: %+v", path, err)
 	}
 	return err
 }
 
+// RemoveEmptyDirectory removes the empty dirs under path recursively, path itself is kept
+func RemoveEmptyDirectory(ctx context.Context, path string) error {
+	err := CheckACL(ctx, model.ACLDelete, path)
+	if err == nil {
+		err = removeEmptyDirectory(ctx, path)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditRemove, Path: path, Detail: "empty directories"}, err)
+	if err != nil {
+		log.Errorf("failed remove empty directory %s: %+v", path, err)
+	}
+	return err
+}
+
 func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) error {
-	err := putDirectly(ctx, dstDirPath, file, lazyCache...)
+	dstPath := stdpath.Join(dstDirPath, file.GetName())
+	err := CheckACL(ctx, model.ACLWrite, dstPath)
+	if err == nil {
+		err = putDirectly(ctx, dstDirPath, file, lazyCache...)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditUpload, Path: dstPath, Size: file.GetSize()}, err)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
 	}
@@ -106,7 +167,13 @@ func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer
 }
 
 func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) (task.TaskExtensionInfo, error) {
-	t, err := putAsTask(ctx, dstDirPath, file)
+	dstPath := stdpath.Join(dstDirPath, file.GetName())
+	err := CheckACL(ctx, model.ACLWrite, dstPath)
+	var t task.TaskExtensionInfo
+	if err == nil {
+		t, err = putAsTask(ctx, dstDirPath, file)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditUpload, Path: dstPath, Size: file.GetSize(), Detail: "task"}, err)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
 	}
@@ -114,7 +181,11 @@ func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 }
 
 func ArchiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (*model.ArchiveMetaProvider, error) {
-	meta, err := archiveMeta(ctx, path, args)
+	err := CheckACL(ctx, model.ACLRead, path)
+	var meta *model.ArchiveMetaProvider
+	if err == nil {
+		meta, err = archiveMeta(ctx, path, args)
+	}
 	if err != nil {
 		log.Errorf("failed get archive meta %s: %+v", path, err)
 	}
@@ -122,7 +193,11 @@ func ArchiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (
 }
 
 func ArchiveList(ctx context.Context, path string, args model.ArchiveListArgs) ([]model.Obj, error) {
-	objs, err := archiveList(ctx, path, args)
+	err := CheckACL(ctx, model.ACLRead, path)
+	var objs []model.Obj
+	if err == nil {
+		objs, err = archiveList(ctx, path, args)
+	}
 	if err != nil {
 		log.Errorf("failed list archive [%s]%s: %+v", path, args.InnerPath, err)
 	}
@@ -130,7 +205,12 @@ func ArchiveList(ctx context.Context, path string, args model.ArchiveListArgs) (
 }
 
 func ArchiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args model.ArchiveDecompressArgs, lazyCache ...bool) (task.TaskExtensionInfo, error) {
-	t, err := archiveDecompress(ctx, srcObjPath, dstDirPath, args, lazyCache...)
+	err := checkTransferACL(ctx, model.ACLDecompress, srcObjPath, dstDirPath)
+	var t task.TaskExtensionInfo
+	if err == nil {
+		t, err = archiveDecompress(ctx, srcObjPath, dstDirPath, args, lazyCache...)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditDecompress, Path: srcObjPath, DstPath: dstDirPath, Detail: args.InnerPath}, err)
 	if err != nil {
 		log.Errorf("failed decompress [%s]%s: %+v", srcObjPath, args.InnerPath, err)
 	}
@@ -138,7 +218,13 @@ func ArchiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 }
 
 func ArchiveDriverExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (*model.Link, model.Obj, error) {
-	l, obj, err := archiveDriverExtract(ctx, path, args)
+	err := CheckACL(ctx, model.ACLRead, path)
+	var l *model.Link
+	var obj model.Obj
+	if err == nil {
+		l, obj, err = archiveDriverExtract(ctx, path, args)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditDownload, Path: path, Detail: args.InnerPath}, err)
 	if err != nil {
 		log.Errorf("failed extract [%s]%s: %+v", path, args.InnerPath, err)
 	}
@@ -146,11 +232,17 @@ func ArchiveDriverExtract(ctx context.Context, path string, args model.ArchiveIn
 }
 
 func ArchiveInternalExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (io.ReadCloser, int64, error) {
-	l, obj, err := archiveInternalExtract(ctx, path, args)
+	err := CheckACL(ctx, model.ACLRead, path)
+	var rc io.ReadCloser
+	var size int64
+	if err == nil {
+		rc, size, err = archiveInternalExtract(ctx, path, args)
+	}
+	audit.Record(ctx, model.AuditLog{Action: model.AuditDownload, Path: path, Size: size, Detail: args.InnerPath}, err)
 	if err != nil {
 		log.Errorf("failed extract [%s]%s: %+v", path, args.InnerPath, err)
 	}
-	return l, obj, err
+	return rc, size, err
 }
 
 type GetStoragesArgs struct {
@@ -164,7 +256,20 @@ func GetStorage(path string, args *GetStoragesArgs) (driver.Driver, error) {
 	return storageDriver, nil
 }
 
+// GetCapabilities returns the capabilities of the storage path is in,
+// virtual directories above the mount points have no capabilities
+func GetCapabilities(ctx context.Context, path string) driver.Capabilities {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return driver.Capabilities{}
+	}
+	return op.GetStorageCapabilities(ctx, storage, actualPath)
+}
+
 func Other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
+	if err := CheckACL(ctx, model.ACLRead, args.Path); err != nil {
+		return nil, err
+	}
 	res, err := other(ctx, args)
 	if err != nil {
 		log.Errorf("failed remove %s: %+v", args.Path, err)
@@ -173,6 +278,9 @@ func Other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
 }
 
 func PutURL(ctx context.Context, path, dstName, urlStr string) error {
+	if err := CheckACL(ctx, model.ACLWrite, stdpath.Join(path, dstName)); err != nil {
+		return err
+	}
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return errs.Wrap(err, "failed get storage")
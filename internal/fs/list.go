@@ -6,6 +6,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/utility/errs"
@@ -38,6 +39,10 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 		}
 	}
 
+	if storage != nil && storage.GetStorage().EnableTrash {
+		_objs = hideTrash(storage, actualPath, _objs)
+	}
+
 	om := model.NewObjMerge()
 	if whetherHide(user, meta, path) {
 		om.InitHideReg(meta.Hide)
@@ -46,9 +51,29 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 	return objs, nil
 }
 
+// hideTrash drops the trash folder and the objs renamed into the trash from the list
+func hideTrash(storage driver.Driver, dirPath string, objs []model.Obj) []model.Obj {
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		if !op.IsTrashObj(storage, dirPath, obj.GetName()) {
+			res = append(res, obj)
+		}
+	}
+	return res
+}
+
+// InTrash reports whether path is in the trash of its storage
+func InTrash(path string) bool {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil || !storage.GetStorage().EnableTrash {
+		return false
+	}
+	return op.InTrash(storage, actualPath)
+}
+
 func whetherHide(user *model.User, meta *model.Meta, path string) bool {
-	// if is admin, don't hide
-	if user == nil || user.CanSeeHides() {
+	// if is admin or a group shows the hides of path, don't hide
+	if user == nil || user.CanSeeHides() || user.GetMetaOverride(path).SeeHides {
 		return false
 	}
 	// if meta is nil, don't hide
@@ -2,11 +2,14 @@ package fs
 
 import (
 	"context"
+	stdpath "path"
 
+	"github.com/dongdio/OpenList/v4/consts"
 	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/generic"
 	"github.com/dongdio/OpenList/v4/utility/task"
 )
 
@@ -31,9 +34,85 @@ func remove(ctx context.Context, path string) error {
 	if err != nil {
 		return errs.Wrap(err, "failed get storage")
 	}
+	if op.TrashEnabled(storage) {
+		return op.RemoveToTrash(ctx, storage, actualPath)
+	}
 	return op.Remove(ctx, storage, actualPath)
 }
 
+func removeEmptyDirectory(ctx context.Context, srcDir string) error {
+	meta, err := op.GetNearestMeta(srcDir)
+	if err != nil && !errs.Is(errs.Cause(err), errs.MetaNotFound) {
+		return err
+	}
+	ctx = context.WithValue(ctx, consts.MetaKey, meta)
+
+	rootFiles, err := List(ctx, srcDir, &ListArgs{})
+	if err != nil {
+		return err
+	}
+
+	// the parent path of each dir
+	filePathMap := make(map[model.Obj]string)
+	// the parent dir of each dir
+	fileParentMap := make(map[model.Obj]model.Obj)
+	// dirs to check
+	removingFiles := generic.NewQueue[model.Obj]()
+	// removed dirs
+	removedFiles := make(map[string]bool)
+
+	// start from the top level dirs
+	for _, file := range rootFiles {
+		if !file.IsDir() {
+			continue
+		}
+		removingFiles.Push(file)
+		filePathMap[file] = srcDir
+	}
+
+	// remove the empty dirs from the bottom up
+	for !removingFiles.IsEmpty() {
+		removingFile := removingFiles.Pop()
+		removingFilePath := stdpath.Join(filePathMap[removingFile], removingFile.GetName())
+
+		if removedFiles[removingFilePath] {
+			continue
+		}
+
+		subFiles, err := List(ctx, removingFilePath, &ListArgs{Refresh: true})
+		if err != nil {
+			return err
+		}
+
+		if len(subFiles) == 0 {
+			// remove the empty dir
+			err = Remove(ctx, removingFilePath)
+			if err != nil {
+				return err
+			}
+			removedFiles[removingFilePath] = true
+
+			// check the parent again, it may be empty now
+			parentFile, exist := fileParentMap[removingFile]
+			if exist {
+				removingFiles.Push(parentFile)
+			}
+		} else {
+			// check the sub dirs
+			for _, subFile := range subFiles {
+				if !subFile.IsDir() {
+					continue
+				}
+				removingFiles.Push(subFile)
+				filePathMap[subFile] = removingFilePath
+				fileParentMap[subFile] = removingFile
+			}
+		}
+	}
+
+	return nil
+}
+
 func other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
 	storage, actualPath, err := op.GetStorageAndActualPath(args.Path)
 	if err != nil {
@@ -52,6 +131,8 @@ type TaskData struct {
 	DstStorage    driver.Driver `json:"-"`
 	SrcStorageMp  string        `json:"src_storage_mp"`
 	DstStorageMp  string        `json:"dst_storage_mp"`
+	// Verify compares each transferred file with its source, see consts.VerifyKey
+	Verify bool `json:"verify,omitempty"`
 }
 
 func (t *TaskData) GetStatus() string {
@@ -13,6 +13,7 @@ import (
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/internal/task_group"
+	"github.com/dongdio/OpenList/v4/internal/webhook"
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/utility/errs"
 	"github.com/dongdio/OpenList/v4/utility/task"
@@ -41,11 +42,15 @@ func (t *UploadTask) Run() error {
 }
 
 func (t *UploadTask) OnSucceeded() {
-	task_group.TransferCoordinator.Done(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), true)
+	dstDirPath := stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath)
+	task_group.TransferCoordinator.Done(dstDirPath, true)
+	webhook.NotifyTask("upload", stdpath.Join(dstDirPath, t.file.GetName()), t, true)
 }
 
 func (t *UploadTask) OnFailed() {
-	task_group.TransferCoordinator.Done(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), false)
+	dstDirPath := stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath)
+	task_group.TransferCoordinator.Done(dstDirPath, false)
+	webhook.NotifyTask("upload", stdpath.Join(dstDirPath, t.file.GetName()), t, false)
 }
 
 func (t *UploadTask) SetRetry(retry int, maxRetry int) {
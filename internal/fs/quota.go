@@ -0,0 +1,125 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// quotaMaxDepth bounds the walk computing the usage
+const quotaMaxDepth = 64
+
+// CheckQuota checks writing size bytes to path doesn't exceed the quota of the user in ctx
+// or of the storage path belongs to, size may be 0 if it's unknown yet
+func CheckQuota(ctx context.Context, path string, size int64) error {
+	storage, _, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		// the storage is checked by the upload itself
+		storage = nil
+	}
+	return op.CheckQuota(ctx, storage, size)
+}
+
+// GetQuota returns the quota applying to writes to path, see op.GetQuota
+func GetQuota(ctx context.Context, path string) (model.QuotaInfo, error) {
+	storage, _, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		storage = nil
+	}
+	return op.GetQuota(ctx, storage)
+}
+
+// RecomputeQuota walks the storages and the base paths of the users having a quota,
+// and replaces the usage tracked by the writes with the real one
+func RecomputeQuota(ctx context.Context) error {
+	var es error
+	for _, storage := range op.GetAllStorages() {
+		s := storage.GetStorage()
+		if s.Quota <= 0 {
+			continue
+		}
+		used, err := storageUsage(ctx, storage, "/", 0)
+		if err == nil {
+			err = op.SetStorageQuotaUsed(s.ID, used)
+		}
+		if err != nil {
+			es = errs.Join(es, errs.WithMessagef(err, "failed recompute usage of storage [%s]", s.MountPath))
+		}
+	}
+	for page := 1; ; page++ {
+		users, total, err := op.GetUsers(page, 100)
+		if err != nil {
+			return errs.Join(es, err)
+		}
+		for _, user := range users {
+			if user.Quota <= 0 {
+				continue
+			}
+			used, err := pathUsage(ctx, user.GetBasePath())
+			if err == nil {
+				err = op.SetUserQuotaUsed(user.ID, used)
+			}
+			if err != nil {
+				es = errs.Join(es, errs.WithMessagef(err, "failed recompute usage of user [%s]", user.Username))
+			}
+		}
+		if int64(page*100) >= total {
+			break
+		}
+	}
+	return es
+}
+
+// storageUsage sums the files in storage under path, the storages mounted inside it are not counted
+func storageUsage(ctx context.Context, storage driver.Driver, path string, depth int) (int64, error) {
+	if depth > quotaMaxDepth {
+		return 0, nil
+	}
+	objs, err := op.List(ctx, storage, path, model.ListArgs{})
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	for _, obj := range objs {
+		if utils.IsCanceled(ctx) {
+			return 0, ctx.Err()
+		}
+		if !obj.IsDir() {
+			used += obj.GetSize()
+			continue
+		}
+		size, err := storageUsage(ctx, storage, stdpath.Join(path, obj.GetName()), depth+1)
+		if err != nil {
+			log.Warnf("failed get usage of [%s]: %+v", stdpath.Join(storage.GetStorage().MountPath, path, obj.GetName()), err)
+			continue
+		}
+		used += size
+	}
+	return used, nil
+}
+
+// pathUsage sums the files under the mount path, including all the storages mounted in it
+func pathUsage(ctx context.Context, path string) (int64, error) {
+	root, err := Get(ctx, path, &GetArgs{NoLog: true})
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	err = WalkFS(ctx, quotaMaxDepth, utils.FixAndCleanPath(path), root, func(_ string, info model.Obj) error {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		if !info.IsDir() {
+			used += info.GetSize()
+		}
+		return nil
+	})
+	return used, err
+}
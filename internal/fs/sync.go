@@ -0,0 +1,394 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/tache"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/task"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// syncMaxDepth bounds the walk of the src and dst trees
+const syncMaxDepth = 64
+
+const (
+	SyncMkdir  = "mkdir"
+	SyncCopy   = "copy"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// SyncArgs describes a one-way mirror from SrcPath to DstPath, both are mount paths
+type SyncArgs struct {
+	SrcPath string `json:"src_path"`
+	DstPath string `json:"dst_path"`
+	// Delete removes the objs in DstPath which don't exist in SrcPath
+	Delete bool `json:"delete"`
+	// Include only syncs the files matching one of the globs, dirs are always walked
+	Include []string `json:"include"`
+	// Exclude skips the files and dirs matching one of the globs, excluded objs in DstPath are never deleted
+	Exclude []string `json:"exclude"`
+	Verify  bool     `json:"verify"`
+}
+
+// SyncAction is an operation needed to make DstPath match SrcPath
+type SyncAction struct {
+	Op string `json:"op"`
+	// Path is relative to SrcPath and DstPath
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type SyncTask struct {
+	task.TaskExtension
+	SyncArgs
+	Status string `json:"-"`
+}
+
+func (t *SyncTask) GetName() string {
+	return fmt.Sprintf("sync [%s] to [%s]", t.SrcPath, t.DstPath)
+}
+
+func (t *SyncTask) GetStatus() string {
+	return t.Status
+}
+
+func (t *SyncTask) Run() error {
+	if err := t.ReinitCtx(); err != nil {
+		return err
+	}
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	return t.run(t.Ctx())
+}
+
+func (t *SyncTask) run(ctx context.Context) error {
+	t.Status = "comparing"
+	actions, err := syncPlan(ctx, t.SyncArgs)
+	if err != nil {
+		return err
+	}
+	if len(actions) == 0 {
+		t.Status = "already in sync"
+		return nil
+	}
+	if t.Verify {
+		ctx = context.WithValue(ctx, consts.VerifyKey, true)
+	}
+	srcStorage, _, err := op.GetStorageAndActualPath(t.SrcPath)
+	if err != nil {
+		return errs.WithMessage(err, "failed get src storage")
+	}
+	dstStorage, _, err := op.GetStorageAndActualPath(t.DstPath)
+	if err != nil {
+		return errs.WithMessage(err, "failed get dst storage")
+	}
+	sameStorage := srcStorage.GetStorage() == dstStorage.GetStorage()
+	user, _ := ctx.Value(consts.UserKey).(*model.User)
+	// an update overwrites the dst file, it needs the permission to remove as a delete does
+	canRemove := user == nil || user.CanRemove()
+
+	var es error
+	var transfers []syncTransfer
+	for i, action := range actions {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		t.Status = fmt.Sprintf("%s %s", action.Op, action.Path)
+		srcPath := stdpath.Join(t.SrcPath, action.Path)
+		dstPath := stdpath.Join(t.DstPath, action.Path)
+		switch action.Op {
+		case SyncMkdir:
+			err = MakeDir(ctx, dstPath)
+		case SyncUpdate:
+			if !canRemove {
+				err = errs.WithStack(errs.PermissionDenied)
+				break
+			}
+			// a copy in the same storage doesn't overwrite the existing file
+			if sameStorage {
+				err = syncReplace(ctx, srcPath, dstPath)
+				break
+			}
+			fallthrough
+		case SyncCopy:
+			// the transfer is done by the copy workers, each file becomes a copy task
+			var tsk task.TaskExtensionInfo
+			tsk, err = Copy(ctx, srcPath, stdpath.Dir(dstPath))
+			if tsk != nil {
+				transfers = append(transfers, syncTransfer{path: action.Path, task: tsk})
+			}
+		case SyncDelete:
+			if !canRemove {
+				err = errs.WithStack(errs.PermissionDenied)
+				break
+			}
+			err = Remove(ctx, dstPath)
+		}
+		if err != nil {
+			es = errs.Join(es, errs.WithMessagef(err, "failed %s [%s]", action.Op, action.Path))
+		}
+		t.SetProgress(float64(i+1) * 100 / float64(len(actions)))
+	}
+	if err = t.waitTransfers(ctx, transfers); err != nil {
+		es = errs.Join(es, err)
+	}
+	if es != nil {
+		return es
+	}
+	t.Status = fmt.Sprintf("done, %d actions", len(actions))
+	return nil
+}
+
+// syncTransfer is a copy task queued by the sync
+type syncTransfer struct {
+	path string
+	task task.TaskExtensionInfo
+}
+
+// waitTransfers waits until the queued copy tasks end, the sync is only done when the files are transferred
+func (t *SyncTask) waitTransfers(ctx context.Context, transfers []syncTransfer) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		var running int
+		var es error
+		for _, tr := range transfers {
+			switch tr.task.GetState() {
+			case tache.StateSucceeded:
+			case tache.StateFailed, tache.StateCanceled:
+				err := tr.task.GetErr()
+				if err == nil {
+					err = context.Canceled
+				}
+				es = errs.Join(es, errs.WithMessagef(err, "failed %s [%s]", SyncCopy, tr.path))
+			default:
+				running++
+			}
+		}
+		if running == 0 {
+			return es
+		}
+		t.Status = fmt.Sprintf("waiting for %d of %d transfers", running, len(transfers))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncReplace copies srcPath over dstPath in the same storage.
+// The old file is renamed aside and only removed once the copy is done, it's restored if the copy fails.
+func syncReplace(ctx context.Context, srcPath, dstPath string) error {
+	name := stdpath.Base(dstPath)
+	oldName := name + ".openlist_sync_old"
+	oldPath := stdpath.Join(stdpath.Dir(dstPath), oldName)
+	if err := Rename(ctx, dstPath, oldName); err != nil {
+		return errs.WithMessage(err, "failed move the old file aside")
+	}
+	// wait for the copy instead of queuing it
+	_, err := Copy(context.WithValue(ctx, consts.NoTaskKey, struct{}{}), srcPath, stdpath.Dir(dstPath))
+	if err != nil {
+		// drop what the failed copy left
+		if _, e := Get(ctx, dstPath, &GetArgs{NoLog: true}); e == nil {
+			_ = Remove(ctx, dstPath)
+		}
+		if e := Rename(ctx, oldPath, name); e != nil {
+			return errs.Join(err, errs.WithMessagef(e, "failed restore [%s] from [%s]", dstPath, oldName))
+		}
+		return err
+	}
+	return Remove(ctx, oldPath)
+}
+
+// syncPlan walks both trees and returns the actions to make args.DstPath match args.SrcPath.
+// A file is transferred if it's missing in dst, or the size or the hash differs,
+// or the src is newer when there is no hash to compare.
+func syncPlan(ctx context.Context, args SyncArgs) ([]SyncAction, error) {
+	srcObj, err := Get(ctx, args.SrcPath, &GetArgs{})
+	if err != nil {
+		return nil, errs.WithMessage(err, "failed get src")
+	}
+	if !srcObj.IsDir() {
+		return nil, errs.Wrapf(errs.NotFolder, "src [%s]", args.SrcPath)
+	}
+	srcObjs, srcOrder, err := syncWalk(ctx, args.SrcPath, srcObj, args)
+	if err != nil {
+		return nil, errs.WithMessage(err, "failed walk src")
+	}
+	dstObjs := make(map[string]model.Obj)
+	var dstOrder []string
+	if dstObj, err := Get(ctx, args.DstPath, &GetArgs{NoLog: true}); err == nil {
+		if !dstObj.IsDir() {
+			return nil, errs.Wrapf(errs.NotFolder, "dst [%s]", args.DstPath)
+		}
+		dstObjs, dstOrder, err = syncWalk(ctx, args.DstPath, dstObj, args)
+		if err != nil {
+			return nil, errs.WithMessage(err, "failed walk dst")
+		}
+	} else if !errs.IsObjectNotFound(err) {
+		return nil, errs.WithMessage(err, "failed get dst")
+	}
+
+	var actions []SyncAction
+	for _, p := range srcOrder {
+		obj := srcObjs[p]
+		if !obj.IsDir() && !syncIncluded(args.Include, p) {
+			continue
+		}
+		dst, exist := dstObjs[p]
+		switch {
+		case obj.IsDir():
+			if !exist {
+				actions = append(actions, SyncAction{Op: SyncMkdir, Path: p})
+			} else if !dst.IsDir() {
+				actions = append(actions, SyncAction{Op: SyncDelete, Path: p}, SyncAction{Op: SyncMkdir, Path: p})
+			}
+		case !exist:
+			actions = append(actions, SyncAction{Op: SyncCopy, Path: p, Size: obj.GetSize()})
+		case dst.IsDir():
+			actions = append(actions, SyncAction{Op: SyncDelete, Path: p}, SyncAction{Op: SyncCopy, Path: p, Size: obj.GetSize()})
+		case syncChanged(obj, dst):
+			actions = append(actions, SyncAction{Op: SyncUpdate, Path: p, Size: obj.GetSize()})
+		}
+	}
+	if args.Delete {
+		var deleted []string
+		for _, p := range dstOrder {
+			if _, ok := srcObjs[p]; ok {
+				continue
+			}
+			// the parent is already deleted
+			if syncParentDeleted(deleted, p) {
+				continue
+			}
+			// only walked dirs are in dstObjs, include globs don't apply to dirs
+			if !dstObjs[p].IsDir() && !syncIncluded(args.Include, p) {
+				continue
+			}
+			deleted = append(deleted, p)
+			actions = append(actions, SyncAction{Op: SyncDelete, Path: p, Size: dstObjs[p].GetSize()})
+		}
+	}
+	return actions, nil
+}
+
+// syncWalk returns the objs under root which pass the excludes, keyed by the path relative to root.
+// The order is the walk order, parents come before their children.
+func syncWalk(ctx context.Context, root string, rootObj model.Obj, args SyncArgs) (map[string]model.Obj, []string, error) {
+	objs := make(map[string]model.Obj)
+	var order []string
+	err := WalkFS(ctx, syncMaxDepth, root, rootObj, func(reqPath string, info model.Obj) error {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(reqPath, root), "/")
+		if rel == "" {
+			return nil
+		}
+		if syncMatch(args.Exclude, rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		objs[rel] = info
+		order = append(order, rel)
+		return nil
+	})
+	return objs, order, err
+}
+
+func syncChanged(src, dst model.Obj) bool {
+	if src.GetSize() != dst.GetSize() {
+		return true
+	}
+	if ht, srcSum, dstSum := commonHash(src.GetHash(), dst.GetHash()); ht != nil {
+		return !strings.EqualFold(srcSum, dstSum)
+	}
+	return src.ModTime().After(dst.ModTime())
+}
+
+// syncIncluded reports whether the file at rel passes the include globs
+func syncIncluded(include []string, rel string) bool {
+	return len(include) == 0 || syncMatch(include, rel)
+}
+
+// syncMatch matches a glob containing "/" against the whole relative path,
+// and other globs against the name only
+func syncMatch(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := stdpath.Base(rel)
+		if strings.Contains(pattern, "/") {
+			pattern, name = strings.TrimPrefix(pattern, "/"), rel
+		}
+		if ok, _ := stdpath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func syncParentDeleted(parents []string, p string) bool {
+	for _, parent := range parents {
+		if strings.HasPrefix(p, parent+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncPlan returns the actions a sync would run without running them
+func SyncPlan(ctx context.Context, args SyncArgs) ([]SyncAction, error) {
+	args, err := checkSyncArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return syncPlan(ctx, args)
+}
+
+// Sync mirrors args.SrcPath to args.DstPath, the files are transferred by copy tasks
+func Sync(ctx context.Context, args SyncArgs) (task.TaskExtensionInfo, error) {
+	args, err := checkSyncArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	t := &SyncTask{SyncArgs: args}
+	if ctx.Value(consts.NoTaskKey) != nil {
+		return nil, t.run(ctx)
+	}
+	t.Creator, _ = ctx.Value(consts.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiURL(ctx)
+	SyncTaskManager.Add(t)
+	return t, nil
+}
+
+func checkSyncArgs(args SyncArgs) (SyncArgs, error) {
+	args.SrcPath = utils.FixAndCleanPath(args.SrcPath)
+	args.DstPath = utils.FixAndCleanPath(args.DstPath)
+	if utils.PathEqual(args.SrcPath, args.DstPath) ||
+		utils.IsSubPath(args.SrcPath, args.DstPath) || utils.IsSubPath(args.DstPath, args.SrcPath) {
+		return args, errs.New("src and dst of a sync can't contain each other")
+	}
+	for _, pattern := range append(append([]string{}, args.Include...), args.Exclude...) {
+		if _, err := stdpath.Match(pattern, ""); err != nil {
+			return args, errs.Wrapf(err, "invalid glob [%s]", pattern)
+		}
+	}
+	return args, nil
+}
+
+var SyncTaskManager *tache.Manager[*SyncTask]
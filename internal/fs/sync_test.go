@@ -0,0 +1,60 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+func TestSyncUpdate(t *testing.T) {
+	root := t.TempDir()
+	for name, data := range map[string]string{"src/a.txt": "new content", "dst/a.txt": "old"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "dst/a.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/sync_test",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	args := SyncArgs{SrcPath: "/sync_test/src", DstPath: "/sync_test/dst"}
+
+	// the update overwrites the dst file, it needs the permission to remove
+	ctx := context.WithValue(context.Background(), consts.UserKey, &model.User{Role: model.GENERAL})
+	ctx = context.WithValue(ctx, consts.NoTaskKey, struct{}{})
+	if _, err = Sync(ctx, args); !errs.Is(err, errs.PermissionDenied) {
+		t.Fatalf("expected the update to be denied, got %v", err)
+	}
+
+	ctx = context.WithValue(context.Background(), consts.UserKey, &model.User{Role: model.GENERAL, Permission: 1 << 7})
+	ctx = context.WithValue(ctx, consts.NoTaskKey, struct{}{})
+	if _, err = Sync(ctx, args); err != nil {
+		t.Fatalf("failed sync: %+v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "dst/a.txt")); err != nil || string(data) != "new content" {
+		t.Errorf("expected the dst file to be updated, got %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(root, "dst"))
+	if len(entries) != 1 {
+		t.Errorf("expected the old file to be removed, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,142 @@
+package fs
+
+import (
+	"context"
+	"os"
+	stdpath "path"
+	"strings"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/stream"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	hash_extend "github.com/dongdio/OpenList/v4/utility/utils/hash"
+)
+
+// verifyHashTypes is the order to pick a hash type when both sides provide several
+var verifyHashTypes = []*utils.HashType{utils.SHA256, utils.SHA1, utils.MD5, hash_extend.GCID}
+
+// needVerify reports whether the caller asked to verify the transferred files, see consts.VerifyKey
+func needVerify(ctx context.Context) bool {
+	verify, _ := ctx.Value(consts.VerifyKey).(bool)
+	return verify
+}
+
+// verifySource is the file a transfer reads from
+type verifySource struct {
+	size int64
+	hash utils.HashInfo
+	// rehash reads the whole file again to compute the hash
+	rehash func(ht *utils.HashType) (string, error)
+}
+
+func objVerifySource(ctx context.Context, storage driver.Driver, path string, obj model.Obj) verifySource {
+	return verifySource{
+		size: obj.GetSize(),
+		hash: obj.GetHash(),
+		rehash: func(ht *utils.HashType) (string, error) {
+			return hashObj(ctx, storage, path, obj, ht)
+		},
+	}
+}
+
+func localVerifySource(path string, size int64) verifySource {
+	return verifySource{
+		size: size,
+		hash: utils.NewHashInfo(nil, ""),
+		rehash: func(ht *utils.HashType) (string, error) {
+			file, err := os.Open(path)
+			if err != nil {
+				return "", errs.WithStack(err)
+			}
+			defer file.Close()
+			return utils.HashReader(ht, file, size)
+		},
+	}
+}
+
+// verifyTransfer checks the file put to dstPath matches src.
+// The hashes given by both drivers are compared if they share a hash type,
+// otherwise the size is compared and the missing hash is computed by reading the file again.
+func verifyTransfer(ctx context.Context, src verifySource, dstStorage driver.Driver, dstPath string) error {
+	if _, ok := dstStorage.(driver.Getter); !ok {
+		// the obj just put may not be in the list cache yet
+		op.DeleteCache(dstStorage, stdpath.Dir(dstPath))
+	}
+	dstObj, err := op.Get(ctx, dstStorage, dstPath)
+	if err != nil {
+		return errs.WithMessagef(err, "failed get [%s] to verify", dstPath)
+	}
+	if dstObj.GetSize() != src.size {
+		return errs.Wrapf(errs.VerifyFailed, "size of [%s] is %d, expect %d", dstPath, dstObj.GetSize(), src.size)
+	}
+	dstHash := dstObj.GetHash()
+	if ht, srcSum, dstSum := commonHash(src.hash, dstHash); ht != nil {
+		return compareHash(ht, srcSum, dstSum, dstPath)
+	}
+
+	// no hash type in common, compute the missing one
+	for _, ht := range verifyHashTypes {
+		if srcSum := src.hash.GetHash(ht); srcSum != "" {
+			dstSum, err := hashObj(ctx, dstStorage, dstPath, dstObj, ht)
+			if err != nil {
+				return errs.WithMessagef(err, "failed hash [%s] to verify", dstPath)
+			}
+			return compareHash(ht, srcSum, dstSum, dstPath)
+		}
+		if dstSum := dstHash.GetHash(ht); dstSum != "" {
+			srcSum, err := src.rehash(ht)
+			if err != nil {
+				return errs.WithMessage(err, "failed hash src to verify")
+			}
+			return compareHash(ht, srcSum, dstSum, dstPath)
+		}
+	}
+	srcSum, err := src.rehash(utils.MD5)
+	if err != nil {
+		return errs.WithMessage(err, "failed hash src to verify")
+	}
+	dstSum, err := hashObj(ctx, dstStorage, dstPath, dstObj, utils.MD5)
+	if err != nil {
+		return errs.WithMessagef(err, "failed hash [%s] to verify", dstPath)
+	}
+	return compareHash(utils.MD5, srcSum, dstSum, dstPath)
+}
+
+// commonHash returns the first hash type both a and b provide, ht is nil if there is none
+func commonHash(a, b utils.HashInfo) (ht *utils.HashType, aSum, bSum string) {
+	for _, ht = range verifyHashTypes {
+		aSum, bSum = a.GetHash(ht), b.GetHash(ht)
+		if aSum != "" && bSum != "" {
+			return ht, aSum, bSum
+		}
+	}
+	return nil, "", ""
+}
+
+func compareHash(ht *utils.HashType, srcSum, dstSum, dstPath string) error {
+	if !strings.EqualFold(srcSum, dstSum) {
+		return errs.Wrapf(errs.VerifyFailed, "%s of [%s] is %s, expect %s", ht.Name, dstPath, dstSum, srcSum)
+	}
+	return nil
+}
+
+func hashObj(ctx context.Context, storage driver.Driver, path string, obj model.Obj, ht *utils.HashType) (string, error) {
+	link, _, err := op.Link(ctx, storage, path, model.LinkArgs{})
+	if err != nil {
+		return "", err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{
+		Obj: obj,
+		Ctx: ctx,
+	}, link)
+	if err != nil {
+		_ = link.Close()
+		return "", err
+	}
+	defer ss.Close()
+	return utils.HashReader(ht, ss, obj.GetSize())
+}
@@ -0,0 +1,243 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	_ "github.com/dongdio/OpenList/v4/drivers/local"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	op.RegisterDriver(func() driver.Driver {
+		return &memDriver{}
+	})
+}
+
+type memAddition struct {
+	// Corrupt flips the first byte of the files put
+	Corrupt bool `json:"corrupt"`
+	// HashSHA1 gives the SHA1 of the files
+	HashSHA1 bool `json:"hash_sha1"`
+}
+
+// memDriver keeps the files in memory, its root only has files
+type memDriver struct {
+	model.Storage
+	memAddition
+	mu    sync.Mutex
+	files map[string][]byte
+	// links counts the files read
+	links int
+}
+
+func (d *memDriver) Config() driver.Config {
+	return driver.Config{Name: "VerifyMem", LocalSort: true, NoCache: true}
+}
+
+func (d *memDriver) GetAddition() driver.Additional {
+	return &d.memAddition
+}
+
+func (d *memDriver) Init(context.Context) error {
+	d.files = make(map[string][]byte)
+	return nil
+}
+
+func (d *memDriver) Drop(context.Context) error {
+	return nil
+}
+
+func (d *memDriver) obj(path string, data []byte) model.Obj {
+	obj := &model.Object{Path: path, Name: stdpath.Base(path), Size: int64(len(data))}
+	if d.HashSHA1 {
+		obj.HashInfo = utils.NewHashInfo(utils.SHA1, utils.HashData(utils.SHA1, data))
+	}
+	return obj
+}
+
+func (d *memDriver) List(_ context.Context, dir model.Obj, _ model.ListArgs) ([]model.Obj, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var objs []model.Obj
+	for path, data := range d.files {
+		if stdpath.Dir(path) == dir.GetPath() {
+			objs = append(objs, d.obj(path, data))
+		}
+	}
+	return objs, nil
+}
+
+func (d *memDriver) Get(_ context.Context, path string) (model.Obj, error) {
+	if path == "/" {
+		return &model.Object{Path: "/", Name: "root", IsFolder: true}, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	data, ok := d.files[path]
+	if !ok {
+		return nil, errs.ObjectNotFound
+	}
+	return d.obj(path, data), nil
+}
+
+func (d *memDriver) Link(_ context.Context, file model.Obj, _ model.LinkArgs) (*model.Link, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.links++
+	return &model.Link{MFile: bytes.NewReader(d.files[file.GetPath()])}, nil
+}
+
+func (d *memDriver) Put(_ context.Context, dstDir model.Obj, file model.FileStreamer, _ driver.UpdateProgress) error {
+	data := make([]byte, file.GetSize())
+	if _, err := file.Read(data); err != nil && file.GetSize() > 0 {
+		return err
+	}
+	if d.Corrupt && len(data) > 0 {
+		data[0] ^= 0xff
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files[stdpath.Join(dstDir.GetPath(), file.GetName())] = data
+	return nil
+}
+
+func (d *memDriver) Remove(_ context.Context, obj model.Obj) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.files, obj.GetPath())
+	return nil
+}
+
+func mountMem(t *testing.T, mountPath string, addition memAddition) *memDriver {
+	t.Helper()
+	b, _ := utils.JSONTool.Marshal(addition)
+	_, err := op.CreateStorage(context.Background(), model.Storage{Driver: "VerifyMem", MountPath: mountPath, Addition: string(b)})
+	if err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath(mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage.(*memDriver)
+}
+
+// bytesVerifySource is a src without hash, rehash reads data again
+func bytesVerifySource(data []byte, rehashed *int) verifySource {
+	return verifySource{
+		size: int64(len(data)),
+		hash: utils.NewHashInfo(nil, ""),
+		rehash: func(ht *utils.HashType) (string, error) {
+			*rehashed++
+			return utils.HashData(ht, data), nil
+		},
+	}
+}
+
+func TestVerifySharedHash(t *testing.T) {
+	d := mountMem(t, "/verify_hash", memAddition{HashSHA1: true})
+	data := []byte("hello")
+	d.files["/a.txt"] = data
+	ctx := context.Background()
+
+	src := verifySource{
+		size: int64(len(data)),
+		hash: utils.NewHashInfo(utils.SHA1, utils.HashData(utils.SHA1, data)),
+		rehash: func(*utils.HashType) (string, error) {
+			t.Error("expected the src not to be read again")
+			return "", nil
+		},
+	}
+	if err := verifyTransfer(ctx, src, d, "/a.txt"); err != nil {
+		t.Errorf("expected the shared hash to match: %+v", err)
+	}
+	if d.links != 0 {
+		t.Errorf("expected the dst not to be read, got %d reads", d.links)
+	}
+	src.hash = utils.NewHashInfo(utils.SHA1, utils.HashData(utils.SHA1, []byte("world")))
+	if err := verifyTransfer(ctx, src, d, "/a.txt"); !errs.Is(err, errs.VerifyFailed) {
+		t.Errorf("expected the shared hash not to match, got %v", err)
+	}
+}
+
+func TestVerifySizeAndReread(t *testing.T) {
+	d := mountMem(t, "/verify_reread", memAddition{})
+	data := []byte("hello")
+	d.files["/a.txt"] = data
+	ctx := context.Background()
+
+	var rehashed int
+	if err := verifyTransfer(ctx, bytesVerifySource(data, &rehashed), d, "/a.txt"); err != nil {
+		t.Errorf("expected the re-read files to match: %+v", err)
+	}
+	if rehashed != 1 || d.links != 1 {
+		t.Errorf("expected both sides to be read once, got src %d, dst %d", rehashed, d.links)
+	}
+
+	// same size, different content
+	rehashed = 0
+	if err := verifyTransfer(ctx, bytesVerifySource([]byte("world"), &rehashed), d, "/a.txt"); !errs.Is(err, errs.VerifyFailed) {
+		t.Errorf("expected the re-read files not to match, got %v", err)
+	}
+	// the size differs, nothing is read
+	rehashed = 0
+	if err := verifyTransfer(ctx, bytesVerifySource([]byte("hello!"), &rehashed), d, "/a.txt"); !errs.Is(err, errs.VerifyFailed) {
+		t.Errorf("expected the size not to match, got %v", err)
+	}
+	if rehashed != 0 {
+		t.Errorf("expected the src not to be read, got %d reads", rehashed)
+	}
+}
+
+func TestMoveVerifyFailedKeepsSrc(t *testing.T) {
+	root := t.TempDir()
+	srcFile := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(srcFile, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/verify_src",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	dst := mountMem(t, "/verify_dst", memAddition{Corrupt: true})
+
+	ctx := context.WithValue(context.Background(), consts.UserKey, &model.User{Role: model.ADMIN})
+	ctx = context.WithValue(ctx, consts.NoTaskKey, struct{}{})
+	ctx = context.WithValue(ctx, consts.VerifyKey, true)
+	if _, err = Move(ctx, "/verify_src/a.txt", "/verify_dst"); !errs.Is(err, errs.VerifyFailed) {
+		t.Fatalf("expected the verification to fail, got %v", err)
+	}
+	if _, err = os.Stat(srcFile); err != nil {
+		t.Errorf("expected the src to be kept: %v", err)
+	}
+	if _, ok := dst.files["/a.txt"]; !ok {
+		t.Error("expected the corrupted file to be put")
+	}
+}
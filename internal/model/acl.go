@@ -0,0 +1,143 @@
+package model
+
+import (
+	stdpath "path"
+	"strings"
+)
+
+// The actions an ACL rule can allow or deny
+const (
+	ACLList            = "list"
+	ACLRead            = "read"
+	ACLWrite           = "write"
+	ACLRename          = "rename"
+	ACLMove            = "move"
+	ACLCopy            = "copy"
+	ACLDelete          = "delete"
+	ACLDecompress      = "decompress"
+	ACLOfflineDownload = "offline_download"
+)
+
+var ACLActions = []string{ACLList, ACLRead, ACLWrite, ACLRename, ACLMove, ACLCopy, ACLDelete, ACLDecompress, ACLOfflineDownload}
+
+// The subjects of an ACL rule
+const (
+	ACLSubjectAll   = "all"
+	ACLSubjectUser  = "user"
+	ACLSubjectGroup = "group"
+)
+
+const (
+	ACLAllow = "allow"
+	ACLDeny  = "deny"
+)
+
+// ACLRule allows or denies some actions on the paths matching a glob.
+// The rules of a user are evaluated by Priority, lower first, and the first matching rule decides.
+// The rules never grant more than the permission of the user, they only narrow it down.
+type ACLRule struct {
+	ID       uint `json:"id" gorm:"primaryKey"`
+	Priority int  `json:"priority" gorm:"index"`
+	// SubjectType is all, user or group, SubjectID is the id of the user or the group
+	SubjectType string `json:"subject_type" binding:"required"`
+	SubjectID   uint   `json:"subject_id"`
+	// Path is a glob of mount paths, * matches within a path segment and ** matches any number of segments
+	Path string `json:"path" binding:"required"`
+	// Actions is a comma separated list of actions, * means all actions
+	Actions  string `json:"actions" binding:"required"`
+	Effect   string `json:"effect" binding:"required"`
+	Disabled bool   `json:"disabled"`
+	Comment  string `json:"comment"`
+}
+
+// HasAction reports whether the rule is about action
+func (r *ACLRule) HasAction(action string) bool {
+	for _, a := range strings.Split(r.Actions, ",") {
+		a = strings.TrimSpace(a)
+		if a == "*" || a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliesTo reports whether the rule is about user
+func (r *ACLRule) AppliesTo(user *User) bool {
+	switch r.SubjectType {
+	case ACLSubjectAll:
+		return true
+	case ACLSubjectUser:
+		return r.SubjectID == user.ID
+	case ACLSubjectGroup:
+		for _, g := range user.Groups {
+			if g.ID == r.SubjectID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchPath reports whether the mount path p matches the glob of the rule
+func (r *ACLRule) MatchPath(p string) bool {
+	return MatchPathGlob(r.Path, p)
+}
+
+// MatchSubPath reports whether the glob of the rule may match a path under the mount path dir, dir itself excluded
+func (r *ACLRule) MatchSubPath(dir string) bool {
+	return MatchPathGlobUnder(r.Path, dir)
+}
+
+// MatchPathGlob matches the clean absolute path p against pattern,
+// * matches within a path segment and ** matches any number of segments, none included
+func MatchPathGlob(pattern, p string) bool {
+	return matchSegments(splitPath(pattern), splitPath(p))
+}
+
+// MatchPathGlobUnder reports whether pattern may match a path under the clean absolute path dir, dir itself excluded.
+// It's conservative, a pattern matching dir with ** left may match anything below.
+func MatchPathGlobUnder(pattern, dir string) bool {
+	patterns, segments := splitPath(pattern), splitPath(dir)
+	for len(segments) > 0 {
+		if len(patterns) == 0 {
+			return false
+		}
+		if patterns[0] == "**" {
+			return true
+		}
+		if ok, _ := stdpath.Match(patterns[0], segments[0]); !ok {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(patterns) > 0
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := stdpath.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
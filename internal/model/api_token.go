@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// APITokenPrefix marks a secret as a personal api token, so it can be told apart from a password or a jwt
+const APITokenPrefix = "olt_"
+
+// APIToken is a personal token a user creates to access the api, WebDAV, FTP, SFTP and S3 without the password.
+// Only the hash of the token is stored, the token itself is shown once when it's created.
+type APIToken struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	UserID    uint   `json:"user_id" gorm:"index"`
+	Name      string `json:"name" binding:"required"`
+	TokenHash string `json:"-" gorm:"unique"`
+	// KeyID is the public part of the token, it's the S3 access key id
+	KeyID string `json:"key_id" gorm:"unique"`
+	// BasePath limits the token to a path relative to the base path of the user, empty means no limit
+	BasePath string `json:"base_path"`
+	// Permission is a subset of the permission bits of the user, nil means all of them
+	Permission *int32     `json:"permission"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *APIToken) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// Scoped reports whether the token grants less than the user it belongs to
+func (t *APIToken) Scoped() bool {
+	return t.Permission != nil || (t.BasePath != "" && t.BasePath != "/")
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+// The actions recorded in the audit log
+const (
+	AuditMkdir           = "mkdir"
+	AuditUpload          = "upload"
+	AuditRename          = "rename"
+	AuditMove            = "move"
+	AuditCopy            = "copy"
+	AuditRemove          = "remove"
+	AuditDecompress      = "decompress"
+	AuditOfflineDownload = "offline_download"
+	AuditDownload        = "download"
+	AuditLogin           = "login"
+	AuditAdminChange     = "admin_change"
+)
+
+// AuditLog records who did what on which path through which protocol
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username" gorm:"index"`
+	// Protocol is web, webdav, ftp, sftp, s3, share, file_request, scim or internal for the changes made by the server itself
+	Protocol string `json:"protocol" gorm:"index"`
+	Action   string `json:"action" gorm:"index"`
+	Path     string `json:"path" gorm:"type:text"`
+	// DstPath is the destination of a move, copy, rename or decompress
+	DstPath string `json:"dst_path" gorm:"type:text"`
+	// Size is the bytes uploaded or downloaded, 0 if unknown
+	Size    int64  `json:"size"`
+	Success bool   `json:"success"`
+	Error   string `json:"error" gorm:"type:text"`
+	IP      string `json:"ip"`
+	// Detail is extra information such as the api called for an admin change
+	Detail string `json:"detail" gorm:"type:text"`
+}
+
+// AuditQuery filters the audit log, the zero values don't filter
+type AuditQuery struct {
+	Username string    `json:"username" form:"username"`
+	Protocol string    `json:"protocol" form:"protocol"`
+	Action   string    `json:"action" form:"action"`
+	Path     string    `json:"path" form:"path"`
+	Success  *bool     `json:"success" form:"success"`
+	IP       string    `json:"ip" form:"ip"`
+	Since    time.Time `json:"since" form:"since"`
+	Until    time.Time `json:"until" form:"until"`
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"sync"
+
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// CheckpointPart is a part already committed on the remote
+type CheckpointPart struct {
+	Number int    `json:"number"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	ETag   string `json:"etag,omitempty"`
+}
+
+// TransferCheckpoint records how far a resumable upload has gone.
+// It's persisted together with the task, so the upload can continue after a retry or a restart.
+type TransferCheckpoint struct {
+	// Fingerprint identifies the src file and the dst, the checkpoint is dropped if it changes
+	Fingerprint string `json:"fingerprint"`
+	// SessionID is the upload id or the upload session url given by the remote
+	SessionID string           `json:"session_id,omitempty"`
+	Uploaded  int64            `json:"uploaded"`
+	Parts     []CheckpointPart `json:"parts,omitempty"`
+	// Extra is for the driver to keep anything else it needs to resume
+	Extra map[string]string `json:"extra,omitempty"`
+
+	mu     sync.Mutex
+	commit func()
+}
+
+func NewTransferCheckpoint(fingerprint string) *TransferCheckpoint {
+	return &TransferCheckpoint{Fingerprint: fingerprint}
+}
+
+// ResumeCheckpoint returns c if it was made for fingerprint, otherwise a new checkpoint,
+// the upload of a changed file or to another dst starts over
+func ResumeCheckpoint(c *TransferCheckpoint, fingerprint string) *TransferCheckpoint {
+	if c == nil || c.Fingerprint != fingerprint {
+		return NewTransferCheckpoint(fingerprint)
+	}
+	return c
+}
+
+// SetCommitFunc sets the function saving the checkpoint, it's called by Commit
+func (c *TransferCheckpoint) SetCommitFunc(commit func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commit = commit
+}
+
+// Start records the session of a new upload and drops the parts of the previous one
+func (c *TransferCheckpoint) Start(sessionID string) {
+	c.mu.Lock()
+	c.SessionID = sessionID
+	c.Uploaded = 0
+	c.Parts = nil
+	c.Extra = nil
+	commit := c.commit
+	c.mu.Unlock()
+	if commit != nil {
+		commit()
+	}
+}
+
+// Commit appends the part which has been committed on the remote and saves the checkpoint
+func (c *TransferCheckpoint) Commit(part CheckpointPart) {
+	c.mu.Lock()
+	c.Parts = append(c.Parts, part)
+	c.Uploaded = max(c.Uploaded, part.Offset+part.Size)
+	commit := c.commit
+	c.mu.Unlock()
+	if commit != nil {
+		commit()
+	}
+}
+
+// Truncate keeps the first n parts, the rest are uploaded again
+func (c *TransferCheckpoint) Truncate(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n >= len(c.Parts) {
+		return
+	}
+	c.Parts = c.Parts[:n]
+	c.Uploaded = 0
+	for _, part := range c.Parts {
+		c.Uploaded = max(c.Uploaded, part.Offset+part.Size)
+	}
+}
+
+// Reset drops the session, the next upload starts from byte zero
+func (c *TransferCheckpoint) Reset() {
+	c.Start("")
+}
+
+// GetSessionID returns the session to resume, "" means there is nothing to resume
+func (c *TransferCheckpoint) GetSessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.SessionID
+}
+
+// GetParts returns a copy of the committed parts
+func (c *TransferCheckpoint) GetParts() []CheckpointPart {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CheckpointPart(nil), c.Parts...)
+}
+
+func (c *TransferCheckpoint) SetExtra(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Extra == nil {
+		c.Extra = make(map[string]string)
+	}
+	c.Extra[key] = value
+}
+
+func (c *TransferCheckpoint) GetExtra(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Extra[key]
+}
+
+// MarshalJSON locks the checkpoint, the task may be persisted while the driver is committing parts
+func (c *TransferCheckpoint) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return utils.JSONTool.Marshal(&struct {
+		Fingerprint string            `json:"fingerprint"`
+		SessionID   string            `json:"session_id,omitempty"`
+		Uploaded    int64             `json:"uploaded"`
+		Parts       []CheckpointPart  `json:"parts,omitempty"`
+		Extra       map[string]string `json:"extra,omitempty"`
+	}{c.Fingerprint, c.SessionID, c.Uploaded, c.Parts, c.Extra})
+}
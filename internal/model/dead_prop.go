@@ -0,0 +1,14 @@
+package model
+
+// DeadProp is a WebDAV dead property set by PROPPATCH, such as the timestamps set by Windows Explorer.
+// Path is the full path of the resource, the properties follow it when it's renamed, moved, copied or removed.
+type DeadProp struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Path string `json:"path" gorm:"index"`
+	// Space and Local are the xml name of the property
+	Space string `json:"space"`
+	Local string `json:"local"`
+	Lang  string `json:"lang"`
+	// InnerXML is the raw xml value of the property
+	InnerXML string `json:"inner_xml" gorm:"type:text"`
+}
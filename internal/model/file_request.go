@@ -0,0 +1,106 @@
+package model
+
+import (
+	"crypto/subtle"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+// The policies renaming an upload of a file request, so the existing files are never overwritten
+const (
+	// FileRequestRenameNumber appends " (1)", " (2)"... to the name if it's taken
+	FileRequestRenameNumber = "number"
+	// FileRequestRenameTimestamp always appends the upload time to the name
+	FileRequestRenameTimestamp = "timestamp"
+)
+
+// FileRequest is a public link to upload files into a folder without seeing anything in it.
+// The uploads are made by a pseudo user scoped to the folder on behalf of the owner.
+type FileRequest struct {
+	ID     string `json:"id" gorm:"primaryKey;size:64"`
+	UserID uint   `json:"user_id" gorm:"index"`
+	// Path is the absolute path of the folder receiving the files
+	Path string `json:"path" gorm:"type:text"`
+	// Password is asked to the uploaders if it's not empty, only its hash is stored
+	Password  string     `json:"password" gorm:"-"`
+	PwdHash   string     `json:"-"`
+	Salt      string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// MaxFileSize limits the size of every file, 0 means no limit
+	MaxFileSize int64 `json:"max_file_size"`
+	// AllowedTypes are the comma separated extensions allowed, empty means any
+	AllowedTypes string `json:"allowed_types"`
+	// Quota limits the bytes uploaded through the link, 0 means no limit
+	Quota        int64  `json:"quota"`
+	RenamePolicy string `json:"rename_policy"`
+	Disabled     bool   `json:"disabled"`
+	Remark       string `json:"remark"`
+	// the upload statistics
+	Used         int64      `json:"used"`
+	Uploads      int64      `json:"uploads"`
+	LastUploadAt *time.Time `json:"last_upload_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (r *FileRequest) Expired() bool {
+	return r.ExpiresAt != nil && time.Now().After(*r.ExpiresAt)
+}
+
+// SetPassword stores the hash of pwd, an empty pwd removes the password
+func (r *FileRequest) SetPassword(pwd string) {
+	r.Password = ""
+	if pwd == "" {
+		r.PwdHash, r.Salt = "", ""
+		return
+	}
+	r.Salt = random.String(16)
+	r.PwdHash = TwoHashPwd(pwd, r.Salt)
+}
+
+// CheckPassword reports whether pwd opens the file request
+func (r *FileRequest) CheckPassword(pwd string) bool {
+	if r.PwdHash == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(TwoHashPwd(pwd, r.Salt)), []byte(r.PwdHash)) == 1
+}
+
+// MaskPassword tells the client whether a password is set without returning it
+func (r *FileRequest) MaskPassword() {
+	r.Password = ""
+	if r.PwdHash != "" {
+		r.Password = SharePasswordMask
+	}
+}
+
+// AllowType reports whether a file named name can be uploaded
+func (r *FileRequest) AllowType(name string) bool {
+	if strings.TrimSpace(r.AllowedTypes) == "" {
+		return true
+	}
+	ext := utils.Ext(name)
+	for _, t := range strings.Split(r.AllowedTypes, ",") {
+		if strings.ToLower(strings.TrimPrefix(strings.TrimSpace(t), ".")) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// CandidateName returns the n-th name tried for an upload named name at now, n starts at 0
+func (r *FileRequest) CandidateName(name string, n int, now time.Time) string {
+	ext := stdpath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if r.RenamePolicy == FileRequestRenameTimestamp {
+		base += now.Format("_20060102150405")
+	}
+	if n > 0 {
+		base += fmt.Sprintf(" (%d)", n)
+	}
+	return base + ext
+}
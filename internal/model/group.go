@@ -0,0 +1,74 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// Group shares permissions, a base path and meta overrides between its members
+type Group struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"unique" binding:"required"`
+	Description string `json:"description"`
+	// Permission is added to the permission of the members, the bits are the same as User.Permission
+	Permission int32 `json:"permission"`
+	// BasePath is the base path of the members whose own base path is "/"
+	BasePath string `json:"base_path"`
+	// Metas overrides the metas of some paths for the members
+	Metas []GroupMeta `json:"metas" gorm:"serializer:json;type:text"`
+	// ExternalGroups are the LDAP groups and the SSO group claims mapped to the group, one per line
+	ExternalGroups string `json:"external_groups" gorm:"type:text"`
+	// Require2FA makes the members enable the 2FA or a WebAuthn credential, see consts.TwoFactorGraceDays
+	Require2FA bool `json:"require_2fa"`
+}
+
+// GroupMeta overrides the meta of Path for the members of a group
+type GroupMeta struct {
+	Path string `json:"path"`
+	// Sub applies the override to the sub paths too
+	Sub bool `json:"sub"`
+	// NoPassword skips the password of the meta
+	NoPassword bool `json:"no_password"`
+	// Write allows to write even if the meta doesn't
+	Write bool `json:"write"`
+	// SeeHides shows the objs hidden by the meta
+	SeeHides bool `json:"see_hides"`
+}
+
+func (m GroupMeta) apply(reqPath string) bool {
+	return utils.PathEqual(m.Path, reqPath) || (m.Sub && utils.IsSubPath(m.Path, reqPath))
+}
+
+// UserGroup is the membership of a user in a group
+type UserGroup struct {
+	UserID  uint `gorm:"primaryKey"`
+	GroupID uint `gorm:"primaryKey;index"`
+}
+
+// MatchExternal reports whether one of names is mapped to the group.
+// A name can be a plain group name or the DN of an LDAP group, whose CN is matched too.
+func (g *Group) MatchExternal(names []string) bool {
+	for _, line := range strings.Split(g.ExternalGroups, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for _, name := range names {
+			if strings.EqualFold(line, name) || strings.EqualFold(line, ldapCN(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ldapCN returns the value of the first RDN of dn if it's a CN, otherwise ""
+func ldapCN(dn string) string {
+	rdn, _, _ := strings.Cut(dn, ",")
+	key, value, ok := strings.Cut(rdn, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(key), "cn") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	QuotaUser    = "user"
+	QuotaStorage = "storage"
+)
+
+// QuotaUsage is the bytes used by a user or a storage, Kind is QuotaUser or QuotaStorage
+type QuotaUsage struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Kind      string    `json:"kind" gorm:"size:16;uniqueIndex:idx_quota_owner"`
+	OwnerID   uint      `json:"owner_id" gorm:"uniqueIndex:idx_quota_owner"`
+	Used      int64     `json:"used"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// QuotaInfo is the quota state shown to the user, Quota 0 means unlimited
+type QuotaInfo struct {
+	Quota int64 `json:"quota"`
+	Used  int64 `json:"used"`
+}
+
+// Available returns the bytes left, -1 means unlimited
+func (q QuotaInfo) Available() int64 {
+	if q.Quota <= 0 {
+		return -1
+	}
+	return max(q.Quota-q.Used, 0)
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+)
+
+// S3KeyPrefix marks an access key id as an S3 key of a user, so it can be told apart from the global key and the api tokens
+const S3KeyPrefix = "olk_"
+
+// S3Key is an S3 credential of a user, the requests signed with it run as the user.
+// The secret access key is random and kept encrypted in Secret, it's shown once when the key is created.
+type S3Key struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	UserID      uint   `json:"user_id" gorm:"index"`
+	Name        string `json:"name" binding:"required"`
+	AccessKeyID string `json:"access_key_id" gorm:"unique"`
+	Secret      string `json:"-" gorm:"type:text"`
+	// Buckets limits the key to some buckets, empty means all the buckets the user can access
+	Buckets    []S3KeyBucket `json:"buckets" gorm:"serializer:json;type:text"`
+	Disabled   bool          `json:"disabled"`
+	LastUsedAt *time.Time    `json:"last_used_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// S3KeyBucket allows a key to read a bucket, and to write it if Write is set
+type S3KeyBucket struct {
+	Name  string `json:"name"`
+	Write bool   `json:"write"`
+}
+
+// CanRead reports whether the key is allowed to read the bucket
+func (k *S3Key) CanRead(bucket string) bool {
+	if len(k.Buckets) == 0 {
+		return true
+	}
+	for _, b := range k.Buckets {
+		if b.Name == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// CanWrite reports whether the key is allowed to write the bucket
+func (k *S3Key) CanWrite(bucket string) bool {
+	if len(k.Buckets) == 0 {
+		return true
+	}
+	for _, b := range k.Buckets {
+		if b.Name == bucket {
+			return b.Write
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// S3MultipartUpload is a multipart upload in progress of the S3 server, its parts are staged in the temp dir.
+// It's kept in the database so the upload can go on after a restart.
+type S3MultipartUpload struct {
+	ID     string `json:"id" gorm:"primaryKey;size:64"`
+	Bucket string `json:"bucket"`
+	Key    string `json:"key" gorm:"type:text"`
+	UserID uint   `json:"user_id" gorm:"index"`
+	// Node identifies the temp dir staging the parts, only the node using that dir can go on with the upload
+	Node string `json:"node" gorm:"index;size:32"`
+	// Meta is the headers of the initiate request which are stored with the object
+	Meta      map[string]string `json:"meta" gorm:"serializer:json;type:text"`
+	Initiated time.Time         `json:"initiated"`
+	// LastActive is when a part was last uploaded, the upload expires when it's idle for long
+	LastActive time.Time `json:"last_active" gorm:"index"`
+}
+
+// S3MultipartPart is a part staged for an upload, a part uploaded again with the same number replaces it
+type S3MultipartPart struct {
+	UploadID     string    `json:"upload_id" gorm:"primaryKey;size:64"`
+	Number       int       `json:"number" gorm:"primaryKey;autoIncrement:false"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
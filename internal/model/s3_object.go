@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// S3Object keeps what the S3 server knows of an object but the storage can't keep, the metadata and the MD5.
+// Path is the full path of the object, the record follows it when it's renamed, moved, copied or removed.
+type S3Object struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Path string `json:"path" gorm:"index"`
+	// Metadata is the Content-*, Cache-Control and X-Amz-Meta-* headers set by the client
+	Metadata map[string]string `json:"metadata" gorm:"serializer:json;type:text"`
+	// MD5 is the hex MD5 of the content, computed when it was uploaded or fully read through S3.
+	// It's only valid while the size and the modified time of the object are Size and Modified.
+	MD5      string    `json:"md5"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// MD5Of returns the MD5 if it's still valid for obj, the modified time is compared in seconds
+// as some databases don't keep the nanoseconds
+func (o *S3Object) MD5Of(obj Obj) string {
+	if o.MD5 == "" || o.Size != obj.GetSize() || o.Modified.Unix() != obj.ModTime().Unix() {
+		return ""
+	}
+	return o.MD5
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+type ScheduledJob struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" binding:"required"`
+	// Cron is a standard 5 fields spec or a descriptor such as @daily, see robfig/cron
+	Cron string `json:"cron" binding:"required"`
+	// Type is one of the job types registered in internal/schedule, such as index, sync and copy
+	Type string `json:"type" binding:"required"`
+	// Args is the json arguments of the job type
+	Args      string     `json:"args" gorm:"type:text"`
+	Disabled  bool       `json:"disabled"`
+	LastRunAt *time.Time `json:"last_run_at"`
+	// LastError is empty if the last run succeeded
+	LastError string `json:"last_error" gorm:"type:text"`
+}
+
+type ScheduledJobRun struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	JobID      uint       `json:"job_id" gorm:"index"`
+	StartedAt  time.Time  `json:"started_at" gorm:"index"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Success    bool       `json:"success"`
+	// Message is the error of a failed run or the result of a successful one
+	Message string `json:"message" gorm:"type:text"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// Session is a login of a user, every jwt issued to the session carries its ID as the jti.
+// Deleting the session revokes the jwt and the refresh token at once.
+type Session struct {
+	ID     string `json:"id" gorm:"primaryKey;size:64"`
+	UserID uint   `json:"user_id" gorm:"index"`
+	// PwdTS is the password timestamp of the user at login, the session ends when the password changes
+	PwdTS int64 `json:"-"`
+	// RefreshHash is the hash of the refresh token, the token is rotated on every refresh
+	RefreshHash string `json:"-" gorm:"unique;size:64"`
+	// PrevRefreshHash is the hash of the refresh token replaced by the last rotation,
+	// it's presented again only if the token is stolen so the session is revoked then
+	PrevRefreshHash string    `json:"-" gorm:"index;size:64"`
+	Device          string    `json:"device"`
+	IP              string    `json:"ip"`
+	UserAgent       string    `json:"user_agent"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	// ExpiresAt is when the refresh token expires, the session can't be refreshed later
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	// Current is set for the session of the request listing the sessions
+	Current bool `json:"current" gorm:"-"`
+}
+
+func (s *Session) Expired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
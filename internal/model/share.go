@@ -0,0 +1,70 @@
+package model
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+// SharePasswordMask replaces a set password in the responses
+const SharePasswordMask = "******"
+
+// Share is a public link to a file or a folder, the visitors act as the owner but stay jailed to Path
+// and can only do what the share allows
+type Share struct {
+	ID     string `json:"id" gorm:"primaryKey;size:64"`
+	UserID uint   `json:"user_id" gorm:"index"`
+	// Path is the absolute path of the shared file or folder
+	Path  string `json:"path" gorm:"type:text"`
+	IsDir bool   `json:"is_dir"`
+	// Password is asked to the visitors if it's not empty, only its hash is stored
+	Password  string     `json:"password" gorm:"-"`
+	PwdHash   string     `json:"-"`
+	Salt      string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// MaxDownloads limits the downloads of the share, 0 means no limit
+	MaxDownloads  int64  `json:"max_downloads"`
+	AllowPreview  bool   `json:"allow_preview"`
+	AllowDownload bool   `json:"allow_download"`
+	AllowUpload   bool   `json:"allow_upload"`
+	Disabled      bool   `json:"disabled"`
+	Remark        string `json:"remark"`
+	// the access statistics
+	Views        int64      `json:"views"`
+	Downloads    int64      `json:"downloads"`
+	Uploads      int64      `json:"uploads"`
+	LastAccessAt *time.Time `json:"last_access_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (s *Share) Expired() bool {
+	return s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt)
+}
+
+// SetPassword stores the hash of pwd, an empty pwd removes the password
+func (s *Share) SetPassword(pwd string) {
+	s.Password = ""
+	if pwd == "" {
+		s.PwdHash, s.Salt = "", ""
+		return
+	}
+	s.Salt = random.String(16)
+	s.PwdHash = TwoHashPwd(pwd, s.Salt)
+}
+
+// CheckPassword reports whether pwd opens the share
+func (s *Share) CheckPassword(pwd string) bool {
+	if s.PwdHash == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(TwoHashPwd(pwd, s.Salt)), []byte(s.PwdHash)) == 1
+}
+
+// MaskPassword tells the client whether a password is set without returning it
+func (s *Share) MaskPassword() {
+	s.Password = ""
+	if s.PwdHash != "" {
+		s.Password = SharePasswordMask
+	}
+}
@@ -17,8 +17,11 @@ type Storage struct {
 	Disabled        bool      `json:"disabled"` // if disabled
 	DisableIndex    bool      `json:"disable_index"`
 	EnableSign      bool      `json:"enable_sign"`
+	// Quota is the bytes the storage can hold, 0 means unlimited
+	Quota int64 `json:"quota"`
 	Sort
 	Proxy
+	Trash
 }
 
 type Sort struct {
@@ -36,6 +39,13 @@ type Proxy struct {
 	DisableProxySign bool `json:"disable_proxy_sign"`
 }
 
+type Trash struct {
+	// EnableTrash moves the removed objs into the trash folder instead of deleting them
+	EnableTrash bool `json:"enable_trash"`
+	// TrashRetention is the days to keep the objs in the trash, 0 keeps them until purged
+	TrashRetention int `json:"trash_retention"`
+}
+
 func (s *Storage) GetStorage() *Storage {
 	return s
 }
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+// TrashItem is an obj removed into the trash of a storage, the paths are relative to the storage root
+type TrashItem struct {
+	ID        uint `json:"id" gorm:"primaryKey"`
+	StorageID uint `json:"storage_id" gorm:"index"`
+	// Path is where the obj was before it was removed
+	Path string `json:"path" gorm:"type:text"`
+	// TrashPath is where the obj is now
+	TrashPath string    `json:"trash_path" gorm:"type:text"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	IsDir     bool      `json:"is_dir"`
+	Deleter   string    `json:"deleter"`
+	DeletedAt time.Time `json:"deleted_at" gorm:"index"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// RecoveryCode is a one-time code to pass the 2FA when the authenticator is lost,
+// only the hash of the code is stored
+type RecoveryCode struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Hash      string    `json:"-" gorm:"size:64"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TwoFactorGrace records when the 2FA policy first required a user who had no 2FA,
+// the user can keep using the api without 2FA until the grace period from Since ends
+type TwoFactorGrace struct {
+	UserID uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Since  time.Time `json:"since"`
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/OpenListTeam/go-cache"
 	"github.com/go-webauthn/webauthn/webauthn"
 
 	"github.com/dongdio/OpenList/v4/utility/errs"
@@ -22,14 +21,6 @@ const (
 
 const StaticHashSalt = "https://github.com/alist-org/alist"
 
-// LoginCache 用于跟踪 IP 登录尝试次数的缓存
-var LoginCache = cache.NewMemCache[int]()
-
-var (
-	DefaultLockDuration   = time.Minute * 5
-	DefaultMaxAuthRetries = 5
-)
-
 type User struct {
 	ID       uint   `json:"id" gorm:"primaryKey"`                      // unique key
 	Username string `json:"username" gorm:"unique" binding:"required"` // username
@@ -40,6 +31,8 @@ type User struct {
 	BasePath string `json:"base_path"`                                 // base path
 	Role     int    `json:"role"`                                      // user's role
 	Disabled bool   `json:"disabled"`
+	// Quota is the bytes the user can store, 0 means unlimited
+	Quota int64 `json:"quota"`
 	// Determine permissions by bit
 	//   0:  can see hidden files
 	//   1:  can access without password
@@ -59,6 +52,14 @@ type User struct {
 	OtpSecret  string `json:"-"`
 	SsoID      string `json:"sso_id"` // unique by sso platform
 	Authn      string `gorm:"type:text" json:"-"`
+	// GroupIDs are the groups the user joins, nil means unchanged when the user is saved
+	GroupIDs []uint `json:"group_ids" gorm:"-"`
+	// Groups are loaded from GroupIDs, they add to the permission of the user
+	Groups []Group `json:"-" gorm:"-"`
+	// APITokenID is set when the user is authenticated with an api token, the user is then scoped by the token
+	APITokenID uint `gorm:"-" json:"-"`
+	// FileRequestID is set for the pseudo user uploading through a file request
+	FileRequestID string `gorm:"-" json:"-"`
 }
 
 func (u *User) IsGuest() bool {
@@ -90,64 +91,102 @@ func (u *User) SetPassword(pwd string) *User {
 	return u
 }
 
+// GetPermission returns the permission of the user and the groups the user joins
+func (u *User) GetPermission() int32 {
+	p := u.Permission
+	for _, g := range u.Groups {
+		p |= g.Permission
+	}
+	return p
+}
+
+// GetBasePath returns the base path of the user, a user with the root base path takes the first base path of the groups
+func (u *User) GetBasePath() string {
+	if !utils.PathEqual(u.BasePath, "/") || u.IsAdmin() {
+		return u.BasePath
+	}
+	for _, g := range u.Groups {
+		if g.BasePath != "" {
+			return g.BasePath
+		}
+	}
+	return u.BasePath
+}
+
+// GetMetaOverride merges the meta overrides of the groups applying to reqPath
+func (u *User) GetMetaOverride(reqPath string) GroupMeta {
+	var res GroupMeta
+	for _, g := range u.Groups {
+		for _, m := range g.Metas {
+			if !m.apply(reqPath) {
+				continue
+			}
+			res.NoPassword = res.NoPassword || m.NoPassword
+			res.Write = res.Write || m.Write
+			res.SeeHides = res.SeeHides || m.SeeHides
+		}
+	}
+	return res
+}
+
 func (u *User) CanSeeHides() bool {
-	return u.Permission&1 == 1
+	return u.GetPermission()&1 == 1
 }
 
 func (u *User) CanAccessWithoutPassword() bool {
-	return (u.Permission>>1)&1 == 1
+	return (u.GetPermission()>>1)&1 == 1
 }
 
 func (u *User) CanAddOfflineDownloadTasks() bool {
-	return (u.Permission>>2)&1 == 1
+	return (u.GetPermission()>>2)&1 == 1
 }
 
 func (u *User) CanWrite() bool {
-	return (u.Permission>>3)&1 == 1
+	return (u.GetPermission()>>3)&1 == 1
 }
 
 func (u *User) CanRename() bool {
-	return (u.Permission>>4)&1 == 1
+	return (u.GetPermission()>>4)&1 == 1
 }
 
 func (u *User) CanMove() bool {
-	return (u.Permission>>5)&1 == 1
+	return (u.GetPermission()>>5)&1 == 1
 }
 
 func (u *User) CanCopy() bool {
-	return (u.Permission>>6)&1 == 1
+	return (u.GetPermission()>>6)&1 == 1
 }
 
 func (u *User) CanRemove() bool {
-	return (u.Permission>>7)&1 == 1
+	return (u.GetPermission()>>7)&1 == 1
 }
 
 func (u *User) CanWebdavRead() bool {
-	return (u.Permission>>8)&1 == 1
+	return (u.GetPermission()>>8)&1 == 1
 }
 
 func (u *User) CanWebdavManage() bool {
-	return (u.Permission>>9)&1 == 1
+	return (u.GetPermission()>>9)&1 == 1
 }
 
 func (u *User) CanFTPAccess() bool {
-	return (u.Permission>>10)&1 == 1
+	return (u.GetPermission()>>10)&1 == 1
 }
 
 func (u *User) CanFTPManage() bool {
-	return (u.Permission>>11)&1 == 1
+	return (u.GetPermission()>>11)&1 == 1
 }
 
 func (u *User) CanReadArchives() bool {
-	return (u.Permission>>12)&1 == 1
+	return (u.GetPermission()>>12)&1 == 1
 }
 
 func (u *User) CanDecompress() bool {
-	return (u.Permission>>13)&1 == 1
+	return (u.GetPermission()>>13)&1 == 1
 }
 
 func (u *User) JoinPath(reqPath string) (string, error) {
-	return utils.JoinBasePath(u.BasePath, reqPath)
+	return utils.JoinBasePath(u.GetBasePath(), reqPath)
 }
 
 func StaticHash(password string) string {
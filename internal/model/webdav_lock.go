@@ -0,0 +1,42 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// WebDAVLock is a lock taken by a WebDAV client with LOCK.
+// It's kept in the database so it survives restarts and is seen by all the replicas,
+// the writes through the web, FTP and SFTP are refused on the locked paths too.
+type WebDAVLock struct {
+	Token string `json:"token" gorm:"primaryKey;size:64"`
+	// Root is the full path of the locked resource
+	Root string `json:"root" gorm:"type:text"`
+	// RootHash is the sha256 of Root, only one lock can be taken on a path
+	RootHash string `json:"-" gorm:"uniqueIndex;size:64"`
+	// ZeroDepth locks only Root, or Root and all its sub paths
+	ZeroDepth bool `json:"zero_depth"`
+	// OwnerXML is the raw <owner> xml given by the client
+	OwnerXML string `json:"owner_xml" gorm:"type:text"`
+	// Duration is the timeout asked by the client, negative for infinite
+	Duration  time.Duration `json:"duration"`
+	CreatedAt time.Time     `json:"created_at"`
+	// ExpiresAt is nil if the lock never expires
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"`
+}
+
+// Expired reports whether the lock is expired at now
+func (l *WebDAVLock) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Covers reports whether the lock applies to path, path must be clean
+func (l *WebDAVLock) Covers(path string) bool {
+	if path == l.Root {
+		return true
+	}
+	if l.ZeroDepth {
+		return false
+	}
+	return l.Root == "/" || strings.HasPrefix(path, l.Root+"/")
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type Webhook struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" binding:"required"`
+	URL  string `json:"url" binding:"required"`
+	// Secret signs the payload with utility/sign, the signature is sent in X-OpenList-Signature.
+	// It's masked in the responses, updating with the mask keeps it.
+	Secret string `json:"secret"`
+	// Events is a comma separated list of event names, empty means all events
+	Events string `json:"events"`
+	// PathPrefix only sends file events under this path, empty means all paths
+	PathPrefix string `json:"path_prefix"`
+	MaxRetry   int    `json:"max_retry"`
+	Disabled   bool   `json:"disabled"`
+}
+
+// WebhookSecretMask replaces a set secret in the responses
+const WebhookSecretMask = "******"
+
+// MaskSecret hides the secret before w is returned to the client
+func (w *Webhook) MaskSecret() {
+	if w.Secret != "" {
+		w.Secret = WebhookSecretMask
+	}
+}
+
+func (w *Webhook) Accept(event string) bool {
+	if strings.TrimSpace(w.Events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(w.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDelivery struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	WebhookID  uint      `json:"webhook_id" gorm:"index"`
+	Event      string    `json:"event"`
+	Payload    string    `json:"payload" gorm:"type:text"`
+	StatusCode int       `json:"status_code"`
+	Response   string    `json:"response" gorm:"type:text"`
+	Error      string    `json:"error" gorm:"type:text"`
+	Attempts   int       `json:"attempts"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/dongdio/OpenList/v4/drivers/thunder"
 	"github.com/dongdio/OpenList/v4/drivers/thunder_browser"
 	"github.com/dongdio/OpenList/v4/drivers/thunderx"
+	"github.com/dongdio/OpenList/v4/internal/audit"
 	"github.com/dongdio/OpenList/v4/internal/conf"
 	"github.com/dongdio/OpenList/v4/internal/fs"
 	"github.com/dongdio/OpenList/v4/internal/model"
@@ -43,7 +44,13 @@ type AddURLArgs struct {
 	DeletePolicy DeletePolicy
 }
 
-func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, error) {
+func AddURL(ctx context.Context, args *AddURLArgs) (_ task.TaskExtensionInfo, err error) {
+	defer func() {
+		audit.Record(ctx, model.AuditLog{Action: model.AuditOfflineDownload, Path: args.DstDirPath, Detail: args.URL}, err)
+	}()
+	if err = fs.CheckACL(ctx, model.ACLOfflineDownload, args.DstDirPath); err != nil {
+		return nil, err
+	}
 	// check storage
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(args.DstDirPath)
 	if err != nil {
@@ -53,6 +60,10 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 	if storage.Config().NoUpload {
 		return nil, errs.WithStack(errs.UploadNotSupported)
 	}
+	// the size is unknown before downloading, only check the quota isn't used up
+	if err = op.CheckQuota(ctx, storage, 0); err != nil {
+		return nil, err
+	}
 	// check path is valid
 	obj, err := op.Get(ctx, storage, dstDirActualPath)
 	if err != nil {
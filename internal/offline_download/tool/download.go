@@ -16,6 +16,7 @@ import (
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/internal/setting"
 	"github.com/dongdio/OpenList/v4/internal/task_group"
+	"github.com/dongdio/OpenList/v4/internal/webhook"
 	"github.com/dongdio/OpenList/v4/utility/task"
 )
 
@@ -228,4 +229,12 @@ func (t *DownloadTask) GetStatus() string {
 	return t.Status
 }
 
+func (t *DownloadTask) OnSucceeded() {
+	webhook.NotifyTask("offline_download", t.DstDirPath, t, true)
+}
+
+func (t *DownloadTask) OnFailed() {
+	webhook.NotifyTask("offline_download", t.DstDirPath, t, false)
+}
+
 var DownloadTaskManager *tache.Manager[*DownloadTask]
\ No newline at end of file
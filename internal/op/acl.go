@@ -0,0 +1,220 @@
+package op
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+var (
+	enabledACLRules      []model.ACLRule
+	enabledACLRulesValid bool
+	enabledACLRulesMu    sync.RWMutex
+)
+
+func invalidateACLRules() {
+	enabledACLRulesMu.Lock()
+	defer enabledACLRulesMu.Unlock()
+	enabledACLRulesValid = false
+	enabledACLRules = nil
+}
+
+// getEnabledACLRules returns the enabled rules in the evaluation order, the result is cached until a rule is changed
+func getEnabledACLRules() ([]model.ACLRule, error) {
+	enabledACLRulesMu.RLock()
+	if enabledACLRulesValid {
+		defer enabledACLRulesMu.RUnlock()
+		return enabledACLRules, nil
+	}
+	enabledACLRulesMu.RUnlock()
+
+	rules, err := db.GetEnabledACLRules()
+	if err != nil {
+		return nil, err
+	}
+	enabledACLRulesMu.Lock()
+	defer enabledACLRulesMu.Unlock()
+	enabledACLRules, enabledACLRulesValid = rules, true
+	return rules, nil
+}
+
+func GetACLRules(pageIndex, pageSize int) ([]model.ACLRule, int64, error) {
+	return db.GetACLRules(pageIndex, pageSize)
+}
+
+func GetACLRuleById(id uint) (*model.ACLRule, error) {
+	return db.GetACLRuleByID(id)
+}
+
+func checkACLRule(r *model.ACLRule) error {
+	switch r.SubjectType {
+	case model.ACLSubjectAll:
+		r.SubjectID = 0
+	case model.ACLSubjectUser:
+		if _, err := db.GetUserByID(r.SubjectID); err != nil {
+			return errs.WithMessage(err, "invalid acl rule user")
+		}
+	case model.ACLSubjectGroup:
+		if _, err := db.GetGroupByID(r.SubjectID); err != nil {
+			return errs.WithMessage(err, "invalid acl rule group")
+		}
+	default:
+		return errs.Errorf("unsupported acl rule subject type: %s", r.SubjectType)
+	}
+	if r.Effect != model.ACLAllow && r.Effect != model.ACLDeny {
+		return errs.Errorf("unsupported acl rule effect: %s", r.Effect)
+	}
+	actions := strings.Split(r.Actions, ",")
+	for i := range actions {
+		actions[i] = strings.TrimSpace(actions[i])
+		if actions[i] != "*" && !slices.Contains(model.ACLActions, actions[i]) {
+			return errs.Errorf("unsupported acl rule action: %s", actions[i])
+		}
+	}
+	r.Actions = strings.Join(actions, ",")
+	r.Path = utils.FixAndCleanPath(r.Path)
+	return nil
+}
+
+func CreateACLRule(r *model.ACLRule) error {
+	if err := checkACLRule(r); err != nil {
+		return err
+	}
+	defer invalidateACLRules()
+	return db.CreateACLRule(r)
+}
+
+func UpdateACLRule(r *model.ACLRule) error {
+	if _, err := db.GetACLRuleByID(r.ID); err != nil {
+		return err
+	}
+	if err := checkACLRule(r); err != nil {
+		return err
+	}
+	defer invalidateACLRules()
+	return db.UpdateACLRule(r)
+}
+
+func DeleteACLRuleById(id uint) error {
+	defer invalidateACLRules()
+	return db.DeleteACLRuleByID(id)
+}
+
+// MatchACLRule returns the first enabled rule of the user matching action on the mount path reqPath,
+// nil means no rule matches. The admin is never restricted by the rules.
+func MatchACLRule(user *model.User, action, reqPath string) (*model.ACLRule, error) {
+	if user == nil || user.IsAdmin() {
+		return nil, nil
+	}
+	rules, err := getEnabledACLRules()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		r := &rules[i]
+		if r.HasAction(action) && r.AppliesTo(user) && r.MatchPath(reqPath) {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// CheckACL returns errs.PermissionDenied if a rule denies the user to do action on the mount path reqPath
+func CheckACL(user *model.User, action, reqPath string) error {
+	r, err := MatchACLRule(user, action, reqPath)
+	if err != nil {
+		return err
+	}
+	if r != nil && r.Effect == model.ACLDeny {
+		return errs.WithStack(errs.PermissionDenied)
+	}
+	return nil
+}
+
+// CheckACLTree is CheckACL on reqPath and all the paths under it, for the actions applying to a whole dir.
+// A deny rule which may match a sub path denies the dir, even if an allow rule before it matches the same sub path.
+func CheckACLTree(user *model.User, action, reqPath string) error {
+	if err := CheckACL(user, action, reqPath); err != nil {
+		return err
+	}
+	if user == nil || user.IsAdmin() {
+		return nil
+	}
+	rules, err := getEnabledACLRules()
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		r := &rules[i]
+		if r.Effect == model.ACLDeny && r.HasAction(action) && r.AppliesTo(user) && r.MatchSubPath(reqPath) {
+			return errs.Wrapf(errs.PermissionDenied, "%s is denied under %s", action, reqPath)
+		}
+	}
+	return nil
+}
+
+// HasACLRules reports whether any enabled rule may apply to the user, so that the callers can skip the per object checks
+func HasACLRules(user *model.User) bool {
+	if user == nil || user.IsAdmin() {
+		return false
+	}
+	rules, err := getEnabledACLRules()
+	if err != nil {
+		return true
+	}
+	for i := range rules {
+		if rules[i].AppliesTo(user) {
+			return true
+		}
+	}
+	return false
+}
+
+// ACLDecision is the answer to "can the user do action on path"
+type ACLDecision struct {
+	Allowed bool           `json:"allowed"`
+	Reason  string         `json:"reason"`
+	Rule    *model.ACLRule `json:"rule,omitempty"`
+}
+
+// aclPermissions maps the actions to the permission they require besides the rules
+var aclPermissions = map[string]func(u *model.User) bool{
+	model.ACLWrite:           (*model.User).CanWrite,
+	model.ACLRename:          (*model.User).CanRename,
+	model.ACLMove:            (*model.User).CanMove,
+	model.ACLCopy:            (*model.User).CanCopy,
+	model.ACLDelete:          (*model.User).CanRemove,
+	model.ACLDecompress:      (*model.User).CanDecompress,
+	model.ACLOfflineDownload: (*model.User).CanAddOfflineDownloadTasks,
+}
+
+// DecideACL explains whether the user can do action on the mount path reqPath,
+// taking the base path, the permission and the rules into account as the fs layer does
+func DecideACL(user *model.User, action, reqPath string) (*ACLDecision, error) {
+	if !slices.Contains(model.ACLActions, action) {
+		return nil, errs.Errorf("unsupported acl action: %s", action)
+	}
+	reqPath = utils.FixAndCleanPath(reqPath)
+	if user.Disabled {
+		return &ACLDecision{Reason: "the user is disabled"}, nil
+	}
+	if !utils.IsSubPath(user.GetBasePath(), reqPath) {
+		return &ACLDecision{Reason: "the path is out of the base path of the user"}, nil
+	}
+	if can, ok := aclPermissions[action]; ok && !can(user) {
+		return &ACLDecision{Reason: "the user lacks the permission"}, nil
+	}
+	r, err := MatchACLRule(user, action, reqPath)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return &ACLDecision{Allowed: true, Reason: "no rule matches"}, nil
+	}
+	return &ACLDecision{Allowed: r.Effect == model.ACLAllow, Reason: "the rule matches", Rule: r}, nil
+}
@@ -0,0 +1,90 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+// TestACLFirstMatchWins checks the rules are evaluated in order and only narrow the permission down
+func TestACLFirstMatchWins(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "acl", BasePath: "/", Permission: 1 << 3}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	user, err := op.GetUserByName("acl")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	rules := []model.ACLRule{
+		{Priority: 1, SubjectType: model.ACLSubjectUser, SubjectID: user.ID, Path: "/share/public/**", Actions: "*", Effect: model.ACLAllow},
+		{Priority: 2, SubjectType: model.ACLSubjectAll, Path: "/share/**", Actions: "write, delete", Effect: model.ACLDeny},
+	}
+	for i := range rules {
+		if err = op.CreateACLRule(&rules[i]); err != nil {
+			t.Fatalf("failed create acl rule: %+v", err)
+		}
+	}
+
+	tests := []struct {
+		action, path string
+		allowed      bool
+	}{
+		{model.ACLWrite, "/share/public/a.txt", true},
+		{model.ACLWrite, "/share", false},
+		{model.ACLWrite, "/share/private/a.txt", false},
+		{model.ACLRead, "/share/private/a.txt", true},
+		{model.ACLWrite, "/other/a.txt", true},
+		// the permission stays the ceiling
+		{model.ACLDelete, "/share/public/a.txt", false},
+	}
+	for _, tt := range tests {
+		d, err := op.DecideACL(user, tt.action, tt.path)
+		if err != nil {
+			t.Fatalf("failed decide %s on %s: %+v", tt.action, tt.path, err)
+		}
+		if d.Allowed != tt.allowed {
+			t.Errorf("expected %s on %s allowed=%v, got %+v", tt.action, tt.path, tt.allowed, d)
+		}
+	}
+}
+
+// TestACLTree checks a deny rule on a sub path denies the actions on the dirs above it
+func TestACLTree(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "acl_tree", BasePath: "/"}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	user, err := op.GetUserByName("acl_tree")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	rules := []model.ACLRule{
+		{Priority: 1, SubjectType: model.ACLSubjectUser, SubjectID: user.ID, Path: "/data/*/secret/**", Actions: "delete", Effect: model.ACLDeny},
+		{Priority: 2, SubjectType: model.ACLSubjectUser, SubjectID: user.ID, Path: "/logs/*.log", Actions: "move", Effect: model.ACLDeny},
+	}
+	for i := range rules {
+		if err = op.CreateACLRule(&rules[i]); err != nil {
+			t.Fatalf("failed create acl rule: %+v", err)
+		}
+	}
+
+	tests := []struct {
+		action, path string
+		allowed      bool
+	}{
+		{model.ACLDelete, "/", false},
+		{model.ACLDelete, "/data", false},
+		{model.ACLDelete, "/data/a", false},
+		{model.ACLDelete, "/data/a/secret", false},
+		{model.ACLDelete, "/data/a/public", true},
+		{model.ACLDelete, "/other", true},
+		{model.ACLMove, "/logs", false},
+		{model.ACLMove, "/logs/a.txt", true},
+		{model.ACLCopy, "/logs", true},
+	}
+	for _, tt := range tests {
+		if err := op.CheckACLTree(user, tt.action, tt.path); (err == nil) != tt.allowed {
+			t.Errorf("expected %s on %s allowed=%v, got %v", tt.action, tt.path, tt.allowed, err)
+		}
+	}
+}
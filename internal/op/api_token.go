@@ -0,0 +1,237 @@
+package op
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/go-cache"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	apiTokenKeyIDLen  = 16
+	apiTokenSecretLen = 32
+	// apiTokenTouchInterval limits how often the last used time is written
+	apiTokenTouchInterval = time.Minute
+)
+
+var apiTokenCache = cache.NewMemCache(cache.WithShards[*model.APIToken](2))
+
+// apiTokenTouched keeps the last used time written by this node for each token id,
+// the cached tokens are shared by the requests so they are never changed
+var apiTokenTouched sync.Map
+
+// IsAPIToken reports whether s looks like an api token rather than a password or a jwt
+func IsAPIToken(s string) bool {
+	return strings.HasPrefix(s, model.APITokenPrefix) && len(s) == len(model.APITokenPrefix)+apiTokenKeyIDLen+apiTokenSecretLen
+}
+
+func hashAPIToken(raw string) string {
+	return utils.HashData(utils.SHA256, []byte(raw))
+}
+
+// APITokenAccessKeyID is the S3 access key id of the token
+func APITokenAccessKeyID(t *model.APIToken) string {
+	return model.APITokenPrefix + t.KeyID
+}
+
+// APITokenS3Secret derives the S3 secret access key of the token.
+// SigV4 needs the secret on the server side, so it's derived from the stored hash and the jwt secret instead of being stored.
+func APITokenS3Secret(t *model.APIToken) string {
+	h := hmac.New(sha256.New, []byte(conf.Conf.JwtSecret))
+	h.Write([]byte("s3:" + t.TokenHash))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checkAPIToken(user *model.User, t *model.APIToken) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errs.New("name of the api token is required")
+	}
+	if t.BasePath != "" {
+		if _, err := utils.JoinBasePath("/", t.BasePath); err != nil {
+			return err
+		}
+		t.BasePath = utils.FixAndCleanPath(t.BasePath)
+	}
+	if t.Permission != nil {
+		// a token never grants more than the user has
+		p := *t.Permission & user.GetPermission()
+		t.Permission = &p
+	}
+	return nil
+}
+
+// CreateAPIToken creates a token for user and returns the token, it can't be got again later
+func CreateAPIToken(user *model.User, t *model.APIToken) (string, error) {
+	if err := checkAPIToken(user, t); err != nil {
+		return "", err
+	}
+	t.ID = 0
+	t.UserID = user.ID
+	t.KeyID = random.String(apiTokenKeyIDLen)
+	raw := model.APITokenPrefix + t.KeyID + random.String(apiTokenSecretLen)
+	t.TokenHash = hashAPIToken(raw)
+	t.LastUsedAt = nil
+	t.CreatedAt = time.Now()
+	if err := db.CreateAPIToken(t); err != nil {
+		return "", err
+	}
+	CallAPITokenHooks("add", t)
+	return raw, nil
+}
+
+// UpdateAPIToken updates the name, scope and expiry of a token, the secret is kept
+func UpdateAPIToken(user *model.User, t *model.APIToken) error {
+	old, err := GetAPITokenByIdAndUserId(t.ID, user.ID)
+	if err != nil {
+		return err
+	}
+	if err = checkAPIToken(user, t); err != nil {
+		return err
+	}
+	t.UserID = old.UserID
+	t.KeyID = old.KeyID
+	t.TokenHash = old.TokenHash
+	t.LastUsedAt = old.LastUsedAt
+	t.CreatedAt = old.CreatedAt
+	apiTokenCache.Del(old.TokenHash)
+	if err = db.UpdateAPIToken(t); err != nil {
+		return err
+	}
+	CallAPITokenHooks("update", t)
+	return nil
+}
+
+func GetAPITokensByUserId(userID uint, pageIndex, pageSize int) ([]model.APIToken, int64, error) {
+	return db.GetAPITokensByUserID(userID, pageIndex, pageSize)
+}
+
+func GetValidAPITokens() ([]model.APIToken, error) {
+	return db.GetValidAPITokens()
+}
+
+// GetAPITokenByIdAndUserId ensures the token belongs to the user
+func GetAPITokenByIdAndUserId(id, userID uint) (*model.APIToken, error) {
+	t, err := db.GetAPITokenByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.UserID != userID {
+		return nil, errs.Errorf("api token %d does not belong to user %d", id, userID)
+	}
+	return t, nil
+}
+
+func DeleteAPITokenById(id uint) error {
+	t, err := db.GetAPITokenByID(id)
+	if err != nil {
+		return err
+	}
+	apiTokenCache.Del(t.TokenHash)
+	apiTokenTouched.Delete(t.ID)
+	if err = db.DeleteAPITokenByID(id); err != nil {
+		return err
+	}
+	CallAPITokenHooks("del", t)
+	return nil
+}
+
+// ValidateAPIToken returns the owner of the token scoped by the token
+func ValidateAPIToken(raw string) (*model.User, error) {
+	if !IsAPIToken(raw) {
+		return nil, errs.WithStack(errs.InvalidAPIToken)
+	}
+	hash := hashAPIToken(raw)
+	t, ok := apiTokenCache.Get(hash)
+	if !ok {
+		var err error
+		t, err = db.GetAPITokenByHash(hash)
+		if err != nil {
+			return nil, errs.WithStack(errs.InvalidAPIToken)
+		}
+		apiTokenCache.Set(hash, t, cache.WithEx[*model.APIToken](time.Hour))
+	}
+	return apiTokenUser(t)
+}
+
+// ValidateAPITokenKeyID returns the owner of the token with the S3 access key id scoped by the token,
+// the signature made with the secret is verified by the S3 server
+func ValidateAPITokenKeyID(accessKeyID string) (*model.User, error) {
+	keyID := strings.TrimPrefix(accessKeyID, model.APITokenPrefix)
+	t, err := db.GetAPITokenByKeyID(keyID)
+	if err != nil {
+		return nil, errs.WithStack(errs.InvalidAPIToken)
+	}
+	return apiTokenUser(t)
+}
+
+func apiTokenUser(t *model.APIToken) (*model.User, error) {
+	if t.Expired() {
+		return nil, errs.WithStack(errs.APITokenExpired)
+	}
+	user, err := GetUserById(t.UserID)
+	if err != nil {
+		return nil, errs.WithStack(errs.InvalidAPIToken)
+	}
+	if user.Disabled {
+		return nil, errs.New("the owner of the api token is disabled")
+	}
+	// the tokens made in the grace period stop working with it as the login of the owner does
+	if err = CheckTwoFactorPolicy(user); err != nil {
+		return nil, err
+	}
+	touchAPIToken(t)
+	return ScopeUser(user, t)
+}
+
+// touchAPIToken writes the last used time of t at most once per apiTokenTouchInterval
+func touchAPIToken(t *model.APIToken) {
+	last := t.LastUsedAt
+	if v, ok := apiTokenTouched.Load(t.ID); ok {
+		touched := v.(time.Time)
+		last = &touched
+	}
+	if now := time.Now(); last == nil || now.Sub(*last) > apiTokenTouchInterval {
+		apiTokenTouched.Store(t.ID, now)
+		_ = db.UpdateAPITokenLastUsed(t.ID, now)
+	}
+}
+
+// ScopeUser returns a copy of user limited to the base path and the permission of the token.
+// A scoped token drops the groups of the user after taking their permission and base path,
+// and a scoped admin token loses the admin role, otherwise the limits would be bypassed.
+func ScopeUser(user *model.User, t *model.APIToken) (*model.User, error) {
+	scoped := *user
+	scoped.APITokenID = t.ID
+	if !t.Scoped() {
+		return &scoped, nil
+	}
+	scoped.Permission = user.GetPermission()
+	if t.Permission != nil {
+		scoped.Permission &= *t.Permission
+	}
+	basePath, err := user.JoinPath(t.BasePath)
+	if err != nil {
+		return nil, err
+	}
+	scoped.BasePath = basePath
+	// the groups only keep their ids, so their acl rules still apply but they add nothing to the scope
+	scoped.Groups = make([]model.Group, len(user.Groups))
+	for i, g := range user.Groups {
+		scoped.Groups[i] = model.Group{ID: g.ID, Name: g.Name}
+	}
+	if scoped.IsAdmin() {
+		scoped.Role = model.GENERAL
+	}
+	return &scoped, nil
+}
@@ -0,0 +1,47 @@
+package op_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// TestAPITokenScope checks a token only grants its path and a subset of the permission of the user
+func TestAPITokenScope(t *testing.T) {
+	user := &model.User{Username: "token", BasePath: "/home", Permission: 0x0F}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	perm := int32(0x3C)
+	token := &model.APIToken{Name: "ci", BasePath: "/docs", Permission: &perm}
+	raw, err := op.CreateAPIToken(user, token)
+	if err != nil {
+		t.Fatalf("failed create token: %+v", err)
+	}
+	scoped, err := op.ValidateAPIToken(raw)
+	if err != nil {
+		t.Fatalf("failed validate token: %+v", err)
+	}
+	if scoped.BasePath != "/home/docs" || scoped.Permission != 0x0C || scoped.APITokenID != token.ID {
+		t.Errorf("unexpected scoped user: %+v", scoped)
+	}
+	wrong := raw[:len(raw)-1] + "x"
+	if wrong == raw {
+		wrong = raw[:len(raw)-1] + "y"
+	}
+	if _, err = op.ValidateAPIToken(wrong); !errs.Is(err, errs.InvalidAPIToken) {
+		t.Errorf("expected a wrong token to be rejected, got %v", err)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	token.ExpiresAt = &expired
+	if err = op.UpdateAPIToken(user, token); err != nil {
+		t.Fatalf("failed update token: %+v", err)
+	}
+	if _, err = op.ValidateAPIToken(raw); !errs.Is(err, errs.APITokenExpired) {
+		t.Errorf("expected the token to be expired, got %v", err)
+	}
+}
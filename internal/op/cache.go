@@ -0,0 +1,190 @@
+package op
+
+import (
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/go-cache"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/cache_backend"
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+)
+
+// The in-process listCache and linkCache are the first level,
+// the configured cache_backend is the second level shared by restarts and replicas.
+
+const (
+	listKeyPrefix    = "list:"
+	linkKeyPrefix    = "link:"
+	sessionKeyPrefix = "session:"
+	shareKeyPrefix   = "share:"
+	// shareDownloadKeyPrefix is the prefix of the share downloads counted in the backend, they are never cached locally
+	shareDownloadKeyPrefix = "share_download:"
+)
+
+func listCacheEx(storage driver.Driver) time.Duration {
+	return time.Minute * time.Duration(storage.GetStorage().CacheExpiration)
+}
+
+func getListCache(storage driver.Driver, key string) ([]model.Obj, bool) {
+	if objs, ok := listCache.Get(key); ok {
+		return objs, true
+	}
+	if !cache_backend.Enabled() {
+		return nil, false
+	}
+	data, ok, err := cache_backend.Get().Get(listKeyPrefix + key)
+	if err != nil {
+		log.Warnf("failed get list cache [%s] from backend: %+v", key, err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	objs, err := cache_backend.DecodeObjs(data)
+	if err != nil {
+		log.Warnf("failed decode list cache [%s]: %+v", key, err)
+		return nil, false
+	}
+	listCache.Set(key, objs, cache.WithEx[[]model.Obj](listCacheEx(storage)))
+	return objs, true
+}
+
+func setListCache(storage driver.Driver, key string, objs []model.Obj) {
+	listCache.Set(key, objs, cache.WithEx[[]model.Obj](listCacheEx(storage)))
+	syncListCache(storage, key, objs)
+}
+
+// syncListCache writes objs to the backend and tells the other nodes to drop their copy
+func syncListCache(storage driver.Driver, key string, objs []model.Obj) {
+	if !cache_backend.Enabled() {
+		return
+	}
+	b := cache_backend.Get()
+	var err error
+	if data, ok := cache_backend.EncodeObjs(objs); ok {
+		err = b.Set(listKeyPrefix+key, data, listCacheEx(storage))
+	} else {
+		err = b.Del(listKeyPrefix + key)
+	}
+	if err != nil {
+		log.Warnf("failed save list cache [%s] to backend: %+v", key, err)
+	}
+	publishInvalidation(false, listKeyPrefix+key)
+}
+
+func delListCache(key string) {
+	listCache.Del(key)
+	if !cache_backend.Enabled() {
+		return
+	}
+	if err := cache_backend.Get().Del(listKeyPrefix + key); err != nil {
+		log.Warnf("failed delete list cache [%s] from backend: %+v", key, err)
+	}
+	publishInvalidation(false, listKeyPrefix+key)
+}
+
+// clearListCache deletes key and all the sub directories of key
+func clearListCache(key string) {
+	clearLocalListCache(key)
+	if !cache_backend.Enabled() {
+		return
+	}
+	b := cache_backend.Get()
+	if err := b.Del(listKeyPrefix + key); err != nil {
+		log.Warnf("failed delete list cache [%s] from backend: %+v", key, err)
+	}
+	if err := b.DelPrefix(listKeyPrefix + strings.TrimSuffix(key, "/") + "/"); err != nil {
+		log.Warnf("failed clear list cache [%s] from backend: %+v", key, err)
+	}
+	publishInvalidation(true, listKeyPrefix+key)
+}
+
+func clearLocalListCache(key string) {
+	objs, ok := listCache.Get(key)
+	if ok {
+		for _, obj := range objs {
+			if obj.IsDir() {
+				clearLocalListCache(stdpath.Join(key, obj.GetName()))
+			}
+		}
+	}
+	listCache.Del(key)
+}
+
+func getLinkCache(key string) (*model.Link, bool) {
+	if link, ok := linkCache.Get(key); ok {
+		return link, true
+	}
+	if !cache_backend.Enabled() {
+		return nil, false
+	}
+	data, ok, err := cache_backend.Get().Get(linkKeyPrefix + key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	link, err := cache_backend.DecodeLink(data)
+	if err != nil {
+		log.Warnf("failed decode link cache [%s]: %+v", key, err)
+		return nil, false
+	}
+	return link, true
+}
+
+func setLinkCache(key string, link *model.Link) {
+	linkCache.Set(key, link, cache.WithEx[*model.Link](*link.Expiration))
+	if !cache_backend.Enabled() {
+		return
+	}
+	if data, ok := cache_backend.EncodeLink(link); ok {
+		if err := cache_backend.Get().Set(linkKeyPrefix+key, data, *link.Expiration); err != nil {
+			log.Warnf("failed save link cache [%s] to backend: %+v", key, err)
+		}
+	}
+}
+
+func delLinkCache(key string) {
+	linkCache.Del(key)
+	if !cache_backend.Enabled() {
+		return
+	}
+	if err := cache_backend.Get().Del(linkKeyPrefix + key); err != nil {
+		log.Warnf("failed delete link cache [%s] from backend: %+v", key, err)
+	}
+	publishInvalidation(false, linkKeyPrefix+key)
+}
+
+func publishInvalidation(recursive bool, keys ...string) {
+	err := cache_backend.Get().Publish(cache_backend.Invalidation{Keys: keys, Recursive: recursive})
+	if err != nil {
+		log.Warnf("failed publish cache invalidation %v: %+v", keys, err)
+	}
+}
+
+// SubscribeCacheInvalidation drops the in-process cache when another node changes the shared one
+func SubscribeCacheInvalidation() {
+	cache_backend.Get().Subscribe(func(inv cache_backend.Invalidation) {
+		for _, key := range inv.Keys {
+			switch {
+			case strings.HasPrefix(key, listKeyPrefix):
+				key = strings.TrimPrefix(key, listKeyPrefix)
+				if inv.Recursive {
+					clearLocalListCache(key)
+				} else {
+					listCache.Del(key)
+				}
+			case strings.HasPrefix(key, linkKeyPrefix):
+				linkCache.Del(strings.TrimPrefix(key, linkKeyPrefix))
+			case strings.HasPrefix(key, sessionKeyPrefix):
+				id := strings.TrimPrefix(key, sessionKeyPrefix)
+				sessionCache.Del(id)
+				sessionTouched.Delete(id)
+			case strings.HasPrefix(key, shareKeyPrefix):
+				shareCache.Del(strings.TrimPrefix(key, shareKeyPrefix))
+			}
+		}
+	})
+}
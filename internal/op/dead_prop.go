@@ -0,0 +1,68 @@
+package op
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+func GetDeadProps(path string) ([]model.DeadProp, error) {
+	return db.GetDeadProps(utils.FixAndCleanPath(path))
+}
+
+// GetDeadPropsUnder returns the dead props of path and its sub paths
+func GetDeadPropsUnder(path string) ([]model.DeadProp, error) {
+	return db.GetDeadPropsUnder(utils.FixAndCleanPath(path))
+}
+
+// GetDeadPropsWithChildren returns the dead props of path and its direct children
+func GetDeadPropsWithChildren(path string) ([]model.DeadProp, error) {
+	return db.GetDeadPropsWithChildren(utils.FixAndCleanPath(path))
+}
+
+// PatchDeadProps sets props and removes the props named by remove of path, either all or none of them are applied
+func PatchDeadProps(path string, props []model.DeadProp, remove []model.DeadProp) error {
+	return db.PatchDeadProps(utils.FixAndCleanPath(path), props, remove)
+}
+
+// MoveDeadProps moves the dead props of src and its sub paths to dst, the props of dst are dropped first
+func MoveDeadProps(src, dst string) error {
+	return db.MoveDeadProps(utils.FixAndCleanPath(src), utils.FixAndCleanPath(dst))
+}
+
+// CopyDeadProps copies the dead props of src and its sub paths to dst, the props of dst are dropped first
+func CopyDeadProps(src, dst string) error {
+	return db.CopyDeadProps(utils.FixAndCleanPath(src), utils.FixAndCleanPath(dst))
+}
+
+// DeleteDeadProps deletes the dead props of path and its sub paths
+func DeleteDeadProps(path string) error {
+	return db.DeleteDeadProps(utils.FixAndCleanPath(path))
+}
+
+// FollowDeadProps keeps the WebDAV dead props with their objects when they are renamed, moved,
+// copied or removed through any protocol, it should be called once when the server starts
+func FollowDeadProps() {
+	SubscribeEvents(func(e Event) {
+		var err error
+		switch e.Type {
+		case EventRenamed, EventMoved:
+			err = MoveDeadProps(e.SrcPath, e.Path)
+		case EventCopied:
+			err = CopyDeadProps(e.SrcPath, e.Path)
+		case EventRemoved:
+			if e.Trashed {
+				// the props are moved to the trash with the object, so they are back when it's restored
+				return
+			}
+			err = DeleteDeadProps(e.Path)
+		default:
+			return
+		}
+		if err != nil {
+			log.Errorf("failed %s dead props of %s: %+v", e.Type, e.Path, err)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+// TestDeadPropsFollowObjects checks the dead props are patched atomically and follow the moves, copies and removes
+func TestDeadPropsFollowObjects(t *testing.T) {
+	mtime := model.DeadProp{Space: "urn:schemas-microsoft-com:", Local: "Win32LastModifiedTime", InnerXML: "Wed, 01 Jan 2025 00:00:00 GMT"}
+	color := model.DeadProp{Space: "urn:example", Local: "color", InnerXML: "red"}
+	if err := op.PatchDeadProps("/dav/docs/a.txt", []model.DeadProp{mtime, color}, nil); err != nil {
+		t.Fatalf("failed patch dead props: %+v", err)
+	}
+	if err := op.PatchDeadProps("/dav/docs_other/b.txt", []model.DeadProp{color}, nil); err != nil {
+		t.Fatalf("failed patch dead props: %+v", err)
+	}
+	color.InnerXML = "blue"
+	if err := op.PatchDeadProps("/dav/docs/a.txt", []model.DeadProp{color}, []model.DeadProp{mtime}); err != nil {
+		t.Fatalf("failed patch dead props: %+v", err)
+	}
+	props, err := op.GetDeadProps("/dav/docs/a.txt")
+	if err != nil || len(props) != 1 || props[0].InnerXML != "blue" {
+		t.Fatalf("expected only the updated color, got %+v, %v", props, err)
+	}
+
+	if err = op.MoveDeadProps("/dav/docs", "/dav/moved"); err != nil {
+		t.Fatalf("failed move dead props: %+v", err)
+	}
+	if props, _ = op.GetDeadProps("/dav/moved/a.txt"); len(props) != 1 {
+		t.Errorf("expected the props to follow the moved dir, got %+v", props)
+	}
+	if props, _ = op.GetDeadProps("/dav/docs_other/b.txt"); len(props) != 1 {
+		t.Errorf("expected the sibling with the same prefix to be kept, got %+v", props)
+	}
+
+	if err = op.CopyDeadProps("/dav/moved/a.txt", "/dav/copy.txt"); err != nil {
+		t.Fatalf("failed copy dead props: %+v", err)
+	}
+	if err = op.DeleteDeadProps("/dav/moved"); err != nil {
+		t.Fatalf("failed delete dead props: %+v", err)
+	}
+	if props, _ = op.GetDeadProps("/dav/moved/a.txt"); len(props) != 0 {
+		t.Errorf("expected the props to be deleted with the dir, got %+v", props)
+	}
+	if props, _ = op.GetDeadProps("/dav/copy.txt"); len(props) != 1 || props[0].InnerXML != "blue" {
+		t.Errorf("expected the copy to keep its props, got %+v", props)
+	}
+
+	// a move without props overwriting dst drops the props of dst
+	if err = op.MoveDeadProps("/dav/none.txt", "/dav/copy.txt"); err != nil {
+		t.Fatalf("failed move dead props: %+v", err)
+	}
+	if props, _ = op.GetDeadProps("/dav/copy.txt"); len(props) != 0 {
+		t.Errorf("expected the overwritten props to be dropped, got %+v", props)
+	}
+
+	if props, err = op.GetDeadPropsWithChildren("/dav"); err != nil || len(props) != 0 {
+		t.Errorf("expected no props of /dav and its children, got %+v, %v", props, err)
+	}
+	if props, err = op.GetDeadPropsWithChildren("/dav/docs_other"); err != nil || len(props) != 1 {
+		t.Errorf("expected the props of the child, got %+v, %v", props, err)
+	}
+}
@@ -2,12 +2,16 @@
 package op
 
 import (
+	"context"
 	"reflect"
 	"strings"
 
+	log "github.com/sirupsen/logrus"
+
 	"github.com/dongdio/OpenList/v4/consts"
 	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
 )
 
 type DriverConstructor func() driver.Driver
@@ -15,11 +19,11 @@ type DriverConstructor func() driver.Driver
 var driverMap = map[string]DriverConstructor{}
 var driverInfoMap = map[string]driver.Info{}
 
-func RegisterDriver(driver DriverConstructor) {
-	tempDriver := driver()
+func RegisterDriver(constructor DriverConstructor) {
+	tempDriver := constructor()
 	tempConfig := tempDriver.Config()
-	registerDriverItems(tempConfig, tempDriver.GetAddition())
-	driverMap[tempConfig.Name] = driver
+	registerDriverItems(tempConfig, tempDriver.GetAddition(), driver.GetCapabilities(tempDriver))
+	driverMap[tempConfig.Name] = constructor
 }
 
 func GetDriver(name string) (DriverConstructor, error) {
@@ -42,7 +46,23 @@ func GetDriverInfoMap() map[string]driver.Info {
 	return driverInfoMap
 }
 
-func registerDriverItems(config driver.Config, addition driver.Additional) {
+// GetStorageCapabilities returns the capabilities of storage at actualPath,
+// drivers forwarding to other storages are resolved down to the storage the path lands on
+func GetStorageCapabilities(ctx context.Context, storage driver.Driver, actualPath string) driver.Capabilities {
+	caps := driver.GetCapabilities(storage)
+	r, ok := storage.(driver.CapabilitiesResolver)
+	if !ok {
+		return caps
+	}
+	resolved, err := r.ResolveCapabilities(ctx, utils.FixAndCleanPath(actualPath))
+	if err != nil {
+		log.Debugf("failed resolve capabilities of [%s]%s: %+v", storage.GetStorage().MountPath, actualPath, err)
+		return caps
+	}
+	return caps.And(resolved)
+}
+
+func registerDriverItems(config driver.Config, addition driver.Additional, capabilities driver.Capabilities) {
 	tAddition := reflect.TypeOf(addition)
 	for tAddition.Kind() == reflect.Pointer {
 		tAddition = tAddition.Elem()
@@ -50,9 +70,10 @@ func registerDriverItems(config driver.Config, addition driver.Additional) {
 	mainItems := getMainItems(config)
 	additionalItems := getAdditionalItems(tAddition, config.DefaultRoot)
 	driverInfoMap[config.Name] = driver.Info{
-		Common:     mainItems,
-		Additional: additionalItems,
-		Config:     config,
+		Common:       mainItems,
+		Additional:   additionalItems,
+		Config:       config,
+		Capabilities: capabilities,
 	}
 }
 
@@ -137,6 +158,23 @@ func getMainItems(config driver.Config) []driver.Item {
 		Type:    consts.TypeSelect,
 		Options: "front,back",
 	})
+	items = append(items, driver.Item{
+		Name:    "quota",
+		Type:    consts.TypeNumber,
+		Default: "0",
+		Help:    "The bytes this storage can hold, 0 for unlimited",
+	})
+	items = append(items, []driver.Item{{
+		Name:    "enable_trash",
+		Type:    consts.TypeBool,
+		Default: "false",
+		Help:    "Move the removed objects into the .trash folder of this storage",
+	}, {
+		Name:    "trash_retention",
+		Type:    consts.TypeNumber,
+		Default: "30",
+		Help:    "Days to keep the removed objects in the trash, 0 to keep them until purged",
+	}}...)
 	items = append(items, driver.Item{
 		Name:     "disable_index",
 		Type:     consts.TypeBool,
@@ -0,0 +1,94 @@
+package op
+
+import (
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+)
+
+// EventType is the kind of change that happened to an object
+type EventType string
+
+const (
+	EventCreated  EventType = "created"
+	EventRemoved  EventType = "removed"
+	EventRenamed  EventType = "renamed"
+	EventMoved    EventType = "moved"
+	EventCopied   EventType = "copied"
+	EventUploaded EventType = "uploaded"
+)
+
+// Event describes a change made through op, paths are full paths including the mount path
+type Event struct {
+	Type EventType `json:"type"`
+	// Path is the path of the object after the change
+	Path string `json:"path"`
+	// SrcPath is the path before the change, only set for renamed, moved and copied
+	SrcPath string    `json:"src_path,omitempty"`
+	Name    string    `json:"name"`
+	IsDir   bool      `json:"is_dir"`
+	Size    int64     `json:"size"`
+	Time    time.Time `json:"time"`
+	// Trashed is set for removed when the object is moved to the trash, it can be restored later
+	Trashed bool `json:"trashed,omitempty"`
+}
+
+// EventHandler is called synchronously on the goroutine that made the change, it must not block
+type EventHandler func(e Event)
+
+var (
+	eventHandlers   = make(map[int]EventHandler)
+	eventHandlersMu sync.RWMutex
+	eventHandlerID  int
+)
+
+// SubscribeEvents registers handler for all the events and returns the function to unregister it
+func SubscribeEvents(handler EventHandler) (unsubscribe func()) {
+	eventHandlersMu.Lock()
+	defer eventHandlersMu.Unlock()
+	eventHandlerID++
+	id := eventHandlerID
+	eventHandlers[id] = handler
+	return func() {
+		eventHandlersMu.Lock()
+		defer eventHandlersMu.Unlock()
+		delete(eventHandlers, id)
+	}
+}
+
+// PublishEvent sends e to all the subscribers
+func PublishEvent(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	eventHandlersMu.RLock()
+	defer eventHandlersMu.RUnlock()
+	for _, handler := range eventHandlers {
+		handler(e)
+	}
+}
+
+// publishObjEvent converts the storage relative paths to full paths and publishes the event
+func publishObjEvent(storage driver.Driver, typ EventType, path, srcPath string, obj model.Obj) {
+	PublishEvent(objEvent(storage, typ, path, srcPath, obj))
+}
+
+func objEvent(storage driver.Driver, typ EventType, path, srcPath string, obj model.Obj) Event {
+	mountPath := storage.GetStorage().MountPath
+	e := Event{
+		Type: typ,
+		Path: stdpath.Join(mountPath, path),
+		Name: stdpath.Base(path),
+	}
+	if srcPath != "" {
+		e.SrcPath = stdpath.Join(mountPath, srcPath)
+	}
+	if obj != nil {
+		e.IsDir = obj.IsDir()
+		e.Size = obj.GetSize()
+	}
+	return e
+}
@@ -0,0 +1,203 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/go-cache"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	fileRequestIDLen = 12
+	// fileRequestPermission lets the pseudo user upload without the meta passwords, and nothing else
+	fileRequestPermission int32 = 1<<1 | 1<<3
+)
+
+var fileRequestCache = cache.NewMemCache(cache.WithShards[*model.FileRequest](2))
+
+func checkFileRequest(owner *model.User, r *model.FileRequest) error {
+	if !owner.CanWrite() {
+		return errs.WithStack(errs.PermissionDenied)
+	}
+	switch r.RenamePolicy {
+	case "":
+		r.RenamePolicy = model.FileRequestRenameNumber
+	case model.FileRequestRenameNumber, model.FileRequestRenameTimestamp:
+	default:
+		return errs.Errorf("unknown rename policy: %s", r.RenamePolicy)
+	}
+	r.MaxFileSize, r.Quota = max(r.MaxFileSize, 0), max(r.Quota, 0)
+	return nil
+}
+
+// CreateFileRequest creates a file request uploading into the folder at r.Path,
+// which must be an absolute path under the base path of owner
+func CreateFileRequest(owner *model.User, r *model.FileRequest) error {
+	r.Path = utils.FixAndCleanPath(r.Path)
+	if !utils.IsSubPath(owner.GetBasePath(), r.Path) {
+		return errs.WithStack(errs.PermissionDenied)
+	}
+	if err := checkFileRequest(owner, r); err != nil {
+		return err
+	}
+	r.ID = random.String(fileRequestIDLen)
+	r.UserID = owner.ID
+	r.SetPassword(r.Password)
+	r.Used, r.Uploads, r.LastUploadAt = 0, 0, nil
+	r.CreatedAt = time.Now()
+	return db.CreateFileRequest(r)
+}
+
+// UpdateFileRequest updates the password, expiry, limits and rename policy of a file request,
+// the folder, the owner and the statistics are kept, so is the password if it's the mask
+func UpdateFileRequest(r *model.FileRequest) error {
+	old, err := db.GetFileRequestByID(r.ID)
+	if err != nil {
+		return err
+	}
+	owner, err := GetUserById(old.UserID)
+	if err != nil {
+		return err
+	}
+	r.UserID, r.Path = old.UserID, old.Path
+	if err = checkFileRequest(owner, r); err != nil {
+		return err
+	}
+	if r.Password == model.SharePasswordMask {
+		r.PwdHash, r.Salt, r.Password = old.PwdHash, old.Salt, ""
+	} else {
+		r.SetPassword(r.Password)
+	}
+	r.Used, r.Uploads, r.LastUploadAt, r.CreatedAt = old.Used, old.Uploads, old.LastUploadAt, old.CreatedAt
+	fileRequestCache.Del(r.ID)
+	return db.UpdateFileRequest(r)
+}
+
+// GetFileRequests returns the file requests of the user, or of all the users if userID is 0
+func GetFileRequests(userID uint, pageIndex, pageSize int) ([]model.FileRequest, int64, error) {
+	return db.GetFileRequests(userID, pageIndex, pageSize)
+}
+
+func GetFileRequestById(id string) (*model.FileRequest, error) {
+	return db.GetFileRequestByID(id)
+}
+
+// GetFileRequestByIdAndUserId ensures the file request belongs to the user
+func GetFileRequestByIdAndUserId(id string, userID uint) (*model.FileRequest, error) {
+	r, err := db.GetFileRequestByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.UserID != userID {
+		return nil, errs.Errorf("file request %s does not belong to user %d", id, userID)
+	}
+	return r, nil
+}
+
+func DeleteFileRequestById(id string) error {
+	fileRequestCache.Del(id)
+	return db.DeleteFileRequestByID(id)
+}
+
+func DeleteFileRequestsByUserId(userID uint) error {
+	ids, err := db.DeleteFileRequestsByUserID(userID)
+	for _, id := range ids {
+		fileRequestCache.Del(id)
+	}
+	return err
+}
+
+// ValidateFileRequest returns the file request with id and the pseudo user uploading through it
+// if the file request can be used with password
+func ValidateFileRequest(id, password string) (*model.FileRequest, *model.User, error) {
+	r, ok := fileRequestCache.Get(id)
+	if !ok {
+		var err error
+		r, err = db.GetFileRequestByID(id)
+		if err != nil {
+			return nil, nil, errs.WithStack(errs.FileRequestNotFound)
+		}
+		fileRequestCache.Set(id, r, cache.WithEx[*model.FileRequest](time.Hour))
+	}
+	if r.Disabled {
+		return nil, nil, errs.WithStack(errs.FileRequestNotFound)
+	}
+	if r.Expired() {
+		return nil, nil, errs.WithStack(errs.FileRequestExpired)
+	}
+	owner, err := GetUserById(r.UserID)
+	if err != nil || owner.Disabled || !owner.CanWrite() || !utils.IsSubPath(owner.GetBasePath(), r.Path) {
+		// the owner can't upload to the folder any more
+		return nil, nil, errs.WithStack(errs.FileRequestNotFound)
+	}
+	if !r.CheckPassword(password) {
+		return nil, nil, errs.WithStack(errs.WrongFileRequestPassword)
+	}
+	return r, FileRequestUser(owner, r), nil
+}
+
+// FileRequestUser returns the pseudo user of the file request, it acts as the owner so the uploads
+// count to the quota of the owner, but it's jailed to the folder and can only upload.
+// The groups only keep their ids, so their acl rules still apply.
+func FileRequestUser(owner *model.User, r *model.FileRequest) *model.User {
+	pseudo := *owner
+	pseudo.Role = model.GENERAL
+	pseudo.BasePath = r.Path
+	pseudo.Permission = fileRequestPermission
+	pseudo.Groups = make([]model.Group, len(owner.Groups))
+	for i, g := range owner.Groups {
+		pseudo.Groups[i] = model.Group{ID: g.ID, Name: g.Name}
+	}
+	pseudo.APITokenID = 0
+	pseudo.FileRequestID = r.ID
+	return &pseudo
+}
+
+// CheckFileRequestUpload checks a file named name of size bytes can be uploaded through the file request,
+// the size must be known so the limits can't be bypassed with a chunked request
+func CheckFileRequestUpload(r *model.FileRequest, name string, size int64) error {
+	if size < 0 {
+		return errs.New("the size of the upload must be known")
+	}
+	if !r.AllowType(name) {
+		return errs.Errorf("the type of %s is not allowed, allowed types: %s", name, r.AllowedTypes)
+	}
+	if r.MaxFileSize > 0 && size > r.MaxFileSize {
+		return errs.Errorf("the file is larger than %d bytes", r.MaxFileSize)
+	}
+	if r.Quota > 0 && r.Used+size > r.Quota {
+		return errs.Wrapf(errs.FileRequestQuotaExceeded, "used %d of %d bytes, need %d more", r.Used, r.Quota, size)
+	}
+	return nil
+}
+
+// TakeFileRequestQuota reserves size bytes of the quota of the file request before the upload,
+// it's checked against the stored usage so the concurrent uploads can't exceed the quota
+func TakeFileRequestQuota(r *model.FileRequest, size int64) error {
+	fileRequestCache.Del(r.ID)
+	ok, err := db.TakeFileRequestQuota(r.ID, size)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errs.Wrapf(errs.FileRequestQuotaExceeded, "need %d more bytes", size)
+	}
+	return nil
+}
+
+// ReleaseFileRequestQuota gives back the bytes reserved for a failed upload
+func ReleaseFileRequestQuota(r *model.FileRequest, size int64) {
+	fileRequestCache.Del(r.ID)
+	_ = db.ReleaseFileRequestQuota(r.ID, size)
+}
+
+// FileRequestUploaded counts an upload through the file request, its size is already taken from the quota
+func FileRequestUploaded(r *model.FileRequest) {
+	fileRequestCache.Del(r.ID)
+	_ = db.AddFileRequestUpload(r.ID)
+}
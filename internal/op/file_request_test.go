@@ -0,0 +1,107 @@
+package op_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// TestFileRequestUser checks the pseudo user is jailed to the folder and the limits of the link apply
+func TestFileRequestUser(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "requester", BasePath: "/", Permission: 0xff}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	owner, err := op.GetUserByName("requester")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	r := &model.FileRequest{Path: "/inbox", AllowedTypes: "pdf, .DOCX", MaxFileSize: 100, Quota: 150}
+	if err = op.CreateFileRequest(owner, r); err != nil {
+		t.Fatalf("failed create file request: %+v", err)
+	}
+	r, user, err := op.ValidateFileRequest(r.ID, "")
+	if err != nil {
+		t.Fatalf("failed validate file request: %+v", err)
+	}
+	if user.ID != owner.ID || user.FileRequestID != r.ID || user.GetBasePath() != "/inbox" {
+		t.Errorf("expected a pseudo user of the owner jailed to /inbox, got %+v", user)
+	}
+	if !user.CanWrite() || user.CanRemove() || user.CanSeeHides() {
+		t.Errorf("expected the pseudo user can only upload, got permission %b", user.Permission)
+	}
+
+	if err = op.CheckFileRequestUpload(r, "report.exe", 10); err == nil {
+		t.Errorf("expected the type not allowed to be rejected")
+	}
+	if err = op.CheckFileRequestUpload(r, "report.docx", 101); err == nil {
+		t.Errorf("expected the file too large to be rejected")
+	}
+	if err = op.CheckFileRequestUpload(r, "report.pdf", 100); err != nil {
+		t.Fatalf("expected the upload to be allowed: %+v", err)
+	}
+	if err = op.CheckFileRequestUpload(r, "report.pdf", -1); err == nil {
+		t.Errorf("expected the upload of unknown size to be rejected")
+	}
+	if err = op.TakeFileRequestQuota(r, 100); err != nil {
+		t.Fatalf("failed take the quota: %+v", err)
+	}
+	op.FileRequestUploaded(r)
+	// r is stale, the quota is checked against the stored usage
+	if err = op.TakeFileRequestQuota(r, 60); !errs.Is(err, errs.FileRequestQuotaExceeded) {
+		t.Errorf("expected the stored quota to be exceeded, got %v", err)
+	}
+	r, _, err = op.ValidateFileRequest(r.ID, "")
+	if err != nil {
+		t.Fatalf("failed validate file request: %+v", err)
+	}
+	if err = op.CheckFileRequestUpload(r, "report.pdf", 60); !errs.Is(err, errs.FileRequestQuotaExceeded) {
+		t.Errorf("expected the quota of the link to be exceeded, got %v", err)
+	}
+	if r.Uploads != 1 || r.Used != 100 {
+		t.Errorf("expected 1 upload of 100 bytes, got %d of %d bytes", r.Uploads, r.Used)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if name := r.CandidateName("report.pdf", 2, now); name != "report (2).pdf" {
+		t.Errorf("unexpected renamed name %q", name)
+	}
+	r.RenamePolicy = model.FileRequestRenameTimestamp
+	if name := r.CandidateName("report.pdf", 0, now); name != "report_20260102030405.pdf" {
+		t.Errorf("unexpected renamed name %q", name)
+	}
+}
+
+// TestFileRequestPassword checks only the hash of the password is stored and the mask keeps it
+func TestFileRequestPassword(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "collector", BasePath: "/", Permission: 0xff}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	owner, err := op.GetUserByName("collector")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	r := &model.FileRequest{Path: "/drop", Password: "secret"}
+	if err = op.CreateFileRequest(owner, r); err != nil {
+		t.Fatalf("failed create file request: %+v", err)
+	}
+	stored, err := op.GetFileRequestById(r.ID)
+	if err != nil {
+		t.Fatalf("failed get file request: %+v", err)
+	}
+	if stored.Password != "" || stored.PwdHash == "" {
+		t.Errorf("expected only the hash of the password to be stored, got %+v", stored)
+	}
+	stored.Password = model.SharePasswordMask
+	if err = op.UpdateFileRequest(stored); err != nil {
+		t.Fatalf("failed update file request: %+v", err)
+	}
+	if _, _, err = op.ValidateFileRequest(r.ID, "wrong"); !errs.Is(err, errs.WrongFileRequestPassword) {
+		t.Errorf("expected the wrong password to be rejected, got %v", err)
+	}
+	if _, _, err = op.ValidateFileRequest(r.ID, "secret"); err != nil {
+		t.Errorf("expected the password to be kept, got %v", err)
+	}
+}
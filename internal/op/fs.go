@@ -10,6 +10,7 @@ import (
 	"github.com/OpenListTeam/go-cache"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/dongdio/OpenList/v4/consts"
 	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/utility/errs"
@@ -26,7 +27,7 @@ var listG singleflight.Group[[]model.Obj]
 
 func updateCacheObj(storage driver.Driver, path string, oldObj model.Obj, newObj model.Obj) {
 	key := Key(storage, path)
-	objs, ok := listCache.Get(key)
+	objs, ok := getListCache(storage, key)
 	if ok {
 		for i, obj := range objs {
 			if obj.GetName() == newObj.GetName() {
@@ -40,13 +41,13 @@ func updateCacheObj(storage driver.Driver, path string, oldObj model.Obj, newObj
 				break
 			}
 		}
-		listCache.Set(key, objs, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+		setListCache(storage, key, objs)
 	}
 }
 
 func delCacheObj(storage driver.Driver, path string, obj model.Obj) {
 	key := Key(storage, path)
-	objs, ok := listCache.Get(key)
+	objs, ok := getListCache(storage, key)
 	if ok {
 		for i, oldObj := range objs {
 			if oldObj.GetName() == obj.GetName() {
@@ -54,7 +55,7 @@ func delCacheObj(storage driver.Driver, path string, obj model.Obj) {
 				break
 			}
 		}
-		listCache.Set(key, objs, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+		setListCache(storage, key, objs)
 	}
 }
 
@@ -62,11 +63,12 @@ var addSortDebounceMap generic_sync.MapOf[string, func(func())]
 
 func addCacheObj(storage driver.Driver, path string, newObj model.Obj) {
 	key := Key(storage, path)
-	objs, ok := listCache.Get(key)
+	objs, ok := getListCache(storage, key)
 	if ok {
 		for i, obj := range objs {
 			if obj.GetName() == newObj.GetName() {
 				objs[i] = newObj
+				syncListCache(storage, key, objs)
 				return
 			}
 		}
@@ -84,28 +86,21 @@ func addCacheObj(storage driver.Driver, path string, newObj model.Obj) {
 			debounce(func() {
 				log.Debug("addCacheObj: start sort")
 				model.SortFiles(objs, storage.GetStorage().OrderBy, storage.GetStorage().OrderDirection)
+				syncListCache(storage, key, objs)
 				addSortDebounceMap.Delete(key)
 			})
 		}
 
-		listCache.Set(key, objs, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+		setListCache(storage, key, objs)
 	}
 }
 
 func ClearCache(storage driver.Driver, path string) {
-	objs, ok := listCache.Get(Key(storage, path))
-	if ok {
-		for _, obj := range objs {
-			if obj.IsDir() {
-				ClearCache(storage, stdpath.Join(path, obj.GetName()))
-			}
-		}
-	}
-	listCache.Del(Key(storage, path))
+	clearListCache(Key(storage, path))
 }
 
 func DeleteCache(storage driver.Driver, path string) {
-	listCache.Del(Key(storage, path))
+	delListCache(Key(storage, path))
 }
 
 func Key(storage driver.Driver, path string) string {
@@ -121,7 +116,7 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	log.Debugf("op.List %s", path)
 	key := Key(storage, path)
 	if !args.Refresh {
-		if files, ok := listCache.Get(key); ok {
+		if files, ok := getListCache(storage, key); ok {
 			log.Debugf("use cache when list %s", path)
 			return files, nil
 		}
@@ -161,10 +156,10 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 		if !storage.Config().NoCache {
 			if len(files) > 0 {
 				log.Debugf("set cache: %s => %+v", key, files)
-				listCache.Set(key, files, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+				setListCache(storage, key, files)
 			} else {
 				log.Debugf("del cache: %s", key)
-				listCache.Del(key)
+				delListCache(key)
 			}
 		}
 		return files, nil
@@ -263,7 +258,7 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 	)
 	// use cache directly
 	dir, name := stdpath.Split(stdpath.Join(storage.GetStorage().MountPath, path))
-	if cacheFiles, ok := listCache.Get(strings.TrimSuffix(dir, "/")); ok {
+	if cacheFiles, ok := getListCache(storage, strings.TrimSuffix(dir, "/")); ok {
 		for _, f := range cacheFiles {
 			if f.GetName() == name {
 				file = model.UnwrapObj(f)
@@ -288,7 +283,7 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 	}
 
 	key := stdpath.Join(Key(storage, path), args.Type)
-	if link, ok := linkCache.Get(key); ok {
+	if link, ok := getLinkCache(key); ok {
 		return link, file, nil
 	}
 
@@ -304,7 +299,7 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 			return nil, errLinkMFileCache
 		}
 		if link.Expiration != nil {
-			linkCache.Set(key, link, cache.WithEx[*model.Link](*link.Expiration))
+			setLinkCache(key, link)
 		}
 		link.AddIfCloser(forget)
 		return link, nil
@@ -377,6 +372,9 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string, lazyCache
 		f, err := GetUnwrap(ctx, storage, path)
 		if err != nil {
 			if errs.IsObjectNotFound(err) {
+				if err = checkObjWebDAVLock(ctx, storage, path); err != nil {
+					return nil, err
+				}
 				parentPath, dirName := stdpath.Split(path)
 				err = MakeDir(ctx, storage, parentPath)
 				if err != nil {
@@ -407,6 +405,9 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string, lazyCache
 				default:
 					return nil, errs.NotImplement
 				}
+				if err == nil {
+					publishObjEvent(storage, EventCreated, path, "", &model.Object{Name: dirName, IsFolder: true})
+				}
 				return nil, errs.WithStack(err)
 			}
 			return nil, errs.WithMessage(err, "failed to check if dir exists")
@@ -427,6 +428,12 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	}
 	srcPath = utils.FixAndCleanPath(srcPath)
 	dstDirPath = utils.FixAndCleanPath(dstDirPath)
+	if err := checkObjWebDAVLock(ctx, storage, srcPath); err != nil {
+		return err
+	}
+	if err := checkObjWebDAVLock(ctx, storage, stdpath.Join(dstDirPath, stdpath.Base(srcPath))); err != nil {
+		return err
+	}
 	srcRawObj, err := Get(ctx, storage, srcPath)
 	if err != nil {
 		return errs.WithMessage(err, "failed to get src object")
@@ -461,6 +468,10 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		moveQuotaUsed(ctx, storage, srcPath, stdpath.Join(dstDirPath, srcRawObj.GetName()), srcRawObj.GetSize())
+		publishObjEvent(storage, EventMoved, stdpath.Join(dstDirPath, srcRawObj.GetName()), srcPath, srcRawObj)
+	}
 	return errs.WithStack(err)
 }
 
@@ -469,6 +480,12 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 		return errs.Errorf("storage not init: %s", storage.GetStorage().Status)
 	}
 	srcPath = utils.FixAndCleanPath(srcPath)
+	if err := checkObjWebDAVLock(ctx, storage, srcPath); err != nil {
+		return err
+	}
+	if err := checkObjWebDAVLock(ctx, storage, stdpath.Join(stdpath.Dir(srcPath), dstName)); err != nil {
+		return err
+	}
 	srcRawObj, err := Get(ctx, storage, srcPath)
 	if err != nil {
 		return errs.WithMessage(err, "failed to get src object")
@@ -495,6 +512,9 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		publishObjEvent(storage, EventRenamed, stdpath.Join(srcDirPath, dstName), srcPath, srcRawObj)
+	}
 	return errs.WithStack(err)
 }
 
@@ -505,6 +525,9 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	}
 	srcPath = utils.FixAndCleanPath(srcPath)
 	dstDirPath = utils.FixAndCleanPath(dstDirPath)
+	if err := checkObjWebDAVLock(ctx, storage, stdpath.Join(dstDirPath, stdpath.Base(srcPath))); err != nil {
+		return err
+	}
 	srcObj, err := GetUnwrap(ctx, storage, srcPath)
 	if err != nil {
 		return errs.Wrap(err, "failed to get src object")
@@ -513,11 +536,15 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	if err != nil {
 		return errs.Wrap(err, "failed to get dst dir")
 	}
+	if err = CheckQuota(ctx, storage, srcObj.GetSize()); err != nil {
+		return err
+	}
+	driverCtx := driverQuotaCtx(ctx)
 
 	switch s := storage.(type) {
 	case driver.CopyResult:
 		var newObj model.Obj
-		newObj, err = s.Copy(ctx, srcObj, dstDir)
+		newObj, err = s.Copy(driverCtx, srcObj, dstDir)
 		if err == nil {
 			if newObj != nil {
 				addCacheObj(storage, dstDirPath, model.WrapObjName(newObj))
@@ -526,13 +553,17 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 			}
 		}
 	case driver.Copy:
-		err = s.Copy(ctx, srcObj, dstDir)
+		err = s.Copy(driverCtx, srcObj, dstDir)
 		if err == nil && !utils.IsBool(lazyCache...) {
 			DeleteCache(storage, dstDirPath)
 		}
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		addQuotaUsed(ctx, storage, stdpath.Join(dstDirPath, srcObj.GetName()), srcObj.GetSize())
+		publishObjEvent(storage, EventCopied, stdpath.Join(dstDirPath, srcObj.GetName()), srcPath, srcObj)
+	}
 	return errs.WithStack(err)
 }
 
@@ -544,6 +575,9 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 		return errs.New("delete root folder is not allowed, please goto the manage page to delete the storage instead")
 	}
 	path = utils.FixAndCleanPath(path)
+	if err := checkObjWebDAVLock(ctx, storage, path); err != nil {
+		return err
+	}
 	rawObj, err := Get(ctx, storage, path)
 	if err != nil {
 		// if object not found, it's ok
@@ -557,13 +591,15 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 
 	switch s := storage.(type) {
 	case driver.Remove:
-		err = s.Remove(ctx, model.UnwrapObj(rawObj))
+		err = s.Remove(driverQuotaCtx(ctx), model.UnwrapObj(rawObj))
 		if err == nil {
+			addQuotaUsed(ctx, storage, path, -rawObj.GetSize())
 			delCacheObj(storage, dirPath, rawObj)
 			// clear folder cache recursively
 			if rawObj.IsDir() {
 				ClearCache(storage, path)
 			}
+			publishObjEvent(storage, EventRemoved, path, "", rawObj)
 		}
 	default:
 		return errs.NotImplement
@@ -592,7 +628,18 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	dstPath := stdpath.Join(dstDirPath, file.GetName())
 	tempName := file.GetName() + ".openlist_to_delete"
 	tempPath := stdpath.Join(dstDirPath, tempName)
+	if err := checkObjWebDAVLock(ctx, storage, dstPath); err != nil {
+		return err
+	}
 	fi, err := GetUnwrap(ctx, storage, dstPath)
+	// the size of the obj overwritten in place, it's freed when the upload succeeds
+	var replaced int64
+	if err == nil && fi.GetSize() > 0 && !storage.Config().NoOverwriteUpload {
+		replaced = fi.GetSize()
+	}
+	if err := CheckQuota(ctx, storage, file.GetSize()-replaced); err != nil {
+		return err
+	}
 	if err == nil {
 		if fi.GetSize() == 0 {
 			err = Remove(ctx, storage, dstPath)
@@ -623,26 +670,37 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		up = func(p float64) {}
 	}
 
-	switch s := storage.(type) {
-	case driver.PutResult:
-		var newObj model.Obj
-		newObj, err = s.Put(ctx, parentDir, file, up)
-		if err == nil {
-			if newObj != nil {
-				addCacheObj(storage, dstDirPath, model.WrapObjName(newObj))
-			} else if !utils.IsBool(lazyCache...) {
-				DeleteCache(storage, dstDirPath)
-			}
+	// the checkpoint only belongs to this storage, don't pass it to the storages called by the driver
+	cp, _ := ctx.Value(consts.CheckpointKey).(*model.TransferCheckpoint)
+	if cp != nil {
+		ctx = context.WithValue(ctx, consts.CheckpointKey, nil)
+	}
+	driverCtx := driverQuotaCtx(ctx)
+	var newObj model.Obj
+	if s, ok := storage.(driver.ResumablePut); ok && cp != nil {
+		newObj, err = s.PutResumable(driverCtx, parentDir, file, cp, up)
+	} else {
+		switch s := storage.(type) {
+		case driver.PutResult:
+			newObj, err = s.Put(driverCtx, parentDir, file, up)
+		case driver.Put:
+			err = s.Put(driverCtx, parentDir, file, up)
+		default:
+			return errs.NotImplement
 		}
-	case driver.Put:
-		err = s.Put(ctx, parentDir, file, up)
-		if err == nil && !utils.IsBool(lazyCache...) {
+	}
+	if err == nil {
+		if newObj != nil {
+			addCacheObj(storage, dstDirPath, model.WrapObjName(newObj))
+		} else if !utils.IsBool(lazyCache...) {
 			DeleteCache(storage, dstDirPath)
 		}
-	default:
-		return errs.NotImplement
 	}
 	log.Debugf("put file [%s] done", file.GetName())
+	if err == nil {
+		addQuotaUsed(ctx, storage, dstPath, max(file.GetSize(), 0)-replaced)
+		publishObjEvent(storage, EventUploaded, dstPath, "", file)
+	}
 	if storage.Config().NoOverwriteUpload && fi != nil && fi.GetSize() > 0 {
 		if err != nil {
 			// upload failed, recover old obj
@@ -657,7 +715,7 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 				return err
 			} else {
 				key := Key(storage, stdpath.Join(dstDirPath, file.GetName()))
-				linkCache.Del(key)
+				delLinkCache(key)
 			}
 		}
 	}
@@ -669,6 +727,9 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 		return errs.Errorf("storage not init: %s", storage.GetStorage().Status)
 	}
 	dstDirPath = utils.FixAndCleanPath(dstDirPath)
+	if err := checkObjWebDAVLock(ctx, storage, stdpath.Join(dstDirPath, dstName)); err != nil {
+		return err
+	}
 	_, err := GetUnwrap(ctx, storage, stdpath.Join(dstDirPath, dstName))
 	if err == nil {
 		return errs.New("obj already exists")
@@ -701,5 +762,8 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 		return errs.NotImplement
 	}
 	log.Debugf("put url [%s](%s) done", dstName, url)
+	if err == nil {
+		publishObjEvent(storage, EventUploaded, stdpath.Join(dstDirPath, dstName), "", &model.Object{Name: dstName})
+	}
 	return errs.WithStack(err)
 }
\ No newline at end of file
@@ -0,0 +1,190 @@
+package op
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+func GetGroups(pageIndex, pageSize int) ([]model.Group, int64, error) {
+	return db.GetGroups(pageIndex, pageSize)
+}
+
+func GetGroupById(id uint) (*model.Group, error) {
+	return db.GetGroupByID(id)
+}
+
+func GetGroupByName(name string) (*model.Group, error) {
+	return db.GetGroupByName(name)
+}
+
+func checkGroup(g *model.Group) {
+	if g.BasePath != "" {
+		g.BasePath = utils.FixAndCleanPath(g.BasePath)
+	}
+	for i := range g.Metas {
+		g.Metas[i].Path = utils.FixAndCleanPath(g.Metas[i].Path)
+	}
+}
+
+func CreateGroup(g *model.Group) error {
+	checkGroup(g)
+	return db.CreateGroup(g)
+}
+
+// CreateGroupWithMembers creates the group with the users as its members, either both or none of them are saved
+func CreateGroupWithMembers(g *model.Group, userIDs []uint) error {
+	userIDs, err := checkUserIDs(userIDs)
+	if err != nil {
+		return err
+	}
+	checkGroup(g)
+	if err = db.CreateGroupWithMembers(g, userIDs); err != nil {
+		return err
+	}
+	clearUserCache()
+	return nil
+}
+
+// UpdateGroup updates the group, the cached users are dropped so the members get the change
+func UpdateGroup(g *model.Group) error {
+	if _, err := db.GetGroupByID(g.ID); err != nil {
+		return err
+	}
+	checkGroup(g)
+	if err := db.UpdateGroup(g); err != nil {
+		return err
+	}
+	clearUserCache()
+	return nil
+}
+
+func DeleteGroupById(id uint) error {
+	if err := db.DeleteGroupByID(id); err != nil {
+		return err
+	}
+	clearUserCache()
+	return nil
+}
+
+// checkGroupIDs drops the duplicated ids and makes sure all the groups exist
+func checkGroupIDs(ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	groups, err := db.GetGroupsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) != len(ids) {
+		return nil, errs.New("some of the groups don't exist")
+	}
+	return ids, nil
+}
+
+// GetGroupIDsByExternal returns the groups mapped from the LDAP groups or the SSO group claims
+func GetGroupIDsByExternal(names []string) ([]uint, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	groups, err := db.GetAllGroups()
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for i := range groups {
+		if groups[i].MatchExternal(names) {
+			ids = append(ids, groups[i].ID)
+		}
+	}
+	return ids, nil
+}
+
+// SyncExternalGroups makes the groups of user mapped from external groups follow names, which are the
+// LDAP groups or the SSO group claims of the user at login. The groups without a mapping are managed by
+// the admin and kept. It returns the user with the synced groups.
+func SyncExternalGroups(user *model.User, names []string) (*model.User, error) {
+	groups, err := db.GetAllGroups()
+	if err != nil {
+		return nil, err
+	}
+	current, err := db.GetUserGroupIDs(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for i := range groups {
+		if strings.TrimSpace(groups[i].ExternalGroups) == "" {
+			if slices.Contains(current, groups[i].ID) {
+				ids = append(ids, groups[i].ID)
+			}
+		} else if groups[i].MatchExternal(names) {
+			ids = append(ids, groups[i].ID)
+		}
+	}
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if slices.Equal(ids, slices.Compact(slices.Sorted(slices.Values(current)))) {
+		return user, nil
+	}
+	if err = db.SetUserGroups(user.ID, ids); err != nil {
+		return nil, err
+	}
+	userCache.Del(user.Username)
+	return db.GetUserByID(user.ID)
+}
+
+// GetGroupMembers returns the users in the group
+func GetGroupMembers(groupID uint) ([]model.User, error) {
+	return db.GetGroupMembers(groupID)
+}
+
+// checkUserIDs drops the duplicated ids and makes sure all the users exist
+func checkUserIDs(ids []uint) ([]uint, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	for _, id := range ids {
+		if _, err := db.GetUserByID(id); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// AddGroupMembers adds the users to the group, the cached users are dropped so the members get the change
+func AddGroupMembers(groupID uint, userIDs []uint) error {
+	userIDs, err := checkUserIDs(userIDs)
+	if err != nil {
+		return err
+	}
+	if err = db.AddGroupMembers(groupID, userIDs); err != nil {
+		return err
+	}
+	clearUserCache()
+	return nil
+}
+
+// RemoveGroupMembers removes the users from the group
+func RemoveGroupMembers(groupID uint, userIDs []uint) error {
+	if err := db.RemoveGroupMembers(groupID, userIDs); err != nil {
+		return err
+	}
+	clearUserCache()
+	return nil
+}
+
+// SetGroupMembers replaces the members of the group
+func SetGroupMembers(groupID uint, userIDs []uint) error {
+	userIDs, err := checkUserIDs(userIDs)
+	if err != nil {
+		return err
+	}
+	if err = db.SetGroupMembers(groupID, userIDs); err != nil {
+		return err
+	}
+	clearUserCache()
+	return nil
+}
@@ -0,0 +1,72 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+// TestGroupInheritance checks the members get the permission, the base path and the meta overrides of the groups
+func TestGroupInheritance(t *testing.T) {
+	group := &model.Group{
+		Name:           "editors",
+		Permission:     1 << 3,
+		BasePath:       "/team",
+		Metas:          []model.GroupMeta{{Path: "/team/secret", Sub: true, NoPassword: true}},
+		ExternalGroups: "cn=editors,ou=groups,dc=example,dc=com",
+	}
+	if err := op.CreateGroup(group); err != nil {
+		t.Fatalf("failed create group: %+v", err)
+	}
+	ids, err := op.GetGroupIDsByExternal([]string{"cn=Editors,ou=groups,dc=example,dc=com"})
+	if err != nil || len(ids) != 1 || ids[0] != group.ID {
+		t.Fatalf("expected the ldap group to map to the group, got %v, %v", ids, err)
+	}
+	if err = op.CreateUser(&model.User{Username: "member", BasePath: "/", GroupIDs: ids}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+
+	user, err := op.GetUserByName("member")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	if !user.CanWrite() || user.GetBasePath() != "/team" || !user.GetMetaOverride("/team/secret/a").NoPassword {
+		t.Errorf("expected the user to inherit the group, got %+v", user)
+	}
+
+	group.Permission = 0
+	if err = op.UpdateGroup(group); err != nil {
+		t.Fatalf("failed update group: %+v", err)
+	}
+	if user, err = op.GetUserByName("member"); err != nil || user.CanWrite() {
+		t.Errorf("expected the cached user to see the group change, got %+v, %v", user, err)
+	}
+}
+
+// TestSyncExternalGroups checks the mapped groups follow the external groups at login and the others are kept
+func TestSyncExternalGroups(t *testing.T) {
+	mapped := &model.Group{Name: "sync-mapped", ExternalGroups: "sync-staff"}
+	manual := &model.Group{Name: "sync-manual"}
+	for _, g := range []*model.Group{mapped, manual} {
+		if err := op.CreateGroup(g); err != nil {
+			t.Fatalf("failed create group: %+v", err)
+		}
+	}
+	if err := op.CreateUser(&model.User{Username: "synced", BasePath: "/", GroupIDs: []uint{mapped.ID, manual.ID}}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	user, err := op.GetUserByName("synced")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	if user, err = op.SyncExternalGroups(user, []string{"other"}); err != nil {
+		t.Fatalf("failed sync groups: %+v", err)
+	}
+	if len(user.GroupIDs) != 1 || user.GroupIDs[0] != manual.ID {
+		t.Errorf("expected only the manual group to be kept, got %v", user.GroupIDs)
+	}
+	if user, err = op.GetUserByName("synced"); err != nil || len(user.GroupIDs) != 1 {
+		t.Errorf("expected the cached user to see the sync, got %+v, %v", user, err)
+	}
+}
@@ -162,4 +162,67 @@ func RegisterStorageHook(hook StorageHook) {
 		return
 	}
 	storageHooks = append(storageHooks, hook)
-}
\ No newline at end of file
+}
+
+// ScheduledJobHook is called after a scheduled job is added, updated or deleted, typ is "add", "update" or "del"
+type ScheduledJobHook func(typ string, job *model.ScheduledJob)
+
+var scheduledJobHooks = make([]ScheduledJobHook, 0)
+
+// CallScheduledJobHooks calls all registered scheduled job hooks
+func CallScheduledJobHooks(typ string, job *model.ScheduledJob) {
+	for _, hook := range scheduledJobHooks {
+		hook(typ, job)
+	}
+}
+
+// RegisterScheduledJobHook registers a new hook for scheduled job operations
+func RegisterScheduledJobHook(hook ScheduledJobHook) {
+	if hook == nil {
+		log.Warn("attempted to register nil ScheduledJobHook")
+		return
+	}
+	scheduledJobHooks = append(scheduledJobHooks, hook)
+}
+
+// APITokenHook is called after an api token is added, updated or deleted, typ is "add", "update" or "del"
+type APITokenHook func(typ string, token *model.APIToken)
+
+var apiTokenHooks = make([]APITokenHook, 0)
+
+// CallAPITokenHooks calls all registered api token hooks
+func CallAPITokenHooks(typ string, token *model.APIToken) {
+	for _, hook := range apiTokenHooks {
+		hook(typ, token)
+	}
+}
+
+// RegisterAPITokenHook registers a new hook for api token operations
+func RegisterAPITokenHook(hook APITokenHook) {
+	if hook == nil {
+		log.Warn("attempted to register nil APITokenHook")
+		return
+	}
+	apiTokenHooks = append(apiTokenHooks, hook)
+}
+
+// S3KeyHook is called after an s3 key is added, updated or deleted, typ is "add", "update" or "del"
+type S3KeyHook func(typ string, key *model.S3Key)
+
+var s3KeyHooks = make([]S3KeyHook, 0)
+
+// CallS3KeyHooks calls all registered s3 key hooks
+func CallS3KeyHooks(typ string, key *model.S3Key) {
+	for _, hook := range s3KeyHooks {
+		hook(typ, key)
+	}
+}
+
+// RegisterS3KeyHook registers a new hook for s3 key operations
+func RegisterS3KeyHook(hook S3KeyHook) {
+	if hook == nil {
+		log.Warn("attempted to register nil S3KeyHook")
+		return
+	}
+	s3KeyHooks = append(s3KeyHooks, hook)
+}
@@ -0,0 +1,160 @@
+package op
+
+import (
+	"context"
+	stdpath "path"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// GetUserQuota returns the quota and the bytes used by user
+func GetUserQuota(user *model.User) (model.QuotaInfo, error) {
+	used, err := db.GetQuotaUsed(model.QuotaUser, user.ID)
+	return model.QuotaInfo{Quota: user.Quota, Used: max(used, 0)}, err
+}
+
+// GetStorageQuota returns the quota and the bytes used by storage
+func GetStorageQuota(storage driver.Driver) (model.QuotaInfo, error) {
+	s := storage.GetStorage()
+	used, err := db.GetQuotaUsed(model.QuotaStorage, s.ID)
+	return model.QuotaInfo{Quota: s.Quota, Used: max(used, 0)}, err
+}
+
+// GetQuota returns the tighter of the quotas of the user in ctx and of storage,
+// Quota of the result is 0 if neither has a quota
+func GetQuota(ctx context.Context, storage driver.Driver) (model.QuotaInfo, error) {
+	var res model.QuotaInfo
+	if user := quotaUser(ctx); user != nil && user.Quota > 0 {
+		q, err := GetUserQuota(user)
+		if err != nil {
+			return res, err
+		}
+		res = q
+	}
+	if storage != nil && storage.GetStorage().Quota > 0 {
+		q, err := GetStorageQuota(storage)
+		if err != nil {
+			return res, err
+		}
+		if res.Quota <= 0 || q.Available() < res.Available() {
+			res = q
+		}
+	}
+	return res, nil
+}
+
+// CheckQuota returns errs.QuotaExceeded if writing size more bytes to storage
+// exceeds the quota of the user in ctx or of the storage.
+// size may be 0 if unknown, then it only checks the quota isn't used up.
+func CheckQuota(ctx context.Context, storage driver.Driver, size int64) error {
+	q, err := GetQuota(ctx, storage)
+	if err != nil {
+		return err
+	}
+	if q.Quota <= 0 {
+		return nil
+	}
+	if q.Used+max(size, 0) > q.Quota || (size <= 0 && q.Used >= q.Quota) {
+		return errs.Wrapf(errs.QuotaExceeded, "used %d of %d bytes, need %d more", q.Used, q.Quota, size)
+	}
+	return nil
+}
+
+// The usage of a user is the size of the files under the base path of the user, whoever wrote them,
+// as RecomputeQuota of the fs package sums it. So the writes and the removes are accounted
+// to every user with a quota whose base path contains the path.
+
+// addQuotaUsed accounts delta bytes written to path of storage
+func addQuotaUsed(ctx context.Context, storage driver.Driver, path string, delta int64) {
+	if delta == 0 {
+		return
+	}
+	s := storage.GetStorage()
+	if !quotaAccounted(ctx) {
+		fullPath := stdpath.Join(s.MountPath, path)
+		addUsersQuotaUsed(func(basePath string) int64 {
+			if utils.IsSubPath(basePath, fullPath) {
+				return delta
+			}
+			return 0
+		})
+	}
+	if err := db.AddQuotaUsed(model.QuotaStorage, s.ID, delta); err != nil {
+		log.Warnf("failed update quota usage of storage [%s]: %+v", s.MountPath, err)
+	}
+}
+
+// moveQuotaUsed accounts size bytes moved from srcPath to dstPath of storage,
+// only the users whose base path contains one of them are changed
+func moveQuotaUsed(ctx context.Context, storage driver.Driver, srcPath, dstPath string, size int64) {
+	if size == 0 || quotaAccounted(ctx) {
+		return
+	}
+	mountPath := storage.GetStorage().MountPath
+	srcPath, dstPath = stdpath.Join(mountPath, srcPath), stdpath.Join(mountPath, dstPath)
+	addUsersQuotaUsed(func(basePath string) int64 {
+		inSrc, inDst := utils.IsSubPath(basePath, srcPath), utils.IsSubPath(basePath, dstPath)
+		switch {
+		case inSrc && !inDst:
+			return -size
+		case inDst && !inSrc:
+			return size
+		}
+		return 0
+	})
+}
+
+// addUsersQuotaUsed adds the delta returned by deltaOf the base path of each user having a quota
+func addUsersQuotaUsed(deltaOf func(basePath string) int64) {
+	users, err := db.GetQuotaUsers()
+	if err != nil {
+		log.Warnf("failed get the users having a quota: %+v", err)
+		return
+	}
+	for i := range users {
+		delta := deltaOf(users[i].GetBasePath())
+		if delta == 0 {
+			continue
+		}
+		if err = db.AddQuotaUsed(model.QuotaUser, users[i].ID, delta); err != nil {
+			log.Warnf("failed update quota usage of user [%s]: %+v", users[i].Username, err)
+		}
+	}
+}
+
+// quotaAccounted reports whether the users are already accounted for the writes in ctx,
+// it's true for the writes made by a driver on other storages, see driverQuotaCtx
+func quotaAccounted(ctx context.Context) bool {
+	accounted, _ := ctx.Value(consts.QuotaAccountedKey).(bool)
+	return accounted
+}
+
+// quotaUser returns the user whose quota is checked for the writes, it's nil for the writes made by a driver
+// on other storages, the user is already checked by the storage the driver belongs to
+func quotaUser(ctx context.Context) *model.User {
+	if accounted, _ := ctx.Value(consts.QuotaAccountedKey).(bool); accounted {
+		return nil
+	}
+	user, _ := ctx.Value(consts.UserKey).(*model.User)
+	return user
+}
+
+// driverQuotaCtx is the ctx passed to the driver, see quotaUser
+func driverQuotaCtx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consts.QuotaAccountedKey, true)
+}
+
+func SetUserQuotaUsed(userID uint, used int64) error {
+	return db.SetQuotaUsed(model.QuotaUser, userID, used)
+}
+
+func SetStorageQuotaUsed(storageID uint, used int64) error {
+	return db.SetQuotaUsed(model.QuotaStorage, storageID, used)
+}
@@ -0,0 +1,36 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// TestCheckQuota checks the quota of the user in ctx against the tracked usage
+func TestCheckQuota(t *testing.T) {
+	user := &model.User{ID: 1000, Username: "quota", Quota: 10}
+	ctx := context.WithValue(context.Background(), consts.UserKey, user)
+	if err := op.SetUserQuotaUsed(user.ID, 8); err != nil {
+		t.Fatalf("failed set usage: %+v", err)
+	}
+	if err := op.CheckQuota(ctx, nil, 2); err != nil {
+		t.Errorf("expected 2 bytes to fit, got %+v", err)
+	}
+	if err := op.CheckQuota(ctx, nil, 3); !errs.Is(err, errs.QuotaExceeded) {
+		t.Errorf("expected quota exceeded, got %v", err)
+	}
+	if err := op.SetUserQuotaUsed(user.ID, 10); err != nil {
+		t.Fatalf("failed set usage: %+v", err)
+	}
+	if err := op.CheckQuota(ctx, nil, 0); !errs.Is(err, errs.QuotaExceeded) {
+		t.Errorf("expected a used up quota to reject unknown sizes, got %v", err)
+	}
+	q, err := op.GetUserQuota(user)
+	if err != nil || q.Available() != 0 {
+		t.Errorf("expected nothing available, got %+v, %v", q, err)
+	}
+}
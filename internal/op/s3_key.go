@@ -0,0 +1,216 @@
+package op
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	s3KeyIDLen     = 16
+	s3KeySecretLen = 40
+)
+
+// S3KeySecret returns the secret access key of k.
+// SigV4 needs the secret on the server side, so it's kept encrypted with conf.Conf.EncryptionKey instead of hashed.
+func S3KeySecret(k *model.S3Key) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(k.Secret)
+	if err != nil {
+		return "", errs.Wrapf(err, "failed decode secret of s3 key %s", k.AccessKeyID)
+	}
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errs.Errorf("secret of s3 key %s is truncated", k.AccessKeyID)
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(k.AccessKeyID))
+	if err != nil {
+		return "", errs.Wrapf(err, "failed decrypt secret of s3 key %s, is encryption_key changed?", k.AccessKeyID)
+	}
+	return string(plain), nil
+}
+
+// setS3KeySecret encrypts secret into k, the access key id is authenticated with it
+// so the secret can't be moved to another key in the database
+func setS3KeySecret(k *model.S3Key, secret string) error {
+	gcm, err := secretCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return errs.Wrap(err, "failed generate nonce")
+	}
+	k.Secret = base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), []byte(k.AccessKeyID)))
+	return nil
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	if conf.Conf.EncryptionKey == "" {
+		return nil, errs.New("encryption_key is not configured")
+	}
+	key := sha256.Sum256([]byte(conf.Conf.EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	return gcm, errs.WithStack(err)
+}
+
+// CheckS3KeyEncryption checks the s3 keys in the database can be decrypted with conf.Conf.EncryptionKey,
+// so a node with a missing or different key fails at start rather than rejecting the keys later
+func CheckS3KeyEncryption() error {
+	k, err := db.GetLatestS3Key()
+	if errs.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if conf.Conf.EncryptionKey == "" {
+		return errs.New("encryption_key is not configured but the s3 keys are encrypted with it, set it to the same value as on the other nodes")
+	}
+	_, err = S3KeySecret(k)
+	return err
+}
+
+func checkS3Key(k *model.S3Key) error {
+	if strings.TrimSpace(k.Name) == "" {
+		return errs.New("name of the s3 key is required")
+	}
+	seen := make(map[string]struct{}, len(k.Buckets))
+	for _, b := range k.Buckets {
+		if b.Name == "" {
+			return errs.New("bucket name of the s3 key is required")
+		}
+		if _, ok := seen[b.Name]; ok {
+			return errs.Errorf("bucket %s is listed twice", b.Name)
+		}
+		seen[b.Name] = struct{}{}
+	}
+	return nil
+}
+
+// CreateS3Key creates an s3 key for user and returns the secret access key, it can't be got again later
+func CreateS3Key(user *model.User, k *model.S3Key) (string, error) {
+	if err := checkS3Key(k); err != nil {
+		return "", err
+	}
+	k.ID = 0
+	k.UserID = user.ID
+	k.AccessKeyID = model.S3KeyPrefix + random.String(s3KeyIDLen)
+	k.LastUsedAt = nil
+	k.CreatedAt = time.Now()
+	secret := random.String(s3KeySecretLen)
+	if err := setS3KeySecret(k, secret); err != nil {
+		return "", err
+	}
+	if err := db.CreateS3Key(k); err != nil {
+		return "", err
+	}
+	CallS3KeyHooks("add", k)
+	return secret, nil
+}
+
+// UpdateS3Key updates the name, the buckets and the disabled state of a key, the secret is kept
+func UpdateS3Key(k *model.S3Key) error {
+	old, err := db.GetS3KeyByID(k.ID)
+	if err != nil {
+		return err
+	}
+	if err = checkS3Key(k); err != nil {
+		return err
+	}
+	k.UserID = old.UserID
+	k.AccessKeyID = old.AccessKeyID
+	k.Secret = old.Secret
+	k.LastUsedAt = old.LastUsedAt
+	k.CreatedAt = old.CreatedAt
+	if err = db.UpdateS3Key(k); err != nil {
+		return err
+	}
+	CallS3KeyHooks("update", k)
+	return nil
+}
+
+func GetS3KeysByUserId(userID uint, pageIndex, pageSize int) ([]model.S3Key, int64, error) {
+	return db.GetS3KeysByUserID(userID, pageIndex, pageSize)
+}
+
+func GetEnabledS3Keys() ([]model.S3Key, error) {
+	return db.GetEnabledS3Keys()
+}
+
+// GetS3KeyByIdAndUserId ensures the key belongs to the user
+func GetS3KeyByIdAndUserId(id, userID uint) (*model.S3Key, error) {
+	k, err := db.GetS3KeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if k.UserID != userID {
+		return nil, errs.Errorf("s3 key %d does not belong to user %d", id, userID)
+	}
+	return k, nil
+}
+
+func DeleteS3KeyById(id uint) error {
+	k, err := db.GetS3KeyByID(id)
+	if err != nil {
+		return err
+	}
+	if err = db.DeleteS3KeyByID(id); err != nil {
+		return err
+	}
+	CallS3KeyHooks("del", k)
+	return nil
+}
+
+func DeleteS3KeysByUserId(userID uint) error {
+	keys, err := db.GetS3KeysOfUser(userID)
+	if err != nil {
+		return err
+	}
+	if err = db.DeleteS3KeysByUserID(userID); err != nil {
+		return err
+	}
+	for i := range keys {
+		CallS3KeyHooks("del", &keys[i])
+	}
+	return nil
+}
+
+// ValidateS3Key returns the key with the access key id and its owner,
+// the signature made with the secret is verified by the S3 server
+func ValidateS3Key(accessKeyID string) (*model.User, *model.S3Key, error) {
+	k, err := db.GetS3KeyByAccessKeyID(accessKeyID)
+	if err != nil || k.Disabled {
+		return nil, nil, errs.WithStack(errs.InvalidS3Key)
+	}
+	user, err := GetUserById(k.UserID)
+	if err != nil {
+		return nil, nil, errs.WithStack(errs.InvalidS3Key)
+	}
+	if user.Disabled {
+		return nil, nil, errs.New("the owner of the s3 key is disabled")
+	}
+	if now := time.Now(); k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > apiTokenTouchInterval {
+		k.LastUsedAt = &now
+		_ = db.UpdateS3KeyLastUsed(k.ID, now)
+	}
+	return user, k, nil
+}
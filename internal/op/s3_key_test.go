@@ -0,0 +1,54 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+// TestS3KeySecretSurvivesJwtRotation checks the secret of an s3 key is kept encrypted rather than derived from the jwt secret
+func TestS3KeySecretSurvivesJwtRotation(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "s3keyer", BasePath: "/"}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	user, err := op.GetUserByName("s3keyer")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	encryptionKey := conf.Conf.EncryptionKey
+	conf.Conf.EncryptionKey = "encryption"
+	defer func() { conf.Conf.EncryptionKey = encryptionKey }()
+	k := &model.S3Key{Name: "backup"}
+	secret, err := op.CreateS3Key(user, k)
+	if err != nil {
+		t.Fatalf("failed create s3 key: %+v", err)
+	}
+	jwtSecret := conf.Conf.JwtSecret
+	conf.Conf.JwtSecret = "rotated"
+	defer func() { conf.Conf.JwtSecret = jwtSecret }()
+
+	stored, err := op.GetS3KeyByIdAndUserId(k.ID, user.ID)
+	if err != nil {
+		t.Fatalf("failed get s3 key: %+v", err)
+	}
+	if stored.Secret == "" || stored.Secret == secret {
+		t.Errorf("expected the secret to be stored encrypted, got %q", stored.Secret)
+	}
+	if got, err := op.S3KeySecret(stored); err != nil || got != secret {
+		t.Errorf("expected secret %q after rotating the jwt secret, got %q, %v", secret, got, err)
+	}
+	if err = op.CheckS3KeyEncryption(); err != nil {
+		t.Errorf("expected the s3 keys to be decrypted: %+v", err)
+	}
+
+	conf.Conf.EncryptionKey = "another"
+	if err = op.CheckS3KeyEncryption(); err == nil {
+		t.Errorf("expected a different encryption key to be rejected")
+	}
+	conf.Conf.EncryptionKey = ""
+	if err = op.CheckS3KeyEncryption(); err == nil {
+		t.Errorf("expected a missing encryption key to be rejected")
+	}
+}
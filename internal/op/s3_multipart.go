@@ -0,0 +1,45 @@
+package op
+
+import (
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+)
+
+func CreateS3MultipartUpload(u *model.S3MultipartUpload) error {
+	return db.CreateS3MultipartUpload(u)
+}
+
+// GetS3MultipartUploads returns the uploads in progress staged on the node with their parts by the upload ids
+func GetS3MultipartUploads(node string) ([]model.S3MultipartUpload, map[string][]model.S3MultipartPart, error) {
+	uploads, err := db.GetS3MultipartUploads(node)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts := make(map[string][]model.S3MultipartPart, len(uploads))
+	for _, u := range uploads {
+		if parts[u.ID], err = db.GetS3MultipartParts(u.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return uploads, parts, nil
+}
+
+func GetS3MultipartUpload(id string) (*model.S3MultipartUpload, error) {
+	return db.GetS3MultipartUploadByID(id)
+}
+
+func SaveS3MultipartPart(p *model.S3MultipartPart) error {
+	return db.SaveS3MultipartPart(p)
+}
+
+func DeleteS3MultipartUpload(id string) error {
+	return db.DeleteS3MultipartUpload(id)
+}
+
+// DeleteIdleS3MultipartUploads drops the records of the uploads staged on the other nodes than node
+// which are idle since before, e.g. the node is gone
+func DeleteIdleS3MultipartUploads(node string, before time.Time) error {
+	return db.DeleteIdleS3MultipartUploads(node, before)
+}
@@ -0,0 +1,75 @@
+package op
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// GetS3Object returns the record of the object at path, nil if there is none
+func GetS3Object(path string) (*model.S3Object, error) {
+	return db.GetS3Object(utils.FixAndCleanPath(path))
+}
+
+// GetS3Objects returns the records of the objects at paths by their paths
+func GetS3Objects(paths []string) (map[string]*model.S3Object, error) {
+	for i := range paths {
+		paths[i] = utils.FixAndCleanPath(paths[i])
+	}
+	objects, err := db.GetS3Objects(paths)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*model.S3Object, len(objects))
+	for i := range objects {
+		res[objects[i].Path] = &objects[i]
+	}
+	return res, nil
+}
+
+// SaveS3Object replaces the record of the object at o.Path
+func SaveS3Object(o *model.S3Object) error {
+	o.Path = utils.FixAndCleanPath(o.Path)
+	return db.SaveS3Object(o)
+}
+
+// SetS3ObjectMD5 records the MD5 of the object at path computed for its size and modified time, the metadata is kept
+func SetS3ObjectMD5(path, md5 string, size int64, modified time.Time) error {
+	o, err := GetS3Object(path)
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		o = &model.S3Object{Path: path}
+	}
+	o.MD5 = md5
+	o.Size = size
+	o.Modified = modified
+	return SaveS3Object(o)
+}
+
+// FollowS3Objects keeps the S3 metadata and MD5 with their objects when they are renamed, moved,
+// copied or removed through any protocol, and drops them when an object is uploaded again.
+// It should be called once when the server starts.
+func FollowS3Objects() {
+	SubscribeEvents(func(e Event) {
+		var err error
+		switch e.Type {
+		case EventRenamed, EventMoved:
+			err = db.MoveS3Objects(e.SrcPath, e.Path)
+		case EventCopied:
+			err = db.CopyS3Objects(e.SrcPath, e.Path)
+		case EventRemoved, EventUploaded:
+			err = db.DeleteS3Objects(e.Path)
+		default:
+			return
+		}
+		if err != nil {
+			log.Errorf("failed %s s3 objects of %s: %+v", e.Type, e.Path, err)
+		}
+	})
+}
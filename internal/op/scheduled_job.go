@@ -0,0 +1,130 @@
+package op
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// ScheduledJobArgsChecker validates the json args of a scheduled job type
+type ScheduledJobArgsChecker func(args string) error
+
+var (
+	scheduledJobTypes   = make(map[string]ScheduledJobArgsChecker)
+	scheduledJobTypesMu sync.RWMutex
+)
+
+// RegisterScheduledJobType makes typ available to the scheduled jobs
+func RegisterScheduledJobType(typ string, check ScheduledJobArgsChecker) {
+	scheduledJobTypesMu.Lock()
+	defer scheduledJobTypesMu.Unlock()
+	scheduledJobTypes[typ] = check
+}
+
+// GetScheduledJobTypes returns the registered job types
+func GetScheduledJobTypes() []string {
+	scheduledJobTypesMu.RLock()
+	defer scheduledJobTypesMu.RUnlock()
+	types := make([]string, 0, len(scheduledJobTypes))
+	for typ := range scheduledJobTypes {
+		types = append(types, typ)
+	}
+	return types
+}
+
+func checkScheduledJob(j *model.ScheduledJob) error {
+	j.Cron = strings.TrimSpace(j.Cron)
+	if _, err := cron.ParseStandard(j.Cron); err != nil {
+		return errs.Wrapf(err, "invalid cron spec [%s]", j.Cron)
+	}
+	scheduledJobTypesMu.RLock()
+	check, ok := scheduledJobTypes[j.Type]
+	scheduledJobTypesMu.RUnlock()
+	if !ok {
+		return errs.Errorf("unknown scheduled job type: %s", j.Type)
+	}
+	if strings.TrimSpace(j.Args) == "" {
+		j.Args = "{}"
+	}
+	if check != nil {
+		if err := check(j.Args); err != nil {
+			return errs.WithMessagef(err, "invalid args of %s job", j.Type)
+		}
+	}
+	return nil
+}
+
+func GetScheduledJobs(pageIndex, pageSize int) ([]model.ScheduledJob, int64, error) {
+	return db.GetScheduledJobs(pageIndex, pageSize)
+}
+
+func GetEnabledScheduledJobs() ([]model.ScheduledJob, error) {
+	return db.GetEnabledScheduledJobs()
+}
+
+func GetScheduledJobById(id uint) (*model.ScheduledJob, error) {
+	return db.GetScheduledJobByID(id)
+}
+
+func CreateScheduledJob(j *model.ScheduledJob) error {
+	if err := checkScheduledJob(j); err != nil {
+		return err
+	}
+	if err := db.CreateScheduledJob(j); err != nil {
+		return err
+	}
+	CallScheduledJobHooks("add", j)
+	return nil
+}
+
+func UpdateScheduledJob(j *model.ScheduledJob) error {
+	if err := checkScheduledJob(j); err != nil {
+		return err
+	}
+	old, err := db.GetScheduledJobByID(j.ID)
+	if err != nil {
+		return err
+	}
+	// the result of the last run is kept by the scheduler, not by the admin
+	j.LastRunAt, j.LastError = old.LastRunAt, old.LastError
+	if err = db.UpdateScheduledJob(j); err != nil {
+		return err
+	}
+	CallScheduledJobHooks("update", j)
+	return nil
+}
+
+// SetScheduledJobDisabled enables or disables a job, the scheduler picks the change up at once
+func SetScheduledJobDisabled(id uint, disabled bool) error {
+	j, err := db.GetScheduledJobByID(id)
+	if err != nil {
+		return err
+	}
+	j.Disabled = disabled
+	if err = db.UpdateScheduledJob(j); err != nil {
+		return err
+	}
+	CallScheduledJobHooks("update", j)
+	return nil
+}
+
+func DeleteScheduledJobById(id uint) error {
+	j, err := db.GetScheduledJobByID(id)
+	if err != nil {
+		return err
+	}
+	if err = db.DeleteScheduledJobByID(id); err != nil {
+		return err
+	}
+	CallScheduledJobHooks("del", j)
+	return nil
+}
+
+func GetScheduledJobRuns(jobID uint, pageIndex, pageSize int) ([]model.ScheduledJobRun, int64, error) {
+	return db.GetScheduledJobRuns(jobID, pageIndex, pageSize)
+}
@@ -0,0 +1,238 @@
+package op
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/go-cache"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	sessionIDLen      = 32
+	refreshTokenLen   = 48
+	defaultRefreshTTL = 720 * time.Hour
+	// sessionTouchInterval limits how often the last seen time is written
+	sessionTouchInterval = time.Minute
+	// sessionCacheEx bounds how long a revoked session keeps working on a node
+	// which misses the invalidation, e.g. when no shared cache backend is configured
+	sessionCacheEx = 30 * time.Second
+)
+
+var sessionCache = cache.NewMemCache(cache.WithShards[*model.Session](2))
+
+// sessionTouched keeps the last seen time and ip written by this node for each session id,
+// the cached sessions are shared by the requests so they are never changed
+var sessionTouched sync.Map
+
+type sessionTouch struct {
+	at time.Time
+	ip string
+}
+
+// delSessionCache drops the cached session here and on the other nodes
+func delSessionCache(ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		sessionCache.Del(id)
+		sessionTouched.Delete(id)
+		keys = append(keys, sessionKeyPrefix+id)
+	}
+	publishInvalidation(false, keys...)
+}
+
+func hashRefreshToken(raw string) string {
+	return utils.HashData(utils.SHA256, []byte(raw))
+}
+
+func refreshTTL() time.Duration {
+	if conf.Conf.RefreshTokenExpiresIn <= 0 {
+		return defaultRefreshTTL
+	}
+	return time.Duration(conf.Conf.RefreshTokenExpiresIn) * time.Hour
+}
+
+// CreateSession starts a session for the user logging in from ip with userAgent,
+// and returns it with its refresh token, the token can't be got again later
+func CreateSession(user *model.User, ip, userAgent string) (*model.Session, string, error) {
+	now := time.Now()
+	raw := random.String(refreshTokenLen)
+	s := &model.Session{
+		ID:          random.String(sessionIDLen),
+		UserID:      user.ID,
+		PwdTS:       user.PwdTS,
+		RefreshHash: hashRefreshToken(raw),
+		Device:      describeDevice(userAgent),
+		IP:          ip,
+		UserAgent:   userAgent,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(refreshTTL()),
+	}
+	// the expired sessions are only dropped here, they can't be used anyway
+	_ = db.DeleteExpiredSessions()
+	if err := db.CreateSession(s); err != nil {
+		return nil, "", err
+	}
+	return s, raw, nil
+}
+
+// GetValidSession returns the session with id if it's neither revoked nor expired
+func GetValidSession(id string) (*model.Session, error) {
+	if id == "" {
+		return nil, errs.WithStack(errs.InvalidSession)
+	}
+	s, ok := sessionCache.Get(id)
+	if !ok {
+		var err error
+		s, err = db.GetSessionByID(id)
+		if err != nil {
+			return nil, errs.WithStack(errs.InvalidSession)
+		}
+		sessionCache.Set(id, s, cache.WithEx[*model.Session](sessionCacheEx))
+	}
+	if s.Expired() {
+		return nil, errs.WithStack(errs.InvalidSession)
+	}
+	return s, nil
+}
+
+// TouchSession records the session is seen from ip, at most once a sessionTouchInterval
+func TouchSession(s *model.Session, ip string) {
+	last := sessionTouch{at: s.LastSeenAt, ip: s.IP}
+	if v, ok := sessionTouched.Load(s.ID); ok {
+		last = v.(sessionTouch)
+	}
+	if now := time.Now(); now.Sub(last.at) > sessionTouchInterval || last.ip != ip {
+		sessionTouched.Store(s.ID, sessionTouch{at: now, ip: ip})
+		_ = db.UpdateSessionLastSeen(s.ID, now, ip)
+	}
+}
+
+// RefreshSession checks the refresh token and rotates it, the old token can't be used again.
+// A rotated token presented again, or two refreshes racing with the same token, means the token
+// is stolen, so the session is revoked for both the thief and the owner.
+// It returns the session, the owner and the new refresh token.
+func RefreshSession(raw, ip, userAgent string) (*model.Session, *model.User, string, error) {
+	hash := hashRefreshToken(raw)
+	s, err := db.GetSessionByRefreshHash(hash)
+	if err != nil {
+		if s, err = db.GetSessionByPrevRefreshHash(hash); err == nil {
+			log.Warnf("the rotated refresh token of session %s of user %d is reused, the session is revoked", s.ID, s.UserID)
+			_ = DeleteSessionById(s.ID)
+		}
+		return nil, nil, "", errs.WithStack(errs.InvalidSession)
+	}
+	if s.Expired() {
+		_ = DeleteSessionById(s.ID)
+		return nil, nil, "", errs.WithStack(errs.InvalidSession)
+	}
+	user, err := GetUserById(s.UserID)
+	if err != nil {
+		return nil, nil, "", errs.WithStack(errs.InvalidSession)
+	}
+	if user.Disabled {
+		return nil, nil, "", errs.New("the user is disabled")
+	}
+	if user.PwdTS != s.PwdTS {
+		// the password is changed since the login
+		_ = DeleteSessionById(s.ID)
+		return nil, nil, "", errs.WithStack(errs.InvalidSession)
+	}
+	now := time.Now()
+	newRaw := random.String(refreshTokenLen)
+	s.RefreshHash = hashRefreshToken(newRaw)
+	s.IP, s.UserAgent, s.Device = ip, userAgent, describeDevice(userAgent)
+	s.LastSeenAt, s.ExpiresAt = now, now.Add(refreshTTL())
+	rotated, err := db.RotateSessionRefresh(s, hash)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !rotated {
+		log.Warnf("the refresh token of session %s of user %d is used twice at once, the session is revoked", s.ID, s.UserID)
+		_ = DeleteSessionById(s.ID)
+		return nil, nil, "", errs.WithStack(errs.InvalidSession)
+	}
+	s.PrevRefreshHash = hash
+	delSessionCache(s.ID)
+	return s, user, newRaw, nil
+}
+
+func GetSessionsByUserId(userID uint) ([]model.Session, error) {
+	return db.GetSessionsByUserID(userID)
+}
+
+// DeleteSessionById revokes the session, the jwt issued to it stops working at once
+func DeleteSessionById(id string) error {
+	err := db.DeleteSessionByID(id)
+	delSessionCache(id)
+	return err
+}
+
+// DeleteSessionByIdAndUserId ensures the session belongs to the user before revoking it
+func DeleteSessionByIdAndUserId(id string, userID uint) error {
+	s, err := db.GetSessionByID(id)
+	if err != nil {
+		return err
+	}
+	if s.UserID != userID {
+		return errs.Errorf("session %s does not belong to user %d", id, userID)
+	}
+	return DeleteSessionById(id)
+}
+
+// DeleteSessionsByUserId revokes all the sessions of the user
+func DeleteSessionsByUserId(userID uint) error {
+	ids, err := db.DeleteSessionsByUserID(userID)
+	delSessionCache(ids...)
+	return err
+}
+
+var (
+	deviceOSs = []struct{ key, name string }{
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"Macintosh", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	}
+	// the order matters, Chrome user agents contain Safari and Edge ones contain Chrome
+	deviceBrowsers = []struct{ key, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"Safari/", "Safari"}, {"curl/", "curl"}, {"okhttp/", "okhttp"},
+	}
+)
+
+// describeDevice makes a short readable description like "Chrome on Windows" from a user agent
+func describeDevice(userAgent string) string {
+	var os, browser string
+	for _, o := range deviceOSs {
+		if strings.Contains(userAgent, o.key) {
+			os = o.name
+			break
+		}
+	}
+	for _, b := range deviceBrowsers {
+		if strings.Contains(userAgent, b.key) {
+			browser = b.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	return "Unknown"
+}
@@ -0,0 +1,58 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+// TestSessionRefreshAndRevoke checks the refresh token is rotated, reusing a rotated token
+// revokes the session and revoking the sessions ends them
+func TestSessionRefreshAndRevoke(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "session", BasePath: "/"}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	user, err := op.GetUserByName("session")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	s, refresh, err := op.CreateSession(user, "127.0.0.1", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/138.0.0.0 Safari/537.36")
+	if err != nil {
+		t.Fatalf("failed create session: %+v", err)
+	}
+	if s.Device != "Chrome on Windows" {
+		t.Errorf("expected the device to be described, got %q", s.Device)
+	}
+
+	_, _, newRefresh, err := op.RefreshSession(refresh, "127.0.0.2", "curl/8.0")
+	if err != nil {
+		t.Fatalf("failed refresh session: %+v", err)
+	}
+	if _, err = op.GetValidSession(s.ID); err != nil {
+		t.Fatalf("expected the session to be valid: %+v", err)
+	}
+	if _, _, _, err = op.RefreshSession(refresh, "127.0.0.2", "curl/8.0"); err == nil {
+		t.Errorf("expected the rotated refresh token to be rejected")
+	}
+	if _, err = op.GetValidSession(s.ID); err == nil {
+		t.Errorf("expected the session to be revoked when the rotated refresh token is reused")
+	}
+	if _, _, _, err = op.RefreshSession(newRefresh, "127.0.0.2", "curl/8.0"); err == nil {
+		t.Errorf("expected the refresh token of the revoked session to be rejected")
+	}
+
+	s, newRefresh, err = op.CreateSession(user, "127.0.0.1", "curl/8.0")
+	if err != nil {
+		t.Fatalf("failed create session: %+v", err)
+	}
+	if err = op.DeleteSessionsByUserId(user.ID); err != nil {
+		t.Fatalf("failed revoke sessions: %+v", err)
+	}
+	if _, err = op.GetValidSession(s.ID); err == nil {
+		t.Errorf("expected the revoked session to be invalid")
+	}
+	if _, _, _, err = op.RefreshSession(newRefresh, "127.0.0.2", "curl/8.0"); err == nil {
+		t.Errorf("expected the revoked session not to be refreshed")
+	}
+}
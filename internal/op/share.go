@@ -0,0 +1,216 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/go-cache"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/cache_backend"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	shareIDLen = 12
+	// shareDownloadWindow is how long the requests of a client for a file of a share count as one download
+	shareDownloadWindow = 6 * time.Hour
+)
+
+var (
+	shareCache = cache.NewMemCache(cache.WithShards[*model.Share](2))
+	// shareDownloads remembers the downloads counted in shareDownloadWindow when there is no cache backend
+	shareDownloads = cache.NewMemCache(cache.WithShards[struct{}](16))
+)
+
+// delShareCache drops the shares with ids from the cache of this node and of the other nodes
+func delShareCache(ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		shareCache.Del(id)
+		keys = append(keys, shareKeyPrefix+id)
+	}
+	publishInvalidation(false, keys...)
+}
+
+func checkShare(owner *model.User, s *model.Share) error {
+	if !s.AllowPreview && !s.AllowDownload && !s.AllowUpload {
+		return errs.New("the share must allow preview, download or upload")
+	}
+	if s.AllowUpload {
+		if !s.IsDir {
+			return errs.New("only a folder share can allow upload")
+		}
+		if !owner.CanWrite() {
+			return errs.WithStack(errs.PermissionDenied)
+		}
+	}
+	if s.MaxDownloads < 0 {
+		s.MaxDownloads = 0
+	}
+	return nil
+}
+
+// CreateShare shares the file or folder at s.Path, which must be an absolute path under the base path of owner
+func CreateShare(owner *model.User, s *model.Share) error {
+	s.Path = utils.FixAndCleanPath(s.Path)
+	if !utils.IsSubPath(owner.GetBasePath(), s.Path) {
+		return errs.WithStack(errs.PermissionDenied)
+	}
+	if err := checkShare(owner, s); err != nil {
+		return err
+	}
+	s.ID = random.String(shareIDLen)
+	s.UserID = owner.ID
+	s.SetPassword(s.Password)
+	s.Views, s.Downloads, s.Uploads, s.LastAccessAt = 0, 0, 0, nil
+	s.CreatedAt = time.Now()
+	return db.CreateShare(s)
+}
+
+// UpdateShare updates the password, expiry, limits and permissions of a share,
+// the shared path, the owner and the statistics are kept, so is the password if it's the mask
+func UpdateShare(s *model.Share) error {
+	old, err := db.GetShareByID(s.ID)
+	if err != nil {
+		return err
+	}
+	owner, err := GetUserById(old.UserID)
+	if err != nil {
+		return err
+	}
+	s.UserID, s.Path, s.IsDir = old.UserID, old.Path, old.IsDir
+	if err = checkShare(owner, s); err != nil {
+		return err
+	}
+	if s.Password == model.SharePasswordMask {
+		s.PwdHash, s.Salt, s.Password = old.PwdHash, old.Salt, ""
+	} else {
+		s.SetPassword(s.Password)
+	}
+	s.Views, s.Downloads, s.Uploads, s.LastAccessAt, s.CreatedAt = old.Views, old.Downloads, old.Uploads, old.LastAccessAt, old.CreatedAt
+	if err = db.UpdateShare(s); err != nil {
+		return err
+	}
+	delShareCache(s.ID)
+	return nil
+}
+
+// GetShares returns the shares of the user, or of all the users if userID is 0
+func GetShares(userID uint, pageIndex, pageSize int) ([]model.Share, int64, error) {
+	return db.GetShares(userID, pageIndex, pageSize)
+}
+
+func GetShareById(id string) (*model.Share, error) {
+	return db.GetShareByID(id)
+}
+
+// GetShareByIdAndUserId ensures the share belongs to the user
+func GetShareByIdAndUserId(id string, userID uint) (*model.Share, error) {
+	s, err := db.GetShareByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.UserID != userID {
+		return nil, errs.Errorf("share %s does not belong to user %d", id, userID)
+	}
+	return s, nil
+}
+
+func DeleteShareById(id string) error {
+	if err := db.DeleteShareByID(id); err != nil {
+		return err
+	}
+	delShareCache(id)
+	return nil
+}
+
+func DeleteSharesByUserId(userID uint) error {
+	ids, err := db.DeleteSharesByUserID(userID)
+	delShareCache(ids...)
+	return err
+}
+
+// ValidateShare returns the share with id and its owner if the share can be visited with password
+func ValidateShare(id, password string) (*model.Share, *model.User, error) {
+	s, ok := shareCache.Get(id)
+	if !ok {
+		var err error
+		s, err = db.GetShareByID(id)
+		if err != nil {
+			return nil, nil, errs.WithStack(errs.ShareNotFound)
+		}
+		shareCache.Set(id, s, cache.WithEx[*model.Share](time.Hour))
+	}
+	if s.Disabled {
+		return nil, nil, errs.WithStack(errs.ShareNotFound)
+	}
+	if s.Expired() {
+		return nil, nil, errs.WithStack(errs.ShareExpired)
+	}
+	owner, err := GetUserById(s.UserID)
+	if err != nil || owner.Disabled || !utils.IsSubPath(owner.GetBasePath(), s.Path) {
+		// the owner can't reach the shared path any more
+		return nil, nil, errs.WithStack(errs.ShareNotFound)
+	}
+	if !s.CheckPassword(password) {
+		return nil, nil, errs.WithStack(errs.WrongSharePassword)
+	}
+	return s, owner, nil
+}
+
+// ShareViewed counts a visit of the share
+func ShareViewed(s *model.Share) {
+	_ = db.IncreaseShareStat(s.ID, "views")
+}
+
+// ShareUploaded counts an upload to the share
+func ShareUploaded(s *model.Share) {
+	_ = db.IncreaseShareStat(s.ID, "uploads")
+}
+
+// TakeShareDownload counts a download of the file at path of the share by client, or returns
+// errs.ShareDownloadsExhausted if the share reaches its max downloads.
+// The downloads of a file by a client in shareDownloadWindow count once.
+func TakeShareDownload(s *model.Share, path, client string) error {
+	key := shareDownloadKeyPrefix + s.ID + "\x00" + path + "\x00" + client
+	if shareDownloadCounted(key) {
+		return nil
+	}
+	ok, err := db.TakeShareDownload(s.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errs.WithStack(errs.ShareDownloadsExhausted)
+	}
+	shareDownloads.Set(key, struct{}{}, cache.WithEx[struct{}](shareDownloadWindow))
+	if cache_backend.Enabled() {
+		if err = cache_backend.Get().Set(key, []byte{1}, shareDownloadWindow); err != nil {
+			log.Warnf("failed save share download to backend: %+v", err)
+		}
+	}
+	return nil
+}
+
+// shareDownloadCounted reports whether the download with key is counted already,
+// the downloads are shared by the nodes through the cache backend
+func shareDownloadCounted(key string) bool {
+	if _, ok := shareDownloads.Get(key); ok {
+		return true
+	}
+	if !cache_backend.Enabled() {
+		return false
+	}
+	_, ok, err := cache_backend.Get().Get(key)
+	if err != nil {
+		log.Warnf("failed get share download from backend: %+v", err)
+	}
+	return ok
+}
@@ -0,0 +1,63 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// TestShareValidateAndDownloadLimit checks the password and the max downloads of a share
+func TestShareValidateAndDownloadLimit(t *testing.T) {
+	if err := op.CreateUser(&model.User{Username: "sharer", BasePath: "/"}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	user, err := op.GetUserByName("sharer")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	s := &model.Share{Path: "/docs/report.pdf", Password: "secret", MaxDownloads: 1, AllowDownload: true}
+	if err = op.CreateShare(user, s); err != nil {
+		t.Fatalf("failed create share: %+v", err)
+	}
+	stored, err := op.GetShareById(s.ID)
+	if err != nil {
+		t.Fatalf("failed get share: %+v", err)
+	}
+	if stored.Password != "" || stored.PwdHash == "" {
+		t.Errorf("expected only the hash of the password to be stored, got %+v", stored)
+	}
+	// the masked password from the responses keeps the password
+	stored.Password = model.SharePasswordMask
+	if err = op.UpdateShare(stored); err != nil {
+		t.Fatalf("failed update share: %+v", err)
+	}
+	if _, _, err = op.ValidateShare(s.ID, "wrong"); !errs.Is(err, errs.WrongSharePassword) {
+		t.Errorf("expected the wrong password to be rejected, got %v", err)
+	}
+	s, owner, err := op.ValidateShare(s.ID, "secret")
+	if err != nil {
+		t.Fatalf("failed validate share: %+v", err)
+	}
+	if owner.ID != user.ID {
+		t.Errorf("expected the owner to be %d, got %d", user.ID, owner.ID)
+	}
+	if err = op.TakeShareDownload(s, s.Path, "10.0.0.1"); err != nil {
+		t.Fatalf("failed take the first download: %+v", err)
+	}
+	// the range requests of the same client are the same download
+	if err = op.TakeShareDownload(s, s.Path, "10.0.0.1"); err != nil {
+		t.Errorf("expected the same client to resume the download, got %v", err)
+	}
+	if err = op.TakeShareDownload(s, s.Path, "10.0.0.2"); !errs.Is(err, errs.ShareDownloadsExhausted) {
+		t.Errorf("expected the second download to exceed the limit, got %v", err)
+	}
+
+	if err = op.DeleteShareById(s.ID); err != nil {
+		t.Fatalf("failed delete share: %+v", err)
+	}
+	if _, _, err = op.ValidateShare(s.ID, "secret"); !errs.Is(err, errs.ShareNotFound) {
+		t.Errorf("expected the deleted share to be gone, got %v", err)
+	}
+}
@@ -276,6 +276,9 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 	if err = db.DeleteStorageByID(id); err != nil {
 		return errs.WithMessage(err, "failed delete storage in database")
 	}
+	if err = db.DeleteTrashItemsByStorageID(id); err != nil {
+		log.Warnf("failed delete trash items of storage %d: %+v", id, err)
+	}
 	return nil
 }
 
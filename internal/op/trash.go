@@ -0,0 +1,216 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// trashRenamePrefix prefixes the objs renamed in place by the drivers which can't move
+const trashRenamePrefix = consts.TrashDir + "-"
+
+// TrashEnabled reports whether the removals of storage go to its trash,
+// the driver must be able to move or rename
+func TrashEnabled(storage driver.Driver) bool {
+	if !storage.GetStorage().EnableTrash {
+		return false
+	}
+	caps := driver.GetCapabilities(storage)
+	return caps.Move || caps.Rename
+}
+
+// InTrash reports whether path, relative to the root of storage, is in the trash.
+// The objs renamed in place must be recorded in the trash, the user files may have the same prefix.
+func InTrash(storage driver.Driver, path string) bool {
+	path = utils.FixAndCleanPath(path)
+	root := "/" + consts.TrashDir
+	if path == root || utils.IsSubPath(root, path) {
+		return true
+	}
+	for p := path; p != "/"; p = stdpath.Dir(p) {
+		if isRenamedTrashObj(storage, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrashObj reports whether name in dirPath is the trash folder or an obj renamed into the trash
+func IsTrashObj(storage driver.Driver, dirPath, name string) bool {
+	return (utils.PathEqual(dirPath, "/") && name == consts.TrashDir) ||
+		isRenamedTrashObj(storage, stdpath.Join(utils.FixAndCleanPath(dirPath), name))
+}
+
+// isRenamedTrashObj reports whether the obj at path is the TrashPath of an item of storage
+func isRenamedTrashObj(storage driver.Driver, path string) bool {
+	rest, ok := strings.CutPrefix(stdpath.Base(path), trashRenamePrefix)
+	if !ok {
+		return false
+	}
+	idStr, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return false
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return false
+	}
+	item, err := db.GetTrashItemByID(uint(id))
+	return err == nil && item.StorageID == storage.GetStorage().ID && item.TrashPath == path
+}
+
+// RemoveToTrash moves the obj at path into the trash of storage and records where it was.
+// The obj is moved to /.trash/<id>/<name>, or renamed in place to .trash-<id>-<name> if the driver can't move.
+// Objs already in the trash are removed permanently.
+func RemoveToTrash(ctx context.Context, storage driver.Driver, path string) error {
+	path = utils.FixAndCleanPath(path)
+	if InTrash(storage, path) {
+		return Remove(ctx, storage, path)
+	}
+	if utils.PathEqual(path, "/") {
+		return errs.New("delete root folder is not allowed, please goto the manage page to delete the storage instead")
+	}
+	obj, err := Get(ctx, storage, path)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return nil
+		}
+		return errs.WithMessage(err, "failed to get object")
+	}
+	item := &model.TrashItem{
+		StorageID: storage.GetStorage().ID,
+		Path:      path,
+		Name:      obj.GetName(),
+		Size:      obj.GetSize(),
+		IsDir:     obj.IsDir(),
+		DeletedAt: time.Now(),
+	}
+	if user, ok := ctx.Value(consts.UserKey).(*model.User); ok {
+		item.Deleter = user.Username
+	}
+	if err = db.CreateTrashItem(item); err != nil {
+		return err
+	}
+	if driver.GetCapabilities(storage).Move {
+		dir := trashItemDir(item.ID)
+		if err = MakeDir(ctx, storage, dir); err == nil {
+			err = Move(ctx, storage, path, dir)
+		}
+		item.TrashPath = stdpath.Join(dir, item.Name)
+	} else {
+		name := fmt.Sprintf("%s%d-%s", trashRenamePrefix, item.ID, item.Name)
+		err = Rename(ctx, storage, path, name)
+		item.TrashPath = stdpath.Join(stdpath.Dir(path), name)
+	}
+	if err != nil {
+		_ = db.DeleteTrashItemByID(item.ID)
+		return errs.WithMessage(err, "failed move to trash")
+	}
+	if err = db.UpdateTrashItem(item); err != nil {
+		return err
+	}
+	e := objEvent(storage, EventRemoved, path, "", obj)
+	e.Trashed = true
+	PublishEvent(e)
+	return nil
+}
+
+func trashItemDir(id uint) string {
+	return stdpath.Join("/", consts.TrashDir, fmt.Sprint(id))
+}
+
+// GetTrashItemStorage returns the storage an item was removed from
+func GetTrashItemStorage(item *model.TrashItem) (driver.Driver, error) {
+	s, err := db.GetStorageByID(item.StorageID)
+	if err != nil {
+		return nil, errs.WithMessage(err, "failed get storage of trash item")
+	}
+	return GetStorageByMountPath(s.MountPath)
+}
+
+func GetTrashItems(storage driver.Driver, pathPrefix string, pageIndex, pageSize int) ([]model.TrashItem, int64, error) {
+	return db.GetTrashItems(storage.GetStorage().ID, utils.FixAndCleanPath(pathPrefix), pageIndex, pageSize)
+}
+
+func GetTrashItemById(id uint) (*model.TrashItem, error) {
+	return db.GetTrashItemByID(id)
+}
+
+// RestoreTrashItem moves the obj back to where it was removed from, the parent dirs are created if needed
+func RestoreTrashItem(ctx context.Context, item *model.TrashItem) error {
+	storage, err := GetTrashItemStorage(item)
+	if err != nil {
+		return err
+	}
+	if _, err = Get(ctx, storage, item.Path); err == nil {
+		return errs.Wrapf(errs.ObjectExists, "[%s]", item.Path)
+	} else if !errs.IsObjectNotFound(err) {
+		return errs.WithMessage(err, "failed get restore path")
+	}
+	dstDir := stdpath.Dir(item.Path)
+	if utils.PathEqual(stdpath.Dir(item.TrashPath), dstDir) {
+		// renamed in place
+		err = Rename(ctx, storage, item.TrashPath, item.Name)
+	} else {
+		if err = MakeDir(ctx, storage, dstDir); err != nil {
+			return errs.WithMessage(err, "failed make restore dir")
+		}
+		if err = Move(ctx, storage, item.TrashPath, dstDir); err == nil {
+			if err := Remove(ctx, storage, stdpath.Dir(item.TrashPath)); err != nil {
+				log.Warnf("failed remove trash dir of item %d: %+v", item.ID, err)
+			}
+		}
+	}
+	if err != nil {
+		return errs.WithMessage(err, "failed restore from trash")
+	}
+	return db.DeleteTrashItemByID(item.ID)
+}
+
+// PurgeTrashItem removes the obj from the trash permanently
+func PurgeTrashItem(ctx context.Context, item *model.TrashItem) error {
+	storage, err := GetTrashItemStorage(item)
+	if err != nil {
+		return err
+	}
+	trashPath := item.TrashPath
+	if dir := trashItemDir(item.ID); utils.IsSubPath(dir, trashPath) {
+		trashPath = dir
+	}
+	if err = Remove(ctx, storage, trashPath); err != nil {
+		return errs.WithMessage(err, "failed purge from trash")
+	}
+	return db.DeleteTrashItemByID(item.ID)
+}
+
+// PurgeExpiredTrash purges the items kept longer than the trash retention of their storage
+func PurgeExpiredTrash(ctx context.Context) {
+	for _, storage := range GetAllStorages() {
+		s := storage.GetStorage()
+		if s.TrashRetention <= 0 {
+			continue
+		}
+		items, err := db.GetTrashItemsBefore(s.ID, time.Now().AddDate(0, 0, -s.TrashRetention))
+		if err != nil {
+			log.Errorf("failed get expired trash items of [%s]: %+v", s.MountPath, err)
+			continue
+		}
+		for i := range items {
+			if err = PurgeTrashItem(ctx, &items[i]); err != nil {
+				log.Errorf("failed purge trash item %d of [%s]: %+v", items[i].ID, s.MountPath, err)
+			}
+		}
+	}
+}
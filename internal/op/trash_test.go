@@ -0,0 +1,123 @@
+package op_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+// TestTrashRemoveAndRestore moves a file into the trash of a Local storage and restores it
+func TestTrashRemoveAndRestore(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "dir", "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/trash_test",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+		Trash:     model.Trash{EnableTrash: true},
+	})
+	if err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/trash_test")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	if !op.TrashEnabled(storage) {
+		t.Fatal("expected the trash to be enabled")
+	}
+
+	op.FollowDeadProps()
+	if err = op.PatchDeadProps("/trash_test/dir/a.txt", []model.DeadProp{{Space: "urn:example", Local: "color", InnerXML: "red"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = op.RemoveToTrash(ctx, storage, "/dir/a.txt"); err != nil {
+		t.Fatalf("failed remove to trash: %+v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "dir", "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected the file to be removed, got %v", err)
+	}
+	items, total, err := op.GetTrashItems(storage, "/dir", 1, 10)
+	if err != nil || total != 1 {
+		t.Fatalf("expected one trash item, got %d, %+v", total, err)
+	}
+	item := items[0]
+	if item.Path != "/dir/a.txt" || !op.InTrash(storage, item.TrashPath) {
+		t.Fatalf("unexpected trash item: %+v", item)
+	}
+	if _, err = os.Stat(filepath.Join(root, consts.TrashDir, fmt.Sprint(item.ID), "a.txt")); err != nil {
+		t.Fatalf("expected the file in the trash: %v", err)
+	}
+
+	if err = op.RestoreTrashItem(ctx, &item); err != nil {
+		t.Fatalf("failed restore: %+v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "dir", "a.txt")); err != nil {
+		t.Fatalf("expected the file to be restored: %v", err)
+	}
+	if props, _ := op.GetDeadProps("/trash_test/dir/a.txt"); len(props) != 1 {
+		t.Errorf("expected the dead props to be restored, got %+v", props)
+	}
+	if _, total, _ = op.GetTrashItems(storage, "/", 1, 10); total != 0 {
+		t.Errorf("expected the trash to be empty, got %d items", total)
+	}
+}
+
+// TestInTrash checks the user files named like the objs renamed into the trash aren't taken for them
+func TestInTrash(t *testing.T) {
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/in_trash_test",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, t.TempDir()),
+		Trash:     model.Trash{EnableTrash: true},
+	})
+	if err != nil {
+		t.Fatalf("failed create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/in_trash_test")
+	if err != nil {
+		t.Fatalf("failed get storage: %+v", err)
+	}
+	cases := map[string]bool{
+		"/" + consts.TrashDir:                   true,
+		"/" + consts.TrashDir + "/1/a.txt":      true,
+		"/dir/a.txt":                            false,
+		"/dir/" + consts.TrashDir + "-1-a.txt":  false,
+		"/dir/" + consts.TrashDir + "-x-a.txt":  false,
+		"/" + consts.TrashDir + "-1-dir/a.txt":  false,
+		"/dir/" + consts.TrashDir + "/a.txt":    false,
+		"/dir/" + consts.TrashDir + "-notes.md": false,
+	}
+	for path, want := range cases {
+		if got := op.InTrash(storage, path); got != want {
+			t.Errorf("InTrash(%s) = %v, expected %v", path, got, want)
+		}
+	}
+
+	// an obj renamed in place by a driver which can't move is recorded in the trash
+	item := &model.TrashItem{StorageID: storage.GetStorage().ID, Path: "/dir/b.txt", Name: "b.txt"}
+	if err = db.CreateTrashItem(item); err != nil {
+		t.Fatal(err)
+	}
+	item.TrashPath = fmt.Sprintf("/dir/%s-%d-b.txt", consts.TrashDir, item.ID)
+	if err = db.UpdateTrashItem(item); err != nil {
+		t.Fatal(err)
+	}
+	if !op.InTrash(storage, item.TrashPath) || !op.InTrash(storage, item.TrashPath+"/c.txt") {
+		t.Errorf("expected %s to be in the trash", item.TrashPath)
+	}
+}
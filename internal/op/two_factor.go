@@ -0,0 +1,135 @@
+package op
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	recoveryCodeCount     = 10
+	recoveryCodeHalfLen   = 5
+	defaultTwoFactorGrace = 7
+)
+
+// HasTwoFactor reports whether u has enabled the TOTP or registered a WebAuthn credential
+func HasTwoFactor(u *model.User) bool {
+	if u.OtpSecret != "" {
+		return true
+	}
+	return u.Authn != "" && len(u.WebAuthnCredentials()) > 0
+}
+
+// TwoFactorRequired reports whether the 2FA policy applies to u,
+// it's required for the admins by consts.TwoFactorRequiredAdmins and for the members of the groups with Require2FA
+func TwoFactorRequired(u *model.User) bool {
+	if u.IsGuest() {
+		return false
+	}
+	if u.IsAdmin() {
+		if item, _ := GetSettingItemByKey(consts.TwoFactorRequiredAdmins); item != nil && item.Value == "true" {
+			return true
+		}
+	}
+	for _, g := range u.Groups {
+		if g.Require2FA {
+			return true
+		}
+	}
+	return false
+}
+
+// TwoFactorDeadline returns until when u can use the api without 2FA,
+// the grace period of consts.TwoFactorGraceDays starts when the policy is first checked for u
+func TwoFactorDeadline(u *model.User) (time.Time, error) {
+	days := defaultTwoFactorGrace
+	if item, _ := GetSettingItemByKey(consts.TwoFactorGraceDays); item != nil {
+		if d, err := strconv.Atoi(item.Value); err == nil {
+			days = d
+		}
+	}
+	g := &model.TwoFactorGrace{UserID: u.ID, Since: time.Now()}
+	if err := db.GetTwoFactorGrace(g); err != nil {
+		return time.Time{}, err
+	}
+	return g.Since.AddDate(0, 0, days), nil
+}
+
+// CheckTwoFactorPolicy returns errs.TwoFactorRequired if u is required to have 2FA but has none after the grace period
+func CheckTwoFactorPolicy(u *model.User) error {
+	if !TwoFactorRequired(u) || HasTwoFactor(u) {
+		return nil
+	}
+	deadline, err := TwoFactorDeadline(u)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(deadline) {
+		return errs.WithStack(errs.TwoFactorRequired)
+	}
+	return nil
+}
+
+// CheckPasswordLogin returns errs.APITokenRequired if u logs in with the password through a protocol
+// which can't ask for the 2FA, such as WebDAV, FTP and SFTP, while 2FA is required for u
+func CheckPasswordLogin(u *model.User) error {
+	if TwoFactorRequired(u) {
+		return errs.WithStack(errs.APITokenRequired)
+	}
+	return nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+func hashRecoveryCode(code string) string {
+	return utils.HashData(utils.SHA256, []byte(normalizeRecoveryCode(code)))
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of u with new ones and returns them,
+// they can't be got again later
+func GenerateRecoveryCodes(u *model.User) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]model.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = strings.ToLower(random.String(recoveryCodeHalfLen) + "-" + random.String(recoveryCodeHalfLen))
+		rows[i] = model.RecoveryCode{UserID: u.ID, Hash: hashRecoveryCode(codes[i])}
+	}
+	if err := db.SetRecoveryCodes(u.ID, rows); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func CountRecoveryCodes(userID uint) (int64, error) {
+	return db.CountRecoveryCodes(userID)
+}
+
+// ValidateTwoFactorCode accepts the TOTP code or an unused recovery code of u, the recovery code is used up
+func ValidateTwoFactorCode(u *model.User, code string) error {
+	if u.OtpSecret != "" && totp.Validate(code, u.OtpSecret) {
+		return nil
+	}
+	ok, err := db.TakeRecoveryCode(u.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errs.WithStack(errs.InvalidRecoveryCode)
+	}
+	return nil
+}
+
+func DeleteRecoveryCodesByUserId(userID uint) error {
+	return db.SetRecoveryCodes(userID, nil)
+}
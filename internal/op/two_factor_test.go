@@ -0,0 +1,66 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// TestTwoFactorPolicyAndRecoveryCodes checks the grace period of a required group and the one-time recovery codes
+func TestTwoFactorPolicyAndRecoveryCodes(t *testing.T) {
+	g := &model.Group{Name: "secured", Require2FA: true}
+	if err := op.CreateGroup(g); err != nil {
+		t.Fatalf("failed create group: %+v", err)
+	}
+	if err := op.CreateUser(&model.User{Username: "secured", BasePath: "/", GroupIDs: []uint{g.ID}}); err != nil {
+		t.Fatalf("failed create user: %+v", err)
+	}
+	user, err := op.GetUserByName("secured")
+	if err != nil {
+		t.Fatalf("failed get user: %+v", err)
+	}
+	if !op.TwoFactorRequired(user) {
+		t.Fatalf("expected 2FA to be required by the group")
+	}
+	if err = op.CheckTwoFactorPolicy(user); err != nil {
+		t.Errorf("expected the user to be in the grace period, got %v", err)
+	}
+	if err = op.CheckPasswordLogin(user); !errs.Is(err, errs.APITokenRequired) {
+		t.Errorf("expected the password login of the protocols to be refused, got %v", err)
+	}
+
+	if err = op.SaveSettingItem(&model.SettingItem{Key: consts.TwoFactorGraceDays, Value: "0", Type: consts.TypeNumber, Group: model.GLOBAL}); err != nil {
+		t.Fatalf("failed save setting: %+v", err)
+	}
+	if err = op.CheckTwoFactorPolicy(user); !errs.Is(err, errs.TwoFactorRequired) {
+		t.Errorf("expected 2FA to be enforced after the grace period, got %v", err)
+	}
+	raw, err := op.CreateAPIToken(user, &model.APIToken{Name: "grace"})
+	if err != nil {
+		t.Fatalf("failed create api token: %+v", err)
+	}
+	if _, err = op.ValidateAPIToken(raw); !errs.Is(err, errs.TwoFactorRequired) {
+		t.Errorf("expected the api token to stop working after the grace period, got %v", err)
+	}
+
+	user.OtpSecret = "JBSWY3DPEHPK3PXP"
+	if err = op.CheckTwoFactorPolicy(user); err != nil {
+		t.Errorf("expected the user with 2FA to pass, got %v", err)
+	}
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
+		t.Fatalf("failed generate recovery codes: %+v", err)
+	}
+	if err = op.ValidateTwoFactorCode(user, " "+codes[0]+" "); err != nil {
+		t.Errorf("expected the recovery code to be accepted, got %v", err)
+	}
+	if err = op.ValidateTwoFactorCode(user, codes[0]); !errs.Is(err, errs.InvalidRecoveryCode) {
+		t.Errorf("expected the used recovery code to be rejected, got %v", err)
+	}
+	if n, _ := op.CountRecoveryCodes(user.ID); n != int64(len(codes)-1) {
+		t.Errorf("expected %d recovery codes left, got %d", len(codes)-1, n)
+	}
+}
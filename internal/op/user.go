@@ -73,6 +73,11 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 
 func CreateUser(u *model.User) error {
 	u.BasePath = utils.FixAndCleanPath(u.BasePath)
+	groupIDs, err := checkGroupIDs(u.GroupIDs)
+	if err != nil {
+		return err
+	}
+	u.GroupIDs = groupIDs
 	return db.CreateUser(u)
 }
 
@@ -85,6 +90,27 @@ func DeleteUserById(id uint) error {
 		return errs.DeleteAdminOrGuest
 	}
 	userCache.Del(old.Username)
+	if err = db.DeleteAPITokensByUserID(id); err != nil {
+		return err
+	}
+	if err = DeleteS3KeysByUserId(id); err != nil {
+		return err
+	}
+	if err = DeleteSessionsByUserId(id); err != nil {
+		return err
+	}
+	if err = DeleteSharesByUserId(id); err != nil {
+		return err
+	}
+	if err = DeleteFileRequestsByUserId(id); err != nil {
+		return err
+	}
+	if err = DeleteRecoveryCodesByUserId(id); err != nil {
+		return err
+	}
+	if err = db.DeleteTwoFactorGrace(id); err != nil {
+		return err
+	}
 	return db.DeleteUserByID(id)
 }
 
@@ -101,11 +127,25 @@ func UpdateUser(u *model.User) error {
 	}
 	userCache.Del(old.Username)
 	u.BasePath = utils.FixAndCleanPath(u.BasePath)
-	return db.UpdateUser(u)
+	if u.GroupIDs, err = checkGroupIDs(u.GroupIDs); err != nil {
+		return err
+	}
+	if err = db.UpdateUser(u); err != nil {
+		return err
+	}
+	if u.Disabled && !old.Disabled {
+		// a disabled user is logged out everywhere
+		return DeleteSessionsByUserId(u.ID)
+	}
+	return nil
 }
 
+// Cancel2FAByUser removes the TOTP secret and the recovery codes of u
 func Cancel2FAByUser(u *model.User) error {
 	u.OtpSecret = ""
+	if err := DeleteRecoveryCodesByUserId(u.ID); err != nil {
+		return err
+	}
 	return UpdateUser(u)
 }
 
@@ -130,4 +170,11 @@ func DelUserCache(username string) error {
 	}
 	userCache.Del(username)
 	return nil
+}
+
+// clearUserCache drops all the cached users, it's used when a change affects many users
+func clearUserCache() {
+	adminUser = nil
+	guestUser = nil
+	userCache.Clear()
 }
\ No newline at end of file
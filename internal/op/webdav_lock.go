@@ -0,0 +1,105 @@
+package op
+
+import (
+	"context"
+	stdpath "path"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/driver"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// CreateWebDAVLock saves l, it returns errs.ObjectLocked if l conflicts with an alive lock
+func CreateWebDAVLock(l *model.WebDAVLock, now time.Time) error {
+	l.Root = utils.FixAndCleanPath(l.Root)
+	l.RootHash = utils.HashData(utils.SHA256, []byte(l.Root))
+	l.CreatedAt = now
+	l.ExpiresAt = webDAVLockExpiresAt(l.Duration, now)
+	return db.CreateWebDAVLock(l, now)
+}
+
+func webDAVLockExpiresAt(duration time.Duration, now time.Time) *time.Time {
+	if duration < 0 {
+		return nil
+	}
+	expiresAt := now.Add(duration)
+	return &expiresAt
+}
+
+// GetWebDAVLock returns the alive lock of token, nil if there is none
+func GetWebDAVLock(token string, now time.Time) (*model.WebDAVLock, error) {
+	l, err := db.GetWebDAVLock(token, now)
+	if errs.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return l, err
+}
+
+// RefreshWebDAVLock restarts the timeout of the alive lock of token with duration, it returns nil if there is none
+func RefreshWebDAVLock(token string, duration time.Duration, now time.Time) (*model.WebDAVLock, error) {
+	l, err := GetWebDAVLock(token, now)
+	if l == nil || err != nil {
+		return nil, err
+	}
+	l.Duration = duration
+	l.ExpiresAt = webDAVLockExpiresAt(duration, now)
+	if err = db.RefreshWebDAVLock(token, l.Duration, l.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// DeleteWebDAVLock releases the lock of token and reports whether there was one
+func DeleteWebDAVLock(token string) (bool, error) {
+	return db.DeleteWebDAVLock(token)
+}
+
+// GetWebDAVLocks returns all the alive locks, the latest first
+func GetWebDAVLocks() ([]model.WebDAVLock, error) {
+	return db.GetWebDAVLocks(time.Now())
+}
+
+// SweepWebDAVLocks deletes the expired locks, the expired locks are ignored before they are swept
+func SweepWebDAVLocks() {
+	if err := db.DeleteExpiredWebDAVLocks(time.Now()); err != nil {
+		log.Errorf("failed sweep expired webdav locks: %+v", err)
+	}
+}
+
+// WebDAVLockHolder is put in the context of the WebDAV requests with consts.WebDAVLockTokensKey,
+// it tells the lock tokens submitted or taken by the request
+type WebDAVLockHolder interface {
+	HoldsWebDAVLock(token string) bool
+}
+
+// CheckWebDAVLock returns errs.ObjectLocked if path, or one of its sub paths, is locked by a WebDAV client.
+// The locks held by the WebDAV request in ctx don't block it.
+func CheckWebDAVLock(ctx context.Context, path string) error {
+	holder, _ := ctx.Value(consts.WebDAVLockTokensKey).(WebDAVLockHolder)
+	path = utils.FixAndCleanPath(path)
+	locks, err := db.GetWebDAVLocksOn(path, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, l := range locks {
+		if holder != nil && holder.HoldsWebDAVLock(l.Token) {
+			continue
+		}
+		if l.Covers(path) || utils.IsSubPath(path, l.Root) {
+			return errs.Wrapf(errs.ObjectLocked, "%s is locked", l.Root)
+		}
+	}
+	return nil
+}
+
+// checkObjWebDAVLock checks the WebDAV locks of the storage relative path
+func checkObjWebDAVLock(ctx context.Context, storage driver.Driver, path string) error {
+	return CheckWebDAVLock(ctx, stdpath.Join(storage.GetStorage().MountPath, path))
+}
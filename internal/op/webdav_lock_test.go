@@ -0,0 +1,62 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+type lockHolder map[string]bool
+
+func (h lockHolder) HoldsWebDAVLock(token string) bool {
+	return h[token]
+}
+
+func TestWebDAVLock(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	locked := &model.WebDAVLock{Token: "opaquelocktoken:locked", Root: "/lock/docs", Duration: time.Hour}
+	if err := op.CreateWebDAVLock(locked, now); err != nil {
+		t.Fatalf("failed create webdav lock: %+v", err)
+	}
+	err := op.CreateWebDAVLock(&model.WebDAVLock{Token: "opaquelocktoken:child", Root: "/lock/docs/a.docx", Duration: time.Hour}, now)
+	if !errs.Is(err, errs.ObjectLocked) {
+		t.Errorf("expected the infinite depth lock to cover the sub paths, got %v", err)
+	}
+	expired := &model.WebDAVLock{Token: "opaquelocktoken:expired", Root: "/lock/old", Duration: time.Second}
+	if err = op.CreateWebDAVLock(expired, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("failed create webdav lock: %+v", err)
+	}
+
+	for path, wantLocked := range map[string]bool{
+		"/lock/docs/a.docx": true,
+		"/lock":             true,
+		"/lock/docs_other":  false,
+		"/lock/old":         false,
+	} {
+		err = op.CheckWebDAVLock(ctx, path)
+		if gotLocked := errs.Is(err, errs.ObjectLocked); gotLocked != wantLocked {
+			t.Errorf("expected %s locked=%v, got %v", path, wantLocked, err)
+		}
+	}
+	held := context.WithValue(ctx, consts.WebDAVLockTokensKey, lockHolder{locked.Token: true})
+	if err = op.CheckWebDAVLock(held, "/lock/docs/a.docx"); err != nil {
+		t.Errorf("expected the webdav request holding the lock to pass, got %v", err)
+	}
+	other := context.WithValue(ctx, consts.WebDAVLockTokensKey, lockHolder{"opaquelocktoken:other": true})
+	if err = op.CheckWebDAVLock(other, "/lock"); !errs.Is(err, errs.ObjectLocked) {
+		t.Errorf("expected the lock on the sub path to block the other requests, got %v", err)
+	}
+
+	if ok, err := op.DeleteWebDAVLock(locked.Token); !ok || err != nil {
+		t.Fatalf("failed release webdav lock: %v, %+v", ok, err)
+	}
+	if err = op.CheckWebDAVLock(ctx, "/lock/docs/a.docx"); err != nil {
+		t.Errorf("expected the released lock not to block, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package op
+
+import (
+	"net/url"
+	"sync"
+
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+var (
+	enabledWebhooks      []model.Webhook
+	enabledWebhooksValid bool
+	enabledWebhooksMu    sync.RWMutex
+)
+
+func invalidateWebhooks() {
+	enabledWebhooksMu.Lock()
+	defer enabledWebhooksMu.Unlock()
+	enabledWebhooksValid = false
+	enabledWebhooks = nil
+}
+
+// GetEnabledWebhooks returns the enabled webhooks, the result is cached until a webhook is changed
+func GetEnabledWebhooks() ([]model.Webhook, error) {
+	enabledWebhooksMu.RLock()
+	if enabledWebhooksValid {
+		defer enabledWebhooksMu.RUnlock()
+		return enabledWebhooks, nil
+	}
+	enabledWebhooksMu.RUnlock()
+
+	hooks, err := db.GetEnabledWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	enabledWebhooksMu.Lock()
+	defer enabledWebhooksMu.Unlock()
+	enabledWebhooks, enabledWebhooksValid = hooks, true
+	return hooks, nil
+}
+
+func GetWebhooks(pageIndex, pageSize int) ([]model.Webhook, int64, error) {
+	return db.GetWebhooks(pageIndex, pageSize)
+}
+
+func GetWebhookById(id uint) (*model.Webhook, error) {
+	return db.GetWebhookByID(id)
+}
+
+func checkWebhook(w *model.Webhook) error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return errs.Wrap(err, "invalid webhook url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errs.Errorf("unsupported webhook url scheme: %s", u.Scheme)
+	}
+	if w.MaxRetry < 0 {
+		w.MaxRetry = 0
+	}
+	if w.PathPrefix != "" {
+		w.PathPrefix = utils.FixAndCleanPath(w.PathPrefix)
+	}
+	return nil
+}
+
+func CreateWebhook(w *model.Webhook) error {
+	if err := checkWebhook(w); err != nil {
+		return err
+	}
+	defer invalidateWebhooks()
+	return db.CreateWebhook(w)
+}
+
+func UpdateWebhook(w *model.Webhook) error {
+	if err := checkWebhook(w); err != nil {
+		return err
+	}
+	old, err := db.GetWebhookByID(w.ID)
+	if err != nil {
+		return err
+	}
+	if w.Secret == model.WebhookSecretMask {
+		w.Secret = old.Secret
+	}
+	defer invalidateWebhooks()
+	return db.UpdateWebhook(w)
+}
+
+func DeleteWebhookById(id uint) error {
+	defer invalidateWebhooks()
+	return db.DeleteWebhookByID(id)
+}
+
+func GetWebhookDeliveries(webhookID uint, pageIndex, pageSize int) ([]model.WebhookDelivery, int64, error) {
+	return db.GetWebhookDeliveries(webhookID, pageIndex, pageSize)
+}
@@ -0,0 +1,261 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/fs"
+	"github.com/dongdio/OpenList/v4/internal/offline_download/tool"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/search"
+	"github.com/dongdio/OpenList/v4/utility/task"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const (
+	TypeIndex           = "index"
+	TypeSync            = "sync"
+	TypeCopy            = "copy"
+	TypeStorageRefresh  = "storage_refresh"
+	TypeRemoveEmptyDirs = "remove_empty_dirs"
+	TypeOfflineDownload = "offline_download"
+	TypeQuota           = "quota"
+)
+
+// jobFunc runs a job with its json args, the returned string is recorded as the message of the run
+type jobFunc func(ctx context.Context, args string) (string, error)
+
+var jobTypes = make(map[string]jobFunc)
+
+// registerJobType registers a job type whose args are decoded into T and checked before running
+func registerJobType[T any](typ string, check func(args *T) error, run func(ctx context.Context, args *T) (string, error)) {
+	parse := func(s string) (*T, error) {
+		args := new(T)
+		if err := utils.JSONTool.UnmarshalFromString(s, args); err != nil {
+			return nil, errs.Wrap(err, "failed parse args")
+		}
+		if err := check(args); err != nil {
+			return nil, err
+		}
+		return args, nil
+	}
+	op.RegisterScheduledJobType(typ, func(s string) error {
+		_, err := parse(s)
+		return err
+	})
+	jobTypes[typ] = func(ctx context.Context, s string) (string, error) {
+		args, err := parse(s)
+		if err != nil {
+			return "", err
+		}
+		return run(ctx, args)
+	}
+}
+
+type IndexArgs struct {
+	Paths    []string `json:"paths"`
+	MaxDepth int      `json:"max_depth"`
+}
+
+type CopyArgs struct {
+	SrcPaths []string `json:"src_paths"`
+	DstDir   string   `json:"dst_dir"`
+}
+
+type StorageRefreshArgs struct {
+	Path string `json:"path"`
+	// Reload drops the storage and initializes it again instead of only refreshing the list cache
+	Reload bool `json:"reload"`
+}
+
+type RemoveEmptyDirsArgs struct {
+	Path string `json:"path"`
+}
+
+type OfflineDownloadArgs struct {
+	URLs         []string `json:"urls"`
+	DstDir       string   `json:"dst_dir"`
+	Tool         string   `json:"tool"`
+	DeletePolicy string   `json:"delete_policy"`
+}
+
+func registerJobTypes() {
+	registerJobType(TypeIndex, checkIndex, runIndex)
+	registerJobType(TypeSync, checkSync, runSync)
+	registerJobType(TypeCopy, checkCopy, runCopy)
+	registerJobType(TypeStorageRefresh, checkStorageRefresh, runStorageRefresh)
+	registerJobType(TypeRemoveEmptyDirs, checkRemoveEmptyDirs, runRemoveEmptyDirs)
+	registerJobType(TypeOfflineDownload, checkOfflineDownload, runOfflineDownload)
+	registerJobType(TypeQuota, func(*struct{}) error { return nil }, runQuota)
+}
+
+func checkIndex(args *IndexArgs) error {
+	if len(args.Paths) == 0 {
+		return errs.New("paths is required")
+	}
+	if args.MaxDepth == 0 {
+		args.MaxDepth = -1
+	}
+	return nil
+}
+
+// runIndex updates the index of the paths, the same as the update index api but waits for the end
+func runIndex(ctx context.Context, args *IndexArgs) (string, error) {
+	if search.Running() {
+		return "", errs.BuildIndexIsRunning
+	}
+	if !search.Config(ctx).AutoUpdate {
+		return "", errs.New("update is not supported for current index")
+	}
+	for _, path := range args.Paths {
+		if err := search.Del(ctx, path); err != nil {
+			return "", errs.WithMessagef(err, "failed delete index on [%s]", path)
+		}
+	}
+	err := search.BuildIndex(ctx, args.Paths, conf.SlicesMap[consts.IgnorePaths], args.MaxDepth, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("index of %d paths updated", len(args.Paths)), nil
+}
+
+func checkSync(args *fs.SyncArgs) error {
+	if args.SrcPath == "" || args.DstPath == "" {
+		return errs.New("src_path and dst_path are required")
+	}
+	return nil
+}
+
+func runSync(ctx context.Context, args *fs.SyncArgs) (string, error) {
+	t, err := fs.Sync(ctx, *args)
+	if err != nil {
+		return "", err
+	}
+	return taskMessage(t), nil
+}
+
+func checkCopy(args *CopyArgs) error {
+	if len(args.SrcPaths) == 0 || args.DstDir == "" {
+		return errs.New("src_paths and dst_dir are required")
+	}
+	return nil
+}
+
+func runCopy(ctx context.Context, args *CopyArgs) (string, error) {
+	var (
+		msgs []string
+		es   error
+	)
+	for _, src := range args.SrcPaths {
+		t, err := fs.Copy(ctx, src, args.DstDir)
+		if err != nil {
+			es = errs.Join(es, errs.WithMessagef(err, "failed copy [%s]", src))
+			continue
+		}
+		msgs = append(msgs, taskMessage(t))
+	}
+	return strings.Join(msgs, "\n"), es
+}
+
+func checkStorageRefresh(args *StorageRefreshArgs) error {
+	if args.Path == "" {
+		return errs.New("path is required")
+	}
+	args.Path = utils.FixAndCleanPath(args.Path)
+	return nil
+}
+
+func runStorageRefresh(ctx context.Context, args *StorageRefreshArgs) (string, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(args.Path)
+	if err != nil {
+		return "", errs.WithMessage(err, "failed get storage")
+	}
+	if args.Reload {
+		if err = op.UpdateStorage(ctx, *storage.GetStorage()); err != nil {
+			return "", errs.WithMessage(err, "failed reload storage")
+		}
+		return fmt.Sprintf("storage [%s] reloaded", storage.GetStorage().MountPath), nil
+	}
+	op.ClearCache(storage, actualPath)
+	objs, err := fs.List(ctx, args.Path, &fs.ListArgs{Refresh: true})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("[%s] refreshed, %d objs", args.Path, len(objs)), nil
+}
+
+func checkRemoveEmptyDirs(args *RemoveEmptyDirsArgs) error {
+	if args.Path == "" {
+		return errs.New("path is required")
+	}
+	args.Path = utils.FixAndCleanPath(args.Path)
+	if args.Path == "/" {
+		return errs.New("can't remove empty dirs from the root")
+	}
+	return nil
+}
+
+func runRemoveEmptyDirs(ctx context.Context, args *RemoveEmptyDirsArgs) (string, error) {
+	if err := fs.RemoveEmptyDirectory(ctx, args.Path); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("empty dirs under [%s] removed", args.Path), nil
+}
+
+func checkOfflineDownload(args *OfflineDownloadArgs) error {
+	if len(args.URLs) == 0 || args.DstDir == "" || args.Tool == "" {
+		return errs.New("urls, dst_dir and tool are required")
+	}
+	if _, err := tool.Tools.Get(args.Tool); err != nil {
+		return err
+	}
+	if args.DeletePolicy == "" {
+		args.DeletePolicy = string(tool.DeleteOnUploadSucceed)
+	}
+	return nil
+}
+
+// runOfflineDownload adds the urls to the offline download tool, polling urls which are updated periodically
+func runOfflineDownload(ctx context.Context, args *OfflineDownloadArgs) (string, error) {
+	var (
+		msgs []string
+		es   error
+	)
+	for _, u := range args.URLs {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		t, err := tool.AddURL(ctx, &tool.AddURLArgs{
+			URL:          u,
+			DstDirPath:   utils.FixAndCleanPath(args.DstDir),
+			Tool:         args.Tool,
+			DeletePolicy: tool.DeletePolicy(args.DeletePolicy),
+		})
+		if err != nil {
+			es = errs.Join(es, errs.WithMessagef(err, "failed add [%s]", u))
+			continue
+		}
+		msgs = append(msgs, taskMessage(t))
+	}
+	return strings.Join(msgs, "\n"), es
+}
+
+// runQuota recomputes the usage of the users and storages having a quota
+func runQuota(ctx context.Context, _ *struct{}) (string, error) {
+	if err := fs.RecomputeQuota(ctx); err != nil {
+		return "", err
+	}
+	return "quota usage recomputed", nil
+}
+
+func taskMessage(t task.TaskExtensionInfo) string {
+	if t == nil {
+		return "done"
+	}
+	return fmt.Sprintf("task %s: %s", t.GetID(), t.GetName())
+}
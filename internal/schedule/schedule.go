@@ -0,0 +1,156 @@
+// Package schedule runs the scheduled jobs defined by the admin in global.CronConfig.
+// Jobs are reloaded as soon as they are changed through op, and every run is recorded in the database.
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// RunRetention is how long the run history is kept
+const RunRetention = 30 * 24 * time.Hour
+
+var (
+	entries   = make(map[uint]cron.EntryID)
+	entriesMu sync.Mutex
+
+	running sync.Map
+)
+
+// Init registers the job types and loads the enabled jobs, it should be called after global.CronConfig is created
+func Init() {
+	registerJobTypes()
+	op.RegisterScheduledJobHook(func(typ string, job *model.ScheduledJob) {
+		switch typ {
+		case "add", "update":
+			reload(job)
+		case "del":
+			remove(job.ID)
+		}
+	})
+	jobs, err := op.GetEnabledScheduledJobs()
+	if err != nil {
+		log.Errorf("failed get scheduled jobs: %+v", err)
+		return
+	}
+	for i := range jobs {
+		reload(&jobs[i])
+	}
+}
+
+// reload replaces the cron entry of job, a disabled job is only removed
+func reload(job *model.ScheduledJob) {
+	remove(job.ID)
+	if job.Disabled || global.CronConfig == nil {
+		return
+	}
+	id := job.ID
+	entryID, err := global.CronConfig.AddFunc(job.Cron, func() {
+		if _, err := RunNow(id); err != nil {
+			log.Errorf("failed run scheduled job %d: %+v", id, err)
+		}
+	})
+	if err != nil {
+		log.Errorf("failed add scheduled job [%s]: %+v", job.Name, err)
+		return
+	}
+	entriesMu.Lock()
+	entries[id] = entryID
+	entriesMu.Unlock()
+}
+
+func remove(id uint) {
+	entriesMu.Lock()
+	entryID, ok := entries[id]
+	delete(entries, id)
+	entriesMu.Unlock()
+	if ok && global.CronConfig != nil {
+		global.CronConfig.Remove(entryID)
+	}
+}
+
+// NextRun returns the next time the job is triggered, it's zero if the job isn't scheduled
+func NextRun(id uint) time.Time {
+	entriesMu.Lock()
+	entryID, ok := entries[id]
+	entriesMu.Unlock()
+	if !ok || global.CronConfig == nil {
+		return time.Time{}
+	}
+	entry := global.CronConfig.Entry(entryID)
+	if !entry.Valid() {
+		return time.Time{}
+	}
+	return entry.Schedule.Next(time.Now())
+}
+
+// RunNow runs the job with the given id at once and waits for it, a job never runs twice at the same time
+func RunNow(id uint) (*model.ScheduledJobRun, error) {
+	job, err := op.GetScheduledJobById(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, loaded := running.LoadOrStore(id, struct{}{}); loaded {
+		return nil, errs.Errorf("scheduled job [%s] is still running", job.Name)
+	}
+	defer running.Delete(id)
+	return run(job)
+}
+
+func run(job *model.ScheduledJob) (*model.ScheduledJobRun, error) {
+	r := &model.ScheduledJobRun{JobID: job.ID, StartedAt: time.Now()}
+	if err := db.CreateScheduledJobRun(r); err != nil {
+		log.Errorf("failed create scheduled job run: %+v", err)
+	}
+	msg, err := runJob(job)
+	finished := time.Now()
+	r.FinishedAt = &finished
+	r.Success, r.Message = err == nil, msg
+	var lastError string
+	if err != nil {
+		lastError = err.Error()
+		r.Message = lastError
+		log.Warnf("scheduled job [%s] failed: %+v", job.Name, err)
+	}
+	if err := db.UpdateScheduledJobRun(r); err != nil {
+		log.Errorf("failed update scheduled job run: %+v", err)
+	}
+	if err := db.UpdateScheduledJobResult(job.ID, r.StartedAt, lastError); err != nil {
+		log.Errorf("failed update scheduled job: %+v", err)
+	}
+	return r, nil
+}
+
+// runJob runs the job as the admin, the tasks it creates are owned by the admin
+func runJob(job *model.ScheduledJob) (string, error) {
+	fn, ok := jobTypes[job.Type]
+	if !ok {
+		return "", errs.Errorf("unknown scheduled job type: %s", job.Type)
+	}
+	admin, err := op.GetAdmin()
+	if err != nil {
+		return "", errs.WithMessage(err, "failed get admin")
+	}
+	ctx := context.WithValue(context.Background(), consts.UserKey, admin)
+	ctx = context.WithValue(ctx, consts.ApiUrlKey, common.GetApiURLFromRequest(nil))
+	return fn(ctx, job.Args)
+}
+
+// CleanRuns removes the runs older than RunRetention
+func CleanRuns() {
+	if err := db.DeleteScheduledJobRunsBefore(time.Now().Add(-RunRetention)); err != nil {
+		log.Errorf("failed clean scheduled job runs: %+v", err)
+	}
+}
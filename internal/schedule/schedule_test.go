@@ -0,0 +1,67 @@
+package schedule_test
+
+import (
+	"testing"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/internal/schedule"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	global.CronConfig = cron.New()
+	schedule.Init()
+}
+
+func TestCreateScheduledJobCheck(t *testing.T) {
+	for _, job := range []model.ScheduledJob{
+		{Name: "bad cron", Cron: "* * *", Type: schedule.TypeRemoveEmptyDirs, Args: `{"path":"/a"}`},
+		{Name: "bad type", Cron: "@daily", Type: "unknown"},
+		{Name: "bad args", Cron: "@daily", Type: schedule.TypeRemoveEmptyDirs, Args: `{"path":"/"}`},
+	} {
+		if err := op.CreateScheduledJob(&job); err == nil {
+			t.Errorf("expected [%s] to be rejected", job.Name)
+		}
+	}
+}
+
+func TestScheduledJobHotReload(t *testing.T) {
+	job := &model.ScheduledJob{Name: "cleanup", Cron: "0 3 * * *", Type: schedule.TypeRemoveEmptyDirs, Args: `{"path":"/a"}`}
+	if err := op.CreateScheduledJob(job); err != nil {
+		t.Fatalf("failed create job: %+v", err)
+	}
+	if schedule.NextRun(job.ID).IsZero() {
+		t.Fatal("expected the job to be scheduled after create")
+	}
+	if err := op.SetScheduledJobDisabled(job.ID, true); err != nil {
+		t.Fatalf("failed disable job: %+v", err)
+	}
+	if !schedule.NextRun(job.ID).IsZero() {
+		t.Error("expected the job to be unscheduled after disable")
+	}
+	if err := op.SetScheduledJobDisabled(job.ID, false); err != nil {
+		t.Fatalf("failed enable job: %+v", err)
+	}
+	if schedule.NextRun(job.ID).IsZero() {
+		t.Error("expected the job to be scheduled after enable")
+	}
+	if err := op.DeleteScheduledJobById(job.ID); err != nil {
+		t.Fatalf("failed delete job: %+v", err)
+	}
+	if !schedule.NextRun(job.ID).IsZero() {
+		t.Error("expected the job to be unscheduled after delete")
+	}
+}
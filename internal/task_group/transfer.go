@@ -14,6 +14,9 @@ import (
 
 type SrcPathToRemove string
 
+// SrcPathToKeep is a src file which failed the verification, it and its parents aren't removed
+type SrcPathToKeep string
+
 type DstPathToRefresh string // ActualPath
 
 func RefreshAndRemove(dstPath string, payloads ...any) {
@@ -29,6 +32,13 @@ func RefreshAndRemove(dstPath string, payloads ...any) {
 		op.DeleteCache(dstStorage, dstActualPath)
 	}
 
+	keep := make(map[string]struct{})
+	for _, payload := range payloads {
+		if p, ok := payload.(SrcPathToKeep); ok {
+			keep[string(p)] = struct{}{}
+		}
+	}
+
 	var ctx context.Context
 	for _, payload := range payloads {
 		switch p := payload.(type) {
@@ -45,7 +55,7 @@ func RefreshAndRemove(dstPath string, payloads ...any) {
 				log.Error(errs.WithMessage(err, "failed get src storage"))
 				continue
 			}
-			err = verifyAndRemove(ctx, srcStorage, dstStorage, srcActualPath, dstActualPath, dstNeedRefresh)
+			err = verifyAndRemove(ctx, srcStorage, dstStorage, srcActualPath, dstActualPath, dstNeedRefresh, keep)
 			if err != nil {
 				log.Error(err)
 			}
@@ -53,7 +63,10 @@ func RefreshAndRemove(dstPath string, payloads ...any) {
 	}
 }
 
-func verifyAndRemove(ctx context.Context, srcStorage, dstStorage driver.Driver, srcPath, dstPath string, refresh bool) error {
+func verifyAndRemove(ctx context.Context, srcStorage, dstStorage driver.Driver, srcPath, dstPath string, refresh bool, keep map[string]struct{}) error {
+	if _, ok := keep[path.Join(srcStorage.GetStorage().MountPath, srcPath)]; ok {
+		return errs.Errorf("keep %s, failed to verify", path.Join(srcStorage.GetStorage().MountPath, srcPath))
+	}
 	srcObj, err := op.Get(ctx, srcStorage, srcPath)
 	if err != nil {
 		return errs.WithMessagef(err, "failed get src [%s] file", path.Join(srcStorage.GetStorage().MountPath, srcPath))
@@ -85,7 +98,7 @@ func verifyAndRemove(ctx context.Context, srcStorage, dstStorage driver.Driver,
 	hasErr := false
 	for _, obj := range srcObjs {
 		srcSubPath := path.Join(srcPath, obj.GetName())
-		err = verifyAndRemove(ctx, srcStorage, dstStorage, srcSubPath, dstObjPath, refresh)
+		err = verifyAndRemove(ctx, srcStorage, dstStorage, srcSubPath, dstObjPath, refresh, keep)
 		if err != nil {
 			log.Error(err)
 			hasErr = true
@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/dongdio/OpenList/v4/utility/task"
+)
+
+// TaskPayload is the data of the task_succeeded and task_failed events
+type TaskPayload struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Name       string     `json:"name"`
+	Path       string     `json:"path"`
+	Creator    string     `json:"creator"`
+	StartTime  *time.Time `json:"start_time"`
+	EndTime    *time.Time `json:"end_time"`
+	TotalBytes int64      `json:"total_bytes"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// NotifyTask is called from the OnSucceeded and OnFailed hooks of the tache tasks.
+// typ is the task manager name such as copy, move, upload or offline_download,
+// path is the destination of the task.
+func NotifyTask(typ, path string, t task.TaskExtensionInfo, succeeded bool) {
+	p := TaskPayload{
+		ID:         t.GetID(),
+		Type:       typ,
+		Name:       t.GetName(),
+		Path:       path,
+		StartTime:  t.GetStartTime(),
+		EndTime:    t.GetEndTime(),
+		TotalBytes: t.GetTotalBytes(),
+	}
+	if creator := t.GetCreator(); creator != nil {
+		p.Creator = creator.Username
+	}
+	event := EventTaskSucceeded
+	if !succeeded {
+		event = EventTaskFailed
+		if err := t.GetErr(); err != nil {
+			p.Error = err.Error()
+		}
+	}
+	Dispatch(event, path, p)
+}
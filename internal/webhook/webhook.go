@@ -0,0 +1,234 @@
+// Package webhook posts file and task events and the login lockouts to the urls configured by the admin.
+// Payloads are signed with utility/sign, failed deliveries are retried with backoff
+// and every delivery is recorded in the database.
+// The dispatched deliveries only hold one of the maxConcurrency slots while they are sent, the retries
+// wait on a timer, and each webhook has at most maxPending deliveries in flight, so a slow or dead
+// receiver can't hold up the others.
+package webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/authguard"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/sign"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const (
+	EventTaskSucceeded = "task_succeeded"
+	EventTaskFailed    = "task_failed"
+	EventAuthLockout   = "auth_lockout"
+	EventPing          = "ping"
+
+	HeaderEvent     = "X-OpenList-Event"
+	HeaderDelivery  = "X-OpenList-Delivery"
+	HeaderSignature = "X-OpenList-Signature"
+
+	// signatureExpire bounds how long a captured request can be replayed
+	signatureExpire = 5 * time.Minute
+	maxResponseSize = 4 * 1024
+	maxConcurrency  = 16
+	// maxPending caps the deliveries of a webhook being sent or waiting for a retry, the others are dropped
+	maxPending = 100
+	// DeliveryRetention is how long the delivery log is kept
+	DeliveryRetention = 30 * 24 * time.Hour
+)
+
+var (
+	// RetryBackoff is the delay before the first retry, it doubles for each following retry
+	RetryBackoff = 5 * time.Second
+	Client       = &http.Client{Timeout: 30 * time.Second}
+
+	sem = make(chan struct{}, maxConcurrency)
+
+	pendingMu sync.Mutex
+	pending   = make(map[uint]int)
+)
+
+// Payload is the json body posted to the webhook url
+type Payload struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+// Init subscribes to the op events and the lockouts, it should be called once when the server starts
+func Init() {
+	op.SubscribeEvents(func(e op.Event) {
+		Dispatch(string(e.Type), e.Path, e)
+	})
+	authguard.RegisterNotifier(func(l authguard.Lockout) {
+		Dispatch(EventAuthLockout, "", l)
+	})
+}
+
+// Dispatch sends the event to all the enabled webhooks accepting it.
+// path is used to match Webhook.PathPrefix, pass "" for events not bound to a path.
+func Dispatch(event, path string, data any) {
+	hooks, err := op.GetEnabledWebhooks()
+	if err != nil {
+		log.Errorf("failed get webhooks: %+v", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	body, err := utils.JSONTool.Marshal(Payload{Event: event, Time: time.Now(), Data: data})
+	if err != nil {
+		log.Errorf("failed marshal webhook payload: %+v", err)
+		return
+	}
+	for i := range hooks {
+		hook := hooks[i]
+		if !hook.Accept(event) || !matchPath(hook.PathPrefix, path) {
+			continue
+		}
+		if !reserve(hook.ID) {
+			log.Warnf("webhook [%s] has %d pending deliveries, the %s event is dropped", hook.Name, maxPending, event)
+			continue
+		}
+		go func() {
+			schedule(&hook, newDelivery(&hook, event, body), body, RetryBackoff)
+		}()
+	}
+}
+
+// reserve takes a pending slot of the webhook with id, it reports false if there are maxPending deliveries already
+func reserve(id uint) bool {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	if pending[id] >= maxPending {
+		return false
+	}
+	pending[id]++
+	return true
+}
+
+func release(id uint) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	if pending[id]--; pending[id] <= 0 {
+		delete(pending, id)
+	}
+}
+
+// schedule makes an attempt of delivery holding a concurrency slot, a failed attempt is retried after backoff
+// without holding anything
+func schedule(hook *model.Webhook, delivery *model.WebhookDelivery, body []byte, backoff time.Duration) {
+	sem <- struct{}{}
+	ok := attempt(hook, delivery, body)
+	<-sem
+	if ok || delivery.Attempts > hook.MaxRetry {
+		finish(delivery)
+		release(hook.ID)
+		return
+	}
+	time.AfterFunc(backoff, func() {
+		schedule(hook, delivery, body, backoff*2)
+	})
+}
+
+func matchPath(prefix, path string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	if path == "" {
+		return true
+	}
+	return utils.PathEqual(prefix, path) || utils.IsSubPath(prefix, path)
+}
+
+// Deliver posts body to the hook with retries and records the delivery, it waits for the retries
+// so it's for the deliveries made on demand such as a ping
+func Deliver(hook *model.Webhook, event string, body []byte) *model.WebhookDelivery {
+	delivery := newDelivery(hook, event, body)
+	backoff := RetryBackoff
+	for !attempt(hook, delivery, body) && delivery.Attempts <= hook.MaxRetry {
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	finish(delivery)
+	return delivery
+}
+
+// newDelivery records a delivery of body to the hook
+func newDelivery(hook *model.Webhook, event string, body []byte) *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     event,
+		Payload:   string(body),
+	}
+	if err := db.CreateWebhookDelivery(delivery); err != nil {
+		log.Errorf("failed create webhook delivery: %+v", err)
+	}
+	return delivery
+}
+
+// attempt sends delivery once, it reports whether it succeeded
+func attempt(hook *model.Webhook, delivery *model.WebhookDelivery, body []byte) bool {
+	delivery.Attempts++
+	delivery.StatusCode, delivery.Response, delivery.Error = 0, "", ""
+	if err := send(hook, delivery, body); err != nil {
+		delivery.Error = err.Error()
+		log.Warnf("webhook [%s] delivery %d attempt %d failed: %v", hook.Name, delivery.ID, delivery.Attempts, err)
+		return false
+	}
+	delivery.Success = true
+	return true
+}
+
+// finish records the result of delivery
+func finish(delivery *model.WebhookDelivery) {
+	if err := db.UpdateWebhookDelivery(delivery); err != nil {
+		log.Errorf("failed update webhook delivery: %+v", err)
+	}
+}
+
+func send(hook *model.Webhook, delivery *model.WebhookDelivery, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OpenList-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(hook.Secret, body))
+	}
+	resp, err := Client.Do(req)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	delivery.StatusCode = resp.StatusCode
+	delivery.Response = string(respBody)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errs.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign signs body with secret, receivers check it with sign.NewHMACSign([]byte(secret)).Verify(body, signature)
+func Sign(secret string, body []byte) string {
+	return sign.NewHMACSign([]byte(secret)).Sign(string(body), time.Now().Add(signatureExpire).Unix())
+}
+
+// CleanDeliveries removes the deliveries older than DeliveryRetention
+func CleanDeliveries() {
+	if err := db.DeleteWebhookDeliveriesBefore(time.Now().Add(-DeliveryRetention)); err != nil {
+		log.Errorf("failed clean webhook deliveries: %+v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package webhook_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/webhook"
+	"github.com/dongdio/OpenList/v4/utility/sign"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	webhook.RetryBackoff = time.Millisecond
+}
+
+func TestDeliverRetryAndSign(t *testing.T) {
+	const secret = "secret"
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := sign.NewHMACSign([]byte(secret)).Verify(string(body), r.Header.Get(webhook.HeaderSignature)); err != nil {
+			t.Errorf("invalid signature: %v", err)
+		}
+		if r.Header.Get(webhook.HeaderEvent) != "uploaded" {
+			t.Errorf("unexpected event header: %s", r.Header.Get(webhook.HeaderEvent))
+		}
+		// fail the first attempt to exercise the retry
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	hook := &model.Webhook{Name: "test", URL: srv.URL, Secret: secret, MaxRetry: 2}
+	if err := db.CreateWebhook(hook); err != nil {
+		t.Fatalf("failed create webhook: %+v", err)
+	}
+	delivery := webhook.Deliver(hook, "uploaded", []byte(`{"event":"uploaded"}`))
+	if !delivery.Success || delivery.Attempts != 2 || delivery.StatusCode != http.StatusOK || delivery.Response != "ok" {
+		t.Errorf("unexpected delivery: %+v", delivery)
+	}
+
+	deliveries, total, err := db.GetWebhookDeliveries(hook.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("failed get deliveries: %+v", err)
+	}
+	if total != 1 || !deliveries[0].Success {
+		t.Errorf("expected one successful delivery in the log, got %+v", deliveries)
+	}
+}
+
+func TestWebhookAccept(t *testing.T) {
+	hook := model.Webhook{Events: "uploaded, task_failed"}
+	for event, want := range map[string]bool{"uploaded": true, "task_failed": true, "removed": false} {
+		if got := hook.Accept(event); got != want {
+			t.Errorf("Accept(%s) = %v, want %v", event, got, want)
+		}
+	}
+}
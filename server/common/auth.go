@@ -3,13 +3,15 @@ package common
 import (
 	"time"
 
-	"github.com/OpenListTeam/go-cache"
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 
 	"github.com/dongdio/OpenList/v4/utility/errs"
 
+	"github.com/dongdio/OpenList/v4/internal/audit"
 	"github.com/dongdio/OpenList/v4/internal/conf"
 	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
 )
 
 // SecretKey 用于JWT签名的密钥
@@ -22,29 +24,71 @@ type UserClaims struct {
 	jwt.RegisteredClaims
 }
 
-// validTokenCache 有效token的缓存，用于支持主动使token失效
-var validTokenCache = cache.NewMemCache[bool]()
+// LoginToken 登录或刷新后下发的令牌
+type LoginToken struct {
+	Token        string `json:"token"`         // 访问令牌（JWT），jti为会话ID
+	RefreshToken string `json:"refresh_token"` // 刷新令牌，每次刷新后轮换
+	ExpiresAt    int64  `json:"expires_at"`    // 访问令牌的过期时间（Unix时间戳）
+}
 
-// GenerateToken 为指定用户生成JWT令牌
+// GenerateToken 为指定用户创建会话并生成令牌
+// 会话记录请求的IP和User-Agent，可在会话列表中查看和撤销
 //
 // 参数:
+//   - c: 登录请求的上下文
 //   - user: 用户对象
 //
 // 返回:
-//   - tokenString: 生成的令牌字符串
+//   - *LoginToken: 生成的令牌
 //   - err: 错误信息
-func GenerateToken(user *model.User) (tokenString string, err error) {
+func GenerateToken(c *gin.Context, user *model.User) (*LoginToken, error) {
 	// 检查用户是否为nil
 	if user == nil {
-		return "", errs.New("用户不能为空")
+		return nil, errs.New("用户不能为空")
+	}
+
+	session, refreshToken, err := op.CreateSession(user, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		return nil, errs.Wrap(err, "创建会话失败")
 	}
+	// 记录登录的审计日志
+	audit.Record(c.Request.Context(), model.AuditLog{
+		Action:   model.AuditLogin,
+		UserID:   user.ID,
+		Username: user.Username,
+		Detail:   session.Device,
+	}, nil)
+	return signToken(user, session.ID, refreshToken)
+}
+
+// RefreshToken 使用刷新令牌为同一会话换取新的令牌
+// 旧的刷新令牌随即失效，密码修改或会话被撤销后无法刷新
+//
+// 参数:
+//   - c: 刷新请求的上下文
+//   - refreshToken: 刷新令牌
+//
+// 返回:
+//   - *LoginToken: 新的令牌
+//   - err: 错误信息
+func RefreshToken(c *gin.Context, refreshToken string) (*LoginToken, error) {
+	session, user, newRefreshToken, err := op.RefreshSession(refreshToken, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		return nil, err
+	}
+	return signToken(user, session.ID, newRefreshToken)
+}
 
+// signToken 为会话签发访问令牌
+func signToken(user *model.User, sessionID, refreshToken string) (*LoginToken, error) {
 	// 创建JWT声明
+	expiresAt := time.Now().Add(time.Duration(conf.Conf.TokenExpiresIn) * time.Hour)
 	claim := UserClaims{
 		Username: user.Username,
 		PwdTS:    user.PwdTS,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(conf.Conf.TokenExpiresIn) * time.Hour)),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -52,14 +96,15 @@ func GenerateToken(user *model.User) (tokenString string, err error) {
 
 	// 创建并签名令牌
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
-	tokenString, err = token.SignedString(SecretKey)
+	tokenString, err := token.SignedString(SecretKey)
 	if err != nil {
-		return "", errs.Wrap(err, "签名令牌失败")
+		return nil, errs.Wrap(err, "签名令牌失败")
 	}
-
-	// 将令牌加入有效缓存
-	validTokenCache.Set(tokenString, true)
-	return tokenString, nil
+	return &LoginToken{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt.Unix(),
+	}, nil
 }
 
 // ParseToken 解析JWT令牌
@@ -76,11 +121,6 @@ func ParseToken(tokenString string) (*UserClaims, error) {
 		return nil, errs.New("令牌不能为空")
 	}
 
-	// 检查令牌是否已被注销
-	if IsTokenInvalidated(tokenString) {
-		return nil, errs.New("令牌已被注销")
-	}
-
 	// 解析令牌
 	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (any, error) {
 		return SecretKey, nil
@@ -108,15 +148,23 @@ func ParseToken(tokenString string) (*UserClaims, error) {
 		return nil, errs.New("无法解析令牌")
 	}
 
-	// 提取声明
+	// 提取声明，并检查会话是否已被撤销
 	if claims, ok := token.Claims.(*UserClaims); ok && token.Valid {
+		// 引入会话之前签发的令牌没有jti，无法撤销，需要重新登录
+		// 旧版本的令牌只保存在进程内存中，重启后本就失效，因此升级不会让原本有效的令牌失效
+		if claims.ID == "" {
+			return nil, errs.New("令牌缺少会话信息，请重新登录")
+		}
+		if _, err = op.GetValidSession(claims.ID); err != nil {
+			return nil, errs.New("令牌已被注销")
+		}
 		return claims, nil
 	}
 
 	return nil, errs.New("无法处理此令牌")
 }
 
-// InvalidateToken 使令牌失效
+// InvalidateToken 使令牌失效，即撤销令牌所属的会话
 //
 // 参数:
 //   - tokenString: 令牌字符串
@@ -129,19 +177,9 @@ func InvalidateToken(tokenString string) error {
 		return nil
 	}
 
-	// 从有效缓存中移除
-	validTokenCache.Del(tokenString)
-	return nil
-}
-
-// IsTokenInvalidated 检查令牌是否已被注销
-//
-// 参数:
-//   - tokenString: 令牌字符串
-//
-// 返回:
-//   - bool: 如果令牌已被注销返回true，否则返回false
-func IsTokenInvalidated(tokenString string) bool {
-	_, ok := validTokenCache.Get(tokenString)
-	return !ok
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return err
+	}
+	return op.DeleteSessionById(claims.ID)
 }
\ No newline at end of file
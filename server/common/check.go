@@ -26,15 +26,36 @@ func IsStorageSignEnabled(rawPath string) bool {
 	return storage != nil && storage.GetStorage().EnableSign
 }
 
+// IsACLSignRequired 检查访问控制规则是否禁止游客读取指定路径
+// 禁止时该路径的直链和代理链接需要签名，签名只发给允许读取的用户
+//
+// 参数:
+//   - rawPath: 原始路径
+//
+// 返回:
+//   - bool: 如果需要签名返回true，否则返回false
+func IsACLSignRequired(rawPath string) bool {
+	guest, err := op.GetGuest()
+	if err != nil {
+		return true
+	}
+	return op.CheckACL(guest, model.ACLRead, rawPath) != nil
+}
+
 // CanWrite 检查指定元数据和路径是否具有写入权限
+// 用户所在用户组对该路径的元数据覆盖允许写入时，同样具有写入权限
 //
 // 参数:
+//   - user: 用户对象，可以为nil
 //   - meta: 元数据对象
 //   - path: 请求路径
 //
 // 返回:
 //   - bool: 如果有写入权限返回true，否则返回false
-func CanWrite(meta *model.Meta, path string) bool {
+func CanWrite(user *model.User, meta *model.Meta, path string) bool {
+	if user != nil && user.GetMetaOverride(path).Write {
+		return true
+	}
 	if meta == nil || !meta.Write {
 		return false
 	}
@@ -75,9 +96,12 @@ func CanAccess(user *model.User, meta *model.Meta, reqPath string, password stri
 		return false
 	}
 
+	// 用户组对请求路径的元数据覆盖
+	override := user.GetMetaOverride(reqPath)
+
 	// 检查隐藏规则
 	// 如果元数据存在且用户不能查看隐藏内容，且元数据有隐藏规则，且规则应用于请求路径的父目录
-	if meta != nil && !user.CanSeeHides() && meta.Hide != "" &&
+	if meta != nil && !user.CanSeeHides() && !override.SeeHides && meta.Hide != "" &&
 		IsApply(meta.Path, path.Dir(reqPath), meta.HSub) {
 		// 检查文件名是否匹配隐藏规则
 		for _, hide := range strings.Split(meta.Hide, "\n") {
@@ -95,8 +119,8 @@ func CanAccess(user *model.User, meta *model.Meta, reqPath string, password stri
 		}
 	}
 
-	// 如果用户可以在没有密码的情况下访问
-	if user.CanAccessWithoutPassword() {
+	// 如果用户或用户组可以在没有密码的情况下访问
+	if user.CanAccessWithoutPassword() || override.NoPassword {
 		return true
 	}
 
@@ -16,6 +16,8 @@ import (
 	"github.com/dongdio/OpenList/v4/utility/errs"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
 	"github.com/dongdio/OpenList/v4/internal/conf"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
@@ -114,20 +116,25 @@ func (d *FtpMainDriver) ClientDisconnected(cc ftpserver.ClientContext) {
 func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftpserver.ClientDriver, error) {
 	var userObj *model.User
 	var err error
+	ip := cc.RemoteAddr().String()
 	if user == "anonymous" || user == "guest" {
 		userObj, err = op.GetGuest()
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		userObj, err = op.GetUserByName(user)
-		if err != nil {
-			return nil, err
+		if authguard.Locked(user, ip) > 0 {
+			return nil, errs.WithStack(errs.TooManyLoginAttempts)
 		}
-		passHash := model.StaticHash(pass)
-		if err = userObj.ValidatePwdStaticHash(passHash); err != nil {
+		userObj, err = ftpPasswordUser(user, pass)
+		if err != nil {
+			// the right password refused by the 2FA policy is not a guess
+			if !errs.Is(err, errs.APITokenRequired) {
+				authguard.Fail(user, ip, audit.ProtocolFTP)
+			}
 			return nil, err
 		}
+		authguard.Success(user, ip)
 	}
 	if userObj.Disabled || !userObj.CanFTPAccess() {
 		return nil, errs.New("user is not allowed to access via FTP")
@@ -140,11 +147,37 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 	} else {
 		ctx = context.WithValue(ctx, consts.MetaPassKey, "")
 	}
-	ctx = context.WithValue(ctx, consts.ClientIPKey, cc.RemoteAddr().String())
+	ctx = context.WithValue(ctx, consts.ClientIPKey, ip)
+	ctx = context.WithValue(ctx, consts.ProtocolKey, audit.ProtocolFTP)
 	ctx = context.WithValue(ctx, consts.ProxyHeaderKey, d.proxyHeader)
 	return ftp.NewAferoAdapter(ctx), nil
 }
 
+// ftpPasswordUser accepts the password or an api token of the user as the password
+func ftpPasswordUser(user, pass string) (*model.User, error) {
+	if op.IsAPIToken(pass) {
+		userObj, err := op.ValidateAPIToken(pass)
+		if err != nil {
+			return nil, err
+		}
+		if userObj.Username != user {
+			return nil, errs.WithStack(errs.InvalidAPIToken)
+		}
+		return userObj, nil
+	}
+	userObj, err := op.GetUserByName(user)
+	if err != nil {
+		return nil, err
+	}
+	if err = userObj.ValidatePwdStaticHash(model.StaticHash(pass)); err != nil {
+		return nil, err
+	}
+	if err = op.CheckPasswordLogin(userObj); err != nil {
+		return nil, err
+	}
+	return userObj, nil
+}
+
 func (d *FtpMainDriver) GetTLSConfig() (*tls.Config, error) {
 	if d.tlsConfig == nil {
 		return nil, errs.New("TLS config not provided")
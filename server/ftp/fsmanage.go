@@ -41,11 +41,16 @@ func Mkdir(ctx context.Context, path string) error {
 		}
 
 		// 检查目录的写入权限
-		if !common.CanWrite(meta, reqPath) {
+		if !common.CanWrite(user, meta, reqPath) {
 			return errs.PermissionDenied
 		}
 	}
 
+	// 存储不支持时提前拒绝
+	if !fs.GetCapabilities(ctx, reqPath).Mkdir {
+		return errs.NotImplement
+	}
+
 	// 创建目录
 	return fs.MakeDir(ctx, reqPath)
 }
@@ -71,6 +76,11 @@ func Remove(ctx context.Context, path string) error {
 		return err
 	}
 
+	// 存储不支持时提前拒绝
+	if !fs.GetCapabilities(ctx, reqPath).Remove {
+		return errs.NotImplement
+	}
+
 	// 删除文件或目录
 	return fs.Remove(ctx, reqPath)
 }
@@ -107,12 +117,19 @@ func Rename(ctx context.Context, oldPath, newPath string) error {
 		if !user.CanRename() || !user.CanFTPManage() {
 			return errs.PermissionDenied
 		}
+		if !fs.GetCapabilities(ctx, srcPath).Rename {
+			return errs.NotImplement
+		}
 		return fs.Rename(ctx, srcPath, dstBase)
 	} else {
 		// 不同目录下的移动操作（可能同时包含重命名）
 		if !user.CanFTPManage() || !user.CanMove() || (srcBase != dstBase && !user.CanRename()) {
 			return errs.PermissionDenied
 		}
+		// 移动可能跨存储，能否移动由 fs.Move 判断；需要改名时由目标存储重命名，提前检查目标存储的能力
+		if srcBase != dstBase && !fs.GetCapabilities(ctx, dstDir).Rename {
+			return errs.NotImplement
+		}
 
 		// 尝试移动文件/目录
 		if _, err = fs.Move(ctx, srcPath, dstDir); err != nil {
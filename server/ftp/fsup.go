@@ -53,11 +53,17 @@ func uploadAuth(ctx context.Context, path string) error {
 	// 检查权限
 	metaPass, _ := ctx.Value(consts.MetaPassKey).(string)
 	if !(common.CanAccess(user, meta, path, metaPass) &&
-		((user.CanFTPManage() && user.CanWrite()) || common.CanWrite(meta, stdpath.Dir(path)))) {
+		((user.CanFTPManage() && user.CanWrite()) || common.CanWrite(user, meta, stdpath.Dir(path)))) {
 		return errs.PermissionDenied
 	}
 
-	return nil
+	// 存储不支持上传时提前拒绝，避免先把数据写入临时文件
+	if !fs.GetCapabilities(ctx, path).Put {
+		return errs.NotImplement
+	}
+
+	// 大小未知，只检查配额是否已用完
+	return fs.CheckQuota(ctx, path, 0)
 }
 
 // OpenUpload 打开一个文件用于上传
@@ -176,6 +182,9 @@ func OpenUploadWithLength(ctx context.Context, path string, trunc bool, length i
 	if err != nil {
 		return nil, err
 	}
+	if err = fs.CheckQuota(ctx, path, length); err != nil {
+		return nil, err
+	}
 
 	// 如果需要截断，先删除原文件
 	if trunc {
@@ -0,0 +1,113 @@
+package handles
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+// ListACLRules returns a paginated list of acl rules in the evaluation order
+func ListACLRules(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+
+	rules, total, err := op.GetACLRules(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: rules,
+		Total:   total,
+	})
+}
+
+// GetACLRule retrieves an acl rule by ID
+func GetACLRule(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	rule, err := op.GetACLRuleById(id)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, rule)
+}
+
+// CreateACLRule creates a new acl rule
+func CreateACLRule(c *gin.Context) {
+	var req model.ACLRule
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.ID = 0
+	if err := op.CreateACLRule(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, req)
+}
+
+// UpdateACLRule updates an existing acl rule
+func UpdateACLRule(c *gin.Context) {
+	var req model.ACLRule
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateACLRule(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteACLRule deletes an acl rule
+func DeleteACLRule(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	if err := op.DeleteACLRuleById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// CheckACLReq asks whether a user can do an action on a mount path
+type CheckACLReq struct {
+	UserID uint   `form:"user_id" binding:"required"`
+	Action string `form:"action" binding:"required"`
+	Path   string `form:"path" binding:"required"`
+}
+
+// CheckACL answers whether the user can do the action on the path,
+// the answer is the one the fs api, the download links, WebDAV, FTP, SFTP and S3 get
+func CheckACL(c *gin.Context) {
+	var req CheckACLReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user, err := op.GetUserById(req.UserID)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	decision, err := op.DecideACL(user, req.Action, req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, decision)
+}
@@ -0,0 +1,93 @@
+package handles
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+// APITokenCreateResp carries the token, it's only returned once
+type APITokenCreateResp struct {
+	model.APIToken
+	Token string `json:"token"`
+	// S3AccessKeyID and S3SecretAccessKey let the token sign S3 requests
+	S3AccessKeyID     string `json:"s3_access_key_id"`
+	S3SecretAccessKey string `json:"s3_secret_access_key"`
+}
+
+// ListMyAPITokens returns the api tokens of the current user
+func ListMyAPITokens(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	tokens, total, err := op.GetAPITokensByUserId(user.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: tokens,
+		Total:   total,
+	})
+}
+
+// CreateMyAPIToken creates an api token for the current user
+func CreateMyAPIToken(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.APIToken
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	token, err := op.CreateAPIToken(user, &req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, APITokenCreateResp{
+		APIToken:          req,
+		Token:             token,
+		S3AccessKeyID:     op.APITokenAccessKeyID(&req),
+		S3SecretAccessKey: op.APITokenS3Secret(&req),
+	})
+}
+
+// UpdateMyAPIToken updates the name, scope and expiry of an api token of the current user
+func UpdateMyAPIToken(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.APIToken
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateAPIToken(user, &req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteMyAPIToken revokes an api token of the current user
+func DeleteMyAPIToken(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	if _, err := op.GetAPITokenByIdAndUserId(id, user.ID); err != nil {
+		common.ErrorStrResp(c, "failed to get api token", 404)
+		return
+	}
+	if err := op.DeleteAPITokenById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
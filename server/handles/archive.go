@@ -1,6 +1,7 @@
 package handles
 
 import (
+	"context"
 	"fmt"
 	stdpath "path"
 
@@ -278,6 +279,8 @@ type ArchiveDecompressReq struct {
 	InnerPath     string        `json:"inner_path" form:"inner_path"`
 	CacheFull     bool          `json:"cache_full" form:"cache_full"`
 	PutIntoNewDir bool          `json:"put_into_new_dir" form:"put_into_new_dir"`
+	// Verify 上传后校验解压出的文件
+	Verify bool `json:"verify" form:"verify"`
 }
 
 // FsArchiveDecompress 解压缩归档文件
@@ -319,10 +322,15 @@ func FsArchiveDecompress(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	if req.Verify {
+		ctx = context.WithValue(ctx, consts.VerifyKey, true)
+	}
+
 	// 处理每个源文件
 	tasks := make([]task.TaskExtensionInfo, 0, len(srcPaths))
 	for _, srcPath := range srcPaths {
-		tk, err := fs.ArchiveDecompress(c.Request.Context(), srcPath, dstDir, model.ArchiveDecompressArgs{
+		tk, err := fs.ArchiveDecompress(ctx, srcPath, dstDir, model.ArchiveDecompressArgs{
 			ArchiveInnerArgs: model.ArchiveInnerArgs{
 				ArchiveArgs: model.ArchiveArgs{
 					LinkArgs: model.LinkArgs{
@@ -0,0 +1,65 @@
+package handles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+// ListAuditLogsReq filters and paginates the audit log
+type ListAuditLogsReq struct {
+	model.PageReq
+	model.AuditQuery
+}
+
+// ListAuditLogs returns the audit logs matching the filters, the latest first
+func ListAuditLogs(c *gin.Context) {
+	var req ListAuditLogsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	logs, total, err := audit.GetAuditLogs(&req.AuditQuery, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: logs,
+		Total:   total,
+	})
+}
+
+// ExportAuditLogs streams the audit logs matching the filters as csv or jsonl, the oldest first
+func ExportAuditLogs(c *gin.Context) {
+	var req model.AuditQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	format := c.DefaultQuery("format", audit.FormatCSV)
+	var contentType string
+	switch format {
+	case audit.FormatCSV:
+		contentType = "text/csv; charset=utf-8"
+	case audit.FormatJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		common.ErrorStrResp(c, "Unsupported format, must be csv or jsonl", 400)
+		return
+	}
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	// the header is sent already, an error can only be logged
+	if err := audit.Export(c.Writer, &req, format); err != nil {
+		log.Errorf("failed export audit logs: %+v", err)
+	}
+}
@@ -4,22 +4,22 @@ import (
 	"bytes"
 	"encoding/base64"
 	"image/png"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pquerna/otp/totp"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
 )
 
 const (
-	// 登录尝试缓存过期时间
-	defaultLoginCacheDuration = time.Minute * 5
-	// 最大登录尝试次数，超过此次数将进行速率限制
-	defaultMaxLoginAttempts = 5
 	// 2FA OTP 发行方名称
 	otpIssuerName = "OpenList"
 	// QR 码尺寸
@@ -60,12 +60,9 @@ func LoginHash(c *gin.Context) {
 
 // processLogin 处理 Login 和 LoginHash 的通用登录逻辑
 func processLogin(c *gin.Context, req *LoginRequest) {
-	// 检查速率限制
+	// 检查账号和 IP 是否已被锁定
 	ip := c.ClientIP()
-	attempts, ok := model.LoginCache.Get(ip)
-	if ok && attempts >= defaultMaxLoginAttempts {
-		common.ErrorStrResp(c, "Too many unsuccessful sign-in attempts have been made using an incorrect username or password. Try again later.", 429)
-		model.LoginCache.Expire(ip, defaultLoginCacheDuration)
+	if loginLocked(c, req.Username) {
 		return
 	}
 
@@ -73,56 +70,87 @@ func processLogin(c *gin.Context, req *LoginRequest) {
 	user, err := op.GetUserByName(req.Username)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
-		incrementLoginAttempts(ip)
+		loginFailed(c, req.Username, err)
 		return
 	}
 
 	if err = user.ValidatePwdStaticHash(req.Password); err != nil {
 		common.ErrorResp(c, err, 400)
-		incrementLoginAttempts(ip)
+		loginFailed(c, req.Username, err)
 		return
 	}
 
-	// 如果启用了 2FA，验证 OTP 码
-	if user.OtpSecret != "" {
-		if req.OtpCode == "" {
-			common.ErrorStrResp(c, "2FA code is required", 400)
-			incrementLoginAttempts(ip)
-			return
-		}
-
-		if !totp.Validate(req.OtpCode, user.OtpSecret) {
-			common.ErrorStrResp(c, "Invalid 2FA code", 402)
-			incrementLoginAttempts(ip)
-			return
-		}
+	// 如果启用了 2FA，验证第二因素
+	if !checkSecondFactor(c, user, req.OtpCode) {
+		return
 	}
 
 	// 生成身份验证令牌
-	token, err := common.GenerateToken(user)
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 500, true)
 		return
 	}
 
-	// 登录成功 - 清除速率限制计数器
-	model.LoginCache.Del(ip)
-	common.SuccessResp(c, gin.H{"token": token})
+	// 登录成功 - 清除账号和 IP 的失败计数
+	authguard.Success(req.Username, ip)
+	common.SuccessResp(c, token)
+}
+
+// checkSecondFactor 校验密码登录的第二因素，所有使用密码的登录方式都要调用
+// 启用了 TOTP 时需要 OTP 码或一次性恢复码；只注册了通行密钥时只接受恢复码，否则只能使用通行密钥登录
+func checkSecondFactor(c *gin.Context, user *model.User, code string) bool {
+	if !op.HasTwoFactor(user) {
+		return true
+	}
+	if code == "" {
+		if user.OtpSecret == "" {
+			common.ErrorStrResp(c, "This account signs in with its passkey or a recovery code", 400)
+		} else {
+			common.ErrorStrResp(c, "2FA code is required", 400)
+		}
+		loginFailed(c, user.Username, errs.New("2FA code is required"))
+		return false
+	}
+	if err := op.ValidateTwoFactorCode(user, code); err != nil {
+		common.ErrorStrResp(c, "Invalid 2FA code", 402)
+		loginFailed(c, user.Username, errs.New("invalid 2FA code"))
+		return false
+	}
+	return true
 }
 
-// incrementLoginAttempts 增加 IP 的失败登录尝试次数
-func incrementLoginAttempts(ip string) {
-	count, ok := model.LoginCache.Get(ip)
-	if !ok {
-		count = 0
+// loginLocked 检查账号或 IP 是否因多次登录失败被锁定，已锁定时返回 429 并设置 Retry-After
+func loginLocked(c *gin.Context, username string) bool {
+	remaining := authguard.Locked(username, c.ClientIP())
+	if remaining <= 0 {
+		return false
 	}
-	model.LoginCache.Set(ip, count+1)
+	c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+	common.ErrorStrResp(c, "Too many unsuccessful sign-in attempts have been made using an incorrect username or password. Try again later.", 429)
+	return true
+}
+
+// loginFailed 记录失败的登录并计入账号和 IP 的失败次数
+func loginFailed(c *gin.Context, username string, err error) {
+	authguard.Fail(username, c.ClientIP(), audit.ProtocolWeb)
+	auditLoginFailure(c, username, err)
+}
+
+// auditLoginFailure 记录失败的登录
+func auditLoginFailure(c *gin.Context, username string, err error) {
+	audit.Record(c.Request.Context(), model.AuditLog{Action: model.AuditLogin, Username: username}, err)
 }
 
 // UserResponse 扩展 User 模型，用于 API 响应
 type UserResponse struct {
 	model.User
-	HasOTP bool `json:"otp"` // 指示是否启用了 2FA
+	HasOTP        bool  `json:"otp"`            // 指示是否启用了 2FA
+	RecoveryCodes int64 `json:"recovery_codes"` // 剩余的恢复码数量
+	QuotaUsed     int64 `json:"quota_used"`     // 已使用的配额字节数
+	// Require2FA 指示策略要求该用户启用 2FA 或通行密钥，TwoFactorDeadline 为宽限期结束时间
+	Require2FA        bool       `json:"require_2fa"`
+	TwoFactorDeadline *time.Time `json:"two_factor_deadline,omitempty"`
 }
 
 // CurrentUser 返回当前认证用户的信息
@@ -141,6 +169,21 @@ func CurrentUser(c *gin.Context) {
 	// 设置 OTP 标志（如果启用了 2FA）
 	if user.OtpSecret != "" {
 		userResp.HasOTP = true
+		userResp.RecoveryCodes, _ = op.CountRecoveryCodes(user.ID)
+	}
+
+	// 2FA 策略要求但尚未启用时返回宽限期
+	if op.TwoFactorRequired(user) {
+		userResp.Require2FA = true
+		if !op.HasTwoFactor(user) {
+			if deadline, err := op.TwoFactorDeadline(user); err == nil {
+				userResp.TwoFactorDeadline = &deadline
+			}
+		}
+	}
+
+	if q, err := op.GetUserQuota(user); err == nil {
+		userResp.QuotaUsed = q.Used
 	}
 
 	common.SuccessResp(c, userResp)
@@ -263,6 +306,70 @@ func Verify2FA(c *gin.Context) {
 		return
 	}
 
+	// 生成恢复码，仅在此时返回一次
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"recovery_codes": codes})
+}
+
+// TwoFACodeRequest 需要 2FA 代码确认的请求参数
+type TwoFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RegenerateRecoveryCodes 为当前用户重新生成恢复码，旧的恢复码立即失效
+// 需要提供当前的 2FA 代码，不接受恢复码
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFACodeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Value(consts.UserKey).(*model.User)
+	if user.OtpSecret == "" {
+		common.ErrorStrResp(c, "2FA is not enabled", 400)
+		return
+	}
+	if !totp.Validate(req.Code, user.OtpSecret) {
+		common.ErrorStrResp(c, "Invalid 2FA code", 400)
+		return
+	}
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"recovery_codes": codes})
+}
+
+// Cancel2FA 关闭当前用户的 2FA，需要提供 2FA 代码或恢复码
+func Cancel2FA(c *gin.Context) {
+	var req TwoFACodeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Value(consts.UserKey).(*model.User)
+	if user.OtpSecret == "" {
+		common.ErrorStrResp(c, "2FA is not enabled", 400)
+		return
+	}
+	if err := op.ValidateTwoFactorCode(user, req.Code); err != nil {
+		common.ErrorStrResp(c, "Invalid 2FA code", 400)
+		return
+	}
+	// 策略要求 2FA 时只有已注册通行密钥的用户可以关闭 TOTP
+	if op.TwoFactorRequired(user) && (user.Authn == "" || len(user.WebAuthnCredentials()) == 0) {
+		common.ErrorResp(c, errs.TwoFactorRequired, 403)
+		return
+	}
+	if err := op.Cancel2FAByUser(user); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
 	common.SuccessResp(c)
 }
 
@@ -22,8 +22,13 @@ func ListDriverNames(c *gin.Context) {
 }
 
 // GetDriverInfo returns detailed information about a specific storage driver
-// The driver name must be provided as a query parameter
+// The driver name must be provided as a query parameter, or a path to get the driver
+// of the storage mounted there with the capabilities resolved for that path
 func GetDriverInfo(c *gin.Context) {
+	if path := c.Query("path"); path != "" {
+		getStorageDriverInfo(c, path)
+		return
+	}
 	driverName := c.Query("driver")
 	if driverName == "" {
 		common.ErrorStrResp(c, "driver name is required", 400)
@@ -37,5 +42,21 @@ func GetDriverInfo(c *gin.Context) {
 		return
 	}
 
+	common.SuccessResp(c, driverInfo)
+}
+
+// getStorageDriverInfo returns the driver information of the storage mounted at path
+func getStorageDriverInfo(c *gin.Context, path string) {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return
+	}
+	driverInfo, exists := op.GetDriverInfoMap()[storage.Config().Name]
+	if !exists {
+		common.ErrorStrResp(c, fmt.Sprintf("driver [%s] not found", storage.Config().Name), 404)
+		return
+	}
+	driverInfo.Capabilities = op.GetStorageCapabilities(c.Request.Context(), storage, actualPath)
 	common.SuccessResp(c, driverInfo)
 }
\ No newline at end of file
@@ -0,0 +1,214 @@
+package handles
+
+import (
+	stdpath "path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/fs"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// maxRenameAttempts bounds the names tried for an upload of a file request
+const maxRenameAttempts = 1000
+
+// FileRequestInfoResp is what an uploader of a file request can know about it
+type FileRequestInfoResp struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Owner        string     `json:"owner"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	MaxFileSize  int64      `json:"max_file_size"`
+	AllowedTypes string     `json:"allowed_types"`
+	// Remaining is the bytes can still be uploaded, -1 means no limit
+	Remaining int64  `json:"remaining"`
+	Remark    string `json:"remark"`
+}
+
+// fileRequestRename returns the first name not taken in the folder of path following the rename policy
+// of the file request of the request, path is returned as it is for the other uploads
+func fileRequestRename(c *gin.Context, path string) (string, error) {
+	r, ok := c.Value(consts.FileRequestKey).(*model.FileRequest)
+	if !ok {
+		return path, nil
+	}
+	dir, name := stdpath.Split(path)
+	now := time.Now()
+	for n := 0; n < maxRenameAttempts; n++ {
+		candidate := stdpath.Join(dir, r.CandidateName(name, n, now))
+		if obj, _ := fs.Get(c.Request.Context(), candidate, &fs.GetArgs{NoLog: true}); obj == nil {
+			return candidate, nil
+		}
+	}
+	return "", errs.Errorf("no available name for %s", name)
+}
+
+// FileRequestInfo returns the file request to an uploader
+func FileRequestInfo(c *gin.Context) {
+	r := c.Value(consts.FileRequestKey).(*model.FileRequest)
+	owner, err := op.GetUserById(r.UserID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	remaining := int64(-1)
+	if r.Quota > 0 {
+		remaining = max(r.Quota-r.Used, 0)
+	}
+	common.SuccessResp(c, FileRequestInfoResp{
+		ID:           r.ID,
+		Name:         stdpath.Base(r.Path),
+		Owner:        owner.Username,
+		ExpiresAt:    r.ExpiresAt,
+		MaxFileSize:  r.MaxFileSize,
+		AllowedTypes: r.AllowedTypes,
+		Remaining:    remaining,
+		Remark:       r.Remark,
+	})
+}
+
+// ListMyFileRequests returns the file requests of the current user with their upload statistics
+func ListMyFileRequests(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	listFileRequests(c, user.ID)
+}
+
+// CreateMyFileRequest creates a file request uploading into a folder of the current user,
+// the path is relative to the base path of the user
+func CreateMyFileRequest(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.FileRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if !obj.IsDir() {
+		common.ErrorStrResp(c, "the path of a file request must be a folder", 400)
+		return
+	}
+	req.Path = reqPath
+	if err = op.CreateFileRequest(user, &req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.MaskPassword()
+	common.SuccessResp(c, req)
+}
+
+// UpdateMyFileRequest updates a file request of the current user
+func UpdateMyFileRequest(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.FileRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if _, err := op.GetFileRequestByIdAndUserId(req.ID, user.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateFileRequest(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteMyFileRequest deletes a file request of the current user, the link stops working at once
+func DeleteMyFileRequest(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	id := c.Query("id")
+	if _, err := op.GetFileRequestByIdAndUserId(id, user.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.DeleteFileRequestById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// ListFileRequests returns the file requests of all the users, or of the user in the user_id query for the admin
+func ListFileRequests(c *gin.Context) {
+	var userID int
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		var err error
+		if userID, err = strconv.Atoi(userIDStr); err != nil {
+			common.ErrorStrResp(c, "Invalid user_id format, must be a number", 400)
+			return
+		}
+	}
+	listFileRequests(c, uint(userID))
+}
+
+func listFileRequests(c *gin.Context, userID uint) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	requests, total, err := op.GetFileRequests(userID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	for i := range requests {
+		requests[i].MaskPassword()
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: requests,
+		Total:   total,
+	})
+}
+
+// GetFileRequest returns a file request for the admin
+func GetFileRequest(c *gin.Context) {
+	r, err := op.GetFileRequestById(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return
+	}
+	r.MaskPassword()
+	common.SuccessResp(c, r)
+}
+
+// UpdateFileRequest updates any file request for the admin
+func UpdateFileRequest(c *gin.Context) {
+	var req model.FileRequest
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateFileRequest(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteFileRequest deletes any file request for the admin
+func DeleteFileRequest(c *gin.Context) {
+	if err := op.DeleteFileRequestById(c.Query("id")); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -1,6 +1,7 @@
 package handles
 
 import (
+	"context"
 	"fmt"
 	stdpath "path"
 	"strings"
@@ -14,7 +15,6 @@ import (
 	"github.com/dongdio/OpenList/v4/internal/sign"
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/utility/errs"
-	"github.com/dongdio/OpenList/v4/utility/generic"
 	"github.com/dongdio/OpenList/v4/utility/task"
 	"github.com/dongdio/OpenList/v4/utility/utils"
 )
@@ -47,7 +47,7 @@ func FsMkdir(c *gin.Context) {
 			return
 		}
 
-		if !common.CanWrite(meta, reqPath) {
+		if !common.CanWrite(user, meta, reqPath) {
 			common.ErrorResp(c, errs.PermissionDenied, 403)
 			return
 		}
@@ -67,6 +67,8 @@ type MoveCopyReq struct {
 	DstDir    string   `json:"dst_dir" binding:"required"`
 	Names     []string `json:"names" binding:"required"`
 	Overwrite bool     `json:"overwrite"`
+	// Verify 跨存储传输后校验目标文件与源文件一致
+	Verify bool `json:"verify"`
 }
 
 // FsMove 文件移动处理函数
@@ -101,6 +103,9 @@ func FsMove(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	if req.Verify {
+		ctx = context.WithValue(ctx, consts.VerifyKey, true)
+	}
 	if !req.Overwrite {
 		for _, name := range req.Names {
 			if res, _ := fs.Get(ctx, stdpath.Join(dstDir, name), &fs.GetArgs{NoLog: true}); res != nil {
@@ -171,6 +176,9 @@ func FsCopy(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	if req.Verify {
+		ctx = context.WithValue(ctx, consts.VerifyKey, true)
+	}
 	if !req.Overwrite {
 		for _, name := range req.Names {
 			if res, _ := fs.Get(ctx, stdpath.Join(dstDir, name), &fs.GetArgs{NoLog: true}); res != nil {
@@ -334,80 +342,11 @@ func FsRemoveEmptyDirectory(c *gin.Context) {
 		return
 	}
 
-	meta, err := op.GetNearestMeta(srcDir)
-	if err != nil && !errs.Is(errs.Cause(err), errs.MetaNotFound) {
-		common.ErrorResp(c, err, 500, true)
-		return
-	}
-	common.GinWithValue(c, consts.MetaKey, meta)
-
-	ctx := c.Request.Context()
-	rootFiles, err := fs.List(ctx, srcDir, &fs.ListArgs{})
-	if err != nil {
+	if err = fs.RemoveEmptyDirectory(c.Request.Context(), srcDir); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
 
-	// 记录文件路径
-	filePathMap := make(map[model.Obj]string)
-	// 记录父文件
-	fileParentMap := make(map[model.Obj]model.Obj)
-	// 待删除文件队列
-	removingFiles := generic.NewQueue[model.Obj]()
-	// 已删除文件记录
-	removedFiles := make(map[string]bool)
-
-	// 初始化队列，添加所有顶层目录
-	for _, file := range rootFiles {
-		if !file.IsDir() {
-			continue
-		}
-		removingFiles.Push(file)
-		filePathMap[file] = srcDir
-	}
-
-	// 递归处理空目录
-	for !removingFiles.IsEmpty() {
-		removingFile := removingFiles.Pop()
-		removingFilePath := stdpath.Join(filePathMap[removingFile], removingFile.GetName())
-
-		if removedFiles[removingFilePath] {
-			continue
-		}
-
-		subFiles, err := fs.List(ctx, removingFilePath, &fs.ListArgs{Refresh: true})
-		if err != nil {
-			common.ErrorResp(c, err, 500)
-			return
-		}
-
-		if len(subFiles) == 0 {
-			// 删除空目录
-			err = fs.Remove(ctx, removingFilePath)
-			if err != nil {
-				common.ErrorResp(c, err, 500)
-				return
-			}
-			removedFiles[removingFilePath] = true
-
-			// 重新检查父文件夹
-			parentFile, exist := fileParentMap[removingFile]
-			if exist {
-				removingFiles.Push(parentFile)
-			}
-		} else {
-			// 递归处理子目录
-			for _, subFile := range subFiles {
-				if !subFile.IsDir() {
-					continue
-				}
-				removingFiles.Push(subFile)
-				filePathMap[subFile] = removingFilePath
-				fileParentMap[subFile] = removingFile
-			}
-		}
-	}
-
 	common.SuccessResp(c)
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/driver"
 	"github.com/dongdio/OpenList/v4/internal/fs"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
@@ -58,6 +59,8 @@ type FsListResp struct {
 	Header   string    `json:"header"`
 	Write    bool      `json:"write"`
 	Provider string    `json:"provider"`
+	// Capabilities 当前路径所在存储支持的操作
+	Capabilities driver.Capabilities `json:"capabilities"`
 }
 
 // FsList 获取文件列表
@@ -94,7 +97,7 @@ func FsList(c *gin.Context) {
 	}
 
 	// 检查刷新权限
-	if !user.CanWrite() && !common.CanWrite(meta, reqPath) && req.Refresh {
+	if !user.CanWrite() && !common.CanWrite(user, meta, reqPath) && req.Refresh {
 		common.ErrorStrResp(c, "Refresh without permission", 403)
 		return
 	}
@@ -118,12 +121,13 @@ func FsList(c *gin.Context) {
 
 	// 返回结果
 	common.SuccessResp(c, FsListResp{
-		Content:  toObjsResp(objs, reqPath, isEncrypt(meta, reqPath)),
-		Total:    int64(total),
-		Readme:   getReadme(meta, reqPath),
-		Header:   getHeader(meta, reqPath),
-		Write:    user.CanWrite() || common.CanWrite(meta, reqPath),
-		Provider: provider,
+		Content:      toObjsResp(user, objs, reqPath, isEncrypt(meta, reqPath)),
+		Total:        int64(total),
+		Readme:       getReadme(meta, reqPath),
+		Header:       getHeader(meta, reqPath),
+		Write:        user.CanWrite() || common.CanWrite(user, meta, reqPath),
+		Provider:     provider,
+		Capabilities: fs.GetCapabilities(c.Request.Context(), reqPath),
 	})
 }
 
@@ -218,7 +222,7 @@ func getHeader(meta *model.Meta, path string) string {
 
 // isEncrypt 检查路径是否加密
 func isEncrypt(meta *model.Meta, path string) bool {
-	if common.IsStorageSignEnabled(path) {
+	if common.IsStorageSignEnabled(path) || common.IsACLSignRequired(path) {
 		return true
 	}
 	if meta == nil || meta.Password == "" {
@@ -250,8 +254,18 @@ func pagination(objs []model.Obj, req *model.PageReq) (int, []model.Obj) {
 	return total, objs[start:end]
 }
 
+// objSign 生成对象的签名
+// 访问控制规则禁止用户读取时不生成签名，禁止游客读取时必须签名
+func objSign(user *model.User, obj model.Obj, parent string, encrypt bool) string {
+	objPath := stdpath.Join(parent, obj.GetName())
+	if op.CheckACL(user, model.ACLRead, objPath) != nil {
+		return ""
+	}
+	return common.Sign(obj, parent, encrypt || common.IsACLSignRequired(objPath))
+}
+
 // toObjsResp 转换对象为响应格式
-func toObjsResp(objs []model.Obj, parent string, encrypt bool) []ObjResp {
+func toObjsResp(user *model.User, objs []model.Obj, parent string, encrypt bool) []ObjResp {
 	resp := make([]ObjResp, 0, len(objs))
 
 	for _, obj := range objs {
@@ -266,7 +280,7 @@ func toObjsResp(objs []model.Obj, parent string, encrypt bool) []ObjResp {
 			Created:     obj.CreateTime(),
 			HashInfoStr: obj.GetHash().String(),
 			HashInfo:    obj.GetHash().Export(),
-			Sign:        common.Sign(obj, parent, encrypt),
+			Sign:        objSign(user, obj, parent, encrypt),
 			Thumb:       thumb,
 			Type:        utils.GetObjType(obj.GetName(), obj.IsDir()),
 		})
@@ -289,6 +303,8 @@ type FsGetResp struct {
 	Header   string    `json:"header"`
 	Provider string    `json:"provider"`
 	Related  []ObjResp `json:"related"`
+	// Capabilities 文件所在存储支持的操作
+	Capabilities driver.Capabilities `json:"capabilities"`
 }
 
 // FsGet 获取文件信息
@@ -339,8 +355,8 @@ func FsGet(c *gin.Context) {
 		return
 	}
 
-	// 处理非目录文件
-	if !obj.IsDir() {
+	// 处理非目录文件，访问控制规则禁止读取时不返回链接
+	if !obj.IsDir() && fs.CheckACL(c.Request.Context(), model.ACLRead, reqPath) == nil {
 		if storage.Config().MustProxy() || storage.GetStorage().WebProxy {
 			rawURL = common.GenerateDownProxyURL(storage.GetStorage(), reqPath)
 			if rawURL == "" {
@@ -397,15 +413,16 @@ func FsGet(c *gin.Context) {
 			Created:     obj.CreateTime(),
 			HashInfoStr: obj.GetHash().String(),
 			HashInfo:    obj.GetHash().Export(),
-			Sign:        common.Sign(obj, parentPath, isEncrypt(meta, reqPath)),
+			Sign:        objSign(user, obj, parentPath, isEncrypt(meta, reqPath)),
 			Type:        utils.GetFileType(obj.GetName()),
 			Thumb:       thumb,
 		},
-		RawURL:   rawURL,
-		Readme:   getReadme(meta, reqPath),
-		Header:   getHeader(meta, reqPath),
-		Provider: provider,
-		Related:  toObjsResp(related, parentPath, isEncrypt(parentMeta, parentPath)),
+		RawURL:       rawURL,
+		Readme:       getReadme(meta, reqPath),
+		Header:       getHeader(meta, reqPath),
+		Provider:     provider,
+		Related:      toObjsResp(user, related, parentPath, isEncrypt(parentMeta, parentPath)),
+		Capabilities: fs.GetCapabilities(c.Request.Context(), reqPath),
 	})
 }
 
@@ -0,0 +1,82 @@
+package handles
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/fs"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/task"
+)
+
+// SyncReq 同步请求
+type SyncReq struct {
+	SrcDir string `json:"src_dir" binding:"required"`
+	DstDir string `json:"dst_dir" binding:"required"`
+	// Delete 删除目标目录中源目录不存在的文件
+	Delete  bool     `json:"delete"`
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	Verify  bool     `json:"verify"`
+	// DryRun 只返回需要执行的操作，不实际执行
+	DryRun bool `json:"dry_run"`
+}
+
+// FsSync 将源目录单向同步到目标目录，只传输新增或变化的文件
+func FsSync(c *gin.Context) {
+	var req SyncReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.Value(consts.UserKey).(*model.User)
+	if !user.CanCopy() || (req.Delete && !user.CanRemove()) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+
+	srcDir, err := user.JoinPath(req.SrcDir)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+
+	dstDir, err := user.JoinPath(req.DstDir)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+
+	args := fs.SyncArgs{
+		SrcPath: srcDir,
+		DstPath: dstDir,
+		Delete:  req.Delete,
+		Include: req.Include,
+		Exclude: req.Exclude,
+		Verify:  req.Verify,
+	}
+	ctx := c.Request.Context()
+	if req.DryRun {
+		actions, err := fs.SyncPlan(ctx, args)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		common.SuccessResp(c, gin.H{
+			"actions": actions,
+		})
+		return
+	}
+
+	t, err := fs.Sync(ctx, args)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"tasks": getTaskInfos([]task.TaskExtensionInfo{t}),
+	})
+}
@@ -0,0 +1,146 @@
+package handles
+
+import (
+	"context"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// TrashListReq 回收站列表请求，列出从 Path 下删除的对象
+type TrashListReq struct {
+	model.PageReq
+	Path string `json:"path" form:"path"`
+}
+
+// TrashItemResp 回收站对象，Path 为删除前的路径
+type TrashItemResp struct {
+	ID        uint      `json:"id"`
+	Path      string    `json:"path"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	IsDir     bool      `json:"is_dir"`
+	Deleter   string    `json:"deleter"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TrashReq 还原或彻底删除回收站对象的请求
+type TrashReq struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// FsTrashList 列出回收站中的对象
+func FsTrashList(c *gin.Context) {
+	var req TrashListReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+
+	user := c.Value(consts.UserKey).(*model.User)
+	if !user.CanRemove() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(reqPath)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	items, total, err := op.GetTrashItems(storage, actualPath, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	mountPath := storage.GetStorage().MountPath
+	content := make([]TrashItemResp, 0, len(items))
+	for _, item := range items {
+		content = append(content, TrashItemResp{
+			ID:        item.ID,
+			Path:      userRelPath(user, stdpath.Join(mountPath, item.Path)),
+			Name:      item.Name,
+			Size:      item.Size,
+			IsDir:     item.IsDir,
+			Deleter:   item.Deleter,
+			DeletedAt: item.DeletedAt,
+		})
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: content,
+		Total:   total,
+	})
+}
+
+// FsTrashRestore 将对象还原到删除前的位置
+func FsTrashRestore(c *gin.Context) {
+	handleTrashItems(c, op.RestoreTrashItem)
+}
+
+// FsTrashPurge 从回收站中彻底删除对象
+func FsTrashPurge(c *gin.Context) {
+	handleTrashItems(c, op.PurgeTrashItem)
+}
+
+func handleTrashItems(c *gin.Context, handle func(ctx context.Context, item *model.TrashItem) error) {
+	var req TrashReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.Value(consts.UserKey).(*model.User)
+	if !user.CanRemove() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+
+	ctx := c.Request.Context()
+	for _, id := range req.IDs {
+		item, err := op.GetTrashItemById(id)
+		if err != nil {
+			common.ErrorResp(c, err, 404)
+			return
+		}
+		storage, err := op.GetTrashItemStorage(item)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		// 只能处理基础路径下删除的对象
+		fullPath := stdpath.Join(storage.GetStorage().MountPath, item.Path)
+		if !utils.PathEqual(user.GetBasePath(), fullPath) && !utils.IsSubPath(user.GetBasePath(), fullPath) {
+			common.ErrorResp(c, errs.PermissionDenied, 403)
+			return
+		}
+		if err = handle(ctx, item); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+
+	common.SuccessResp(c)
+}
+
+// userRelPath 将完整路径转换为相对于用户基础路径的路径
+func userRelPath(user *model.User, fullPath string) string {
+	if utils.PathEqual(user.GetBasePath(), "/") {
+		return fullPath
+	}
+	return utils.FixAndCleanPath(strings.TrimPrefix(fullPath, utils.FixAndCleanPath(user.GetBasePath())))
+}
@@ -88,6 +88,13 @@ func FsStream(c *gin.Context) {
 		return
 	}
 
+	// 文件请求链接上传的文件按链接的策略自动重命名，不覆盖已有文件
+	path, err = fileRequestRename(c, path)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
 	// 检查文件是否存在（如果不允许覆盖）
 	if !overwrite {
 		if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
@@ -189,6 +196,13 @@ func FsForm(c *gin.Context) {
 		return
 	}
 
+	// 文件请求链接上传的文件按链接的策略自动重命名，不覆盖已有文件
+	path, err = fileRequestRename(c, path)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
 	// 检查文件是否存在（如果不允许覆盖）
 	if !overwrite {
 		if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
@@ -0,0 +1,242 @@
+package handles
+
+import (
+	"io"
+	"net/http"
+	stdpath "path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const (
+	watchBufferSize   = 256
+	watchPingInterval = 30 * time.Second
+	watchWriteTimeout = 10 * time.Second
+	watchMemoTTL      = time.Minute
+)
+
+// WatchReq 监听变更请求参数
+type WatchReq struct {
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+}
+
+var watchUpgrader = websocket.Upgrader{
+	// 认证依赖 Authorization 头，不依赖 cookie，因此不校验来源
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// FsWatch 通过 SSE 或 WebSocket 推送路径下的变更事件
+// 请求头带有 Upgrade: websocket 时使用 WebSocket，否则使用 SSE
+func FsWatch(c *gin.Context) {
+	var req WatchReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Path == "" {
+		req.Path = "/"
+	}
+
+	user := c.Value(consts.UserKey).(*model.User)
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errs.Is(errs.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !common.CanAccess(user, meta, reqPath, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+		return
+	}
+
+	events := make(chan op.Event, watchBufferSize)
+	unsubscribe := op.SubscribeEvents(func(e op.Event) {
+		// 写操作的协程上只比较路径前缀，可见性由推送协程检查，避免拖慢写操作
+		if !watchUnder(reqPath, e.Path) && (e.SrcPath == "" || !watchUnder(reqPath, e.SrcPath)) {
+			return
+		}
+		select {
+		case events <- e:
+		default:
+			// 客户端消费过慢时丢弃事件，避免阻塞写操作
+			log.Warnf("watch event dropped for user [%s]: %s %s", user.Username, e.Type, e.Path)
+		}
+	})
+	defer unsubscribe()
+
+	filter := newWatchFilter(user, reqPath, req.Password)
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		watchWebSocket(c, events, filter)
+		return
+	}
+	watchSSE(c, events, filter)
+}
+
+// watchSSE 以 Server-Sent Events 推送事件，事件名为事件类型
+func watchSSE(c *gin.Context, events <-chan op.Event, filter *watchFilter) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	ticker := time.NewTicker(watchPingInterval)
+	defer ticker.Stop()
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e := <-events:
+			if e, ok := filter.filter(e); ok {
+				c.SSEvent(string(e.Type), e)
+			}
+		case <-ticker.C:
+			c.SSEvent("ping", time.Now().Unix())
+		case <-ctx.Done():
+			return false
+		}
+		return true
+	})
+}
+
+// watchWebSocket 以 WebSocket 文本消息推送事件，每条消息是一个 JSON 编码的事件
+func watchWebSocket(c *gin.Context, events <-chan op.Event, filter *watchFilter) {
+	conn, err := watchUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Warnf("failed upgrade watch websocket: %+v", err)
+		return
+	}
+	defer conn.Close()
+
+	// 读取并丢弃客户端消息，连接关闭时结束
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(watchPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-events:
+			e, ok := filter.filter(e)
+			if !ok {
+				continue
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(watchWriteTimeout))
+			if err = conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(watchWriteTimeout))
+			if err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// watchFilter 检查事件对订阅用户是否可见，规则与列目录相同：
+// 隐藏规则、元数据密码和 ACL 的列出权限都作用于根目录下的每一级路径
+type watchFilter struct {
+	user     *model.User
+	root     string
+	password string
+	// dirs 缓存目录的可见性，元数据和 ACL 变化后最多 watchMemoTTL 生效
+	dirs      map[string]bool
+	dirsSince time.Time
+}
+
+func newWatchFilter(user *model.User, root, password string) *watchFilter {
+	return &watchFilter{user: user, root: root, password: password}
+}
+
+// filter 过滤用户不可见的事件
+// 移动或重命名时如果只有一端可见，则转换为对应的创建或删除事件
+func (f *watchFilter) filter(e op.Event) (op.Event, bool) {
+	if time.Since(f.dirsSince) > watchMemoTTL {
+		f.dirs = make(map[string]bool)
+		f.dirsSince = time.Now()
+	}
+	dstVisible := f.visible(e.Path)
+	if e.SrcPath == "" {
+		return e, dstVisible
+	}
+	srcVisible := f.visible(e.SrcPath)
+	switch {
+	case dstVisible && srcVisible:
+		return e, true
+	case dstVisible:
+		e.Type = op.EventCreated
+		e.SrcPath = ""
+		return e, true
+	case srcVisible && e.Type != op.EventCopied:
+		e.Type = op.EventRemoved
+		e.Path, e.SrcPath = e.SrcPath, ""
+		e.Name = stdpath.Base(e.Path)
+		return e, true
+	default:
+		return e, false
+	}
+}
+
+// visible 检查 path 及其在根目录下的每一级父目录都对用户可见
+func (f *watchFilter) visible(path string) bool {
+	if !watchUnder(f.root, path) {
+		return false
+	}
+	if utils.PathEqual(f.root, path) {
+		// 根目录在订阅时已经检查过
+		return true
+	}
+	dir := stdpath.Dir(path)
+	if visible, ok := f.dirs[dir]; ok {
+		if !visible {
+			return false
+		}
+	} else {
+		visible = f.visible(dir)
+		f.dirs[dir] = visible
+		if !visible {
+			return false
+		}
+	}
+	return f.entryVisible(path)
+}
+
+// entryVisible 检查单个路径：隐藏规则取父目录的元数据，密码取路径自身最近的元数据，再检查 ACL
+func (f *watchFilter) entryVisible(path string) bool {
+	for _, p := range []string{stdpath.Dir(path), path} {
+		meta, err := op.GetNearestMeta(p)
+		if err != nil && !errs.Is(errs.Cause(err), errs.MetaNotFound) {
+			return false
+		}
+		if !common.CanAccess(f.user, meta, path, f.password) {
+			return false
+		}
+	}
+	return op.CheckACL(f.user, model.ACLList, path) == nil
+}
+
+func watchUnder(root, path string) bool {
+	return utils.PathEqual(root, path) || utils.IsSubPath(root, path)
+}
@@ -0,0 +1,63 @@
+package handles
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestWatchFilter(t *testing.T) {
+	metas := []model.Meta{
+		{Path: "/w", Hide: "^hidden", HSub: true},
+		{Path: "/w/locked", Password: "pw", PSub: true},
+	}
+	for i := range metas {
+		if err := op.CreateMeta(&metas[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := &model.User{ID: 100, Username: "watcher", Role: model.GENERAL, BasePath: "/"}
+	filter := newWatchFilter(user, "/w", "")
+
+	cases := []struct {
+		path    string
+		visible bool
+	}{
+		{"/w/a.txt", true},
+		{"/w/hidden.txt", false},
+		{"/w/hidden/x.txt", false},
+		{"/w/sub/hidden/x.txt", false},
+		{"/w/sub/x.txt", true},
+		{"/w/locked", false},
+		{"/w/locked/x.txt", false},
+		{"/other/x.txt", false},
+	}
+	for _, c := range cases {
+		if _, ok := filter.filter(op.Event{Type: op.EventCreated, Path: c.path}); ok != c.visible {
+			t.Errorf("%s: expected visible %v, got %v", c.path, c.visible, ok)
+		}
+	}
+
+	e, ok := filter.filter(op.Event{Type: op.EventMoved, Path: "/w/b.txt", SrcPath: "/w/hidden/x.txt"})
+	if !ok || e.Type != op.EventCreated || e.SrcPath != "" {
+		t.Errorf("expected a move out of a hidden folder to be a creation, got %+v, %v", e, ok)
+	}
+	if _, ok = newWatchFilter(user, "/w", "pw").filter(op.Event{Type: op.EventCreated, Path: "/w/locked/x.txt"}); !ok {
+		t.Error("expected the password of the folder to show its events")
+	}
+}
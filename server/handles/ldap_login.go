@@ -9,6 +9,7 @@ import (
 	"github.com/go-ldap/ldap/v3"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
 	"github.com/dongdio/OpenList/v4/internal/db"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
@@ -35,12 +36,8 @@ func loginLdap(c *gin.Context, req *LoginRequest) {
 		return
 	}
 
-	// check count of login
-	ip := c.ClientIP()
-	count, ok := model.LoginCache.Get(ip)
-	if ok && count >= defaultMaxLoginAttempts {
-		common.ErrorStrResp(c, "Too many unsuccessful sign-in attempts have been made using an incorrect username or password, Try again later.", 429)
-		model.LoginCache.Expire(ip, defaultLoginCacheDuration)
+	// check the lockout of the account and the ip
+	if loginLocked(c, req.Username) {
 		return
 	}
 
@@ -50,6 +47,7 @@ func loginLdap(c *gin.Context, req *LoginRequest) {
 	ldapManagerPassword := setting.GetStr(consts.LdapManagerPassword)
 	ldapUserSearchBase := setting.GetStr(consts.LdapUserSearchBase)
 	ldapUserSearchFilter := setting.GetStr(consts.LdapUserSearchFilter) // (uid=%s)
+	ldapGroupAttribute := setting.GetStr(consts.LdapGroupAttribute)     // memberOf
 
 	// Connect to LdapServer
 	l, err := dial(ldapServer)
@@ -74,7 +72,7 @@ func loginLdap(c *gin.Context, req *LoginRequest) {
 		ldapUserSearchBase,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		fmt.Sprintf(ldapUserSearchFilter, req.Username),
-		[]string{"dn"},
+		ldapAttributes(ldapGroupAttribute),
 		nil,
 	)
 	sr, err := l.Search(searchRequest)
@@ -89,13 +87,17 @@ func loginLdap(c *gin.Context, req *LoginRequest) {
 		return
 	}
 	userDN := sr.Entries[0].DN
+	var ldapGroups []string
+	if ldapGroupAttribute != "" {
+		ldapGroups = sr.Entries[0].GetAttributeValues(ldapGroupAttribute)
+	}
 
 	// Bind as the user to verify their password
 	err = l.Bind(userDN, req.Password)
 	if err != nil {
 		utils.Log.Errorf("Failed to auth. %v", err)
 		common.ErrorResp(c, err, 400)
-		model.LoginCache.Set(ip, count+1)
+		loginFailed(c, req.Username, err)
 		return
 	} else {
 		utils.Log.Infof("Auth successful username:%s", req.Username)
@@ -104,28 +106,43 @@ func loginLdap(c *gin.Context, req *LoginRequest) {
 
 	user, err := op.GetUserByName(req.Username)
 	if err != nil {
-		user, err = ladpRegister(req.Username)
+		user, err = ladpRegister(req.Username, ldapGroups)
 		if err != nil {
 			common.ErrorResp(c, err, 400)
-			model.LoginCache.Set(ip, count+1)
+			loginFailed(c, req.Username, err)
+			return
+		}
+	} else if ldapGroupAttribute != "" {
+		// the memberships follow the ldap groups at every login, so the removals take effect
+		if user, err = op.SyncExternalGroups(user, ldapGroups); err != nil {
+			common.ErrorResp(c, err, 500)
 			return
 		}
 	}
+	// the second factor is required as for the local login
+	if !checkSecondFactor(c, user, req.OtpCode) {
+		return
+	}
 
 	// generate token
-	token, err := common.GenerateToken(user)
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400, true)
 		return
 	}
-	common.SuccessResp(c, gin.H{"token": token})
-	model.LoginCache.Del(ip)
+	common.SuccessResp(c, token)
+	authguard.Success(req.Username, c.ClientIP())
 }
 
-func ladpRegister(username string) (*model.User, error) {
+// ladpRegister creates the user logged in with ldap, the ldap groups are mapped to the groups of the user
+func ladpRegister(username string, ldapGroups []string) (*model.User, error) {
 	if username == "" {
 		return nil, errs.New("cannot get username from ldap provider")
 	}
+	groupIDs, err := op.GetGroupIDsByExternal(ldapGroups)
+	if err != nil {
+		return nil, err
+	}
 	user := &model.User{
 		ID:         0,
 		Username:   username,
@@ -134,13 +151,21 @@ func ladpRegister(username string) (*model.User, error) {
 		BasePath:   setting.GetStr(consts.LdapDefaultDir),
 		Role:       0,
 		Disabled:   false,
+		GroupIDs:   groupIDs,
 	}
-	if err := db.CreateUser(user); err != nil {
+	if err = db.CreateUser(user); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
+func ldapAttributes(groupAttribute string) []string {
+	if groupAttribute == "" {
+		return []string{"dn"}
+	}
+	return []string{"dn", groupAttribute}
+}
+
 func dial(ldapServer string) (*ldap.Conn, error) {
 	var tlsEnabled = false
 	if strings.HasPrefix(ldapServer, "ldaps://") {
@@ -0,0 +1,37 @@
+package handles
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/internal/authguard"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+// ListLockouts returns the accounts and the ips locked for too many failed logins
+func ListLockouts(c *gin.Context) {
+	common.SuccessResp(c, authguard.Lockouts())
+}
+
+type UnlockReq struct {
+	// Kind is user or ip
+	Kind  string `json:"kind" binding:"required"`
+	Value string `json:"value" binding:"required"`
+}
+
+// Unlock resets the failed logins of an account or an ip
+func Unlock(c *gin.Context) {
+	var req UnlockReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Kind != authguard.KindUser && req.Kind != authguard.KindIP {
+		common.ErrorStrResp(c, "kind should be user or ip", 400)
+		return
+	}
+	if !authguard.Unlock(req.Kind, req.Value) {
+		common.ErrorStrResp(c, "no lockout found", 404)
+		return
+	}
+	common.SuccessResp(c)
+}
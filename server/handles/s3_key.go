@@ -0,0 +1,135 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+// S3KeyCreateResp carries the secret access key, it's only returned once
+type S3KeyCreateResp struct {
+	model.S3Key
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+// ListMyS3Keys returns the s3 keys of the current user
+func ListMyS3Keys(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	listS3Keys(c, user.ID)
+}
+
+// CreateMyS3Key creates an s3 key for the current user
+func CreateMyS3Key(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.S3Key
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	secret, err := op.CreateS3Key(user, &req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, S3KeyCreateResp{
+		S3Key:           req,
+		SecretAccessKey: secret,
+	})
+}
+
+// UpdateMyS3Key updates the name, buckets and disabled state of an s3 key of the current user
+func UpdateMyS3Key(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.S3Key
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if _, err := op.GetS3KeyByIdAndUserId(req.ID, user.ID); err != nil {
+		common.ErrorStrResp(c, "failed to get s3 key", 404)
+		return
+	}
+	if err := op.UpdateS3Key(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteMyS3Key deletes an s3 key of the current user
+func DeleteMyS3Key(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	if _, err := op.GetS3KeyByIdAndUserId(id, user.ID); err != nil {
+		common.ErrorStrResp(c, "failed to get s3 key", 404)
+		return
+	}
+	if err := op.DeleteS3KeyById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// ListS3Keys returns the s3 keys of the user uid, it's for the admin
+func ListS3Keys(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		common.ErrorStrResp(c, "user id format invalid", 400)
+		return
+	}
+	listS3Keys(c, uint(userID))
+}
+
+// UpdateS3Key updates an s3 key of any user, e.g. to disable it
+func UpdateS3Key(c *gin.Context) {
+	var req model.S3Key
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateS3Key(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteS3Key deletes an s3 key of any user
+func DeleteS3Key(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	if err := op.DeleteS3KeyById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func listS3Keys(c *gin.Context, userID uint) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	keys, total, err := op.GetS3KeysByUserId(userID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: keys,
+		Total:   total,
+	})
+}
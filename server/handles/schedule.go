@@ -0,0 +1,169 @@
+package handles
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/internal/schedule"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+type ScheduledJobResp struct {
+	model.ScheduledJob
+	// NextRunAt is nil if the job is disabled
+	NextRunAt *time.Time `json:"next_run_at"`
+}
+
+func toScheduledJobResp(job model.ScheduledJob) ScheduledJobResp {
+	resp := ScheduledJobResp{ScheduledJob: job}
+	if next := schedule.NextRun(job.ID); !next.IsZero() {
+		resp.NextRunAt = &next
+	}
+	return resp
+}
+
+// ListScheduledJobs returns a paginated list of scheduled jobs
+func ListScheduledJobs(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+
+	jobs, total, err := op.GetScheduledJobs(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	content := make([]ScheduledJobResp, 0, len(jobs))
+	for _, job := range jobs {
+		content = append(content, toScheduledJobResp(job))
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: content,
+		Total:   total,
+	})
+}
+
+// GetScheduledJob retrieves a scheduled job by ID
+func GetScheduledJob(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	job, err := op.GetScheduledJobById(id)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, toScheduledJobResp(*job))
+}
+
+// ListScheduledJobTypes returns the job types a scheduled job can use
+func ListScheduledJobTypes(c *gin.Context) {
+	common.SuccessResp(c, op.GetScheduledJobTypes())
+}
+
+// CreateScheduledJob creates a new scheduled job, it's scheduled at once unless disabled
+func CreateScheduledJob(c *gin.Context) {
+	var req model.ScheduledJob
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.ID = 0
+	if err := op.CreateScheduledJob(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, toScheduledJobResp(req))
+}
+
+// UpdateScheduledJob updates an existing scheduled job and reschedules it
+func UpdateScheduledJob(c *gin.Context) {
+	var req model.ScheduledJob
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateScheduledJob(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteScheduledJob deletes a scheduled job and its run history
+func DeleteScheduledJob(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	if err := op.DeleteScheduledJobById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func EnableScheduledJob(c *gin.Context) {
+	setScheduledJobDisabled(c, false)
+}
+
+func DisableScheduledJob(c *gin.Context) {
+	setScheduledJobDisabled(c, true)
+}
+
+func setScheduledJobDisabled(c *gin.Context, disabled bool) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	if err := op.SetScheduledJobDisabled(id, disabled); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// RunScheduledJob runs a job at once and returns the run, the tasks it creates keep running in the background
+func RunScheduledJob(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	run, err := schedule.RunNow(id)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, run)
+}
+
+// ListScheduledJobRuns returns the run history of a job, newest first
+func ListScheduledJobRuns(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+
+	runs, total, err := op.GetScheduledJobRuns(id, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: runs,
+		Total:   total,
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/dongdio/OpenList/v4/utility/errs"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/fs"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/server/common"
@@ -76,7 +77,7 @@ func filterSearchResults(nodes []model.SearchNode, user *model.User, password st
 
 	for _, node := range nodes {
 		// Skip nodes outside user's base path
-		if !strings.HasPrefix(node.Parent, user.BasePath) {
+		if !strings.HasPrefix(node.Parent, user.GetBasePath()) {
 			continue
 		}
 
@@ -92,6 +93,16 @@ func filterSearchResults(nodes []model.SearchNode, user *model.User, password st
 			continue
 		}
 
+		// Skip nodes the acl rules hide from the user
+		if op.CheckACL(user, model.ACLList, nodePath) != nil {
+			continue
+		}
+
+		// Only admins can see the trash
+		if !user.IsAdmin() && fs.InTrash(nodePath) {
+			continue
+		}
+
 		filteredNodes = append(filteredNodes, node)
 	}
 
@@ -0,0 +1,95 @@
+package handles
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+// RefreshTokenReq carries the refresh token got at login or at the last refresh
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken exchanges a refresh token for a new token of the same session,
+// the refresh token is rotated so the old one can't be used again
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	token, err := common.RefreshToken(c, req.RefreshToken)
+	if err != nil {
+		common.ErrorResp(c, err, 401)
+		return
+	}
+	common.SuccessResp(c, token)
+}
+
+// ListMySessions returns the sessions of the current user, the one of the request is marked as current
+func ListMySessions(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	sessions, err := op.GetSessionsByUserId(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	current, _ := c.Value(consts.SessionIDKey).(string)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	common.SuccessResp(c, sessions)
+}
+
+// RevokeMySession revokes a session of the current user, the devices using it are logged out
+func RevokeMySession(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	id := c.Query("id")
+	if id == "" {
+		common.ErrorStrResp(c, "Missing required parameter: id", 400)
+		return
+	}
+	if err := op.DeleteSessionByIdAndUserId(id, user.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// ListUserSessions returns the sessions of a user for the admin
+func ListUserSessions(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	sessions, err := op.GetSessionsByUserId(id)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, sessions)
+}
+
+// RevokeUserSessions revokes a session of a user, or all of them if session_id is empty,
+// so a compromised account can be logged out without resetting the password
+func RevokeUserSessions(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	var err error
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		err = op.DeleteSessionByIdAndUserId(sessionID, id)
+	} else {
+		err = op.DeleteSessionsByUserId(id)
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -41,6 +41,24 @@ func ResetToken(c *gin.Context) {
 	common.SuccessResp(c, token)
 }
 
+// ResetSCIMToken 生成新的 SCIM 令牌，旧令牌立即失效
+func ResetSCIMToken(c *gin.Context) {
+	token := random.Token()
+	item := model.SettingItem{
+		Key:   consts.SCIMToken,
+		Value: token,
+		Type:  consts.TypeString,
+		Group: model.SSO,
+		Flag:  model.PRIVATE,
+		Help:  `the bearer token of the SCIM client, SCIM is refused if it's empty`,
+	}
+	if err := op.SaveSettingItem(&item); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, token)
+}
+
 // GetSetting 获取设置项
 func GetSetting(c *gin.Context) {
 	key := c.Query("key")
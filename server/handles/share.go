@@ -0,0 +1,446 @@
+package handles
+
+import (
+	"io"
+	"net/url"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/fs"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/stream"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// ShareInfoResp is what a visitor of a share can know about it
+type ShareInfoResp struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	IsDir         bool       `json:"is_dir"`
+	Owner         string     `json:"owner"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	AllowPreview  bool       `json:"allow_preview"`
+	AllowDownload bool       `json:"allow_download"`
+	AllowUpload   bool       `json:"allow_upload"`
+	Remark        string     `json:"remark"`
+}
+
+// ShareListReq lists a folder inside a share, Path is relative to the shared folder
+// and Password is the one of the meta of the folder
+type ShareListReq struct {
+	model.PageReq
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+}
+
+// ShareArchiveListReq lists an archive inside a share, Path is relative to the shared folder
+type ShareArchiveListReq struct {
+	model.PageReq
+	Path        string `json:"path" form:"path"`
+	Password    string `json:"password" form:"password"`
+	InnerPath   string `json:"inner_path" form:"inner_path"`
+	ArchivePass string `json:"archive_pass" form:"archive_pass"`
+}
+
+// sharePath jails rel to the shared path, a file share always resolves to the file itself
+func sharePath(s *model.Share, rel string) (string, error) {
+	if !s.IsDir {
+		return s.Path, nil
+	}
+	return utils.JoinBasePath(s.Path, rel)
+}
+
+// shareRelPath is the path of p relative to the share shown to the visitors
+func shareRelPath(s *model.Share, p string) string {
+	if !s.IsDir {
+		return "/" + stdpath.Base(s.Path)
+	}
+	return utils.FixAndCleanPath(strings.TrimPrefix(p, s.Path))
+}
+
+func currentShare(c *gin.Context) *model.Share {
+	return c.Value(consts.ShareKey).(*model.Share)
+}
+
+// ShareInfo returns the share to a visitor and counts the visit
+func ShareInfo(c *gin.Context) {
+	s := currentShare(c)
+	owner := c.Value(consts.UserKey).(*model.User)
+	op.ShareViewed(s)
+	common.SuccessResp(c, ShareInfoResp{
+		ID:            s.ID,
+		Name:          stdpath.Base(s.Path),
+		IsDir:         s.IsDir,
+		Owner:         owner.Username,
+		ExpiresAt:     s.ExpiresAt,
+		AllowPreview:  s.AllowPreview,
+		AllowDownload: s.AllowDownload,
+		AllowUpload:   s.AllowUpload,
+		Remark:        s.Remark,
+	})
+}
+
+// ShareList lists a folder of a share, or the shared file itself
+func ShareList(c *gin.Context) {
+	s := currentShare(c)
+	if !s.AllowPreview && !s.AllowDownload {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	var req ShareListReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	reqPath, err := sharePath(s, req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if !shareCanAccess(c, reqPath, req.Password) {
+		return
+	}
+	var objs []model.Obj
+	if s.IsDir {
+		objs, err = fs.List(c.Request.Context(), reqPath, &fs.ListArgs{})
+	} else {
+		var obj model.Obj
+		obj, err = fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+		objs = []model.Obj{obj}
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	total, objs := pagination(objs, &req.PageReq)
+	content := make([]ObjResp, 0, len(objs))
+	for _, obj := range objs {
+		resp := toObjsRespWithoutSignAndThumb(obj)
+		resp.Path = shareRelPath(s, stdpath.Join(reqPath, obj.GetName()))
+		content = append(content, resp)
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: content,
+		Total:   int64(total),
+	})
+}
+
+// ShareDown downloads a file of a share
+func ShareDown(c *gin.Context) {
+	if !shareTakeDownload(c) {
+		return
+	}
+	Down(c)
+}
+
+// ShareProxy sends a file of a share through the server, it's a download as well
+// since the whole file can be got through it
+func ShareProxy(c *gin.Context) {
+	if !shareTakeDownload(c) {
+		return
+	}
+	Proxy(c)
+}
+
+// shareTakeDownload checks the visitor can download the file at the path param and counts the download.
+// The requests of a client for a file count once, so the range requests of a player or a resumed download
+// don't use up the max downloads. HEAD counts as well since it may be redirected to the storage.
+// The password of the meta of the file is in the query parameter password.
+func shareTakeDownload(c *gin.Context) bool {
+	s := currentShare(c)
+	if !s.AllowDownload {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return false
+	}
+	reqPath, err := sharePath(s, c.Param("path"))
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return false
+	}
+	if !shareCanAccess(c, reqPath, c.Query("password")) {
+		return false
+	}
+	if err = op.TakeShareDownload(s, reqPath, c.ClientIP()); err != nil {
+		common.ErrorResp(c, err, 403)
+		return false
+	}
+	common.GinWithValue(c, consts.PathKey, reqPath)
+	return true
+}
+
+// shareCanAccess applies the hide and password of the meta of reqPath as for the owner,
+// the meta is saved to the context so the listing hides the files as well
+func shareCanAccess(c *gin.Context, reqPath, password string) bool {
+	owner := c.Value(consts.UserKey).(*model.User)
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errs.Is(errs.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return false
+	}
+	common.GinWithValue(c, consts.MetaKey, meta)
+	if !common.CanAccess(owner, meta, reqPath, password) {
+		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+		return false
+	}
+	return true
+}
+
+// ShareArchiveList lists the content of an archive in a share
+func ShareArchiveList(c *gin.Context) {
+	s := currentShare(c)
+	if !s.AllowPreview && !s.AllowDownload {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	var req ShareArchiveListReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	owner := c.Value(consts.UserKey).(*model.User)
+	if !owner.CanReadArchives() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	reqPath, err := sharePath(s, req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if !shareCanAccess(c, reqPath, req.Password) {
+		return
+	}
+	objs, err := fs.ArchiveList(c.Request.Context(), reqPath, model.ArchiveListArgs{
+		ArchiveInnerArgs: model.ArchiveInnerArgs{
+			ArchiveArgs: model.ArchiveArgs{
+				LinkArgs: model.LinkArgs{
+					Header: c.Request.Header,
+					Type:   c.Query("type"),
+				},
+				Password: req.ArchivePass,
+			},
+			InnerPath: utils.FixAndCleanPath(req.InnerPath),
+		},
+	})
+	if err != nil {
+		if errs.Is(err, errs.WrongArchivePassword) {
+			common.ErrorResp(c, err, 202)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
+		return
+	}
+	total, objs := pagination(objs, &req.PageReq)
+	result, _ := utils.SliceConvert(objs, func(src model.Obj) (ObjResp, error) {
+		return toObjsRespWithoutSignAndThumb(src), nil
+	})
+	common.SuccessResp(c, ArchiveListResp{
+		Content: result,
+		Total:   int64(total),
+	})
+}
+
+// ShareUpload uploads a file into a folder share, the File-Path header is relative to the shared folder.
+// The visitors can only add files, the existing ones are never overwritten.
+func ShareUpload(c *gin.Context) {
+	defer func() {
+		if n, _ := io.ReadFull(c.Request.Body, []byte{0}); n == 1 {
+			_, _ = utils.CopyWithBuffer(io.Discard, c.Request.Body)
+		}
+		_ = c.Request.Body.Close()
+	}()
+	s := currentShare(c)
+	owner := c.Value(consts.UserKey).(*model.User)
+	if !s.AllowUpload || !s.IsDir || !owner.CanWrite() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	path, err := url.PathUnescape(c.GetHeader("File-Path"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if path == "" {
+		common.ErrorStrResp(c, "missing File-Path header", 400)
+		return
+	}
+	path, err = sharePath(s, path)
+	if err != nil || path == s.Path {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
+		common.ErrorStrResp(c, "file exists", 403)
+		return
+	}
+	if err = fs.CheckQuota(c.Request.Context(), path, c.Request.ContentLength); err != nil {
+		common.ErrorResp(c, err, 413)
+		return
+	}
+	dir, name := stdpath.Split(path)
+	mimetype := c.GetHeader("Content-Type")
+	if len(mimetype) == 0 {
+		mimetype = utils.GetMimeType(name)
+	}
+	fileStream := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     c.Request.ContentLength,
+			Modified: getLastModified(c),
+			HashInfo: utils.NewHashInfoByMap(getFileHashes(c)),
+		},
+		Reader:   c.Request.Body,
+		Mimetype: mimetype,
+	}
+	if err = fs.PutDirectly(c.Request.Context(), dir, fileStream, true); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	op.ShareUploaded(s)
+	common.SuccessResp(c)
+}
+
+// ListMyShares returns the shares of the current user with their access statistics
+func ListMyShares(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	listShares(c, user.ID)
+}
+
+// CreateMyShare shares a file or folder of the current user, the path is relative to the base path of the user
+func CreateMyShare(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.Share
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Path, req.IsDir = reqPath, obj.IsDir()
+	if err = op.CreateShare(user, &req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.MaskPassword()
+	common.SuccessResp(c, req)
+}
+
+// UpdateMyShare updates a share of the current user
+func UpdateMyShare(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	var req model.Share
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if _, err := op.GetShareByIdAndUserId(req.ID, user.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateShare(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteMyShare deletes a share of the current user, the link stops working at once
+func DeleteMyShare(c *gin.Context) {
+	user := c.Value(consts.UserKey).(*model.User)
+	id := c.Query("id")
+	if _, err := op.GetShareByIdAndUserId(id, user.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.DeleteShareById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// ListShares returns the shares of all the users, or of the user in the user_id query for the admin
+func ListShares(c *gin.Context) {
+	var userID int
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		var err error
+		if userID, err = strconv.Atoi(userIDStr); err != nil {
+			common.ErrorStrResp(c, "Invalid user_id format, must be a number", 400)
+			return
+		}
+	}
+	listShares(c, uint(userID))
+}
+
+func listShares(c *gin.Context, userID uint) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	shares, total, err := op.GetShares(userID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	for i := range shares {
+		shares[i].MaskPassword()
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: shares,
+		Total:   total,
+	})
+}
+
+// GetShare returns a share for the admin
+func GetShare(c *gin.Context) {
+	s, err := op.GetShareById(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return
+	}
+	s.MaskPassword()
+	common.SuccessResp(c, s)
+}
+
+// UpdateShare updates any share for the admin, e.g. to disable a share leaking files
+func UpdateShare(c *gin.Context) {
+	var req model.Share
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateShare(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteShare deletes any share for the admin
+func DeleteShare(c *gin.Context) {
+	if err := op.DeleteShareById(c.Query("id")); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/dongdio/OpenList/v4/consts"
 	"github.com/dongdio/OpenList/v4/internal/db"
 	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/internal/setting"
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/utility/utils"
@@ -205,9 +206,41 @@ func GetOIDCClient(c *gin.Context, useCompatibility bool, redirectUri, method st
 	}, nil
 }
 
+// ssoGroups 从SSO返回的用户信息中读取用户组声明
+// 声明可以是字符串数组，也可以是以逗号分隔的字符串
+func ssoGroups(data []byte) []string {
+	claim := setting.GetStr(consts.SSOGroupClaim)
+	if claim == "" {
+		return nil
+	}
+	result := utils.GetBytes(data, claim)
+	var groups []string
+	if result.IsArray() {
+		for _, g := range result.Array() {
+			groups = append(groups, g.String())
+		}
+		return groups
+	}
+	for _, g := range strings.Split(result.String(), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// syncSSOGroups 每次SSO登录时按用户组声明同步已有用户的用户组，使SSO中的移除同样生效
+// 未配置用户组声明时不同步
+func syncSSOGroups(user *model.User, data []byte) (*model.User, error) {
+	if setting.GetStr(consts.SSOGroupClaim) == "" {
+		return user, nil
+	}
+	return op.SyncExternalGroups(user, ssoGroups(data))
+}
+
 // autoRegister 根据SSO信息自动注册用户
-// 当用户不存在且启用了自动注册时，创建新用户
-func autoRegister(username, userID string, err error) (*model.User, error) {
+// 当用户不存在且启用了自动注册时，创建新用户，并将SSO用户组映射为OpenList用户组
+func autoRegister(username, userID string, groups []string, err error) (*model.User, error) {
 	// 如果错误不是"记录未找到"或者未启用自动注册，则返回错误
 	if !errs.Is(err, gorm.ErrRecordNotFound) || !setting.GetBool(consts.SSOAutoRegister) {
 		return nil, err
@@ -218,6 +251,12 @@ func autoRegister(username, userID string, err error) (*model.User, error) {
 		return nil, errs.New("cannot get username from SSO provider")
 	}
 
+	// 映射用户组
+	groupIDs, err := op.GetGroupIDsByExternal(groups)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建新用户
 	user := &model.User{
 		ID:         0,
@@ -228,6 +267,7 @@ func autoRegister(username, userID string, err error) (*model.User, error) {
 		Role:       0,
 		Disabled:   false,
 		SsoID:      userID,
+		GroupIDs:   groupIDs,
 	}
 
 	// 尝试保存用户
@@ -286,6 +326,12 @@ window.close()
 </body>`, messageJSON)
 }
 
+// compatibilityLoginURL 生成兼容模式下登录后的跳转地址
+// 刷新令牌放在片段中，不会出现在访问日志、Referer 中
+func compatibilityLoginURL(c *gin.Context, token *common.LoginToken) string {
+	return common.GetApiURL(c) + "/@login?token=" + token.Token + "#refresh_token=" + url.QueryEscape(token.RefreshToken)
+}
+
 // OIDCLoginCallback 处理OIDC登录回调
 func OIDCLoginCallback(c *gin.Context) {
 	// 获取配置
@@ -381,15 +427,17 @@ func OIDCLoginCallback(c *gin.Context) {
 	user, err := db.GetUserBySSOID(userID)
 	if err != nil {
 		// 尝试自动注册
-		user, err = autoRegister(userID, userID, err)
-		if err != nil {
-			common.ErrorResp(c, err, 400)
-			return
-		}
+		user, err = autoRegister(userID, userID, ssoGroups(payload), err)
+	} else {
+		user, err = syncSSOGroups(user, payload)
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
 	}
 
 	// 生成令牌
-	token, err := common.GenerateToken(user)
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		return
@@ -397,12 +445,12 @@ func OIDCLoginCallback(c *gin.Context) {
 
 	// 返回令牌
 	if useCompatibility {
-		c.Redirect(http.StatusFound, common.GetApiURL(c)+"/@login?token="+token)
+		c.Redirect(http.StatusFound, compatibilityLoginURL(c, token))
 		return
 	}
 
 	c.Data(http.StatusOK, "text/html; charset=utf-8",
-		[]byte(generatePostMessageHTML(map[string]string{"token": token})))
+		[]byte(generatePostMessageHTML(map[string]string{"token": token.Token, "refresh_token": token.RefreshToken})))
 }
 
 // SSOLoginCallback 处理SSO登录回调
@@ -602,15 +650,17 @@ func SSOLoginCallback(c *gin.Context) {
 	user, err := db.GetUserBySSOID(userID)
 	if err != nil {
 		// 尝试自动注册
-		user, err = autoRegister(username, userID, err)
-		if err != nil {
-			common.ErrorResp(c, err, 400)
-			return
-		}
+		user, err = autoRegister(username, userID, ssoGroups(userResp.Bytes()), err)
+	} else {
+		user, err = syncSSOGroups(user, userResp.Bytes())
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
 	}
 
 	// 生成令牌
-	token, err := common.GenerateToken(user)
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		return
@@ -618,10 +668,10 @@ func SSOLoginCallback(c *gin.Context) {
 
 	// 返回令牌
 	if useCompatibility {
-		c.Redirect(http.StatusFound, common.GetApiURL(c)+"/@login?token="+token)
+		c.Redirect(http.StatusFound, compatibilityLoginURL(c, token))
 		return
 	}
 
 	c.Data(http.StatusOK, "text/html; charset=utf-8",
-		[]byte(generatePostMessageHTML(map[string]string{"token": token})))
+		[]byte(generatePostMessageHTML(map[string]string{"token": token.Token, "refresh_token": token.RefreshToken})))
 }
\ No newline at end of file
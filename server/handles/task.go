@@ -320,4 +320,6 @@ func SetupTaskRoute(g *gin.RouterGroup) {
 	taskRoute(g.Group("/decompress"), fs.ArchiveDownloadTaskManager)
 	// 解压上传任务
 	taskRoute(g.Group("/decompress_upload"), fs.ArchiveContentUploadTaskManager)
+	// 同步任务
+	taskRoute(g.Group("/sync"), fs.SyncTaskManager)
 }
\ No newline at end of file
@@ -166,13 +166,13 @@ func FinishAuthnLogin(c *gin.Context) {
 	}
 
 	// 生成登录令牌
-	token, err := common.GenerateToken(user)
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, errs.Wrap(err, "failed to generate token"), 400)
 		return
 	}
 
-	common.SuccessResp(c, gin.H{"token": token})
+	common.SuccessResp(c, token)
 }
 
 // BeginAuthnRegistration 开始 WebAuthn 注册流程
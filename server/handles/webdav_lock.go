@@ -0,0 +1,41 @@
+package handles
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+// ListWebDAVLocks returns the alive locks taken by the WebDAV clients
+func ListWebDAVLocks(c *gin.Context) {
+	locks, err := op.GetWebDAVLocks()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, locks)
+}
+
+type ReleaseWebDAVLockReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ReleaseWebDAVLock force releases a lock, e.g. one left by a crashed office client
+func ReleaseWebDAVLock(c *gin.Context) {
+	var req ReleaseWebDAVLockReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	ok, err := op.DeleteWebDAVLock(req.Token)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !ok {
+		common.ErrorStrResp(c, "no webdav lock found", 404)
+		return
+	}
+	common.SuccessResp(c)
+}
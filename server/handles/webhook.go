@@ -0,0 +1,158 @@
+package handles
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/internal/webhook"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// ListWebhooks returns a paginated list of webhooks
+func ListWebhooks(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+
+	hooks, total, err := op.GetWebhooks(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	for i := range hooks {
+		hooks[i].MaskSecret()
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: hooks,
+		Total:   total,
+	})
+}
+
+// GetWebhook retrieves a webhook by ID
+func GetWebhook(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	hook, err := op.GetWebhookById(id)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	hook.MaskSecret()
+	common.SuccessResp(c, hook)
+}
+
+// CreateWebhook creates a new webhook
+func CreateWebhook(c *gin.Context) {
+	var req model.Webhook
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.ID = 0
+	if err := op.CreateWebhook(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	req.MaskSecret()
+	common.SuccessResp(c, req)
+}
+
+// UpdateWebhook updates an existing webhook
+func UpdateWebhook(c *gin.Context) {
+	var req model.Webhook
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateWebhook(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func DeleteWebhook(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	if err := op.DeleteWebhookById(id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// TestWebhook sends a ping event synchronously and returns the delivery
+func TestWebhook(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	hook, err := op.GetWebhookById(id)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	body, err := utils.JSONTool.Marshal(webhook.Payload{
+		Event: webhook.EventPing,
+		Time:  time.Now(),
+		Data:  gin.H{"webhook_id": hook.ID},
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	// don't retry, the admin is waiting for the result
+	hook.MaxRetry = 0
+	common.SuccessResp(c, webhook.Deliver(hook, webhook.EventPing, body))
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook, newest first
+func ListWebhookDeliveries(c *gin.Context) {
+	id, ok := queryID(c)
+	if !ok {
+		return
+	}
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+
+	deliveries, total, err := op.GetWebhookDeliveries(id, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: deliveries,
+		Total:   total,
+	})
+}
+
+func queryID(c *gin.Context) (uint, bool) {
+	idStr := c.Query("id")
+	if idStr == "" {
+		common.ErrorStrResp(c, "Missing required parameter: id", 400)
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorStrResp(c, "Invalid ID format, must be a number", 400)
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// Protocol 中间件，将请求的协议和客户端IP保存到上下文中
+// 审计日志据此记录操作来自哪个协议和IP，后注册的中间件会覆盖先注册的协议
+//
+// 参数:
+//   - protocol: 协议名称，如 audit.ProtocolWeb
+func Protocol(protocol string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		common.GinWithValue(c, consts.ProtocolKey, protocol, consts.ClientIPKey, c.ClientIP())
+		c.Next()
+	}
+}
+
+// AuditChange 中间件，为修改类请求记录审计日志
+// 用于管理接口和账户设置，只记录请求的方法和路径，不记录可能含有密码的请求体
+func AuditChange(c *gin.Context) {
+	c.Next()
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return
+	}
+	// 出错的响应会中止请求，HTTP状态码总是200
+	var err error
+	if c.IsAborted() {
+		err = errs.New("request failed")
+	}
+	audit.Record(c.Request.Context(), model.AuditLog{
+		Action: model.AuditAdminChange,
+		Detail: c.Request.Method + " " + c.Request.URL.RequestURI(),
+	}, err)
+}
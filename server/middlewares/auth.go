@@ -11,6 +11,7 @@ import (
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/internal/setting"
 	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
 )
 
 // Auth 中间件，检查用户是否已登录
@@ -52,6 +53,12 @@ func Auth(c *gin.Context) {
 		return
 	}
 
+	// 验证个人API令牌
+	if op.IsAPIToken(token) {
+		authAPIToken(c, token)
+		return
+	}
+
 	// 验证JWT令牌
 	userClaims, err := common.ParseToken(token)
 	if err != nil {
@@ -81,6 +88,12 @@ func Auth(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	// 检查 2FA 策略，宽限期结束后未启用 2FA 的用户只能启用 2FA
+	if !twoFactorAllowed(c, user) {
+		return
+	}
+
+	useSession(c, userClaims.ID)
 	common.GinWithValue(c, consts.UserKey, user)
 	log.Debugf("使用登录令牌: %+v", user)
 	c.Next()
@@ -119,6 +132,12 @@ func Authn(c *gin.Context) {
 		return
 	}
 
+	// 验证个人API令牌
+	if op.IsAPIToken(token) {
+		authAPIToken(c, token)
+		return
+	}
+
 	// 验证JWT令牌
 	userClaims, err := common.ParseToken(token)
 	if err != nil {
@@ -149,11 +168,49 @@ func Authn(c *gin.Context) {
 		return
 	}
 
+	useSession(c, userClaims.ID)
 	common.GinWithValue(c, consts.UserKey, user)
 	log.Debugf("使用登录令牌: %+v", user)
 	c.Next()
 }
 
+// authAPIToken 使用个人API令牌认证，用户的权限和基础路径受令牌限制
+func authAPIToken(c *gin.Context, token string) {
+	user, err := op.ValidateAPIToken(token)
+	if err != nil {
+		// 令牌所有者的 2FA 宽限期已结束，与登录令牌一样按 2FA 策略拒绝
+		if errs.Is(err, errs.TwoFactorRequired) {
+			common.ErrorResp(c, err, 403)
+		} else {
+			common.ErrorResp(c, err, 401)
+		}
+		c.Abort()
+		return
+	}
+	common.GinWithValue(c, consts.UserKey, user)
+	log.Debugf("使用API令牌: %+v", user)
+	c.Next()
+}
+
+// AuthNotAPIToken 中间件，拒绝使用API令牌认证的请求
+// 用于修改账户和管理令牌等不应由令牌完成的操作，需要在Auth或Authn中间件之后使用
+func AuthNotAPIToken(c *gin.Context) {
+	user, ok := c.Value(consts.UserKey).(*model.User)
+	if !ok {
+		common.ErrorStrResp(c, "用户未认证", 401)
+		c.Abort()
+		return
+	}
+
+	if user.APITokenID != 0 {
+		common.ErrorStrResp(c, "API令牌无权执行此操作，请使用密码登录", 403)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
 // AuthNotGuest 中间件，确保用户不是访客
 // 需要在Auth或Authn中间件之后使用
 func AuthNotGuest(c *gin.Context) {
@@ -190,4 +247,12 @@ func AuthAdmin(c *gin.Context) {
 	}
 
 	c.Next()
+}
+
+// useSession 记录会话的最近活动，并将会话ID保存到上下文中
+func useSession(c *gin.Context, sessionID string) {
+	if session, err := op.GetValidSession(sessionID); err == nil {
+		op.TouchSession(session, c.ClientIP())
+	}
+	common.GinWithValue(c, consts.SessionIDKey, sessionID)
 }
\ No newline at end of file
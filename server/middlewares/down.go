@@ -104,6 +104,11 @@ func needSign(meta *model.Meta, path string) bool {
 		return true
 	}
 
+	// 如果访问控制规则禁止游客读取
+	if common.IsACLSignRequired(path) {
+		return true
+	}
+
 	// 如果元数据不存在或没有密码
 	if meta == nil || meta.Password == "" {
 		return false
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// FileRequestAuth 中间件，校验文件请求链接并以链接的伪用户身份处理上传
+// 伪用户被限制在链接的文件夹内且只能上传，密码从查询参数 pwd 或请求头 X-File-Request-Password 中获取
+// 密码错误与分享链接一样按客户端IP计入防暴力破解的计数
+func FileRequestAuth(c *gin.Context) {
+	password := c.Query("pwd")
+	if password == "" {
+		password = c.GetHeader("X-File-Request-Password")
+	}
+	ip := c.ClientIP()
+	if remaining := authguard.Locked("", ip); remaining > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+		common.ErrorStrResp(c, "Too many wrong file request passwords have been tried. Try again later.", 429)
+		c.Abort()
+		return
+	}
+	fileRequest, user, err := op.ValidateFileRequest(c.Param("id"), password)
+	if err != nil {
+		code := 404
+		switch {
+		case errs.Is(err, errs.FileRequestExpired):
+			code = 410
+		case errs.Is(err, errs.WrongFileRequestPassword):
+			code = 401
+			authguard.Fail("", ip, audit.ProtocolFileRequest)
+		}
+		common.ErrorResp(c, err, code)
+		c.Abort()
+		return
+	}
+	if password != "" && fileRequest.PwdHash != "" {
+		authguard.Success("", ip)
+	}
+	common.GinWithValue(c, consts.FileRequestKey, fileRequest, consts.UserKey, user, consts.ProtocolKey, audit.ProtocolFileRequest)
+	c.Next()
+}
@@ -9,9 +9,11 @@ import (
 	"github.com/dongdio/OpenList/v4/utility/errs"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/fs"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/utils"
 )
 
 // FsUp 中间件，处理文件上传请求的权限验证
@@ -66,11 +68,53 @@ func FsUp(c *gin.Context) {
 	}
 
 	// 检查访问权限和写入权限
-	if !(common.CanAccess(userObj, meta, path, password) && (userObj.CanWrite() || common.CanWrite(meta, parentDir))) {
+	if !(common.CanAccess(userObj, meta, path, password) && (userObj.CanWrite() || common.CanWrite(userObj, meta, parentDir))) {
 		common.ErrorResp(c, errs.PermissionDenied, 403)
 		c.Abort()
 		return
 	}
 
+	// 在读取请求体之前检查配额
+	if err = fs.CheckQuota(c.Request.Context(), path, c.Request.ContentLength); err != nil {
+		common.ErrorResp(c, err, 413)
+		c.Abort()
+		return
+	}
+
+	// 文件请求链接只能上传到链接的文件夹，并受链接的类型、大小和配额限制
+	if fileRequest, ok := c.Value(consts.FileRequestKey).(*model.FileRequest); ok {
+		if !utils.PathEqual(parentDir, fileRequest.Path) {
+			common.ErrorResp(c, errs.PermissionDenied, 403)
+			c.Abort()
+			return
+		}
+		// 未知长度（分块传输）的请求无法检查大小和配额，直接拒绝
+		size := c.Request.ContentLength
+		if size < 0 {
+			common.ErrorStrResp(c, "上传到文件请求链接必须提供Content-Length", 411)
+			c.Abort()
+			return
+		}
+		if err = op.CheckFileRequestUpload(fileRequest, stdpath.Base(path), size); err != nil {
+			common.ErrorResp(c, err, 413)
+			c.Abort()
+			return
+		}
+		// 先在数据库中占用配额，避免并发上传超出配额，上传失败时归还
+		if err = op.TakeFileRequestQuota(fileRequest, size); err != nil {
+			common.ErrorResp(c, err, 413)
+			c.Abort()
+			return
+		}
+		c.Next()
+		// 出错的响应会中止请求
+		if c.IsAborted() {
+			op.ReleaseFileRequestQuota(fileRequest, size)
+		} else {
+			op.FileRequestUploaded(fileRequest)
+		}
+		return
+	}
+
 	c.Next()
 }
\ No newline at end of file
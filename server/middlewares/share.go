@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// ShareAuth 中间件，校验分享链接并以分享者的身份处理请求
+// 分享链接不需要登录，密码从查询参数 pwd 或请求头 X-Share-Password 中获取，
+// 请求路径由处理函数限制在分享的文件或文件夹内
+// 密码错误与登录失败一样按客户端IP计入防暴力破解的计数
+func ShareAuth(c *gin.Context) {
+	password := c.Query("pwd")
+	if password == "" {
+		password = c.GetHeader("X-Share-Password")
+	}
+	ip := c.ClientIP()
+	if remaining := authguard.Locked("", ip); remaining > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+		common.ErrorStrResp(c, "Too many wrong share passwords have been tried. Try again later.", 429)
+		c.Abort()
+		return
+	}
+	share, owner, err := op.ValidateShare(c.Param("id"), password)
+	if err != nil {
+		code := 404
+		switch {
+		case errs.Is(err, errs.ShareExpired):
+			code = 410
+		case errs.Is(err, errs.WrongSharePassword):
+			code = 401
+			authguard.Fail("", ip, audit.ProtocolShare)
+		}
+		common.ErrorResp(c, err, code)
+		c.Abort()
+		return
+	}
+	if password != "" && share.PwdHash != "" {
+		authguard.Success("", ip)
+	}
+	common.GinWithValue(c, consts.ShareKey, share, consts.UserKey, owner, consts.ProtocolKey, audit.ProtocolShare)
+	c.Next()
+}
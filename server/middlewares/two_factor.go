@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// twoFactorExempt 是宽限期结束后未启用 2FA 的用户仍可访问的接口（相对于 /api），用于查看自身状态和启用 2FA
+// 通行密钥的注册接口使用 Authn 中间件，不受此限制
+var twoFactorExempt = []string{"/me", "/auth/logout", "/auth/2fa/generate", "/auth/2fa/verify"}
+
+// twoFactorAllowed 检查用户是否满足 2FA 策略，不满足时返回 403 并中止请求
+func twoFactorAllowed(c *gin.Context, user *model.User) bool {
+	err := op.CheckTwoFactorPolicy(user)
+	if err == nil {
+		return true
+	}
+	if !errs.Is(err, errs.TwoFactorRequired) {
+		common.ErrorResp(c, err, 500)
+		c.Abort()
+		return false
+	}
+	route := strings.TrimPrefix(c.FullPath(), path.Join(conf.URL.Path, "/api"))
+	if slices.Contains(twoFactorExempt, route) {
+		return true
+	}
+	common.ErrorResp(c, err, 403)
+	c.Abort()
+	return false
+}
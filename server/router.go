@@ -5,11 +5,13 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/audit"
 	"github.com/dongdio/OpenList/v4/internal/conf"
 	"github.com/dongdio/OpenList/v4/internal/sign"
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/server/handles"
 	"github.com/dongdio/OpenList/v4/server/middlewares"
+	"github.com/dongdio/OpenList/v4/server/scim"
 	"github.com/dongdio/OpenList/v4/server/static"
 	"github.com/dongdio/OpenList/v4/utility/message"
 	"github.com/dongdio/OpenList/v4/utility/stream"
@@ -35,12 +37,13 @@ func Init(e *gin.Engine) {
 	g.GET("/robots.txt", handles.Robots)
 	g.GET("/i/:link_name", handles.Plist)
 	common.SecretKey = []byte(conf.Conf.JwtSecret)
-	g.Use(middlewares.StoragesLoaded)
+	g.Use(middlewares.StoragesLoaded, middlewares.Protocol(audit.ProtocolWeb))
 	if conf.Conf.MaxConnections > 0 {
 		g.Use(middlewares.MaxAllowed(conf.Conf.MaxConnections))
 	}
 	WebDav(g.Group("/dav"))
 	S3(g.Group("/s3"))
+	scim.Init(g.Group("/scim/v2"))
 
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	signCheck := middlewares.Down(sign.Verify)
@@ -56,20 +59,63 @@ func Init(e *gin.Engine) {
 	g.HEAD("/ap/*path", archiveSignCheck, handles.ArchiveProxy)
 	g.HEAD("/ae/*path", archiveSignCheck, handles.ArchiveInternalExtract)
 
+	// public share links, no login needed but jailed to the shared path
+	share := g.Group("/s/:id", middlewares.ShareAuth)
+	share.GET("", handles.ShareInfo)
+	share.GET("/list", handles.ShareList)
+	share.GET("/archive/list", handles.ShareArchiveList)
+	share.GET("/d/*path", downloadLimiter, handles.ShareDown)
+	share.GET("/p/*path", downloadLimiter, handles.ShareProxy)
+	share.HEAD("/d/*path", handles.ShareDown)
+	share.HEAD("/p/*path", handles.ShareProxy)
+	share.PUT("/upload", middlewares.UploadRateLimiter(stream.ClientUploadLimit), handles.ShareUpload)
+
+	// file requests, outsiders upload into a folder as a pseudo user scoped to the link
+	fileRequest := g.Group("/r/:id", middlewares.FileRequestAuth)
+	fileRequest.GET("", handles.FileRequestInfo)
+	fileRequest.PUT("/put", middlewares.FsUp, middlewares.UploadRateLimiter(stream.ClientUploadLimit), handles.FsStream)
+	fileRequest.PUT("/form", middlewares.FsUp, middlewares.UploadRateLimiter(stream.ClientUploadLimit), handles.FsForm)
+
 	api := g.Group("/api")
 	auth := api.Group("", middlewares.Auth)
-	webauthn := api.Group("/authn", middlewares.Authn)
+	webauthn := api.Group("/authn", middlewares.Authn, middlewares.AuthNotAPIToken)
 
 	api.POST("/auth/login", handles.Login)
 	api.POST("/auth/login/hash", handles.LoginHash)
 	api.POST("/auth/login/ldap", handles.LoginLdap)
+	api.POST("/auth/refresh", handles.RefreshToken)
 	auth.GET("/me", handles.CurrentUser)
-	auth.POST("/me/update", handles.UpdateCurrent)
+	auth.POST("/me/update", middlewares.AuthNotAPIToken, middlewares.AuditChange, handles.UpdateCurrent)
 	auth.GET("/me/sshkey/list", handles.ListMyPublicKey)
-	auth.POST("/me/sshkey/add", handles.AddMyPublicKey)
-	auth.POST("/me/sshkey/delete", handles.DeleteMyPublicKey)
-	auth.POST("/auth/2fa/generate", handles.Generate2FA)
-	auth.POST("/auth/2fa/verify", handles.Verify2FA)
+	auth.POST("/me/sshkey/add", middlewares.AuthNotAPIToken, handles.AddMyPublicKey)
+	auth.POST("/me/sshkey/delete", middlewares.AuthNotAPIToken, handles.DeleteMyPublicKey)
+	token := auth.Group("/me/token", middlewares.AuthNotGuest, middlewares.AuthNotAPIToken, middlewares.AuditChange)
+	token.GET("/list", handles.ListMyAPITokens)
+	token.POST("/create", handles.CreateMyAPIToken)
+	token.POST("/update", handles.UpdateMyAPIToken)
+	token.POST("/delete", handles.DeleteMyAPIToken)
+	s3Key := auth.Group("/me/s3_key", middlewares.AuthNotGuest, middlewares.AuthNotAPIToken, middlewares.AuditChange)
+	s3Key.GET("/list", handles.ListMyS3Keys)
+	s3Key.POST("/create", handles.CreateMyS3Key)
+	s3Key.POST("/update", handles.UpdateMyS3Key)
+	s3Key.POST("/delete", handles.DeleteMyS3Key)
+	sessions := auth.Group("/me/sessions", middlewares.AuthNotGuest, middlewares.AuthNotAPIToken, middlewares.AuditChange)
+	sessions.GET("/list", handles.ListMySessions)
+	sessions.POST("/revoke", handles.RevokeMySession)
+	myShare := auth.Group("/share", middlewares.AuthNotGuest)
+	myShare.GET("/list", handles.ListMyShares)
+	myShare.POST("/create", handles.CreateMyShare)
+	myShare.POST("/update", handles.UpdateMyShare)
+	myShare.POST("/delete", handles.DeleteMyShare)
+	myFileRequest := auth.Group("/file_request", middlewares.AuthNotGuest)
+	myFileRequest.GET("/list", handles.ListMyFileRequests)
+	myFileRequest.POST("/create", handles.CreateMyFileRequest)
+	myFileRequest.POST("/update", handles.UpdateMyFileRequest)
+	myFileRequest.POST("/delete", handles.DeleteMyFileRequest)
+	auth.POST("/auth/2fa/generate", middlewares.AuthNotAPIToken, handles.Generate2FA)
+	auth.POST("/auth/2fa/verify", middlewares.AuthNotAPIToken, handles.Verify2FA)
+	auth.POST("/auth/2fa/recovery_codes", middlewares.AuthNotAPIToken, middlewares.AuditChange, handles.RegenerateRecoveryCodes)
+	auth.POST("/auth/2fa/cancel", middlewares.AuthNotAPIToken, middlewares.AuditChange, handles.Cancel2FA)
 	auth.GET("/auth/logout", handles.LogOut)
 
 	// auth
@@ -94,7 +140,7 @@ func Init(e *gin.Engine) {
 
 	_fs(auth.Group("/fs"))
 	_task(auth.Group("/task", middlewares.AuthNotGuest))
-	admin(auth.Group("/admin", middlewares.AuthAdmin))
+	admin(auth.Group("/admin", middlewares.AuthAdmin, middlewares.AuditChange))
 	if global.Debug || global.Dev {
 		debug(g.Group("/debug"))
 	}
@@ -121,6 +167,71 @@ func admin(g *gin.RouterGroup) {
 	user.POST("/del_cache", handles.DelUserCache)
 	user.GET("/sshkey/list", handles.ListPublicKeys)
 	user.POST("/sshkey/delete", handles.DeletePublicKey)
+	user.GET("/s3_key/list", handles.ListS3Keys)
+	user.POST("/s3_key/update", handles.UpdateS3Key)
+	user.POST("/s3_key/delete", handles.DeleteS3Key)
+	user.GET("/sessions", handles.ListUserSessions)
+	user.POST("/sessions/revoke", handles.RevokeUserSessions)
+
+	group := g.Group("/group")
+	group.GET("/list", handles.ListGroups)
+	group.GET("/get", handles.GetGroup)
+	group.POST("/create", handles.CreateGroup)
+	group.POST("/update", handles.UpdateGroup)
+	group.POST("/delete", handles.DeleteGroup)
+
+	share := g.Group("/share")
+	share.GET("/list", handles.ListShares)
+	share.GET("/get", handles.GetShare)
+	share.POST("/update", handles.UpdateShare)
+	share.POST("/delete", handles.DeleteShare)
+
+	fileRequest := g.Group("/file_request")
+	fileRequest.GET("/list", handles.ListFileRequests)
+	fileRequest.GET("/get", handles.GetFileRequest)
+	fileRequest.POST("/update", handles.UpdateFileRequest)
+	fileRequest.POST("/delete", handles.DeleteFileRequest)
+
+	auditLog := g.Group("/audit")
+	auditLog.GET("/list", handles.ListAuditLogs)
+	auditLog.GET("/export", handles.ExportAuditLogs)
+
+	lockout := g.Group("/lockout")
+	lockout.GET("/list", handles.ListLockouts)
+	lockout.POST("/unlock", handles.Unlock)
+
+	webdavLock := g.Group("/webdav_lock")
+	webdavLock.GET("/list", handles.ListWebDAVLocks)
+	webdavLock.POST("/release", handles.ReleaseWebDAVLock)
+
+	acl := g.Group("/acl")
+	acl.GET("/list", handles.ListACLRules)
+	acl.GET("/get", handles.GetACLRule)
+	acl.GET("/check", handles.CheckACL)
+	acl.POST("/create", handles.CreateACLRule)
+	acl.POST("/update", handles.UpdateACLRule)
+	acl.POST("/delete", handles.DeleteACLRule)
+
+	webhook := g.Group("/webhook")
+	webhook.GET("/list", handles.ListWebhooks)
+	webhook.GET("/get", handles.GetWebhook)
+	webhook.POST("/create", handles.CreateWebhook)
+	webhook.POST("/update", handles.UpdateWebhook)
+	webhook.POST("/delete", handles.DeleteWebhook)
+	webhook.POST("/test", handles.TestWebhook)
+	webhook.GET("/deliveries", handles.ListWebhookDeliveries)
+
+	schedule := g.Group("/schedule")
+	schedule.GET("/list", handles.ListScheduledJobs)
+	schedule.GET("/get", handles.GetScheduledJob)
+	schedule.GET("/types", handles.ListScheduledJobTypes)
+	schedule.POST("/create", handles.CreateScheduledJob)
+	schedule.POST("/update", handles.UpdateScheduledJob)
+	schedule.POST("/delete", handles.DeleteScheduledJob)
+	schedule.POST("/enable", handles.EnableScheduledJob)
+	schedule.POST("/disable", handles.DisableScheduledJob)
+	schedule.POST("/run", handles.RunScheduledJob)
+	schedule.GET("/runs", handles.ListScheduledJobRuns)
 
 	storage := g.Group("/storage")
 	storage.GET("/list", handles.ListStorages)
@@ -144,6 +255,7 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/delete", handles.DeleteSetting)
 	setting.POST("/default", handles.DefaultSettings)
 	setting.POST("/reset_token", handles.ResetToken)
+	setting.POST("/reset_scim_token", handles.ResetSCIMToken)
 	setting.POST("/set_aria2", handles.SetAria2)
 	setting.POST("/set_qbit", handles.SetQbittorrent)
 	setting.POST("/set_transmission", handles.SetTransmission)
@@ -175,6 +287,7 @@ func _fs(g *gin.RouterGroup) {
 	g.Any("/get", handles.FsGet)
 	g.Any("/other", handles.FsOther)
 	g.Any("/dirs", handles.FsDirs)
+	g.GET("/watch", handles.FsWatch)
 	g.POST("/mkdir", handles.FsMkdir)
 	g.POST("/rename", handles.FsRename)
 	g.POST("/batch_rename", handles.FsBatchRename)
@@ -182,8 +295,13 @@ func _fs(g *gin.RouterGroup) {
 	g.POST("/move", handles.FsMove)
 	g.POST("/recursive_move", handles.FsRecursiveMove)
 	g.POST("/copy", handles.FsCopy)
+	g.POST("/sync", handles.FsSync)
 	g.POST("/remove", handles.FsRemove)
 	g.POST("/remove_empty_directory", handles.FsRemoveEmptyDirectory)
+	trash := g.Group("/trash")
+	trash.Any("/list", handles.FsTrashList)
+	trash.POST("/restore", handles.FsTrashRestore)
+	trash.POST("/purge", handles.FsTrashPurge)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	g.PUT("/put", middlewares.FsUp, uploadLimiter, handles.FsStream)
 	g.PUT("/form", middlewares.FsUp, uploadLimiter, handles.FsForm)
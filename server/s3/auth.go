@@ -0,0 +1,309 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/itsHenry35/gofakes3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// registeredFaker is a gofakes3 server with the access keys added to it
+type registeredFaker struct {
+	*gofakes3.GoFakeS3
+	// keys mirrors the access keys of the server, gofakes3 requires signatures once there is any
+	keys map[string]struct{}
+	// apiTokens reports whether the api tokens have been added
+	apiTokens bool
+}
+
+func (f *registeredFaker) addKeys(keys map[string]string) {
+	if len(keys) == 0 {
+		return
+	}
+	f.AddAuthKeys(keys)
+	for k := range keys {
+		f.keys[k] = struct{}{}
+	}
+}
+
+func (f *registeredFaker) delKeys(keys ...string) {
+	f.DelAuthKeys(keys)
+	for _, k := range keys {
+		delete(f.keys, k)
+	}
+}
+
+// addAPITokens lets the api tokens sign requests to f
+func (f *registeredFaker) addAPITokens() {
+	f.apiTokens = true
+	tokens, err := op.GetValidAPITokens()
+	if err != nil {
+		log.Errorf("failed get api tokens for s3: %+v", err)
+		return
+	}
+	keys := make(map[string]string, len(tokens))
+	for i := range tokens {
+		keys[op.APITokenAccessKeyID(&tokens[i])] = op.APITokenS3Secret(&tokens[i])
+	}
+	f.addKeys(keys)
+}
+
+var (
+	fakers   []*registeredFaker
+	fakersMu sync.Mutex
+	hookO    sync.Once
+)
+
+// registerCredentials lets the s3 keys of the users and the api tokens sign requests to faker, authList is the global key.
+// The server is open while it has no key, adding an api token then would lock out the anonymous clients,
+// so the api tokens are only added once there is the global key or an s3 key.
+func registerCredentials(faker *gofakes3.GoFakeS3, authList map[string]string) {
+	f := &registeredFaker{GoFakeS3: faker, keys: make(map[string]struct{})}
+	for k := range authList {
+		f.keys[k] = struct{}{}
+	}
+	s3Keys, err := op.GetEnabledS3Keys()
+	if err != nil {
+		log.Errorf("failed get s3 keys: %+v", err)
+	}
+	keys := make(map[string]string, len(s3Keys))
+	for i := range s3Keys {
+		secret, err := op.S3KeySecret(&s3Keys[i])
+		if err != nil {
+			log.Errorf("failed get secret of s3 key: %+v", err)
+			continue
+		}
+		keys[s3Keys[i].AccessKeyID] = secret
+	}
+	f.addKeys(keys)
+	if len(f.keys) > 0 {
+		f.addAPITokens()
+	}
+	fakersMu.Lock()
+	fakers = append(fakers, f)
+	fakersMu.Unlock()
+	hookO.Do(func() {
+		op.RegisterAPITokenHook(apiTokenHook)
+		op.RegisterS3KeyHook(s3KeyHook)
+	})
+}
+
+// authEnabled reports whether the requests must be signed
+func authEnabled() bool {
+	fakersMu.Lock()
+	defer fakersMu.Unlock()
+	for _, f := range fakers {
+		if len(f.keys) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func apiTokenHook(typ string, token *model.APIToken) {
+	fakersMu.Lock()
+	defer fakersMu.Unlock()
+	for _, f := range fakers {
+		if !f.apiTokens {
+			continue
+		}
+		switch typ {
+		case "add":
+			f.addKeys(map[string]string{op.APITokenAccessKeyID(token): op.APITokenS3Secret(token)})
+		case "del":
+			f.delKeys(op.APITokenAccessKeyID(token))
+		}
+	}
+}
+
+func s3KeyHook(typ string, key *model.S3Key) {
+	fakersMu.Lock()
+	defer fakersMu.Unlock()
+	for _, f := range fakers {
+		if typ == "del" || key.Disabled {
+			f.delKeys(key.AccessKeyID)
+			continue
+		}
+		secret, err := op.S3KeySecret(key)
+		if err != nil {
+			log.Errorf("failed get secret of s3 key: %+v", err)
+			f.delKeys(key.AccessKeyID)
+			continue
+		}
+		f.addKeys(map[string]string{key.AccessKeyID: secret})
+		if !f.apiTokens {
+			f.addAPITokens()
+		}
+	}
+}
+
+// requestAccessKey returns the access key id a request claims to be signed with,
+// the signature itself is verified by gofakes3
+func requestAccessKey(r *http.Request) string {
+	credential := r.URL.Query().Get("X-Amz-Credential")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if i := strings.Index(auth, "Credential="); i >= 0 {
+			credential = auth[i+len("Credential="):]
+		} else if strings.HasPrefix(auth, "AWS ") {
+			credential, _, _ = strings.Cut(strings.TrimPrefix(auth, "AWS "), ":")
+		}
+	}
+	if credential == "" {
+		credential = r.URL.Query().Get("AWSAccessKeyId")
+	}
+	accessKey, _, _ := strings.Cut(credential, "/")
+	return strings.TrimSpace(accessKey)
+}
+
+// apiTokenMiddleware runs the requests signed with an api token as the owner of the token,
+// and denies the requests out of the base path or the permissions of the token
+func apiTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey := requestAccessKey(r)
+		if !strings.HasPrefix(accessKey, model.APITokenPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := op.ValidateAPITokenKeyID(accessKey)
+		if err != nil {
+			accessDenied(w, r, err.Error())
+			return
+		}
+		if msg := checkUserAccess(user, r); msg != "" {
+			accessDenied(w, r, msg)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), consts.UserKey, user)))
+	})
+}
+
+// s3KeyMiddleware runs the requests signed with an s3 key as the owner of the key,
+// and denies the requests to the buckets out of the allow-list of the key
+func s3KeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey := requestAccessKey(r)
+		if !strings.HasPrefix(accessKey, model.S3KeyPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, key, err := op.ValidateS3Key(accessKey)
+		if err != nil {
+			accessDenied(w, r, err.Error())
+			return
+		}
+		if msg := checkKeyAccess(key, r); msg != "" {
+			accessDenied(w, r, msg)
+			return
+		}
+		if msg := checkUserAccess(user, r); msg != "" {
+			accessDenied(w, r, msg)
+			return
+		}
+		ctx := context.WithValue(r.Context(), consts.UserKey, user)
+		ctx = context.WithValue(ctx, consts.S3KeyKey, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// checkKeyAccess returns why the bucket allow-list of key refuses the request, "" means it's allowed
+func checkKeyAccess(key *model.S3Key, r *http.Request) string {
+	bucketName, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucketName == "" {
+		// the buckets are filtered by ListBuckets
+		return ""
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if !key.CanRead(bucketName) {
+			return "the access key can't read bucket " + bucketName
+		}
+	} else if !key.CanWrite(bucketName) {
+		return "the access key can't write bucket " + bucketName
+	}
+	if src := r.Header.Get("X-Amz-Copy-Source"); src != "" {
+		src, _ = url.PathUnescape(src)
+		srcBucketName, _, _ := strings.Cut(strings.TrimPrefix(src, "/"), "/")
+		if !key.CanRead(srcBucketName) {
+			return "the access key can't read bucket " + srcBucketName
+		}
+	}
+	return ""
+}
+
+// checkUserAccess returns why user can't make the request, "" means it's allowed
+func checkUserAccess(user *model.User, r *http.Request) string {
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucketName == "" {
+		// the buckets are filtered by ListBuckets
+		return ""
+	}
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		// let gofakes3 report the missing bucket
+		return ""
+	}
+	if !userCanAccess(user, path.Join(bucket.Path, key)) {
+		return "the path is out of the base path of the access key"
+	}
+	if src := r.Header.Get("X-Amz-Copy-Source"); src != "" {
+		src, _ = url.PathUnescape(src)
+		srcBucketName, srcKey, _ := strings.Cut(strings.TrimPrefix(src, "/"), "/")
+		srcBucket, err := getBucketByName(srcBucketName)
+		if err == nil && !userCanAccess(user, path.Join(srcBucket.Path, srcKey)) {
+			return "the copy source is out of the base path of the access key"
+		}
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if !user.CanWrite() {
+			return "the access key can't write"
+		}
+	case http.MethodPost:
+		if _, ok := r.URL.Query()["delete"]; ok {
+			if !user.CanRemove() {
+				return "the access key can't remove"
+			}
+		} else if !user.CanWrite() {
+			return "the access key can't write"
+		}
+	case http.MethodDelete:
+		// aborting a multipart upload only drops the staged parts
+		if r.URL.Query().Get("uploadId") != "" {
+			if !user.CanWrite() {
+				return "the access key can't write"
+			}
+		} else if !user.CanRemove() {
+			return "the access key can't remove"
+		}
+	}
+	return ""
+}
+
+// userCanAccess reports whether the mount path fp is in the base path of user, it's true if there is no user
+func userCanAccess(user *model.User, fp string) bool {
+	return user == nil || utils.IsSubPath(user.GetBasePath(), fp)
+}
+
+func accessDenied(w http.ResponseWriter, r *http.Request, msg string) {
+	s3Error(w, r, http.StatusForbidden, "AccessDenied", msg)
+}
+
+func s3Error(w http.ResponseWriter, r *http.Request, status int, code gofakes3.ErrorCode, msg string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(gofakes3.ErrorResponse{Code: code, Message: msg})
+}
@@ -0,0 +1,37 @@
+package s3
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+)
+
+func TestCheckKeyAccess(t *testing.T) {
+	key := &model.S3Key{Buckets: []model.S3KeyBucket{{Name: "docs"}, {Name: "uploads", Write: true}}}
+	cases := []struct {
+		method, target, copySource string
+		allowed                    bool
+	}{
+		{"GET", "/", "", true},
+		{"GET", "/docs/a.txt", "", true},
+		{"PUT", "/docs/a.txt", "", false},
+		{"PUT", "/uploads/a.txt", "", true},
+		{"DELETE", "/uploads/a.txt", "", true},
+		{"GET", "/private/a.txt", "", false},
+		{"PUT", "/uploads/a.txt", "/docs/a.txt", true},
+		{"PUT", "/uploads/a.txt", "/private/a.txt", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, c.target, nil)
+		if c.copySource != "" {
+			r.Header.Set("X-Amz-Copy-Source", c.copySource)
+		}
+		if msg := checkKeyAccess(key, r); (msg == "") != c.allowed {
+			t.Errorf("%s %s copy %q: expected allowed %v, got %q", c.method, c.target, c.copySource, c.allowed, msg)
+		}
+	}
+	if msg := checkKeyAccess(&model.S3Key{}, httptest.NewRequest("PUT", "/private/a.txt", nil)); msg != "" {
+		t.Errorf("expected a key without allow-list to allow all buckets, got %q", msg)
+	}
+}
@@ -3,11 +3,9 @@ package s3
 
 import (
 	"context"
-	"encoding/hex"
 	"io"
 	"path"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/itsHenry35/gofakes3"
@@ -30,16 +28,12 @@ var (
 )
 
 // s3Backend implements the gofacess3.Backend interface to make an S3
-// backend for gofakes3
-type s3Backend struct {
-	meta *sync.Map
-}
+// backend for gofakes3, the metadata and the MD5 of the objects are kept in the database by op.SaveS3Object
+type s3Backend struct{}
 
 // newBackend creates a new SimpleBucketBackend.
-func newBackend() gofakes3.Backend {
-	return &s3Backend{
-		meta: new(sync.Map),
-	}
+func newBackend() *s3Backend {
+	return &s3Backend{}
 }
 
 // ListBuckets always returns the default bucket.
@@ -48,8 +42,13 @@ func (b *s3Backend) ListBuckets(ctx context.Context) ([]gofakes3.BucketInfo, err
 	if err != nil {
 		return nil, err
 	}
+	user, _ := ctx.Value(consts.UserKey).(*model.User)
+	key, _ := ctx.Value(consts.S3KeyKey).(*model.S3Key)
 	var response []gofakes3.BucketInfo
 	for _, b := range buckets {
+		if !userCanAccess(user, b.Path) || key != nil && !key.CanRead(b.Name) {
+			continue
+		}
 		node, _ := fs.Get(ctx, b.Path, &fs.GetArgs{})
 		response = append(response, gofakes3.BucketInfo{
 			// Name:         gofakes3.URLEncode(b.Name),
@@ -95,8 +94,6 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 }
 
 // HeadObject returns the fileinfo for the given object name.
-//
-// Note that the metadata is not supported yet.
 func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName string) (*gofakes3.Object, error) {
 	bucket, err := getBucketByName(bucketName)
 	if err != nil {
@@ -116,23 +113,16 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 	}
 
 	size := node.GetSize()
-	// hash := getFileHashByte(fobj)
-
+	rec := getS3Object(fp)
 	meta := map[string]string{
 		"Last-Modified": node.ModTime().Format(timeFormat),
 		"Content-Type":  utils.GetMimeType(fp),
 	}
-
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
-	}
+	mergeMetadata(meta, rec)
 
 	return &gofakes3.Object{
-		Name: objectName,
-		// Hash:     hash,
+		Name:     objectName,
+		Hash:     etagHash(objectETag(fp, node, rec)),
 		Metadata: meta,
 		Size:     size,
 		Contents: noOpReadCloser{},
@@ -192,23 +182,24 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 		return nil, err
 	}
 
+	rec := getS3Object(fp)
 	meta := map[string]string{
 		"Last-Modified":       node.ModTime().Format(timeFormat),
 		"Content-Disposition": utils.GenerateContentDisposition(file.GetName()),
 		"Content-Type":        utils.GetMimeType(fp),
 	}
+	mergeMetadata(meta, rec)
 
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
+	etag := objectETag(fp, node, rec)
+	if rnge == nil && !isMD5(etag) {
+		// the MD5 is computed while the whole object is read, so it's the ETag next time
+		rd = newMD5Recorder(rd, fp, node)
 	}
 
 	return &gofakes3.Object{
 		// Name: gofakes3.URLEncode(objectName),
-		Name: objectName,
-		// Hash:     "",
+		Name:     objectName,
+		Hash:     etagHash(etag),
 		Metadata: meta,
 		Size:     size,
 		Range:    rnge,
@@ -228,9 +219,19 @@ func (b *s3Backend) PutObject(
 	meta map[string]string,
 	input io.Reader, size int64,
 ) (result gofakes3.PutObjectResult, err error) {
+	_, err = b.putObject(ctx, bucketName, objectName, meta, input, size)
+	return result, err
+}
+
+// putObject puts the object and keeps its metadata, it returns the hex MD5 of the content as the ETag
+func (b *s3Backend) putObject(
+	ctx context.Context, bucketName, objectName string,
+	meta map[string]string,
+	input io.Reader, size int64,
+) (etag string, err error) {
 	bucket, err := getBucketByName(bucketName)
 	if err != nil {
-		return result, err
+		return "", err
 	}
 	bucketPath := bucket.Path
 
@@ -256,15 +257,18 @@ func (b *s3Backend) PutObject(
 			log.Debugf("reqPath: %s not found and objectName contains /, need to makeDir", reqPath)
 			err = fs.MakeDir(ctx, reqPath, true)
 			if err != nil {
-				return result, errs.WithMessagef(err, "failed to makeDir, reqPath: %s", reqPath)
+				return "", errs.WithMessagef(err, "failed to makeDir, reqPath: %s", reqPath)
 			}
 		} else {
-			return result, gofakes3.KeyNotFound(objectName)
+			return "", gofakes3.KeyNotFound(objectName)
 		}
 	}
 
 	if isDir {
-		return result, nil
+		return "", nil
+	}
+	if err = fs.CheckQuota(ctx, fp, size); err != nil {
+		return "", gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, err.Error())
 	}
 
 	var ti time.Time
@@ -283,20 +287,31 @@ func (b *s3Backend) PutObject(
 		Modified: ti,
 		Ctime:    time.Now(),
 	}
+	rd := newMD5Reader(input)
 	s := &stream.FileStream{
 		Obj:      &obj,
-		Reader:   input,
+		Reader:   rd,
 		Mimetype: meta["Content-Type"],
 	}
 
 	err = fs.PutDirectly(ctx, reqPath, s)
 	if err != nil {
-		return result, err
+		return "", err
 	}
 
-	b.meta.Store(fp, meta)
+	etag = rd.Sum()
+	rec := &model.S3Object{Path: fp, Metadata: objectMetadata(meta)}
+	// the MD5 is only kept if the driver has read the whole content
+	if node, err := fs.Get(ctx, fp, &fs.GetArgs{}); err == nil && rd.n == size && node.GetSize() == size {
+		rec.MD5 = etag
+		rec.Size = size
+		rec.Modified = node.ModTime()
+	}
+	if err = op.SaveS3Object(rec); err != nil {
+		log.Errorf("failed save s3 object %s: %+v", fp, err)
+	}
 
-	return result, nil
+	return etag, nil
 }
 
 // DeleteMulti deletes multiple objects in a single request.
@@ -371,8 +386,7 @@ func (b *s3Backend) BucketExists(ctx context.Context, name string) (exists bool,
 // CopyObject copy specified object from srcKey to dstKey.
 func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, meta map[string]string) (result gofakes3.CopyObjectResult, err error) {
 	if srcBucket == dstBucket && srcKey == dstKey {
-		// TODO: update meta
-		return result, nil
+		return b.replaceMetadata(ctx, srcBucket, srcKey, meta)
 	}
 
 	srcB, err := getBucketByName(srcBucket)
@@ -393,22 +407,57 @@ func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket
 		_ = c.Contents.Close()
 	}()
 
-	for k, v := range c.Metadata {
-		if _, found := meta[k]; !found && k != "X-Amz-Acl" {
-			meta[k] = v
+	if !strings.EqualFold(meta["X-Amz-Metadata-Directive"], "REPLACE") {
+		// the metadata of the source is copied unless it's replaced
+		for k := range objectMetadata(meta) {
+			delete(meta, k)
+		}
+		if rec := getS3Object(srcFp); rec != nil {
+			for k, v := range rec.Metadata {
+				meta[k] = v
+			}
 		}
 	}
 	if _, ok := meta["mtime"]; !ok {
 		meta["mtime"] = swift.TimeToFloatString(srcNode.ModTime())
 	}
 
-	_, err = b.PutObject(ctx, dstBucket, dstKey, meta, c.Contents, c.Size)
+	etag, err := b.putObject(ctx, dstBucket, dstKey, meta, c.Contents, c.Size)
 	if err != nil {
 		return
 	}
 
 	return gofakes3.CopyObjectResult{
-		ETag:         `"` + hex.EncodeToString(c.Hash) + `"`,
+		ETag:         `"` + etag + `"`,
 		LastModified: gofakes3.NewContentTime(srcNode.ModTime()),
 	}, nil
-}
\ No newline at end of file
+}
+
+// replaceMetadata replaces the metadata of an object copied onto itself with the REPLACE directive,
+// the content is kept
+func (b *s3Backend) replaceMetadata(ctx context.Context, bucketName, objectName string, meta map[string]string) (result gofakes3.CopyObjectResult, err error) {
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		return result, err
+	}
+	fp := path.Join(bucket.Path, objectName)
+	fmeta, _ := op.GetNearestMeta(fp)
+	node, err := fs.Get(context.WithValue(ctx, consts.MetaKey, fmeta), fp, &fs.GetArgs{})
+	if err != nil || node.IsDir() {
+		return result, gofakes3.KeyNotFound(objectName)
+	}
+	rec := getS3Object(fp)
+	if strings.EqualFold(meta["X-Amz-Metadata-Directive"], "REPLACE") {
+		if rec == nil {
+			rec = &model.S3Object{Path: fp}
+		}
+		rec.Metadata = objectMetadata(meta)
+		if err = op.SaveS3Object(rec); err != nil {
+			return result, err
+		}
+	}
+	return gofakes3.CopyObjectResult{
+		ETag:         `"` + objectETag(fp, node, rec) + `"`,
+		LastModified: gofakes3.NewContentTime(node.ModTime()),
+	}, nil
+}
@@ -0,0 +1,129 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// getS3Object returns the record of the object at fp, nil if there is none or it failed
+func getS3Object(fp string) *model.S3Object {
+	rec, err := op.GetS3Object(fp)
+	if err != nil {
+		log.Errorf("failed get s3 object %s: %+v", fp, err)
+		return nil
+	}
+	return rec
+}
+
+// objectMetadata picks the headers kept with an object from meta
+func objectMetadata(meta map[string]string) map[string]string {
+	res := make(map[string]string)
+	for k, v := range meta {
+		k = http.CanonicalHeaderKey(k)
+		switch {
+		case strings.HasPrefix(k, "X-Amz-Meta-"),
+			k == "Content-Type", k == "Content-Disposition", k == "Content-Encoding", k == "Content-Language",
+			k == "Cache-Control", k == "Expires":
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// mergeMetadata overrides meta with the metadata kept in rec
+func mergeMetadata(meta map[string]string, rec *model.S3Object) {
+	if rec == nil {
+		return
+	}
+	for k, v := range rec.Metadata {
+		meta[k] = v
+	}
+}
+
+// objectETag returns the hex ETag of obj at fp, rec is its record and may be nil.
+// It's the MD5 from the storage, or the one computed by the S3 server while the object is unchanged.
+// Otherwise it's derived from the path, the size and the modified time, it has 40 digits so the clients won't check it as an MD5.
+func objectETag(fp string, obj model.Obj, rec *model.S3Object) string {
+	if sum := obj.GetHash().GetHash(utils.MD5); isMD5(sum) {
+		return strings.ToLower(sum)
+	}
+	if rec != nil {
+		if sum := rec.MD5Of(obj); sum != "" {
+			return sum
+		}
+	}
+	return utils.HashData(utils.SHA1, []byte(fmt.Sprintf("%s\n%d\n%d", fp, obj.GetSize(), obj.ModTime().Unix())))
+}
+
+func isMD5(s string) bool {
+	if len(s) != md5.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// etagHash converts the hex ETag to the hash of gofakes3.Object
+func etagHash(etag string) []byte {
+	b, _ := hex.DecodeString(etag)
+	return b
+}
+
+// md5Reader computes the MD5 of what is read through it
+type md5Reader struct {
+	io.Reader
+	h hash.Hash
+	n int64
+}
+
+func newMD5Reader(r io.Reader) *md5Reader {
+	return &md5Reader{Reader: r, h: md5.New()}
+}
+
+func (r *md5Reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.h.Write(p[:n])
+	r.n += int64(n)
+	return n, err
+}
+
+// Sum returns the hex MD5 of what has been read
+func (r *md5Reader) Sum() string {
+	return hex.EncodeToString(r.h.Sum(nil))
+}
+
+// md5Recorder records the MD5 of the object at fp once it's read to the end
+type md5Recorder struct {
+	*md5Reader
+	fp   string
+	obj  model.Obj
+	once sync.Once
+}
+
+func newMD5Recorder(r io.Reader, fp string, obj model.Obj) io.Reader {
+	return &md5Recorder{md5Reader: newMD5Reader(r), fp: fp, obj: obj}
+}
+
+func (r *md5Recorder) Read(p []byte) (int, error) {
+	n, err := r.md5Reader.Read(p)
+	if err == io.EOF && r.n == r.obj.GetSize() {
+		r.once.Do(func() {
+			if err := op.SetS3ObjectMD5(r.fp, r.Sum(), r.obj.GetSize(), r.obj.ModTime()); err != nil {
+				log.Errorf("failed save md5 of s3 object %s: %+v", r.fp, err)
+			}
+		})
+	}
+	return n, err
+}
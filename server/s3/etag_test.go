@@ -0,0 +1,32 @@
+package s3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+func TestObjectETag(t *testing.T) {
+	modified := time.Unix(1700000000, 0)
+	obj := &model.Object{Name: "a.txt", Size: 5, Modified: modified}
+	rec := &model.S3Object{MD5: "5d41402abc4b2a76b9719d911017c592", Size: 5, Modified: modified}
+
+	derived := objectETag("/b/a.txt", obj, nil)
+	if len(derived) != 40 || derived != objectETag("/b/a.txt", obj, nil) {
+		t.Errorf("expected a stable 40 digits etag without md5, got %s", derived)
+	}
+	if etag := objectETag("/b/a.txt", obj, rec); etag != rec.MD5 {
+		t.Errorf("expected the recorded md5, got %s", etag)
+	}
+	changed := &model.Object{Name: "a.txt", Size: 6, Modified: modified}
+	if etag := objectETag("/b/a.txt", changed, rec); etag == rec.MD5 {
+		t.Error("expected the recorded md5 to be dropped once the size changes")
+	}
+	hashed := &model.Object{Name: "a.txt", Size: 5, Modified: modified,
+		HashInfo: utils.NewHashInfo(utils.MD5, "D41D8CD98F00B204E9800998ECF8427E")}
+	if etag := objectETag("/b/a.txt", hashed, rec); etag != "d41d8cd98f00b204e9800998ecf8427e" {
+		t.Errorf("expected the md5 of the storage, got %s", etag)
+	}
+}
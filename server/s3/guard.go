@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
+)
+
+// guardMiddleware counts the requests refused by the signature check of gofakes3 to the access key and the client ip,
+// the locked ones are refused before the check.
+// It wraps the handlers checking the signature directly so the requests refused by apiTokenMiddleware and s3KeyMiddleware are not counted.
+func guardMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey := requestAccessKey(r)
+		if accessKey == "" {
+			// the anonymous requests are not signed
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip, _ := r.Context().Value(consts.ClientIPKey).(string)
+		if remaining := authguard.Locked(accessKey, ip); remaining > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+			s3Error(w, r, http.StatusServiceUnavailable, "SlowDown", "too many requests with a wrong signature, try again later")
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		switch {
+		case sw.status == http.StatusForbidden:
+			if signatureRefused(sw.body.String()) {
+				authguard.Fail(accessKey, ip, audit.ProtocolS3)
+			}
+		case sw.status < http.StatusBadRequest:
+			authguard.Success(accessKey, ip)
+		}
+	})
+}
+
+// signatureRefused reports whether the error body is one of the signature check
+func signatureRefused(body string) bool {
+	return strings.Contains(body, "<Code>SignatureDoesNotMatch</Code>") || strings.Contains(body, "<Code>InvalidAccessKeyId</Code>")
+}
+
+// maxErrorBody is the bytes of an error body kept by statusWriter
+const maxErrorBody = 1024
+
+// statusWriter records the status code written by the next handler and the head of the error body
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	body   strings.Builder
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status >= http.StatusBadRequest && w.body.Len() < maxErrorBody {
+		w.body.Write(b[:min(len(b), maxErrorBody-w.body.Len())])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -3,7 +3,15 @@ package s3
 // Credits: https://pkg.go.dev/github.com/rclone/rclone@v1.65.2/cmd/serve/s3
 // Package s3 implements a fake s3 server for OpenList
 
-import "io"
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
 
 // noOpReadCloser implements a no-operation ReadCloser that always returns EOF
 type noOpReadCloser struct{}
@@ -45,4 +53,96 @@ func (rwc *readerWithCloser) Close() error {
 		return rwc.closer()
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// partsReader reads the staged part files one after another, only one of them is open at a time
+type partsReader struct {
+	paths []string
+	cur   *os.File
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur == nil {
+			if len(p.paths) == 0 {
+				return 0, io.EOF
+			}
+			f, err := os.Open(p.paths[0])
+			if err != nil {
+				return 0, err
+			}
+			p.cur, p.paths = f, p.paths[1:]
+		}
+		n, err := p.cur.Read(b)
+		if err == io.EOF {
+			_ = p.cur.Close()
+			p.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (p *partsReader) Close() error {
+	if p.cur == nil {
+		return nil
+	}
+	return p.cur.Close()
+}
+
+// awsChunkedReader decodes the aws-chunked body sent with the STREAMING-* payloads,
+// the chunk signatures and the trailers are not checked
+type awsChunkedReader struct {
+	r      *bufio.Reader
+	remain int64
+	done   bool
+}
+
+func newAWSChunkedReader(r io.Reader) *awsChunkedReader {
+	return &awsChunkedReader{r: bufio.NewReader(r)}
+}
+
+func (c *awsChunkedReader) Read(p []byte) (int, error) {
+	for c.remain == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	if int64(len(p)) > c.remain {
+		p = p[:c.remain]
+	}
+	n, err := c.r.Read(p)
+	c.remain -= int64(n)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	if err == nil && c.remain == 0 {
+		// the CRLF after the chunk data
+		_, err = c.r.Discard(2)
+	}
+	return n, err
+}
+
+// nextChunk reads the header of the next chunk, "<hex size>[;chunk-signature=<signature>]\r\n"
+func (c *awsChunkedReader) nextChunk() error {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	sizeStr, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+	size, err := strconv.ParseInt(sizeStr, 16, 64)
+	if err != nil || size < 0 {
+		return errs.Errorf("invalid aws-chunked chunk header: %q", line)
+	}
+	c.remain, c.done = size, size == 0
+	return nil
+}
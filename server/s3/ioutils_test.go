@@ -0,0 +1,43 @@
+package s3
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAWSChunkedReader(t *testing.T) {
+	signed := "5;chunk-signature=" + strings.Repeat("a", 64) + "\r\nhello\r\n" +
+		"6;chunk-signature=" + strings.Repeat("b", 64) + "\r\n world\r\n" +
+		"0;chunk-signature=" + strings.Repeat("c", 64) + "\r\n\r\n"
+	unsigned := "5\r\nhello\r\n6\r\n world\r\n0\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n"
+	for name, body := range map[string]string{"signed": signed, "unsigned trailer": unsigned} {
+		data, err := io.ReadAll(newAWSChunkedReader(strings.NewReader(body)))
+		if err != nil || string(data) != "hello world" {
+			t.Errorf("%s: expected hello world, got %q, %v", name, data, err)
+		}
+	}
+	if _, err := io.ReadAll(newAWSChunkedReader(strings.NewReader("5\r\nhel"))); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected a truncated body to fail, got %v", err)
+	}
+}
+
+func TestPartsReader(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i, part := range []string{"multi", "", "part"} {
+		p := filepath.Join(dir, string(rune('1'+i)))
+		if err := os.WriteFile(p, []byte(part), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	rd := &partsReader{paths: paths}
+	defer rd.Close()
+	data, err := io.ReadAll(rd)
+	if err != nil || string(data) != "multipart" {
+		t.Errorf("expected the parts in order, got %q, %v", data, err)
+	}
+}
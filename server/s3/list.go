@@ -10,6 +10,8 @@ import (
 
 	"github.com/itsHenry35/gofakes3"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/internal/op"
 )
 
 // entryListR recursively lists entries in a directory and adds them to the response
@@ -34,7 +36,7 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 			// Key:          gofakes3.URLEncode(path.Join(fdPath, emptyObjectName)),
 			Key:          path.Join(fdPath, emptyObjectName),
 			LastModified: gofakes3.NewContentTime(time.Now()),
-			ETag:         getFileHash(nil), // No entry, so no hash
+			ETag:         `"d41d8cd98f00b204e9800998ecf8427e"`, // the MD5 of the empty content
 			Size:         0,
 			StorageClass: gofakes3.StorageStandard,
 		}
@@ -43,6 +45,18 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 		return nil
 	}
 
+	// the records keep the ETags computed for the files
+	var paths []string
+	for _, entry := range dirEntries {
+		if !entry.IsDir() && strings.HasPrefix(entry.GetName(), name) {
+			paths = append(paths, path.Join(fullPath, entry.GetName()))
+		}
+	}
+	records, err := op.GetS3Objects(paths)
+	if err != nil {
+		log.Errorf("failed get s3 objects of %s: %+v", fullPath, err)
+	}
+
 	// Process each entry in the directory
 	for _, entry := range dirEntries {
 		objectName := entry.GetName()
@@ -68,10 +82,11 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 			}
 		} else {
 			// Add file as a content item
+			fp := path.Join(fullPath, objectName)
 			item := &gofakes3.Content{
 				Key:          objectPath,
 				LastModified: gofakes3.NewContentTime(entry.ModTime()),
-				ETag:         getFileHash(entry),
+				ETag:         `"` + objectETag(fp, entry, records[fp]) + `"`,
 				Size:         entry.GetSize(),
 				StorageClass: gofakes3.StorageStandard,
 			}
@@ -0,0 +1,638 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsHenry35/gofakes3"
+	"github.com/itsHenry35/gofakes3/signature"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/fs"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+const (
+	// multipartExpiry is how long an upload is kept without any part uploaded
+	multipartExpiry        = 24 * time.Hour
+	multipartSweepInterval = time.Hour
+	// nodeFile keeps the node id of the staging dir
+	nodeFile = ".node"
+)
+
+// multipartUpload is an upload in progress, its parts are staged in dir.
+// The upload and its parts are recorded in the database so they are restored after a restart.
+type multipartUpload struct {
+	model.S3MultipartUpload
+
+	dir string
+	mu  sync.Mutex
+	// committing is set while the parts are put to the storage, no part can be added or aborted meanwhile
+	committing bool
+	// receiving counts the parts being received, the upload doesn't expire meanwhile
+	receiving int
+	parts     map[int]model.S3MultipartPart
+}
+
+var (
+	uploads   = make(map[string]*multipartUpload)
+	uploadsMu sync.Mutex
+	sweepOnce sync.Once
+	// nodeID identifies the staging dir of this node, the nodes sharing a database each restore and
+	// expire the uploads staged in their own dir only
+	nodeID string
+)
+
+// multipartMiddleware serves the multipart uploads instead of gofakes3, which keeps all the parts in memory.
+// The parts are staged in conf.Conf.TempDir and put through the backend when the upload completes.
+func multipartMiddleware(backend *s3Backend, next http.Handler) http.Handler {
+	sweepOnce.Do(func() {
+		nodeID = stagingNodeID()
+		restoreMultipartUploads()
+		go sweepMultipartUploads()
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		uploadID := query.Get("uploadId")
+		_, base := query["uploads"]
+		if uploadID == "" && !base {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !verifySignature(w, r) {
+			return
+		}
+		bucket, key, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+		var err error
+		switch {
+		case uploadID == "" && r.Method == http.MethodPost:
+			err = createMultipartUpload(w, r, bucket, key)
+		case uploadID == "" && r.Method == http.MethodGet:
+			err = listMultipartUploads(w, r, bucket)
+		case r.Method == http.MethodPut:
+			err = uploadPart(w, r, bucket, key, uploadID)
+		case r.Method == http.MethodPost:
+			err = completeMultipartUpload(w, r, backend, bucket, key, uploadID)
+		case r.Method == http.MethodDelete:
+			err = abortMultipartUpload(w, r, bucket, key, uploadID)
+		case r.Method == http.MethodGet:
+			err = listParts(w, r, bucket, key, uploadID)
+		default:
+			err = gofakes3.ErrMethodNotAllowed
+		}
+		if err != nil {
+			writeS3Error(w, r, err)
+		}
+	})
+}
+
+// verifySignature checks the signature as gofakes3 does for the requests served without it,
+// the error body is the same so guardMiddleware counts the failures
+func verifySignature(w http.ResponseWriter, r *http.Request) bool {
+	if !authEnabled() {
+		return true
+	}
+	result := signature.V4SignVerify(r)
+	if result == signature.ErrUnsupportAlgorithm {
+		result = signature.V2SignVerify(r)
+	}
+	if result == signature.ErrNone {
+		return true
+	}
+	resp := signature.GetAPIError(result)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(resp.HTTPStatusCode)
+	_, _ = w.Write(signature.EncodeAPIErrorToResponse(resp))
+	return false
+}
+
+func requestUserID(r *http.Request) uint {
+	if user, ok := r.Context().Value(consts.UserKey).(*model.User); ok {
+		return user.ID
+	}
+	return 0
+}
+
+// uploadMeta keeps the headers of the initiate request which are stored with the object
+func uploadMeta(header http.Header) map[string]string {
+	meta := make(map[string]string)
+	for k, v := range header {
+		switch {
+		case k == "Content-Type", k == "Content-Disposition", k == "Content-Encoding", k == "Content-Language",
+			k == "Cache-Control", strings.HasPrefix(k, "X-Amz-Meta-"):
+			meta[k] = v[0]
+		}
+	}
+	return meta
+}
+
+func createMultipartUpload(w http.ResponseWriter, r *http.Request, bucketName, key string) error {
+	if _, err := getBucketByName(bucketName); err != nil {
+		return err
+	}
+	if key == "" {
+		return gofakes3.ErrInvalidURI
+	}
+	now := time.Now()
+	u := &multipartUpload{
+		S3MultipartUpload: model.S3MultipartUpload{
+			ID:        strings.ReplaceAll(uuid.NewString(), "-", ""),
+			Bucket:    bucketName,
+			Key:       key,
+			UserID:    requestUserID(r),
+			Node:      nodeID,
+			Meta:      uploadMeta(r.Header),
+			Initiated: now,
+			// the upload expires if no part is uploaded for multipartExpiry
+			LastActive: now,
+		},
+		parts: make(map[int]model.S3MultipartPart),
+	}
+	u.dir = uploadDir(u.ID)
+	if err := os.MkdirAll(u.dir, 0o755); err != nil {
+		return errs.Wrap(err, "failed create the staging dir of the upload")
+	}
+	if err := op.CreateS3MultipartUpload(&u.S3MultipartUpload); err != nil {
+		_ = os.RemoveAll(u.dir)
+		return err
+	}
+	uploadsMu.Lock()
+	uploads[u.ID] = u
+	uploadsMu.Unlock()
+	return writeXML(w, gofakes3.InitiateMultipartUpload{
+		Bucket:   bucketName,
+		Key:      key,
+		UploadID: gofakes3.UploadID(u.ID),
+	})
+}
+
+func uploadDir(id string) string {
+	return filepath.Join(conf.Conf.TempDir, consts.S3MultipartDir, id)
+}
+
+// stagingNodeID returns the node id kept in the staging dir, it's generated the first time.
+// The nodes sharing the staging dir share the id too.
+func stagingNodeID() string {
+	dir := filepath.Join(conf.Conf.TempDir, consts.S3MultipartDir)
+	name := filepath.Join(dir, nodeFile)
+	if data, err := os.ReadFile(name); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data))
+	}
+	id := random.String(16)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Errorf("failed create the s3 multipart staging dir: %+v", err)
+	} else if err = os.WriteFile(name, []byte(id), 0o644); err != nil {
+		log.Errorf("failed save the node id of the s3 multipart staging dir: %+v", err)
+	}
+	return id
+}
+
+// getUpload returns the upload of id, the bucket, the key and the user must be the ones which initiated it
+func getUpload(r *http.Request, bucketName, key, id string) (*multipartUpload, error) {
+	uploadsMu.Lock()
+	u, ok := uploads[id]
+	uploadsMu.Unlock()
+	if !ok {
+		// the upload may be staged on another node sharing the database
+		rec, err := op.GetS3MultipartUpload(id)
+		if err == nil && rec.Node != nodeID && rec.Bucket == bucketName && rec.Key == key && rec.UserID == requestUserID(r) {
+			return nil, gofakes3.ErrorMessage(gofakes3.ErrNoSuchUpload,
+				"the upload is staged on another node, its requests must be routed to the node which initiated it")
+		}
+		return nil, gofakes3.ErrNoSuchUpload
+	}
+	if u.Bucket != bucketName || u.Key != key || u.UserID != requestUserID(r) {
+		return nil, gofakes3.ErrNoSuchUpload
+	}
+	return u, nil
+}
+
+// partBody returns the body of the part and its size, the aws-chunked body is decoded
+func partBody(r *http.Request) (io.Reader, int64, error) {
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		size, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			return nil, 0, gofakes3.ErrMissingContentLength
+		}
+		return newAWSChunkedReader(r.Body), size, nil
+	}
+	if r.ContentLength < 0 {
+		return nil, 0, gofakes3.ErrMissingContentLength
+	}
+	return r.Body, r.ContentLength, nil
+}
+
+func uploadPart(w http.ResponseWriter, r *http.Request, bucketName, key, id string) error {
+	n, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || n <= 0 || n > gofakes3.MaxUploadPartNumber {
+		return gofakes3.ErrInvalidPart
+	}
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		return gofakes3.ErrNotImplemented
+	}
+	u, err := getUpload(r, bucketName, key, id)
+	if err != nil {
+		return err
+	}
+	body, size, err := partBody(r)
+	if err != nil {
+		return err
+	}
+	if err = checkPartQuota(r, u, n, size); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	u.receiving++
+	u.mu.Unlock()
+	defer func() {
+		u.mu.Lock()
+		u.receiving--
+		u.mu.Unlock()
+	}()
+
+	f, err := os.CreateTemp(u.dir, fmt.Sprintf("%d-*", n))
+	if err != nil {
+		return errs.Wrap(err, "failed create the part file")
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	h := md5.New()
+	written, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(body, size))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errs.Wrap(err, "failed save the part")
+	}
+	if written != size {
+		return gofakes3.ErrIncompleteBody
+	}
+	sum := h.Sum(nil)
+	if md5Base64 := r.Header.Get("Content-MD5"); md5Base64 != "" && md5Base64 != base64.StdEncoding.EncodeToString(sum) {
+		return gofakes3.ErrBadDigest
+	}
+
+	etag := `"` + hex.EncodeToString(sum) + `"`
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.committing {
+		return gofakes3.ErrNoSuchUpload
+	}
+	// a part uploaded again with the same number replaces the old one
+	partPath := filepath.Join(u.dir, strconv.Itoa(n))
+	if err = os.Rename(f.Name(), partPath); err != nil {
+		// the upload is aborted meanwhile
+		return gofakes3.ErrNoSuchUpload
+	}
+	part := model.S3MultipartPart{UploadID: u.ID, Number: n, Size: size, ETag: etag, LastModified: time.Now()}
+	if err = op.SaveS3MultipartPart(&part); err != nil {
+		// the old part is replaced already, the client has to upload it again
+		delete(u.parts, n)
+		_ = os.Remove(partPath)
+		return err
+	}
+	u.parts[n] = part
+	u.LastActive = part.LastModified
+	w.Header().Set("ETag", etag)
+	return nil
+}
+
+// checkPartQuota checks the quota against the size of all the parts of u with the part n of size bytes,
+// so the quota is enforced while the parts are staged rather than only when the upload completes
+func checkPartQuota(r *http.Request, u *multipartUpload, n int, size int64) error {
+	bucket, err := getBucketByName(u.Bucket)
+	if err != nil {
+		return err
+	}
+	total := size
+	u.mu.Lock()
+	for number, p := range u.parts {
+		if number != n {
+			total += p.Size
+		}
+	}
+	u.mu.Unlock()
+	if err = fs.CheckQuota(r.Context(), path.Join(bucket.Path, u.Key), total); err != nil {
+		return gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func completeMultipartUpload(w http.ResponseWriter, r *http.Request, backend *s3Backend, bucketName, key, id string) error {
+	var in gofakes3.CompleteMultipartUploadRequest
+	if err := xml.NewDecoder(r.Body).Decode(&in); err != nil {
+		return gofakes3.ErrMalformedXML
+	}
+	if len(in.Parts) == 0 {
+		return gofakes3.ErrInvalidPart
+	}
+	u, err := getUpload(r, bucketName, key, id)
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	if u.committing {
+		u.mu.Unlock()
+		return gofakes3.ErrNoSuchUpload
+	}
+	paths := make([]string, 0, len(in.Parts))
+	var size int64
+	prev := 0
+	for _, p := range in.Parts {
+		if p.PartNumber <= prev {
+			u.mu.Unlock()
+			return gofakes3.ErrInvalidPartOrder
+		}
+		prev = p.PartNumber
+		part, ok := u.parts[p.PartNumber]
+		if !ok || strings.Trim(p.ETag, `"`) != strings.Trim(part.ETag, `"`) {
+			u.mu.Unlock()
+			return gofakes3.ErrInvalidPart
+		}
+		size += part.Size
+		paths = append(paths, filepath.Join(u.dir, strconv.Itoa(p.PartNumber)))
+	}
+	u.committing = true
+	u.mu.Unlock()
+
+	rd := &partsReader{paths: paths}
+	etag, err := backend.putObject(r.Context(), bucketName, key, u.Meta, rd, size)
+	_ = rd.Close()
+	if err != nil {
+		// the client may complete it again or abort it
+		u.mu.Lock()
+		u.committing = false
+		u.mu.Unlock()
+		return err
+	}
+	removeUpload(u)
+	return writeXML(w, gofakes3.CompleteMultipartUploadResult{
+		Bucket: bucketName,
+		Key:    key,
+		// the etag is the md5 of the whole object rather than of the parts, so it's the same one HeadObject returns
+		ETag: `"` + etag + `"`,
+	})
+}
+
+func abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucketName, key, id string) error {
+	u, err := getUpload(r, bucketName, key, id)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	committing := u.committing
+	u.mu.Unlock()
+	if committing {
+		return gofakes3.ErrNoSuchUpload
+	}
+	removeUpload(u)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// removeUpload forgets u and deletes its staged parts, u.mu is held so no part is recorded meanwhile
+func removeUpload(u *multipartUpload) {
+	uploadsMu.Lock()
+	delete(uploads, u.ID)
+	uploadsMu.Unlock()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := op.DeleteS3MultipartUpload(u.ID); err != nil {
+		log.Errorf("failed delete the record of s3 upload %s: %+v", u.ID, err)
+	}
+	if err := os.RemoveAll(u.dir); err != nil {
+		log.Errorf("failed remove the staged parts of s3 upload %s: %+v", u.ID, err)
+	}
+}
+
+// restoreMultipartUploads loads the uploads staged on this node recorded before a restart, the parts which are
+// not staged any more are dropped, and so are the staged parts of the uploads without record.
+// The uploads of the other nodes sharing the database are left to them.
+func restoreMultipartUploads() {
+	records, parts, err := op.GetS3MultipartUploads(nodeID)
+	if err != nil {
+		log.Errorf("failed restore the s3 multipart uploads: %+v", err)
+		return
+	}
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	for _, rec := range records {
+		u := &multipartUpload{S3MultipartUpload: rec, dir: uploadDir(rec.ID), parts: make(map[int]model.S3MultipartPart)}
+		if u.LastActive.IsZero() {
+			u.LastActive = u.Initiated
+		}
+		if _, err = os.Stat(u.dir); err != nil {
+			log.Warnf("drop the s3 upload %s of %s/%s as its parts are gone", u.ID, u.Bucket, u.Key)
+			_ = op.DeleteS3MultipartUpload(u.ID)
+			continue
+		}
+		for _, p := range parts[rec.ID] {
+			if _, err = os.Stat(filepath.Join(u.dir, strconv.Itoa(p.Number))); err == nil {
+				u.parts[p.Number] = p
+			}
+		}
+		uploads[u.ID] = u
+	}
+	entries, _ := os.ReadDir(filepath.Join(conf.Conf.TempDir, consts.S3MultipartDir))
+	for _, e := range entries {
+		if _, ok := uploads[e.Name()]; e.IsDir() && !ok {
+			_ = os.RemoveAll(filepath.Join(conf.Conf.TempDir, consts.S3MultipartDir, e.Name()))
+		}
+	}
+}
+
+// parseMax parses the max-parts or max-uploads query, it's clamped to limit
+func parseMax(s string, limit int) (int, error) {
+	if s == "" {
+		return limit, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, gofakes3.ErrInvalidURI
+	}
+	if n == 0 || n > limit {
+		n = limit
+	}
+	return n, nil
+}
+
+func listParts(w http.ResponseWriter, r *http.Request, bucketName, key, id string) error {
+	query := r.URL.Query()
+	marker := 0
+	if s := query.Get("part-number-marker"); s != "" {
+		var err error
+		if marker, err = strconv.Atoi(s); err != nil || marker < 0 {
+			return gofakes3.ErrInvalidURI
+		}
+	}
+	maxParts, err := parseMax(query.Get("max-parts"), gofakes3.MaxUploadPartsLimit)
+	if err != nil {
+		return err
+	}
+	u, err := getUpload(r, bucketName, key, id)
+	if err != nil {
+		return err
+	}
+
+	res := gofakes3.ListMultipartUploadPartsResult{
+		Bucket:           bucketName,
+		Key:              key,
+		UploadID:         gofakes3.UploadID(id),
+		PartNumberMarker: marker,
+		MaxParts:         int64(maxParts),
+	}
+	u.mu.Lock()
+	numbers := make([]int, 0, len(u.parts))
+	for n := range u.parts {
+		if n > marker {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	if len(numbers) > maxParts {
+		numbers, res.IsTruncated = numbers[:maxParts], true
+	}
+	for _, n := range numbers {
+		p := u.parts[n]
+		res.Parts = append(res.Parts, gofakes3.ListMultipartUploadPartItem{
+			PartNumber:   n,
+			LastModified: gofakes3.NewContentTime(p.LastModified),
+			ETag:         p.ETag,
+			Size:         p.Size,
+		})
+		res.NextPartNumberMarker = n
+	}
+	u.mu.Unlock()
+	return writeXML(w, res)
+}
+
+func listMultipartUploads(w http.ResponseWriter, r *http.Request, bucketName string) error {
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	maxUploads, err := parseMax(query.Get("max-uploads"), gofakes3.MaxUploadsLimit)
+	if err != nil {
+		return err
+	}
+	prefix, keyMarker, idMarker := query.Get("prefix"), query.Get("key-marker"), query.Get("upload-id-marker")
+	user, _ := r.Context().Value(consts.UserKey).(*model.User)
+	userID := requestUserID(r)
+
+	var list []*multipartUpload
+	uploadsMu.Lock()
+	for _, u := range uploads {
+		if u.Bucket == bucketName && u.UserID == userID && strings.HasPrefix(u.Key, prefix) &&
+			userCanAccess(user, path.Join(bucket.Path, u.Key)) {
+			list = append(list, u)
+		}
+	}
+	uploadsMu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Key != list[j].Key {
+			return list[i].Key < list[j].Key
+		}
+		return list[i].Initiated.Before(list[j].Initiated)
+	})
+
+	res := gofakes3.ListMultipartUploadsResult{
+		Bucket:         bucketName,
+		KeyMarker:      keyMarker,
+		UploadIDMarker: gofakes3.UploadID(idMarker),
+		MaxUploads:     int64(maxUploads),
+		Prefix:         prefix,
+	}
+	// skip the uploads up to the markers, the upload id marker only applies to the key marker
+	passed := idMarker == ""
+	for _, u := range list {
+		if u.Key < keyMarker || u.Key == keyMarker && (idMarker == "" || !passed) {
+			passed = passed || u.ID == idMarker
+			continue
+		}
+		if len(res.Uploads) == maxUploads {
+			res.IsTruncated = true
+			break
+		}
+		res.Uploads = append(res.Uploads, gofakes3.ListMultipartUploadItem{
+			Key:       u.Key,
+			UploadID:  gofakes3.UploadID(u.ID),
+			Initiated: gofakes3.NewContentTime(u.Initiated),
+		})
+		res.NextKeyMarker, res.NextUploadIDMarker = u.Key, gofakes3.UploadID(u.ID)
+	}
+	return writeXML(w, res)
+}
+
+// sweepMultipartUploads aborts the uploads without any part uploaded for multipartExpiry
+func sweepMultipartUploads() {
+	ticker := time.NewTicker(multipartSweepInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		abortExpiredUploads(now)
+	}
+}
+
+func abortExpiredUploads(now time.Time) {
+	var expired []*multipartUpload
+	uploadsMu.Lock()
+	for _, u := range uploads {
+		u.mu.Lock()
+		if !u.committing && u.receiving == 0 && now.Sub(u.LastActive) > multipartExpiry {
+			expired = append(expired, u)
+		}
+		u.mu.Unlock()
+	}
+	uploadsMu.Unlock()
+	for _, u := range expired {
+		log.Infof("abort the abandoned s3 upload %s of %s/%s", u.ID, u.Bucket, u.Key)
+		removeUpload(u)
+	}
+	// the uploads of a node which is gone are never aborted by it, the parts are lost with the node anyway
+	if err := op.DeleteIdleS3MultipartUploads(nodeID, now.Add(-multipartExpiry)); err != nil {
+		log.Errorf("failed delete the records of the abandoned s3 uploads: %+v", err)
+	}
+}
+
+func writeXML(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// writeS3Error writes err as an s3 error, the errors not from gofakes3 are internal errors
+func writeS3Error(w http.ResponseWriter, r *http.Request, err error) {
+	var s3Err gofakes3.Error
+	if !errs.As(err, &s3Err) {
+		log.Errorf("s3 multipart upload failed: %+v", err)
+		s3Error(w, r, http.StatusInternalServerError, gofakes3.ErrInternal, err.Error())
+		return
+	}
+	code := s3Err.ErrorCode()
+	msg := code.Message()
+	if resp, ok := s3Err.(*gofakes3.ErrorResponse); ok {
+		msg = resp.Message
+	}
+	s3Error(w, r, code.Status(), code, msg)
+}
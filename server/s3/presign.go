@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/itsHenry35/gofakes3"
+)
+
+const (
+	// maxPresignExpiry is the longest X-Amz-Expires AWS accepts for a presigned url
+	maxPresignExpiry = 7 * 24 * time.Hour
+	// presignClockSkew is how far in the future a presigned url may be signed
+	presignClockSkew = 15 * time.Minute
+	amzDateFormat    = "20060102T150405Z"
+)
+
+// responseOverrides maps the query parameters a signed GET may use to override the headers of the response
+var responseOverrides = map[string]string{
+	"response-content-type":        "Content-Type",
+	"response-content-language":    "Content-Language",
+	"response-content-disposition": "Content-Disposition",
+	"response-content-encoding":    "Content-Encoding",
+	"response-cache-control":       "Cache-Control",
+	"response-expires":             "Expires",
+}
+
+// presignMiddleware checks the limits of the presigned urls which gofakes3 doesn't check,
+// the signature and the expiry are verified by gofakes3 as for the signed headers.
+// It also applies the response-* query parameters of the signed GET and HEAD requests as AWS does.
+func presignMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("X-Amz-Signature") != "" && r.Header.Get("Authorization") == "" {
+			if status, code, msg := checkPresign(query, time.Now()); msg != "" {
+				s3Error(w, r, status, code, msg)
+				return
+			}
+		}
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && requestAccessKey(r) != "" {
+			headers := make(map[string]string)
+			for param, header := range responseOverrides {
+				if v := query.Get(param); v != "" {
+					headers[header] = v
+				}
+			}
+			if len(headers) > 0 {
+				w = &overrideWriter{ResponseWriter: w, headers: headers}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkPresign returns why the presigned url in query is refused at now, "" means it's accepted here
+func checkPresign(query url.Values, now time.Time) (int, gofakes3.ErrorCode, string) {
+	if v := query.Get("X-Amz-Expires"); v != "" {
+		expires, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && expires < 0 {
+			return http.StatusBadRequest, "AuthorizationQueryParametersError", "X-Amz-Expires must be non-negative"
+		}
+		if err == nil && time.Duration(expires)*time.Second > maxPresignExpiry {
+			return http.StatusBadRequest, "AuthorizationQueryParametersError",
+				"X-Amz-Expires must be less than a week (in seconds) that is 604800"
+		}
+	}
+	if date, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date")); err == nil && date.After(now.Add(presignClockSkew)) {
+		return http.StatusForbidden, "AccessDenied", "Request is not valid yet"
+	}
+	return 0, "", ""
+}
+
+// overrideWriter sets headers on the successful responses
+type overrideWriter struct {
+	http.ResponseWriter
+	headers     map[string]string
+	wroteHeader bool
+}
+
+func (w *overrideWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if code < http.StatusMultipleChoices {
+			for k, v := range w.headers {
+				w.Header().Set(k, v)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *overrideWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *overrideWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
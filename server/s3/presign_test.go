@@ -0,0 +1,28 @@
+package s3
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckPresign(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		expires, date string
+		accepted      bool
+	}{
+		{"3600", "20240501T115000Z", true},
+		{"604800", "20240501T115000Z", true},
+		{"604801", "20240501T115000Z", false},
+		{"-1", "20240501T115000Z", false},
+		{"60", "20240501T121000Z", true},
+		{"60", "20240501T130000Z", false},
+	}
+	for _, c := range cases {
+		query := url.Values{"X-Amz-Expires": {c.expires}, "X-Amz-Date": {c.date}}
+		if _, _, msg := checkPresign(query, now); (msg == "") != c.accepted {
+			t.Errorf("expires %s date %s: expected accepted %v, got %q", c.expires, c.date, c.accepted, msg)
+		}
+	}
+}
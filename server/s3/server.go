@@ -6,9 +6,13 @@ package s3
 import (
 	"context"
 	"math/rand"
+	"net"
 	"net/http"
 
 	"github.com/itsHenry35/gofakes3"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
 )
 
 // NewServer creates and configures a new S3 compatible server
@@ -18,14 +22,38 @@ func NewServer(ctx context.Context) (http.Handler, error) {
 	s3Logger := logger{}
 
 	// Configure and create the S3 server with appropriate options
+	backend := newBackend()
+	authList := authlistResolver()
+	if authList == nil {
+		// the s3 keys of the users are added to the map later
+		authList = make(map[string]string)
+	}
 	faker := gofakes3.New(
-		newBackend(),
+		backend,
 		gofakes3.WithLogger(s3Logger),
 		gofakes3.WithRequestID(rand.Uint64()),
 		gofakes3.WithoutVersioning(),
-		gofakes3.WithV4Auth(authlistResolver()),
+		gofakes3.WithV4Auth(authList),
 		gofakes3.WithIntegrityCheck(true), // Check Content-MD5 if supplied
 	)
 
-	return faker.Server(), nil
+	registerCredentials(faker, authList)
+
+	return protocolMiddleware(s3KeyMiddleware(apiTokenMiddleware(presignMiddleware(guardMiddleware(multipartMiddleware(backend, faker.Server())))))), nil
+}
+
+// protocolMiddleware marks the requests as S3 ones for the audit log,
+// the client ip is taken from the connection unless the http server in front has set it
+func protocolMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), consts.ProtocolKey, audit.ProtocolS3)
+		if _, ok := ctx.Value(consts.ClientIPKey).(string); !ok {
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			ctx = context.WithValue(ctx, consts.ClientIPKey, ip)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
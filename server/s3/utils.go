@@ -74,12 +74,6 @@ func getDirEntries(path string) ([]model.Obj, error) {
 	return dirEntries, nil
 }
 
-// getFileHash returns an empty string as hash calculation is not implemented
-// This is a placeholder for future implementation
-func getFileHash(node any) string {
-	return ""
-}
-
 // prefixParser splits a prefix into path and remaining components
 // For example, "foo/bar/baz" becomes "foo/bar" and "baz"
 func prefixParser(p *gofakes3.Prefix) (path, remaining string) {
@@ -0,0 +1,174 @@
+package scim
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/server/common"
+)
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type filterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type bulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// ServiceProviderConfig tells the client the features supported
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 supported              `json:"patch"`
+	Bulk                  bulkSupported          `json:"bulk"`
+	Filter                filterSupported        `json:"filter"`
+	ChangePassword        supported              `json:"changePassword"`
+	Sort                  supported              `json:"sort"`
+	ETag                  supported              `json:"etag"`
+	AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+	Meta                  Meta                   `json:"meta"`
+}
+
+// ResourceType describes an endpoint of the resources
+type ResourceType struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description"`
+	Schema      string   `json:"schema"`
+	Meta        Meta     `json:"meta"`
+}
+
+// Attribute describes an attribute of a schema
+type Attribute struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	MultiValued bool   `json:"multiValued"`
+	Required    bool   `json:"required"`
+	Mutability  string `json:"mutability"`
+	Returned    string `json:"returned"`
+	Uniqueness  string `json:"uniqueness"`
+}
+
+// Schema describes the attributes of a resource supported
+type Schema struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Attributes  []Attribute `json:"attributes"`
+	Meta        Meta        `json:"meta"`
+}
+
+func serviceProviderConfig(c *gin.Context) {
+	resp(c, http.StatusOK, ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          supported{Supported: true},
+		Filter:         filterSupported{Supported: true, MaxResults: maxCount},
+		ChangePassword: supported{Supported: true},
+		AuthenticationSchemes: []authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "the token set by the scim_token setting",
+			Primary:     true,
+		}},
+		Meta: Meta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     common.GetApiURLFromRequest(c.Request) + "/scim/v2/ServiceProviderConfig",
+		},
+	})
+}
+
+func resourceTypes(c *gin.Context) {
+	types := []any{
+		ResourceType{
+			Schemas:     []string{SchemaResourceType},
+			ID:          "User",
+			Name:        "User",
+			Endpoint:    "/" + resourceUsers,
+			Description: "User Account",
+			Schema:      SchemaUser,
+			Meta:        Meta{ResourceType: "ResourceType", Location: location(c, "ResourceTypes", "User")},
+		},
+		ResourceType{
+			Schemas:     []string{SchemaResourceType},
+			ID:          "Group",
+			Name:        "Group",
+			Endpoint:    "/" + resourceGroups,
+			Description: "Group",
+			Schema:      SchemaGroup,
+			Meta:        Meta{ResourceType: "ResourceType", Location: location(c, "ResourceTypes", "Group")},
+		},
+	}
+	resp(c, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: int64(len(types)),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	})
+}
+
+func attribute(name, typ string, multiValued, required bool, mutability, returned, uniqueness string) Attribute {
+	return Attribute{
+		Name:        name,
+		Type:        typ,
+		MultiValued: multiValued,
+		Required:    required,
+		Mutability:  mutability,
+		Returned:    returned,
+		Uniqueness:  uniqueness,
+	}
+}
+
+func schemas(c *gin.Context) {
+	list := []any{
+		Schema{
+			Schemas:     []string{SchemaSchema},
+			ID:          SchemaUser,
+			Name:        "User",
+			Description: "User Account",
+			Attributes: []Attribute{
+				attribute("userName", "string", false, true, "readWrite", "default", "server"),
+				attribute("externalId", "string", false, false, "readWrite", "default", "none"),
+				attribute("active", "boolean", false, false, "readWrite", "default", "none"),
+				attribute("password", "string", false, false, "writeOnly", "never", "none"),
+				attribute("groups", "complex", true, false, "readOnly", "default", "none"),
+			},
+			Meta: Meta{ResourceType: "Schema", Location: location(c, "Schemas", SchemaUser)},
+		},
+		Schema{
+			Schemas:     []string{SchemaSchema},
+			ID:          SchemaGroup,
+			Name:        "Group",
+			Description: "Group",
+			Attributes: []Attribute{
+				attribute("displayName", "string", false, true, "readWrite", "default", "server"),
+				attribute("members", "complex", true, false, "readWrite", "default", "none"),
+			},
+			Meta: Meta{ResourceType: "Schema", Location: location(c, "Schemas", SchemaGroup)},
+		},
+	}
+	resp(c, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: int64(len(list)),
+		StartIndex:   1,
+		ItemsPerPage: len(list),
+		Resources:    list,
+	})
+}
@@ -0,0 +1,300 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+// Group is a group resource, the members are users only
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// memberFilterReg matches a path selecting a member like `members[value eq "2"]`
+var memberFilterReg = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+// toGroup converts g, its members are loaded unless they are excluded
+func toGroup(c *gin.Context, g *model.Group, withMembers bool) (Group, error) {
+	id := strconv.FormatUint(uint64(g.ID), 10)
+	res := Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          id,
+		DisplayName: g.Name,
+		Meta:        &Meta{ResourceType: "Group", Location: location(c, resourceGroups, id)},
+	}
+	if !withMembers {
+		return res, nil
+	}
+	users, err := op.GetGroupMembers(g.ID)
+	if err != nil {
+		return res, err
+	}
+	res.Members = make([]Member, 0, len(users))
+	for _, u := range users {
+		userID := strconv.FormatUint(uint64(u.ID), 10)
+		res.Members = append(res.Members, Member{Value: userID, Display: u.Username, Ref: location(c, resourceUsers, userID)})
+	}
+	return res, nil
+}
+
+func groupResp(c *gin.Context, status int, g *model.Group) {
+	res, err := toGroup(c, g, true)
+	if err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resp(c, status, res)
+}
+
+func loadGroup(c *gin.Context) (*model.Group, bool) {
+	id, ok := parseID(c)
+	if !ok {
+		return nil, false
+	}
+	g, err := op.GetGroupById(id)
+	if err != nil {
+		errorResp(c, http.StatusNotFound, "", "group not found")
+		return nil, false
+	}
+	return g, true
+}
+
+// checkGroupName makes sure the name isn't taken by another group
+func checkGroupName(c *gin.Context, name string, id uint) bool {
+	if name == "" {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, "displayName is required")
+		return false
+	}
+	if other, err := op.GetGroupByName(name); err == nil && other.ID != id {
+		errorResp(c, http.StatusConflict, "uniqueness", "displayName is already taken")
+		return false
+	}
+	return true
+}
+
+func listGroups(c *gin.Context) {
+	startIndex, count, pageIndex := page(c)
+	var groups []model.Group
+	var total int64
+	if filter := c.Query("filter"); filter != "" {
+		attr, value, ok := parseFilter(filter)
+		if !ok || attr != "displayname" {
+			errorResp(c, http.StatusBadRequest, "invalidFilter", "only `displayName eq` filter is supported")
+			return
+		}
+		if g, err := op.GetGroupByName(value); err == nil {
+			groups, total = []model.Group{*g}, 1
+		}
+	} else if count > 0 {
+		var err error
+		if groups, total, err = op.GetGroups(pageIndex, count); err != nil {
+			errorResp(c, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+	withMembers := !strings.Contains(strings.ToLower(c.Query("excludedAttributes")), "members")
+	resources := make([]any, 0, len(groups))
+	for i := range groups {
+		g, err := toGroup(c, &groups[i], withMembers)
+		if err != nil {
+			errorResp(c, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		resources = append(resources, g)
+	}
+	resp(c, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func getGroup(c *gin.Context) {
+	g, ok := loadGroup(c)
+	if !ok {
+		return
+	}
+	groupResp(c, http.StatusOK, g)
+}
+
+func createGroup(c *gin.Context) {
+	var req Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if !checkGroupName(c, req.DisplayName, 0) {
+		return
+	}
+	ids, err := memberIDs(req.Members)
+	if err != nil {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, "invalid member: "+err.Error())
+		return
+	}
+	g := &model.Group{Name: req.DisplayName}
+	if err = op.CreateGroupWithMembers(g, ids); err != nil {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, err.Error())
+		return
+	}
+	groupResp(c, http.StatusCreated, g)
+}
+
+// replaceGroup renames the group and replaces its members
+func replaceGroup(c *gin.Context) {
+	g, ok := loadGroup(c)
+	if !ok {
+		return
+	}
+	var req Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if !checkGroupName(c, req.DisplayName, g.ID) {
+		return
+	}
+	ids, err := memberIDs(req.Members)
+	if err != nil {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, "invalid member: "+err.Error())
+		return
+	}
+	if !renameGroup(c, g, req.DisplayName) {
+		return
+	}
+	if err = op.SetGroupMembers(g.ID, ids); err != nil {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, err.Error())
+		return
+	}
+	groupResp(c, http.StatusOK, g)
+}
+
+func renameGroup(c *gin.Context, g *model.Group, name string) bool {
+	if g.Name == name {
+		return true
+	}
+	g.Name = name
+	if err := op.UpdateGroup(g); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return false
+	}
+	return true
+}
+
+// patchGroup renames the group, adds, removes or replaces its members
+func patchGroup(c *gin.Context) {
+	g, ok := loadGroup(c)
+	if !ok {
+		return
+	}
+	var req PatchOp
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	for _, operation := range req.Operations {
+		if !patchGroupOperation(c, g, operation) {
+			return
+		}
+	}
+	groupResp(c, http.StatusOK, g)
+}
+
+func patchGroupOperation(c *gin.Context, g *model.Group, operation Operation) bool {
+	opName := strings.ToLower(operation.Op)
+	path := strings.ToLower(operation.Path)
+	if path == "" && opName != "remove" {
+		// the value carries the attributes, e.g. {"displayName": "...", "members": [...]}
+		var attrs map[string]json.RawMessage
+		if err := utils.JSONTool.Unmarshal(operation.Value, &attrs); err != nil {
+			errorResp(c, http.StatusBadRequest, scimTypeInvalid, err.Error())
+			return false
+		}
+		for attr, value := range attrs {
+			if !patchGroupOperation(c, g, Operation{Op: operation.Op, Path: attr, Value: value}) {
+				return false
+			}
+		}
+		return true
+	}
+	if m := memberFilterReg.FindStringSubmatch(operation.Path); m != nil && opName == "remove" {
+		return changeMembers(c, g, opName, []Member{{Value: m[1]}})
+	}
+	switch {
+	case path == "displayname" && opName != "remove":
+		var name string
+		if err := utils.JSONTool.Unmarshal(operation.Value, &name); err != nil {
+			errorResp(c, http.StatusBadRequest, scimTypeInvalid, err.Error())
+			return false
+		}
+		return checkGroupName(c, name, g.ID) && renameGroup(c, g, name)
+	case path == "members":
+		var members []Member
+		if len(operation.Value) > 0 {
+			if err := utils.JSONTool.Unmarshal(operation.Value, &members); err != nil {
+				errorResp(c, http.StatusBadRequest, scimTypeInvalid, err.Error())
+				return false
+			}
+		}
+		if opName == "remove" && len(members) == 0 {
+			// removing the members without a value removes all of them
+			opName = "replace"
+		}
+		return changeMembers(c, g, opName, members)
+	case path == "externalid":
+		// the external id of a group isn't kept
+		return true
+	}
+	errorResp(c, http.StatusBadRequest, "invalidPath", "unsupported path: "+operation.Path)
+	return false
+}
+
+func changeMembers(c *gin.Context, g *model.Group, opName string, members []Member) bool {
+	ids, err := memberIDs(members)
+	if err != nil {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, "invalid member: "+err.Error())
+		return false
+	}
+	switch opName {
+	case "add":
+		err = op.AddGroupMembers(g.ID, ids)
+	case "remove":
+		err = op.RemoveGroupMembers(g.ID, ids)
+	case "replace":
+		err = op.SetGroupMembers(g.ID, ids)
+	default:
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, "unknown operation: "+opName)
+		return false
+	}
+	if err != nil {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, err.Error())
+		return false
+	}
+	return true
+}
+
+func deleteGroup(c *gin.Context) {
+	g, ok := loadGroup(c)
+	if !ok {
+		return
+	}
+	if err := op.DeleteGroupById(g.ID); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
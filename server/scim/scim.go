@@ -0,0 +1,209 @@
+// Package scim is a SCIM 2.0 server, see RFC 7643 and RFC 7644, so an identity provider can provision
+// the users and the groups instead of creating them at the first login.
+// Only the attributes OpenList keeps are supported, the others sent by the client are ignored.
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/setting"
+	"github.com/dongdio/OpenList/v4/server/common"
+	"github.com/dongdio/OpenList/v4/server/middlewares"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+const (
+	contentType     = "application/scim+json"
+	defaultCount    = 100
+	maxCount        = 1000
+	resourceUsers   = "Users"
+	resourceGroups  = "Groups"
+	scimTypeInvalid = "invalidValue"
+)
+
+// Meta is the metadata of a resource
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+// Member is a member of a group, or a group of a user
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// ListResponse is the response of a query
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// PatchOp modifies a resource partially
+type PatchOp struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Error is the response of a failed request
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// Init registers the SCIM endpoints to g, the changes are recorded in the audit log
+func Init(g *gin.RouterGroup) {
+	g.Use(middlewares.Protocol(audit.ProtocolSCIM), middlewares.AuditChange, auth)
+	g.GET("/ServiceProviderConfig", serviceProviderConfig)
+	g.GET("/ResourceTypes", resourceTypes)
+	g.GET("/Schemas", schemas)
+
+	g.GET("/Users", listUsers)
+	g.POST("/Users", createUser)
+	g.GET("/Users/:id", getUser)
+	g.PUT("/Users/:id", replaceUser)
+	g.PATCH("/Users/:id", patchUser)
+	g.DELETE("/Users/:id", deleteUser)
+
+	g.GET("/Groups", listGroups)
+	g.POST("/Groups", createGroup)
+	g.GET("/Groups/:id", getGroup)
+	g.PUT("/Groups/:id", replaceGroup)
+	g.PATCH("/Groups/:id", patchGroup)
+	g.DELETE("/Groups/:id", deleteGroup)
+}
+
+// auth accepts the bearer token set by consts.SCIMToken, SCIM is refused if it's disabled or the token is empty
+func auth(c *gin.Context) {
+	token := setting.GetStr(consts.SCIMToken)
+	if !setting.GetBool(consts.SCIMEnabled) || token == "" {
+		errorResp(c, http.StatusForbidden, "", "SCIM is not enabled")
+		return
+	}
+	raw, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(raw), []byte(token)) != 1 {
+		errorResp(c, http.StatusUnauthorized, "", "invalid bearer token")
+		return
+	}
+	c.Next()
+}
+
+func resp(c *gin.Context, status int, v any) {
+	c.Header("Content-Type", contentType)
+	c.JSON(status, v)
+}
+
+// errorResp responds the error and aborts the request
+func errorResp(c *gin.Context, status int, scimType, detail string) {
+	c.Header("Content-Type", contentType)
+	c.AbortWithStatusJSON(status, Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func location(c *gin.Context, resource, id string) string {
+	return common.GetApiURLFromRequest(c.Request) + "/scim/v2/" + resource + "/" + id
+}
+
+// page returns the 1-based start index and the count of the query, and the page of the count it's in
+func page(c *gin.Context) (startIndex, count, pageIndex int) {
+	startIndex, err := strconv.Atoi(c.Query("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(c.Query("count"))
+	if err != nil || count < 0 {
+		count = defaultCount
+	}
+	count = min(count, maxCount)
+	if count == 0 {
+		return startIndex, 0, 1
+	}
+	// the pages are aligned to the count, which is what the clients do
+	return startIndex, count, (startIndex-1)/count + 1
+}
+
+var filterReg = regexp.MustCompile(`(?i)^\s*([\w.]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parseFilter parses a filter like `userName eq "alice"`, the only one the clients need for provisioning
+func parseFilter(filter string) (attr, value string, ok bool) {
+	m := filterReg.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", false
+	}
+	value, err := strconv.Unquote(`"` + m[2] + `"`)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), value, true
+}
+
+// parseID parses the id of a resource, it's the id of the user or the group
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errorResp(c, http.StatusNotFound, "", "resource not found")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func memberIDs(members []Member) ([]uint, error) {
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m.Value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// parseBool accepts a json bool or a string like "False", some clients send the latter
+func parseBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := utils.JSONTool.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := utils.JSONTool.Unmarshal(raw, &s); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(s)
+}
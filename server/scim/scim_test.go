@@ -0,0 +1,111 @@
+package scim_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/conf"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/server/scim"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+)
+
+const token = "scim-test-token"
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func do(t *testing.T, e *gin.Engine, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/scim+json")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	var res map[string]any
+	if w.Body.Len() > 0 {
+		if err := utils.JSONTool.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("failed parse the response of %s %s: %v", method, path, err)
+		}
+	}
+	return w.Code, res
+}
+
+// TestProvisioning plays an identity provider creating, grouping and deactivating a user
+func TestProvisioning(t *testing.T) {
+	for _, item := range []model.SettingItem{
+		{Key: consts.SCIMEnabled, Value: "true", Type: consts.TypeBool, Group: model.SSO, Flag: model.PRIVATE},
+		{Key: consts.SCIMToken, Value: token, Type: consts.TypeString, Group: model.SSO, Flag: model.PRIVATE},
+	} {
+		if err := op.SaveSettingItem(&item); err != nil {
+			t.Fatalf("failed save setting: %+v", err)
+		}
+	}
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	scim.Init(e.Group("/scim/v2"))
+
+	code, user := do(t, e, "POST", "/scim/v2/Users", `{"schemas":["`+scim.SchemaUser+`"],"userName":"bob","externalId":"00u1","active":true,"emails":[{"value":"bob@example.com"}]}`)
+	if code != http.StatusCreated {
+		t.Fatalf("failed create user: %d %v", code, user)
+	}
+	userID := user["id"].(string)
+	if code, _ = do(t, e, "POST", "/scim/v2/Users", `{"userName":"bob"}`); code != http.StatusConflict {
+		t.Errorf("expected a duplicated userName to conflict, got %d", code)
+	}
+	code, list := do(t, e, "GET", `/scim/v2/Users?filter=userName%20eq%20%22bob%22`, "")
+	if code != http.StatusOK || list["totalResults"].(float64) != 1 {
+		t.Errorf("expected the filter to find the user, got %d %v", code, list)
+	}
+
+	code, group := do(t, e, "POST", "/scim/v2/Groups", `{"displayName":"engineering","members":[{"value":"`+userID+`"}]}`)
+	if code != http.StatusCreated || len(group["members"].([]any)) != 1 {
+		t.Fatalf("failed create group: %d %v", code, group)
+	}
+	u, err := op.GetUserByName("bob")
+	if err != nil || len(u.Groups) != 1 || u.Groups[0].Name != "engineering" {
+		t.Errorf("expected bob to be in the group, got %+v, %v", u, err)
+	}
+	s, _, err := op.CreateSession(u, "127.0.0.1", "curl/8.0")
+	if err != nil {
+		t.Fatalf("failed create session: %+v", err)
+	}
+
+	code, user = do(t, e, "PATCH", "/scim/v2/Users/"+userID, `{"schemas":["`+scim.SchemaPatchOp+`"],"Operations":[{"op":"replace","value":{"active":false}}]}`)
+	if code != http.StatusOK || user["active"] != false {
+		t.Fatalf("failed deactivate user: %d %v", code, user)
+	}
+	if u, _ = op.GetUserByName("bob"); !u.Disabled {
+		t.Errorf("expected the deactivated user to be disabled")
+	}
+	if _, err = op.GetValidSession(s.ID); err == nil {
+		t.Errorf("expected the sessions of the deactivated user to be revoked")
+	}
+
+	code, group = do(t, e, "PATCH", "/scim/v2/Groups/"+group["id"].(string), `{"schemas":["`+scim.SchemaPatchOp+`"],"Operations":[{"op":"remove","path":"members[value eq \"`+userID+`\"]"}]}`)
+	if code != http.StatusOK || group["members"] != nil {
+		t.Errorf("expected the member to be removed, got %d %v", code, group)
+	}
+
+	if code, _ = do(t, e, "DELETE", "/scim/v2/Users/"+userID, ""); code != http.StatusNoContent {
+		t.Errorf("failed delete user: %d", code)
+	}
+	if code, user = do(t, e, "GET", "/scim/v2/Users/"+userID, ""); code != http.StatusOK || user["active"] != false {
+		t.Errorf("expected the deleted user to be kept deactivated, got %d %v", code, user)
+	}
+}
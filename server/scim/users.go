@@ -0,0 +1,285 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/db"
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/internal/setting"
+	"github.com/dongdio/OpenList/v4/utility/utils"
+	"github.com/dongdio/OpenList/v4/utility/utils/random"
+)
+
+// User is a user resource, externalId is the id of the user at the identity provider,
+// it's kept as the sso id so the user can log in with sso too
+type User struct {
+	Schemas    []string `json:"schemas"`
+	ID         string   `json:"id,omitempty"`
+	ExternalID string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+	Active     *bool    `json:"active,omitempty"`
+	// Password is write only
+	Password string   `json:"password,omitempty"`
+	Groups   []Member `json:"groups,omitempty"`
+	Meta     *Meta    `json:"meta,omitempty"`
+}
+
+func toUser(c *gin.Context, u *model.User) User {
+	id := strconv.FormatUint(uint64(u.ID), 10)
+	active := !u.Disabled
+	groups := make([]Member, 0, len(u.Groups))
+	for _, g := range u.Groups {
+		groupID := strconv.FormatUint(uint64(g.ID), 10)
+		groups = append(groups, Member{Value: groupID, Display: g.Name, Ref: location(c, resourceGroups, groupID)})
+	}
+	return User{
+		Schemas:    []string{SchemaUser},
+		ID:         id,
+		ExternalID: u.SsoID,
+		UserName:   u.Username,
+		Active:     &active,
+		Groups:     groups,
+		Meta:       &Meta{ResourceType: "User", Location: location(c, resourceUsers, id)},
+	}
+}
+
+// loadUser returns the user of the id param, the admin and the guest can't be provisioned
+func loadUser(c *gin.Context) (*model.User, bool) {
+	id, ok := parseID(c)
+	if !ok {
+		return nil, false
+	}
+	u, err := op.GetUserById(id)
+	if err != nil {
+		errorResp(c, http.StatusNotFound, "", "user not found")
+		return nil, false
+	}
+	return u, true
+}
+
+func checkManaged(c *gin.Context, u *model.User) bool {
+	if u.IsAdmin() || u.IsGuest() {
+		errorResp(c, http.StatusBadRequest, "mutability", "the admin and the guest can't be provisioned")
+		return false
+	}
+	return true
+}
+
+// checkUserName makes sure the username isn't taken by another user
+func checkUserName(c *gin.Context, username string, id uint) bool {
+	if username == "" {
+		errorResp(c, http.StatusBadRequest, scimTypeInvalid, "userName is required")
+		return false
+	}
+	if other, err := db.GetUserByName(username); err == nil && other.ID != id {
+		errorResp(c, http.StatusConflict, "uniqueness", "userName is already taken")
+		return false
+	}
+	return true
+}
+
+func listUsers(c *gin.Context) {
+	startIndex, count, pageIndex := page(c)
+	var users []model.User
+	var total int64
+	if filter := c.Query("filter"); filter != "" {
+		attr, value, ok := parseFilter(filter)
+		if !ok || (attr != "username" && attr != "externalid") {
+			errorResp(c, http.StatusBadRequest, "invalidFilter", "only `userName eq` and `externalId eq` filters are supported")
+			return
+		}
+		var u *model.User
+		var err error
+		if attr == "username" {
+			u, err = db.GetUserByName(value)
+		} else {
+			u, err = db.GetUserBySSOID(value)
+		}
+		if err == nil {
+			users, total = []model.User{*u}, 1
+		}
+	} else if count > 0 {
+		var err error
+		if users, total, err = op.GetUsers(pageIndex, count); err != nil {
+			errorResp(c, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+	resources := make([]any, 0, len(users))
+	for i := range users {
+		resources = append(resources, toUser(c, &users[i]))
+	}
+	resp(c, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func getUser(c *gin.Context) {
+	u, ok := loadUser(c)
+	if !ok {
+		return
+	}
+	resp(c, http.StatusOK, toUser(c, u))
+}
+
+// createUser provisions a user with the sso default dir and permission,
+// the password is random if the client doesn't set it, the user logs in with sso or ldap then
+func createUser(c *gin.Context) {
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if !checkUserName(c, req.UserName, 0) {
+		return
+	}
+	u := &model.User{
+		Username:   req.UserName,
+		SsoID:      req.ExternalID,
+		Permission: int32(setting.GetInt(consts.SSODefaultPermission, 0)),
+		BasePath:   setting.GetStr(consts.SSODefaultDir),
+		Role:       model.GENERAL,
+		Disabled:   req.Active != nil && !*req.Active,
+	}
+	password := req.Password
+	if password == "" {
+		password = random.String(16)
+	}
+	u.SetPassword(password)
+	if err := op.CreateUser(u); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resp(c, http.StatusCreated, toUser(c, u))
+}
+
+// replaceUser replaces the attributes of a user, active is kept if it's absent
+func replaceUser(c *gin.Context) {
+	u, ok := loadUser(c)
+	if !ok || !checkManaged(c, u) {
+		return
+	}
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if !checkUserName(c, req.UserName, u.ID) {
+		return
+	}
+	u.Username, u.SsoID = req.UserName, req.ExternalID
+	if req.Active != nil {
+		u.Disabled = !*req.Active
+	}
+	if req.Password != "" {
+		u.SetPassword(req.Password)
+	}
+	saveUser(c, u)
+}
+
+// patchUser applies the operations to a user, the attributes not kept are ignored
+func patchUser(c *gin.Context) {
+	u, ok := loadUser(c)
+	if !ok || !checkManaged(c, u) {
+		return
+	}
+	var req PatchOp
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	for _, operation := range req.Operations {
+		var err error
+		switch strings.ToLower(operation.Op) {
+		case "add", "replace":
+			if operation.Path == "" {
+				var attrs map[string]json.RawMessage
+				if err = utils.JSONTool.Unmarshal(operation.Value, &attrs); err == nil {
+					for attr, value := range attrs {
+						if err = setUserAttr(u, attr, value); err != nil {
+							break
+						}
+					}
+				}
+			} else {
+				err = setUserAttr(u, operation.Path, operation.Value)
+			}
+		case "remove":
+			if strings.EqualFold(operation.Path, "externalId") {
+				u.SsoID = ""
+			} else {
+				errorResp(c, http.StatusBadRequest, "mutability", "only externalId can be removed")
+				return
+			}
+		default:
+			errorResp(c, http.StatusBadRequest, scimTypeInvalid, "unknown operation: "+operation.Op)
+			return
+		}
+		if err != nil {
+			errorResp(c, http.StatusBadRequest, scimTypeInvalid, err.Error())
+			return
+		}
+	}
+	if !checkUserName(c, u.Username, u.ID) {
+		return
+	}
+	saveUser(c, u)
+}
+
+func setUserAttr(u *model.User, attr string, value json.RawMessage) error {
+	var err error
+	switch strings.ToLower(attr) {
+	case "username":
+		err = utils.JSONTool.Unmarshal(value, &u.Username)
+	case "externalid":
+		err = utils.JSONTool.Unmarshal(value, &u.SsoID)
+	case "active":
+		var active bool
+		if active, err = parseBool(value); err == nil {
+			u.Disabled = !active
+		}
+	case "password":
+		var password string
+		if err = utils.JSONTool.Unmarshal(value, &password); err == nil && password != "" {
+			u.SetPassword(password)
+		}
+	}
+	return err
+}
+
+// saveUser saves the user with its groups unchanged, a deactivated user is logged out everywhere
+func saveUser(c *gin.Context, u *model.User) {
+	u.GroupIDs = nil
+	if err := op.UpdateUser(u); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resp(c, http.StatusOK, toUser(c, u))
+}
+
+// deleteUser deactivates the user rather than deleting it, so the files, shares and audit logs
+// keep their owner, the user is logged out everywhere
+func deleteUser(c *gin.Context) {
+	u, ok := loadUser(c)
+	if !ok || !checkManaged(c, u) {
+		return
+	}
+	u.Disabled = true
+	u.GroupIDs = nil
+	if err := op.UpdateUser(u); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
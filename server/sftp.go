@@ -12,6 +12,8 @@ import (
 
 	"github.com/dongdio/OpenList/v4/consts"
 	"github.com/dongdio/OpenList/v4/global"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
 	"github.com/dongdio/OpenList/v4/internal/conf"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
@@ -60,7 +62,14 @@ func (d *SftpDriver) GetConfig() *sftpd.Config {
 }
 
 func (d *SftpDriver) GetFileSystem(sc *ssh.ServerConn) (sftpd.FileSystem, error) {
-	userObj, err := op.GetUserByName(sc.User())
+	var userObj *model.User
+	var err error
+	if sc.Permissions != nil && sc.Permissions.Extensions[sftpAPITokenExtension] != "" {
+		// the user logged in with an api token, keep the scope of the token
+		userObj, err = op.ValidateAPIToken(sc.Permissions.Extensions[sftpAPITokenExtension])
+	} else {
+		userObj, err = op.GetUserByName(sc.User())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +77,7 @@ func (d *SftpDriver) GetFileSystem(sc *ssh.ServerConn) (sftpd.FileSystem, error)
 	ctx = context.WithValue(ctx, consts.UserKey, userObj)
 	ctx = context.WithValue(ctx, consts.MetaPassKey, "")
 	ctx = context.WithValue(ctx, consts.ClientIPKey, sc.RemoteAddr().String())
+	ctx = context.WithValue(ctx, consts.ProtocolKey, audit.ProtocolSFTP)
 	ctx = context.WithValue(ctx, consts.ProxyHeaderKey, d.proxyHeader)
 	return &sftp.DriverAdapter{FtpDriver: ftp.NewAferoAdapter(ctx)}, nil
 }
@@ -90,6 +100,26 @@ func (d *SftpDriver) NoClientAuth(conn ssh.ConnMetadata) (*ssh.Permissions, erro
 }
 
 func (d *SftpDriver) PasswordAuth(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	ip := conn.RemoteAddr().String()
+	if authguard.Locked(conn.User(), ip) > 0 {
+		return nil, errs.WithStack(errs.TooManyLoginAttempts)
+	}
+	perm, err := d.passwordAuth(conn, password)
+	if err != nil {
+		// the right password refused by the 2FA policy is not a guess
+		if !errs.Is(err, errs.APITokenRequired) {
+			authguard.Fail(conn.User(), ip, audit.ProtocolSFTP)
+		}
+		return nil, err
+	}
+	authguard.Success(conn.User(), ip)
+	return perm, nil
+}
+
+func (d *SftpDriver) passwordAuth(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	if op.IsAPIToken(string(password)) {
+		return d.apiTokenAuth(conn, string(password))
+	}
 	userObj, err := op.GetUserByName(conn.User())
 	if err != nil {
 		return nil, err
@@ -101,10 +131,35 @@ func (d *SftpDriver) PasswordAuth(conn ssh.ConnMetadata, password []byte) (*ssh.
 	if err = userObj.ValidatePwdStaticHash(passHash); err != nil {
 		return nil, err
 	}
+	if err = op.CheckPasswordLogin(userObj); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
+// sftpAPITokenExtension carries the api token from the auth to GetFileSystem
+const sftpAPITokenExtension = "openlist-api-token"
+
+func (d *SftpDriver) apiTokenAuth(conn ssh.ConnMetadata, token string) (*ssh.Permissions, error) {
+	userObj, err := op.ValidateAPIToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if userObj.Username != conn.User() {
+		return nil, errs.WithStack(errs.InvalidAPIToken)
+	}
+	if !userObj.CanFTPAccess() {
+		return nil, errs.New("user is not allowed to access via SFTP")
+	}
+	return &ssh.Permissions{Extensions: map[string]string{sftpAPITokenExtension: token}}, nil
+}
+
+// PublicKeyAuth refuses the locked accounts and ips, the refused keys are not counted
+// as the clients try all their keys in turn
 func (d *SftpDriver) PublicKeyAuth(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	if authguard.Locked(conn.User(), conn.RemoteAddr().String()) > 0 {
+		return nil, errs.WithStack(errs.TooManyLoginAttempts)
+	}
 	userObj, err := op.GetUserByName(conn.User())
 	if err != nil {
 		return nil, err
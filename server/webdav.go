@@ -4,12 +4,15 @@ import (
 	"crypto/subtle"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/audit"
+	"github.com/dongdio/OpenList/v4/internal/authguard"
 	"github.com/dongdio/OpenList/v4/internal/conf"
 	"github.com/dongdio/OpenList/v4/internal/model"
 	"github.com/dongdio/OpenList/v4/internal/op"
@@ -17,6 +20,7 @@ import (
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/server/middlewares"
 	"github.com/dongdio/OpenList/v4/server/webdav"
+	"github.com/dongdio/OpenList/v4/utility/errs"
 	"github.com/dongdio/OpenList/v4/utility/stream"
 )
 
@@ -25,12 +29,12 @@ var handler *webdav.Handler
 func WebDav(dav *gin.RouterGroup) {
 	handler = &webdav.Handler{
 		Prefix:     path.Join(conf.URL.Path, "/dav"),
-		LockSystem: webdav.NewMemLS(),
+		LockSystem: webdav.NewDBLS(),
 		Logger: func(request *http.Request, err error) {
 			log.Errorf("%s %s %+v", request.Method, request.URL.Path, err)
 		},
 	}
-	dav.Use(WebDAVAuth)
+	dav.Use(middlewares.Protocol(audit.ProtocolWebDAV), WebDAVAuth)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	dav.Any("/*path", uploadLimiter, downloadLimiter, ServeWebDAV)
@@ -50,22 +54,21 @@ func ServeWebDAV(c *gin.Context) {
 }
 
 func WebDAVAuth(c *gin.Context) {
-	// check count of login
+	// check the lockout of the account and the ip
 	ip := c.ClientIP()
 	guest, _ := op.GetGuest()
-	count, cok := model.LoginCache.Get(ip)
-	if cok && count >= model.DefaultMaxAuthRetries {
+	username, password, ok := c.Request.BasicAuth()
+	if remaining := authguard.Locked(username, ip); remaining > 0 {
 		if c.Request.Method == "OPTIONS" {
 			common.GinWithValue(c, consts.UserKey, guest)
 			c.Next()
 			return
 		}
+		c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
 		c.Status(http.StatusTooManyRequests)
 		c.Abort()
-		model.LoginCache.Expire(ip, model.DefaultLockDuration)
 		return
 	}
-	username, password, ok := c.Request.BasicAuth()
 	if !ok {
 		bt := c.GetHeader("Authorization")
 		log.Debugf("[webdav auth] token: %s", bt)
@@ -84,6 +87,18 @@ func WebDAVAuth(c *gin.Context) {
 				c.Next()
 				return
 			}
+			if op.IsAPIToken(bt) {
+				user, err := op.ValidateAPIToken(bt)
+				if err != nil {
+					c.Status(http.StatusUnauthorized)
+					authguard.Fail("", ip, audit.ProtocolWebDAV)
+					c.Abort()
+					return
+				}
+				authguard.Success("", ip)
+				webdavCheckUser(c, user, guest)
+				return
+			}
 		}
 		if c.Request.Method == "OPTIONS" {
 			common.GinWithValue(c, consts.UserKey, guest)
@@ -95,20 +110,53 @@ func WebDAVAuth(c *gin.Context) {
 		c.Abort()
 		return
 	}
-	user, err := op.GetUserByName(username)
-	if err != nil || user.ValidateRawPassword(password) != nil {
+	user, err := webdavBasicUser(username, password)
+	if err != nil {
 		if c.Request.Method == "OPTIONS" {
 			common.GinWithValue(c, consts.UserKey, guest)
 			c.Next()
 			return
 		}
 		c.Status(http.StatusUnauthorized)
-		model.LoginCache.Set(ip, count+1)
+		// the right password refused by the 2FA policy is not a guess
+		if !errs.Is(err, errs.APITokenRequired) {
+			authguard.Fail(username, ip, audit.ProtocolWebDAV)
+		}
 		c.Abort()
 		return
 	}
 	// at least auth is successful till here
-	model.LoginCache.Del(ip)
+	authguard.Success(username, ip)
+	webdavCheckUser(c, user, guest)
+}
+
+// webdavBasicUser accepts the password or an api token of the user as the password
+func webdavBasicUser(username, password string) (*model.User, error) {
+	if op.IsAPIToken(password) {
+		user, err := op.ValidateAPIToken(password)
+		if err != nil {
+			return nil, err
+		}
+		if user.Username != username {
+			return nil, errs.WithStack(errs.InvalidAPIToken)
+		}
+		return user, nil
+	}
+	user, err := op.GetUserByName(username)
+	if err != nil {
+		return nil, err
+	}
+	if err = user.ValidateRawPassword(password); err != nil {
+		return nil, err
+	}
+	if err = op.CheckPasswordLogin(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// webdavCheckUser checks the permissions of an authenticated user for the request method
+func webdavCheckUser(c *gin.Context, user, guest *model.User) {
 	if user.Disabled || !user.CanWebdavRead() {
 		if c.Request.Method == "OPTIONS" {
 			common.GinWithValue(c, consts.UserKey, guest)
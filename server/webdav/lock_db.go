@@ -0,0 +1,137 @@
+package webdav
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
+	"github.com/dongdio/OpenList/v4/utility/errs"
+)
+
+// NewDBLS 返回一个把锁保存在数据库中的LockSystem实现
+// 锁在重启后仍然有效，并且对所有副本以及Web、FTP、SFTP的写操作可见（参见 op.CheckWebDAVLock）
+// 只有Confirm持有锁的状态保存在内存中，它只在单个请求内有效
+func NewDBLS() LockSystem {
+	return &dbLS{held: make(map[string]bool)}
+}
+
+type dbLS struct {
+	mu   sync.Mutex      // 保护held
+	held map[string]bool // 被Confirm持有的锁令牌
+}
+
+func (m *dbLS) Confirm(now time.Time, name0, name1 string, conditions ...Condition) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var t0, t1 string
+	var err error
+	if name0 != "" {
+		if t0, err = m.lookup(now, slashClean(name0), conditions...); err != nil {
+			return nil, err
+		}
+		if t0 == "" {
+			return nil, ErrConfirmationFailed
+		}
+	}
+	if name1 != "" {
+		if t1, err = m.lookup(now, slashClean(name1), conditions...); err != nil {
+			return nil, err
+		}
+		if t1 == "" {
+			return nil, ErrConfirmationFailed
+		}
+	}
+
+	// 避免持有同一个锁两次
+	if t1 == t0 {
+		t1 = ""
+	}
+	for _, t := range []string{t0, t1} {
+		if t != "" {
+			m.held[t] = true
+		}
+	}
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.held, t0)
+		delete(m.held, t1)
+	}, nil
+}
+
+// lookup 返回锁定指定资源且匹配给定条件之一、未被持有的锁令牌，没有时返回空字符串
+func (m *dbLS) lookup(now time.Time, name string, conditions ...Condition) (string, error) {
+	// TODO: 支持Condition.Not和Condition.ETag
+	for _, c := range conditions {
+		if c.Token == "" || m.held[c.Token] {
+			continue
+		}
+		l, err := op.GetWebDAVLock(c.Token, now)
+		if err != nil {
+			return "", err
+		}
+		if l != nil && l.Covers(name) {
+			return l.Token, nil
+		}
+	}
+	return "", nil
+}
+
+func (m *dbLS) Create(now time.Time, details LockDetails) (string, error) {
+	l := &model.WebDAVLock{
+		Token:     "opaquelocktoken:" + uuid.NewString(),
+		Root:      slashClean(details.Root),
+		ZeroDepth: details.ZeroDepth,
+		OwnerXML:  details.OwnerXML,
+		Duration:  details.Duration,
+	}
+	if err := op.CreateWebDAVLock(l, now); err != nil {
+		if errs.Is(err, errs.ObjectLocked) {
+			return "", ErrLocked
+		}
+		return "", err
+	}
+	return l.Token, nil
+}
+
+func (m *dbLS) Refresh(now time.Time, token string, duration time.Duration) (LockDetails, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.held[token] {
+		return LockDetails{}, ErrLocked
+	}
+	l, err := op.RefreshWebDAVLock(token, duration, now)
+	if err != nil {
+		return LockDetails{}, err
+	}
+	if l == nil {
+		return LockDetails{}, ErrNoSuchLock
+	}
+	return LockDetails{
+		Root:      l.Root,
+		Duration:  l.Duration,
+		OwnerXML:  l.OwnerXML,
+		ZeroDepth: l.ZeroDepth,
+	}, nil
+}
+
+func (m *dbLS) Unlock(now time.Time, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.held[token] {
+		return ErrLocked
+	}
+	l, err := op.GetWebDAVLock(token, now)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return ErrNoSuchLock
+	}
+	_, err = op.DeleteWebDAVLock(token)
+	return err
+}
@@ -10,12 +10,15 @@ import (
 	"encoding/xml"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dongdio/OpenList/v4/consts"
+	"github.com/dongdio/OpenList/v4/internal/fs"
 	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/utility/errs"
 	"github.com/dongdio/OpenList/v4/utility/utils"
@@ -102,6 +105,84 @@ type DeadPropsHolder interface {
 	Patch([]Proppatch) ([]Propstat, error)
 }
 
+// dbDeadProps 是存储在数据库中的死属性，按资源的完整路径保存
+// 资源被重命名、移动、复制或删除时，op.FollowDeadProps 会同步处理其死属性
+type dbDeadProps string
+
+func (name dbDeadProps) DeadProps() (map[xml.Name]Property, error) {
+	dps, err := op.GetDeadProps(string(name))
+	if err != nil {
+		return nil, err
+	}
+	return toProperties(dps), nil
+}
+
+func (name dbDeadProps) Patch(patches []Proppatch) ([]Propstat, error) {
+	var set, remove []model.DeadProp
+	pstat := Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			pstat.Props = append(pstat.Props, Property{XMLName: p.XMLName})
+			dp := model.DeadProp{Space: p.XMLName.Space, Local: p.XMLName.Local}
+			if patch.Remove {
+				remove = append(remove, dp)
+				continue
+			}
+			dp.Lang, dp.InnerXML = p.Lang, string(p.InnerXML)
+			set = append(set, dp)
+		}
+	}
+	if err := op.PatchDeadProps(string(name), set, remove); err != nil {
+		return nil, err
+	}
+	return []Propstat{pstat}, nil
+}
+
+func toProperties(dps []model.DeadProp) map[xml.Name]Property {
+	props := make(map[xml.Name]Property, len(dps))
+	for _, dp := range dps {
+		pn := xml.Name{Space: dp.Space, Local: dp.Local}
+		props[pn] = Property{XMLName: pn, Lang: dp.Lang, InnerXML: []byte(dp.InnerXML)}
+	}
+	return props
+}
+
+// deadPropsKey 是 PROPFIND 预先加载的死属性在上下文中的键，值为 map[路径]属性
+type deadPropsKey struct{}
+
+// withDeadProps 一次性加载 root 及 depth 范围内子路径的死属性，避免 PROPFIND 为每个资源查询数据库
+// depth 为 1 时只加载 root 及其直接子项
+func withDeadProps(ctx context.Context, root string, depth int) (context.Context, error) {
+	var dps []model.DeadProp
+	var err error
+	if depth == 1 {
+		dps, err = op.GetDeadPropsWithChildren(root)
+	} else {
+		dps, err = op.GetDeadPropsUnder(root)
+	}
+	if err != nil {
+		return ctx, err
+	}
+	byPath := make(map[string][]model.DeadProp)
+	for _, dp := range dps {
+		byPath[dp.Path] = append(byPath[dp.Path], dp)
+	}
+	return context.WithValue(ctx, deadPropsKey{}, byPath), nil
+}
+
+// ctxDeadProps 返回上下文中 consts.PathKey 路径的死属性
+func ctxDeadProps(ctx context.Context) (map[xml.Name]Property, error) {
+	reqPath, ok := ctx.Value(consts.PathKey).(string)
+	if !ok {
+		return nil, nil
+	}
+	if byPath, ok := ctx.Value(deadPropsKey{}).(map[string][]model.DeadProp); ok {
+		return toProperties(byPath[utils.FixAndCleanPath(reqPath)]), nil
+	}
+	var dph DeadPropsHolder = dbDeadProps(reqPath)
+	return dph.DeadProps()
+}
+
 // liveProps contains all supported properties.
 var liveProps = map[xml.Name]struct {
 	// findFn implements the propfind function of this property. If nil,
@@ -165,8 +246,25 @@ var liveProps = map[xml.Name]struct {
 		findFn: findChecksums,
 		dir:    false,
 	},
+	// RFC 4331 配额属性，只在请求中指定时返回
+	quotaAvailableBytes: {
+		findFn: findQuotaAvailableBytes,
+		dir:    true,
+	},
+	quotaUsedBytes: {
+		findFn: findQuotaUsedBytes,
+		dir:    true,
+	},
 }
 
+var (
+	quotaAvailableBytes = xml.Name{Space: "DAV:", Local: "quota-available-bytes"}
+	quotaUsedBytes      = xml.Name{Space: "DAV:", Local: "quota-used-bytes"}
+)
+
+// errPropNotFound 由 findFn 返回，表示该资源没有这个属性
+var errPropNotFound = errs.New("property not found")
+
 // TODO(nigeltao) merge props and allprop?
 
 // Props returns the status of the properties named pnames for resource name.
@@ -185,14 +283,10 @@ func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name)
 	// }
 	isDir := fi.IsDir()
 
-	var deadProps map[xml.Name]Property
-	// ??? what is this for?
-	// if dph, ok := f.(DeadPropsHolder); ok {
-	//	deadProps, err = dph.DeadProps()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	// }
+	deadProps, err := ctxDeadProps(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	pstatOK := Propstat{Status: http.StatusOK}
 	pstatNotFound := Propstat{Status: http.StatusNotFound}
@@ -205,6 +299,12 @@ func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name)
 		// Otherwise, it must either be a live property or we don't know it.
 		if prop := liveProps[pn]; prop.findFn != nil && (prop.dir || !isDir) {
 			innerXML, err := prop.findFn(ctx, ls, fi.GetName(), fi)
+			if errs.Is(err, errPropNotFound) {
+				pstatNotFound.Props = append(pstatNotFound.Props, Property{
+					XMLName: pn,
+				})
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -234,14 +334,10 @@ func propnames(ctx context.Context, ls LockSystem, fi model.Obj) ([]xml.Name, er
 	// }
 	isDir := fi.IsDir()
 
-	var deadProps map[xml.Name]Property
-	// ??? what is this for?
-	// if dph, ok := f.(DeadPropsHolder); ok {
-	//	deadProps, err = dph.DeadProps()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	// }
+	deadProps, err := ctxDeadProps(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	pnames := make([]xml.Name, 0, len(liveProps)+len(deadProps))
 	for pn, prop := range liveProps {
@@ -268,6 +364,10 @@ func allprop(ctx context.Context, ls LockSystem, fi model.Obj, include []xml.Nam
 	if err != nil {
 		return nil, err
 	}
+	// RFC 4331 要求 allprop 不返回配额属性
+	pnames = slices.DeleteFunc(pnames, func(pn xml.Name) bool {
+		return pn == quotaAvailableBytes || pn == quotaUsedBytes
+	})
 	// Add names from include if they are not already covered in pnames.
 	nameset := make(map[xml.Name]bool)
 	for _, pn := range pnames {
@@ -314,38 +414,20 @@ loop:
 		return makePropstats(pstatForbidden, pstatFailedDep), nil
 	}
 
-	// ------------------------------------------------------------
-	// f, err := fs.OpenFile(ctx, name, os.O_RDWR, 0)
-	// if err != nil {
-	//	return nil, err
-	// }
-	// defer f.Close()
-	// if dph, ok := f.(DeadPropsHolder); ok {
-	//	ret, err := dph.Patch(patches)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//	// http://www.webdav.org/specs/rfc4918.html#ELEMENT_propstat says that
-	//	// "The contents of the prop XML element must only list the names of
-	//	// properties to which the result in the status element applies."
-	//	for _, pstat := range ret {
-	//		for i, p := range pstat.Props {
-	//			pstat.Props[i] = Property{XMLName: p.XMLName}
-	//		}
-	//	}
-	//	return ret, nil
-	// }
-	// ------------------------------------------------------------
-
-	// The file doesn't implement the optional DeadPropsHolder interface, so
-	// all patches are forbidden.
-	pstat := Propstat{Status: http.StatusForbidden}
-	for _, patch := range patches {
-		for _, p := range patch.Props {
-			pstat.Props = append(pstat.Props, Property{XMLName: p.XMLName})
+	var dph DeadPropsHolder = dbDeadProps(name)
+	ret, err := dph.Patch(patches)
+	if err != nil {
+		return nil, err
+	}
+	// http://www.webdav.org/specs/rfc4918.html#ELEMENT_propstat says that
+	// "The contents of the prop XML element must only list the names of
+	// properties to which the result in the status element applies."
+	for _, pstat := range ret {
+		for i, p := range pstat.Props {
+			pstat.Props[i] = Property{XMLName: p.XMLName}
 		}
 	}
-	return []Propstat{pstat}, nil
+	return ret, nil
 }
 
 func escapeXML(s string) string {
@@ -493,4 +575,33 @@ func findChecksums(ctx context.Context, ls LockSystem, name string, fi model.Obj
 		checksums += fmt.Sprintf("<checksum>%s:%s</checksum>", hashType.Name, hashValue)
 	}
 	return checksums, nil
+}
+
+// findQuota 返回 PROPFIND 请求中当前路径的配额，没有配额时返回 errPropNotFound
+func findQuota(ctx context.Context) (model.QuotaInfo, error) {
+	reqPath, _ := ctx.Value(consts.PathKey).(string)
+	q, err := fs.GetQuota(ctx, reqPath)
+	if err != nil {
+		return q, err
+	}
+	if q.Quota <= 0 {
+		return q, errPropNotFound
+	}
+	return q, nil
+}
+
+func findQuotaAvailableBytes(ctx context.Context, ls LockSystem, name string, fi model.Obj) (string, error) {
+	q, err := findQuota(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(q.Available(), 10), nil
+}
+
+func findQuotaUsedBytes(ctx context.Context, ls LockSystem, name string, fi model.Obj) (string, error) {
+	q, err := findQuota(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(q.Used, 10), nil
 }
\ No newline at end of file
@@ -24,6 +24,7 @@ import (
 	"github.com/dongdio/OpenList/v4/consts"
 	"github.com/dongdio/OpenList/v4/internal/fs"
 	"github.com/dongdio/OpenList/v4/internal/model"
+	"github.com/dongdio/OpenList/v4/internal/op"
 	"github.com/dongdio/OpenList/v4/server/common"
 	"github.com/dongdio/OpenList/v4/utility/net"
 	"github.com/dongdio/OpenList/v4/utility/stream"
@@ -198,6 +199,9 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 记录请求通过 confirmLocks 提交或创建的锁令牌，op 检查 WebDAV 锁时忽略这些锁
+	r = r.WithContext(context.WithValue(r.Context(), consts.WebDAVLockTokensKey, &heldLocks{tokens: make(map[string]bool)}))
+
 	// 创建缓冲响应写入器，用于延迟发送响应
 	brw := NewBufferedResponseWriter()
 	useBufferedWriter := true
@@ -275,35 +279,86 @@ func (h *Handler) writeError(w http.ResponseWriter, status int, err error) {
 	}
 }
 
-// lock 创建一个资源的锁
+// implicitLockDuration 是没有 If 头部的写请求所创建临时锁的超时时间
+// 临时锁在请求期间定期续期，进程意外退出时残留的临时锁也会很快过期
+const implicitLockDuration = 30 * time.Second
+
+// heldLocks 记录一个请求提交或创建的锁令牌，实现 op.WebDAVLockHolder
+type heldLocks struct {
+	mu     sync.Mutex
+	tokens map[string]bool
+}
+
+func (h *heldLocks) HoldsWebDAVLock(token string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.tokens[token]
+}
+
+// holdLocks 将令牌记录为请求持有的锁
+func holdLocks(r *http.Request, tokens ...string) {
+	held, ok := r.Context().Value(consts.WebDAVLockTokensKey).(*heldLocks)
+	if !ok {
+		return
+	}
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	for _, t := range tokens {
+		if t != "" {
+			held.tokens[t] = true
+		}
+	}
+}
+
+// lock 创建一个资源的临时锁
 // 用于在无显式锁头的情况下确保资源安全
 //
 // 参数:
 //   - now: 当前时间
 //   - root: 要锁定的资源路径
+//   - zeroDepth: 是否只锁定资源本身，删除和移动文件夹时需要锁定整个子树
 //
 // 返回:
 //   - string: 锁令牌
+//   - func(): 释放函数，停止续期并释放临时锁
 //   - int: HTTP状态码，成功时为0
 //   - error: 错误信息
-func (h *Handler) lock(now time.Time, root string) (token string, status int, err error) {
-	// 使用无限超时创建一个零深度锁
+func (h *Handler) lock(now time.Time, root string, zeroDepth bool) (token string, release func(), status int, err error) {
 	token, err = h.LockSystem.Create(now, LockDetails{
 		Root:      root,
-		Duration:  infiniteTimeout,
-		ZeroDepth: true,
+		Duration:  implicitLockDuration,
+		ZeroDepth: zeroDepth,
 	})
 
 	if err != nil {
 		if errs.Is(err, ErrLocked) {
 			// 资源已被锁定
-			return "", StatusLocked, err
+			return "", nil, StatusLocked, err
 		}
 		// 其他错误
-		return "", http.StatusInternalServerError, err
-	}
-
-	return token, 0, nil
+		return "", nil, http.StatusInternalServerError, err
+	}
+
+	// 请求可能超过临时锁的超时时间，在请求结束前定期续期
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(implicitLockDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if _, err := h.LockSystem.Refresh(time.Now(), token, implicitLockDuration); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return token, func() {
+		close(stop)
+		h.LockSystem.Unlock(time.Now(), token)
+	}, 0, nil
 }
 
 // confirmLocks 确认请求可以访问指定的资源
@@ -325,12 +380,15 @@ func (h *Handler) confirmLocks(r *http.Request, src, dst string) (release func()
 	if header == "" {
 		// 如果If头部为空，表示客户端未创建锁
 		// 但我们仍需检查资源是否被其他客户端锁定
-		// 为此创建临时锁并在请求结束时释放
-		now, srcToken, dstToken := time.Now(), "", ""
+		// 为此创建临时锁并在请求结束时释放，删除、复制和移动会改动整个子树，需要无限深度的锁
+		now := time.Now()
+		zeroDepth := r.Method != "DELETE" && r.Method != "COPY" && r.Method != "MOVE"
+		var srcToken, dstToken string
+		releaseSrc, releaseDst := func() {}, func() {}
 
 		// 如果提供了源路径，尝试锁定它
 		if src != "" {
-			srcToken, status, err = h.lock(now, src)
+			srcToken, releaseSrc, status, err = h.lock(now, src, zeroDepth)
 			if err != nil {
 				return nil, status, err
 			}
@@ -338,24 +396,19 @@ func (h *Handler) confirmLocks(r *http.Request, src, dst string) (release func()
 
 		// 如果提供了目标路径，尝试锁定它
 		if dst != "" {
-			dstToken, status, err = h.lock(now, dst)
+			dstToken, releaseDst, status, err = h.lock(now, dst, zeroDepth)
 			if err != nil {
 				// 如果目标锁定失败，释放源锁
-				if srcToken != "" {
-					h.LockSystem.Unlock(now, srcToken)
-				}
+				releaseSrc()
 				return nil, status, err
 			}
 		}
+		holdLocks(r, srcToken, dstToken)
 
 		// 返回释放函数，用于请求结束时释放临时锁
 		return func() {
-			if dstToken != "" {
-				h.LockSystem.Unlock(now, dstToken)
-			}
-			if srcToken != "" {
-				h.LockSystem.Unlock(now, srcToken)
-			}
+			releaseDst()
+			releaseSrc()
 		}, 0, nil
 	}
 
@@ -381,11 +434,15 @@ func (h *Handler) confirmLocks(r *http.Request, src, dst string) (release func()
 			if parsedURL.Host != r.Host {
 				continue
 			}
-			// 移除前缀并检查路径
+			// 移除前缀并检查路径，锁的根路径是拼接了用户基础路径的完整路径
 			lockSrc, status, err = h.stripPrefix(parsedURL.Path)
 			if err != nil {
 				return nil, status, err
 			}
+			lockSrc, _, status, err = getUserAndPath(r.Context(), lockSrc)
+			if err != nil {
+				return nil, status, err
+			}
 		}
 
 		// 尝试确认锁
@@ -398,7 +455,13 @@ func (h *Handler) confirmLocks(r *http.Request, src, dst string) (release func()
 			return nil, http.StatusInternalServerError, err
 		}
 
-		// 确认成功
+		// 确认成功，客户端提交的令牌视为请求持有的锁，
+		// 子路径上的锁需要客户端同时提交它们的令牌，否则由 op 拒绝
+		for _, c := range item.conditions {
+			if !c.Not {
+				holdLocks(r, c.Token)
+			}
+		}
 		return release, 0, nil
 	}
 
@@ -491,18 +554,30 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request) (status
 		return pathStatus, err
 	}
 
-	// 根据资源类型设置允许的HTTP方法
-	allow := "OPTIONS, LOCK, PUT, MKCOL"
+	// 根据资源类型和存储支持的操作设置允许的HTTP方法
+	caps := fs.GetCapabilities(ctx, reqPath)
+	methods := []string{"OPTIONS", "LOCK"}
 
 	// 获取文件信息
 	fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{})
-	if err == nil {
-		if fi.IsDir() {
-			allow = "OPTIONS, LOCK, DELETE, PROPPATCH, COPY, MOVE, UNLOCK, PROPFIND"
-		} else {
-			allow = "OPTIONS, LOCK, GET, HEAD, POST, DELETE, PROPPATCH, COPY, MOVE, UNLOCK, PROPFIND, PUT"
+	if err != nil {
+		// 资源不存在，只能创建
+		methods = appendIf(methods, caps.Put, "PUT")
+		methods = appendIf(methods, caps.Mkdir, "MKCOL")
+	} else {
+		if !fi.IsDir() {
+			methods = append(methods, "GET", "HEAD", "POST")
+		}
+		methods = appendIf(methods, caps.Remove, "DELETE")
+		methods = append(methods, "PROPPATCH")
+		methods = appendIf(methods, caps.Copy, "COPY")
+		methods = appendIf(methods, caps.Move || caps.Rename, "MOVE")
+		methods = append(methods, "UNLOCK", "PROPFIND")
+		if !fi.IsDir() {
+			methods = appendIf(methods, caps.Put, "PUT")
 		}
 	}
+	allow := strings.Join(methods, ", ")
 
 	// 设置响应头
 	w.Header().Set("Allow", allow)
@@ -514,6 +589,14 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request) (status
 	return 0, nil
 }
 
+// appendIf 条件成立时追加方法
+func appendIf(methods []string, ok bool, method string) []string {
+	if ok {
+		return append(methods, method)
+	}
+	return methods
+}
+
 func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request) (status int, err error) {
 	reqPath, status, err := h.stripPrefix(r.URL.Path)
 	if err != nil {
@@ -604,12 +687,6 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) (status i
 		return status, errs.Wrap(err, "路径前缀处理失败")
 	}
 
-	release, status, err := h.confirmLocks(r, reqPath, "")
-	if err != nil {
-		return status, errs.Wrap(err, "锁确认失败")
-	}
-	defer release()
-
 	ctx := r.Context()
 	user, ok := ctx.Value(consts.UserKey).(*model.User)
 	if !ok || user == nil {
@@ -621,6 +698,12 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) (status i
 		return http.StatusForbidden, errs.Wrap(err, "无法访问请求路径")
 	}
 
+	release, status, err := h.confirmLocks(r, reqPath, "")
+	if err != nil {
+		return status, errs.Wrap(err, "锁确认失败")
+	}
+	defer release()
+
 	// 检查文件是否存在
 	_, err = fs.Get(ctx, reqPath, &fs.GetArgs{})
 	if err != nil {
@@ -649,6 +732,11 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) (status i
 	// 清除父目录缓存
 	clearFileInfoCache(path.Dir(reqPath))
 
+	// 同一存储内的删除已由 op 事件处理，这里确保跨存储等情况下死属性也被删除
+	if err = op.DeleteDeadProps(reqPath); err != nil {
+		utils.Log.Errorf("failed delete dead props of %s: %+v", reqPath, err)
+	}
+
 	return http.StatusNoContent, nil
 }
 
@@ -671,12 +759,6 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request) (status int,
 		return http.StatusMethodNotAllowed, errs.New("空路径不允许PUT操作")
 	}
 
-	release, status, err := h.confirmLocks(r, reqPath, "")
-	if err != nil {
-		return status, errs.Wrap(err, "锁确认失败")
-	}
-	defer release()
-
 	ctx := r.Context()
 	user, ok := ctx.Value(consts.UserKey).(*model.User)
 	if !ok || user == nil {
@@ -688,6 +770,15 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request) (status int,
 		return http.StatusForbidden, errs.Wrap(err, "无法访问请求路径")
 	}
 
+	release, status, err := h.confirmLocks(r, reqPath, "")
+	if err != nil {
+		return status, errs.Wrap(err, "锁确认失败")
+	}
+	defer release()
+	if err = fs.CheckQuota(ctx, reqPath, r.ContentLength); err != nil {
+		return http.StatusInsufficientStorage, err
+	}
+
 	obj := model.Object{
 		Name:     path.Base(reqPath),
 		Size:     r.ContentLength,
@@ -743,12 +834,6 @@ func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request) (status in
 		return status, errs.Wrap(err, "路径前缀处理失败")
 	}
 
-	release, status, err := h.confirmLocks(r, reqPath, "")
-	if err != nil {
-		return status, errs.Wrap(err, "锁确认失败")
-	}
-	defer release()
-
 	ctx := r.Context()
 	user, ok := ctx.Value(consts.UserKey).(*model.User)
 	if !ok || user == nil {
@@ -760,6 +845,12 @@ func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request) (status in
 		return http.StatusForbidden, errs.Wrap(err, "无法访问请求路径")
 	}
 
+	release, status, err := h.confirmLocks(r, reqPath, "")
+	if err != nil {
+		return status, errs.Wrap(err, "锁确认失败")
+	}
+	defer release()
+
 	// RFC 4918 9.3.1: MKCOL 请求不应包含请求体
 	if r.ContentLength > 0 {
 		return http.StatusUnsupportedMediaType, errs.New("MKCOL 请求不应包含请求体")
@@ -868,7 +959,15 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request) (status
 		}
 
 		// 执行复制操作
-		return copyFiles(ctx, src, dst, r.Header.Get("Overwrite") != "F")
+		status, err = copyFiles(ctx, src, dst, r.Header.Get("Overwrite") != "F")
+		if err == nil && status < http.StatusBadRequest {
+			// 跨存储复制不会产生 op 事件，死属性在这里复制
+			if err = op.CopyDeadProps(src, dst); err != nil {
+				utils.Log.Errorf("failed copy dead props of %s: %+v", src, err)
+			}
+			return status, nil
+		}
+		return status, err
 	}
 
 	// 对于MOVE操作，需要锁定源和目标路径
@@ -886,7 +985,15 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request) (status
 	}
 
 	// 执行移动操作
-	return moveFiles(ctx, src, dst, r.Header.Get("Overwrite") == "T")
+	status, err = moveFiles(ctx, src, dst, r.Header.Get("Overwrite") == "T")
+	if err == nil && status < http.StatusBadRequest {
+		// 跨存储移动不会产生 op 事件，死属性在这里移动
+		if err = op.MoveDeadProps(src, dst); err != nil {
+			utils.Log.Errorf("failed move dead props of %s: %+v", src, err)
+		}
+		return status, nil
+	}
+	return status, err
 }
 
 func (h *Handler) handleLock(w http.ResponseWriter, r *http.Request) (retStatus int, retErr error) {
@@ -1051,6 +1158,13 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 		return status, errs.Wrap(err, "解析PROPFIND请求体失败")
 	}
 
+	// 遍历子目录时一次性加载死属性
+	if depth != 0 && fi.IsDir() {
+		if ctx, err = withDeadProps(ctx, reqPath, depth); err != nil {
+			return http.StatusInternalServerError, errs.Wrap(err, "获取死属性失败")
+		}
+	}
+
 	// 创建多状态响应写入器
 	mw := multistatusWriter{w: w}
 
@@ -1061,6 +1175,8 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 		}
 
 		var pstats []Propstat
+		// 配额属性需要知道当前路径
+		ctx := context.WithValue(ctx, consts.PathKey, reqPath)
 		if pf.Propname != nil {
 			// 处理属性名请求
 			pnames, err := propnames(ctx, h.LockSystem, info)
@@ -1087,7 +1203,7 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 		}
 
 		// 构建响应路径
-		href := path.Join(h.Prefix, strings.TrimPrefix(reqPath, user.BasePath))
+		href := path.Join(h.Prefix, strings.TrimPrefix(reqPath, user.GetBasePath()))
 		if href != "/" && info.IsDir() {
 			href += "/"
 		}
@@ -1117,13 +1233,6 @@ func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request) (statu
 		return status, errs.Wrap(err, "路径前缀处理失败")
 	}
 
-	// 确认锁定状态
-	release, status, err := h.confirmLocks(r, reqPath, "")
-	if err != nil {
-		return status, errs.Wrap(err, "锁确认失败")
-	}
-	defer release()
-
 	// 获取用户信息和处理路径
 	ctx := r.Context()
 	var pathStatus int
@@ -1132,6 +1241,13 @@ func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request) (statu
 		return pathStatus, err
 	}
 
+	// 确认锁定状态
+	release, status, err := h.confirmLocks(r, reqPath, "")
+	if err != nil {
+		return status, errs.Wrap(err, "锁确认失败")
+	}
+	defer release()
+
 	// 检查文件是否存在
 	var fileStatus int
 	_, fileStatus, err = getFileInfo(ctx, reqPath)
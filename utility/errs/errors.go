@@ -15,11 +15,13 @@ var (
 
 	MoveBetweenTwoStorages = New("can't move files between two storages, try to copy")
 	UploadNotSupported     = New("upload not supported")
+	QuotaExceeded          = New("quota exceeded")
 
 	MetaNotFound     = New("meta not found")
 	StorageNotFound  = New("storage not found")
 	StreamIncomplete = New("upload/download stream incomplete, possible network issue")
 	StreamPeekFail   = New("StreamPeekFail")
+	VerifyFailed     = New("transferred file doesn't match the source")
 
 	UnknownArchiveFormat      = New("unknown archive format")
 	WrongArchivePassword      = New("wrong archive password")
@@ -30,6 +32,8 @@ var (
 	ObjectNotFound = New("object not found")
 	NotFolder      = New("not a folder")
 	NotFile        = New("not a file")
+	ObjectExists   = New("object already exists")
+	ObjectLocked   = New("object is locked by a WebDAV client")
 )
 
 var (
@@ -46,6 +50,29 @@ var (
 	EmptyPassword      = New("password is empty")
 	WrongPassword      = New("password is incorrect")
 	DeleteAdminOrGuest = New("cannot delete admin or guest")
+	InvalidAPIToken    = New("api token is invalid")
+	APITokenExpired    = New("api token is expired")
+	InvalidS3Key       = New("s3 key is invalid or disabled")
+	InvalidSession     = New("session is invalid or revoked")
+
+	TooManyLoginAttempts = New("too many unsuccessful sign-in attempts, try again later")
+	TwoFactorRequired    = New("2FA is required, enable 2FA or a passkey first")
+	APITokenRequired     = New("2FA is required for the user, log in with an api token instead of the password")
+	InvalidRecoveryCode  = New("recovery code is invalid or used")
+)
+
+var (
+	ShareNotFound           = New("share is not found or disabled")
+	ShareExpired            = New("share is expired")
+	WrongSharePassword      = New("share password is incorrect")
+	ShareDownloadsExhausted = New("share reached its max downloads")
+)
+
+var (
+	FileRequestNotFound      = New("file request is not found or disabled")
+	FileRequestExpired       = New("file request is expired")
+	WrongFileRequestPassword = New("file request password is incorrect")
+	FileRequestQuotaExceeded = New("file request quota exceeded")
 )
 
 // NewErr wrap constant error with an extra message